@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/simplechain-org/go-simplechain/accounts"
 	"github.com/simplechain-org/go-simplechain/common"
@@ -16,6 +17,27 @@ const (
 	PBFT
 )
 
+func (c ConsensusType) String() string {
+	switch c {
+	case DPOS:
+		return "dpos"
+	case RAFT:
+		return "raft"
+	case PBFT:
+		return "pbft"
+	}
+	return fmt.Sprintf("unknown(%d)", c)
+}
+
+func parseConsensus(name string) (ConsensusType, error) {
+	for _, c := range []ConsensusType{DPOS, RAFT, PBFT} {
+		if c.String() == name {
+			return c, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid consensus %q, want one of dpos, raft, pbft", name)
+}
+
 var dposCommand = cli.Command{
 	Name:  "dpos",
 	Usage: "dpos consenses",
@@ -90,6 +112,34 @@ var pbftCommand = cli.Command{
 	},
 }
 
+var upCommand = cli.Command{
+	Name:  "up",
+	Usage: "initialize and start a local devnet of sipe nodes",
+	Flags: []cli.Flag{
+		engineFlag, nFlag, dirFlag, sipeFlag, genesisFlag, basePortFlag, baseRPCPortFlag, baseRaftPortFlag,
+		anchorsFlag, crossBinFlag, timeoutFlag,
+	},
+	Action: up,
+}
+
+var downCommand = cli.Command{
+	Name:  "down",
+	Usage: "stop the nodes of a local devnet",
+	Flags: []cli.Flag{
+		dirFlag, purgeFlag, timeoutFlag,
+	},
+	Action: down,
+}
+
+var statusCommand = cli.Command{
+	Name:  "status",
+	Usage: "report the nodes and chain heads of a local devnet",
+	Flags: []cli.Flag{
+		dirFlag,
+	},
+	Action: status,
+}
+
 var (
 	nFlag = cli.UintFlag{
 		Name:  "n",
@@ -131,4 +181,62 @@ var (
 		Usage: "genesis file path",
 		Value: "genesis_raft.json",
 	}
+
+	engineFlag = cli.StringFlag{
+		Name:  "engine",
+		Usage: "consensus engine of the devnet (dpos, raft or pbft)",
+		Value: "pbft",
+	}
+
+	dirFlag = cli.StringFlag{
+		Name:  "dir",
+		Usage: "devnet data dir",
+		Value: "devnet",
+	}
+
+	sipeFlag = cli.StringFlag{
+		Name:  "sipe",
+		Usage: "sipe executable to run the nodes",
+		Value: "sipe",
+	}
+
+	basePortFlag = cli.IntFlag{
+		Name:  "baseport",
+		Usage: "first p2p port of the devnet nodes",
+		Value: 21000,
+	}
+
+	baseRPCPortFlag = cli.IntFlag{
+		Name:  "baserpcport",
+		Usage: "first http rpc port of the devnet nodes",
+		Value: 8545,
+	}
+
+	baseRaftPortFlag = cli.IntFlag{
+		Name:  "baseraftport",
+		Usage: "first raft port of the devnet nodes",
+		Value: 50400,
+	}
+
+	anchorsFlag = cli.UintFlag{
+		Name:  "anchors",
+		Usage: "number of anchors crossing the devnet with a paired main chain (0 = no cross chain)",
+	}
+
+	crossBinFlag = cli.StringFlag{
+		Name:  "cross.bin",
+		Usage: "cross contract bytecode file",
+		Value: "../../cross/contract/crossdemo/crossDemo.bin",
+	}
+
+	timeoutFlag = cli.DurationFlag{
+		Name:  "timeout",
+		Usage: "maximum time to wait for the nodes",
+		Value: 2 * time.Minute,
+	}
+
+	purgeFlag = cli.BoolFlag{
+		Name:  "purge",
+		Usage: "remove the devnet data dir after stopping the nodes",
+	}
 )
@@ -0,0 +1,682 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/simplechain-org/go-simplechain/accounts"
+	"github.com/simplechain-org/go-simplechain/accounts/abi"
+	"github.com/simplechain-org/go-simplechain/common"
+	"github.com/simplechain-org/go-simplechain/common/hexutil"
+	"github.com/simplechain-org/go-simplechain/core"
+	"github.com/simplechain-org/go-simplechain/core/types"
+	"github.com/simplechain-org/go-simplechain/crypto"
+	"github.com/simplechain-org/go-simplechain/ethclient"
+	"github.com/simplechain-org/go-simplechain/p2p/enode"
+	"github.com/simplechain-org/go-simplechain/params"
+	"github.com/simplechain-org/go-simplechain/rpc"
+
+	"gopkg.in/urfave/cli.v1"
+)
+
+const (
+	DevnetFile      = "devnet.json"
+	GenesisFile     = "genesis.json"
+	GenesisMainFile = "genesis_main.json"
+	PasswordFile    = "password"
+	LogFile         = "sipe.log"
+
+	mainChainID   = 512       // chain id of the main chain paired by anchors
+	crossGasLimit = 100000000 // block gas limit fitting the cross contract deployment
+)
+
+// devnet is the set of sipe nodes started by up, saved in the devnet dir for
+// down and status.
+type devnet struct {
+	Engine       string          `json:"engine"`
+	Nodes        []*devnetNode   `json:"nodes"`
+	MainContract *common.Address `json:"mainContract,omitempty"`
+	SubContract  *common.Address `json:"subContract,omitempty"`
+}
+
+// devnetNode is a sipe child process of a devnet.
+type devnetNode struct {
+	Name       string `json:"name"`
+	Role       string `json:"role"`
+	Datadir    string `json:"datadir"`
+	Pid        int    `json:"pid"`
+	Port       int    `json:"port"`
+	RPCPort    int    `json:"rpcport,omitempty"`    // main chain http port
+	SubRPCPort int    `json:"subrpcport,omitempty"` // subchain http port
+
+	key     *ecdsa.PrivateKey
+	keyFile string   // keystore file of the node account
+	enode   string   // enode url the other nodes dial
+	statics []string // enode urls the node dials
+	apis    string   // rpc apis to expose
+	args    []string // consensus and role specific flags
+}
+
+// up initializes and starts a devnet of the given engine, optionally crossed with
+// a main chain by anchors, and waits until all its chains are producing blocks.
+func up(ctx *cli.Context) (e error) {
+	consensus, err := parseConsensus(ctx.String(engineFlag.Name))
+	if err != nil {
+		return err
+	}
+	n := int(ctx.Uint(nFlag.Name))
+	if n == 0 {
+		return errors.New("devnet requires at least one node")
+	}
+	template := ctx.String(genesisFlag.Name)
+	if !ctx.IsSet(genesisFlag.Name) {
+		template = genesisFiles[consensus]
+	}
+	anchors := int(ctx.Uint(anchorsFlag.Name))
+
+	var crossBin []byte
+	if anchors > 0 {
+		hexBin, err := ioutil.ReadFile(ctx.String(crossBinFlag.Name))
+		if err != nil {
+			return fmt.Errorf("read cross contract failed, %s", err.Error())
+		}
+		if crossBin, err = hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(string(hexBin)), "0x")); err != nil {
+			return fmt.Errorf("decode cross contract failed, %s", err.Error())
+		}
+	}
+
+	var (
+		dir      = ctx.String(dirFlag.Name)
+		sipe     = ctx.String(sipeFlag.Name)
+		deadline = time.Now().Add(ctx.Duration(timeoutFlag.Name))
+		port     = ctx.Int(basePortFlag.Name)
+		rpcPort  = ctx.Int(baseRPCPortFlag.Name)
+		raftPort = ctx.Int(baseRaftPortFlag.Name)
+	)
+	if err := mkdir(dir); err != nil {
+		return err
+	}
+
+	var (
+		net      = &devnet{Engine: consensus.String()}
+		launched bool
+	)
+	defer func() {
+		if e == nil {
+			return
+		}
+		if !launched {
+			os.RemoveAll(dir)
+			return
+		}
+		net.stop(10 * time.Second)
+		net.save(dir)
+		e = fmt.Errorf("%s, node logs are kept in %s", e.Error(), dir)
+	}()
+
+	// Generate the accounts of the devnet nodes, the main chain sealer and the anchors
+	keys := make([]*ecdsa.PrivateKey, n)
+	if anchors > 0 {
+		keys = make([]*ecdsa.PrivateKey, n+1+anchors)
+	}
+	for i := range keys {
+		if keys[i], err = crypto.GenerateKey(); err != nil {
+			return err
+		}
+	}
+	accs, err := writeKeystore(keys, dir)
+	if err != nil {
+		return err
+	}
+
+	genesis, err := makeGenesis(consensus, accs[:n], template)
+	if err != nil {
+		return err
+	}
+	fundGenesis(genesis, accs)
+	if consensus == DPOS {
+		genesis.Timestamp = uint64(time.Now().Unix())
+		genesis.Config.DPoS.GenesisTimestamp = genesis.Timestamp
+	}
+	if anchors > 0 && genesis.GasLimit < crossGasLimit {
+		genesis.GasLimit = crossGasLimit
+	}
+	if err := writeGenesisFile(dir, GenesisFile, genesis); err != nil {
+		return err
+	}
+
+	newNode := func(name, role string, index int) *devnetNode {
+		node := &devnetNode{
+			Name:    name,
+			Role:    role,
+			Datadir: filepath.Join(dir, name),
+			Port:    port,
+			apis:    "eth,net,web3,txpool,admin",
+			key:     keys[index],
+			keyFile: filepath.Join(dir, KeyStore, "key"+strconv.Itoa(index+1)),
+		}
+		port++
+		net.Nodes = append(net.Nodes, node)
+		return node
+	}
+	nextRPCPort := func() int {
+		rpcPort++
+		return rpcPort - 1
+	}
+
+	// Configure the nodes sealing the devnet chain
+	var (
+		subs   = make([]*devnetNode, n)
+		enodes = make([]string, n)
+	)
+	for i := range subs {
+		node := newNode(fmt.Sprintf("%s%d", consensus, i+1), "subchain", i)
+		node.SubRPCPort = nextRPCPort()
+
+		var raft int
+		switch consensus {
+		case PBFT:
+			node.args = []string{"--mine"}
+		case RAFT:
+			raft = raftPort + i
+			node.apis += ",raft"
+			node.args = []string{"--raft", "--raftport", strconv.Itoa(raft), "--raftheartbeat", "1000"}
+		case DPOS:
+			node.args = append(node.unlockArgs(accs[i].Address), "--mine")
+		}
+		node.args = append(node.args, "--miner.gastarget", strconv.FormatUint(genesis.GasLimit, 10))
+		node.enode = enode.NewV4Hostname(&node.key.PublicKey, "127.0.0.1", node.Port, 0, raft).String()
+
+		subs[i], enodes[i] = node, node.enode
+	}
+	for _, node := range subs {
+		node.statics = enodes
+	}
+
+	// Configure the main chain and the anchors crossing it with the devnet chain
+	var (
+		mainNode       *devnetNode
+		mainGenesis    *core.Genesis
+		anchorNodes    []*devnetNode
+		anchorAccounts []common.Address
+	)
+	if anchors > 0 {
+		mainNode = newNode("main", "mainchain", n)
+		mainNode.RPCPort = nextRPCPort()
+		mainNode.args = append(mainNode.unlockArgs(accs[n].Address), "--mine", "--miner.gastarget", strconv.Itoa(crossGasLimit))
+		mainNode.enode = enode.NewV4Hostname(&mainNode.key.PublicKey, "127.0.0.1", mainNode.Port, 0, 0).String()
+
+		mainGenesis = makeMainGenesis(accs[n].Address, accs)
+		if err := writeGenesisFile(dir, GenesisMainFile, mainGenesis); err != nil {
+			return err
+		}
+		for i := 0; i < anchors; i++ {
+			node := newNode(fmt.Sprintf("anchor%d", i+1), "anchor", n+1+i)
+			node.RPCPort, node.SubRPCPort = nextRPCPort(), nextRPCPort()
+			node.apis += ",cross"
+			node.statics = append([]string{mainNode.enode}, enodes...)
+
+			// Raft blocks only reach cluster members, anchors join as learners
+			var raft int
+			if consensus == RAFT {
+				raft = raftPort + n + i
+				node.args = []string{"--raft", "--raftport", strconv.Itoa(raft)}
+			}
+			node.enode = enode.NewV4Hostname(&node.key.PublicKey, "127.0.0.1", node.Port, 0, raft).String()
+
+			anchorNodes = append(anchorNodes, node)
+			anchorAccounts = append(anchorAccounts, accs[n+1+i].Address)
+		}
+	}
+	if err := net.save(dir); err != nil {
+		return err
+	}
+
+	// Initialize every node and start all but the anchors, which need the cross contracts
+	launched = true
+	for _, node := range net.Nodes {
+		files := []string{filepath.Join(dir, GenesisFile)}
+		switch node.Role {
+		case "mainchain":
+			files = []string{filepath.Join(dir, GenesisMainFile)}
+		case "anchor":
+			files = []string{filepath.Join(dir, GenesisMainFile), filepath.Join(dir, GenesisFile)}
+		}
+		if err := node.init(sipe, files...); err != nil {
+			return err
+		}
+	}
+	for _, node := range net.Nodes {
+		if node.Role == "anchor" {
+			continue
+		}
+		if err := node.start(sipe); err != nil {
+			return err
+		}
+		if err := net.save(dir); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("Waiting for the first blocks of %d %s nodes\n", n, consensus)
+	for _, node := range net.Nodes {
+		if node.Role == "anchor" {
+			continue
+		}
+		if err := node.wait(deadline); err != nil {
+			return err
+		}
+	}
+
+	// Deploy and register the cross contracts on both chains, then start the anchors
+	if anchors > 0 {
+		fmt.Printf("Deploying cross contracts for %d anchors\n", anchors)
+
+		var (
+			confirms   = uint8(anchors/2 + 1)
+			mainSigner = types.MakeSigner(mainGenesis.Config)
+			subSigner  = types.MakeSigner(genesis.Config)
+		)
+		mainContract, err := deployCross(mainNode.endpoint(mainNode.RPCPort), mainNode.key, mainSigner, crossBin,
+			genesis.Config.ChainID, confirms, anchorAccounts, deadline)
+		if err != nil {
+			return fmt.Errorf("deploy main chain cross contract failed, %s", err.Error())
+		}
+		subContract, err := deployCross(subs[0].endpoint(subs[0].SubRPCPort), subs[0].key, subSigner, crossBin,
+			mainGenesis.Config.ChainID, confirms, anchorAccounts, deadline)
+		if err != nil {
+			return fmt.Errorf("deploy subchain cross contract failed, %s", err.Error())
+		}
+		net.MainContract, net.SubContract = &mainContract, &subContract
+
+		for i, node := range anchorNodes {
+			if consensus == RAFT {
+				var raftID uint16
+				if err := rpcCall(subs[0].endpoint(subs[0].SubRPCPort), &raftID, "raft_addLearner", node.enode); err != nil {
+					return fmt.Errorf("add raft learner %s failed, %s", node.Name, err.Error())
+				}
+				node.args = append(node.args, "--raftjoinexisting", strconv.Itoa(int(raftID)))
+			}
+			node.args = append(node.args, node.unlockArgs(anchorAccounts[i])...)
+			node.args = append(node.args,
+				"--anchor.signer", anchorAccounts[i].Hex(),
+				"--anchor.confirmdepth", "1",
+				"--contract.main", mainContract.Hex(),
+				"--contract.sub", subContract.Hex(),
+			)
+			if err := node.start(sipe); err != nil {
+				return err
+			}
+			if err := net.save(dir); err != nil {
+				return err
+			}
+		}
+		for _, node := range anchorNodes {
+			if err := node.wait(deadline); err != nil {
+				return err
+			}
+		}
+	}
+	if err := net.save(dir); err != nil {
+		return err
+	}
+
+	fmt.Printf("Devnet is up in %s\n", dir)
+	net.report(os.Stdout, false)
+	return nil
+}
+
+// down stops the nodes of a devnet.
+func down(ctx *cli.Context) error {
+	dir := ctx.String(dirFlag.Name)
+
+	net, err := loadDevnet(dir)
+	if err != nil {
+		return err
+	}
+	net.stop(ctx.Duration(timeoutFlag.Name))
+
+	if ctx.Bool(purgeFlag.Name) {
+		return os.RemoveAll(dir)
+	}
+	return net.save(dir)
+}
+
+// status reports the nodes of a devnet with their chain heads.
+func status(ctx *cli.Context) error {
+	net, err := loadDevnet(ctx.String(dirFlag.Name))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Engine: %s\n", net.Engine)
+	if net.MainContract != nil {
+		fmt.Printf("Main contract: %s\n", net.MainContract.Hex())
+		fmt.Printf("Sub contract:  %s\n", net.SubContract.Hex())
+	}
+	net.report(os.Stdout, true)
+	return nil
+}
+
+func loadDevnet(dir string) (*devnet, error) {
+	b, err := ioutil.ReadFile(filepath.Join(dir, DevnetFile))
+	if err != nil {
+		return nil, fmt.Errorf("read devnet file failed, %s", err.Error())
+	}
+	net := new(devnet)
+	if err := json.Unmarshal(b, net); err != nil {
+		return nil, fmt.Errorf("unmarshal devnet file failed, %s", err.Error())
+	}
+	return net, nil
+}
+
+func (net *devnet) save(dir string) error {
+	b, err := json.MarshalIndent(net, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dir, DevnetFile), b, 0600)
+}
+
+// stop interrupts all running nodes, killing those still alive after timeout.
+func (net *devnet) stop(timeout time.Duration) {
+	for _, node := range net.Nodes {
+		if node.alive() {
+			node.process().Signal(os.Interrupt)
+		}
+	}
+	deadline := time.Now().Add(timeout)
+	for _, node := range net.Nodes {
+		for node.alive() && time.Now().Before(deadline) {
+			time.Sleep(100 * time.Millisecond)
+		}
+		if node.alive() {
+			fmt.Printf("Killing unresponsive node %s (pid %d)\n", node.Name, node.Pid)
+			node.process().Kill()
+		}
+		node.Pid = 0
+	}
+}
+
+// report prints the rpc endpoints of the nodes, with their chain heads and peer
+// counts if heads is set.
+func (net *devnet) report(w *os.File, heads bool) {
+	for _, node := range net.Nodes {
+		state := "running"
+		if !node.alive() {
+			state = "stopped"
+		}
+		for _, chain := range []struct {
+			name string
+			port int
+		}{{"main", node.RPCPort}, {"sub", node.SubRPCPort}} {
+			if chain.port == 0 {
+				continue
+			}
+			endpoint := node.endpoint(chain.port)
+			if !heads {
+				fmt.Fprintf(w, "  %-10s %-4s %s\n", node.Name, chain.name, endpoint)
+				continue
+			}
+			number, peers := "-", "-"
+			if state == "running" {
+				if n, p, err := chainHead(endpoint); err == nil {
+					number, peers = strconv.FormatUint(n, 10), strconv.FormatUint(p, 10)
+				}
+			}
+			fmt.Fprintf(w, "  %-10s %-8s pid=%-7d %-4s %s block=%s peers=%s\n", node.Name, state, node.Pid, chain.name, endpoint, number, peers)
+		}
+	}
+}
+
+func (node *devnetNode) endpoint(port int) string {
+	return "http://127.0.0.1:" + strconv.Itoa(port)
+}
+
+func (node *devnetNode) unlockArgs(account common.Address) []string {
+	return []string{
+		"--etherbase", account.Hex(),
+		"--unlock", account.Hex(),
+		"--password", filepath.Join(node.Datadir, PasswordFile),
+		"--allow-insecure-unlock",
+	}
+}
+
+// init lays out the node keys and static nodes in the data dir of the node and
+// writes the genesis blocks of its role.
+func (node *devnetNode) init(sipe string, genesis ...string) error {
+	if err := os.MkdirAll(filepath.Join(node.Datadir, "sipe"), 0700); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Join(node.Datadir, "keystore"), 0700); err != nil {
+		return err
+	}
+	key, err := ioutil.ReadFile(node.keyFile)
+	if err != nil {
+		return err
+	}
+	if err := writeFile(filepath.Join(node.Datadir, "keystore"), filepath.Base(node.keyFile), key); err != nil {
+		return err
+	}
+	if err := writeFile(filepath.Join(node.Datadir, "sipe"), KeyFile, []byte(hex.EncodeToString(crypto.FromECDSA(node.key)))); err != nil {
+		return err
+	}
+	if err := writeFile(node.Datadir, PasswordFile, nil); err != nil {
+		return err
+	}
+	if len(node.statics) > 0 {
+		if err := writeEnode(node.statics, node.Datadir); err != nil {
+			return err
+		}
+	}
+
+	args := append([]string{"--datadir", node.Datadir, "--role", node.Role, "init"}, genesis...)
+	if out, err := exec.Command(sipe, args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("init %s failed, %s %s", node.Name, err.Error(), bytes.TrimSpace(out))
+	}
+	return nil
+}
+
+// start runs the node as a child process logging into its data dir.
+func (node *devnetNode) start(sipe string) error {
+	logFile, err := os.Create(filepath.Join(node.Datadir, LogFile))
+	if err != nil {
+		return err
+	}
+	defer logFile.Close()
+
+	args := []string{
+		"--datadir", node.Datadir,
+		"--role", node.Role,
+		"--port", strconv.Itoa(node.Port),
+		"--nodiscover", "--ipcdisable", "--nousb",
+		"--syncmode", "full",
+	}
+	if node.RPCPort != 0 {
+		args = append(args, "--rpc", "--rpcaddr", "127.0.0.1", "--rpcport", strconv.Itoa(node.RPCPort), "--rpcapi", node.apis)
+	}
+	if node.SubRPCPort != 0 {
+		args = append(args, "--sub.rpc", "--sub.rpcaddr", "127.0.0.1", "--sub.rpcport", strconv.Itoa(node.SubRPCPort), "--sub.rpcapi", node.apis)
+	}
+	cmd := exec.Command(sipe, append(args, node.args...)...)
+	cmd.Stdout, cmd.Stderr = logFile, logFile
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("start %s failed, %s", node.Name, err.Error())
+	}
+	node.Pid = cmd.Process.Pid
+
+	// Reap the node if it exits while up is still running
+	go cmd.Wait()
+	return nil
+}
+
+// wait blocks until every chain of the node is past its genesis block.
+func (node *devnetNode) wait(deadline time.Time) error {
+	for _, port := range []int{node.RPCPort, node.SubRPCPort} {
+		if port == 0 {
+			continue
+		}
+		for {
+			if number, _, err := chainHead(node.endpoint(port)); err == nil && number > 0 {
+				break
+			}
+			if !node.alive() {
+				return fmt.Errorf("%s exited, see %s", node.Name, filepath.Join(node.Datadir, LogFile))
+			}
+			if time.Now().After(deadline) {
+				return fmt.Errorf("%s has no blocks at %s", node.Name, node.endpoint(port))
+			}
+			time.Sleep(500 * time.Millisecond)
+		}
+	}
+	return nil
+}
+
+func (node *devnetNode) process() *os.Process {
+	process, _ := os.FindProcess(node.Pid)
+	return process
+}
+
+func (node *devnetNode) alive() bool {
+	if node.Pid == 0 {
+		return false
+	}
+	process, err := os.FindProcess(node.Pid)
+	if err != nil {
+		return false
+	}
+	return process.Signal(syscall.Signal(0)) == nil
+}
+
+// chainHead retrieves the head block number and peer count of a chain endpoint.
+func chainHead(endpoint string) (uint64, uint64, error) {
+	var number, peers hexutil.Uint64
+	if err := rpcCall(endpoint, &number, "eth_blockNumber"); err != nil {
+		return 0, 0, err
+	}
+	if err := rpcCall(endpoint, &peers, "net_peerCount"); err != nil {
+		return 0, 0, err
+	}
+	return uint64(number), uint64(peers), nil
+}
+
+func rpcCall(endpoint string, result interface{}, method string, args ...interface{}) error {
+	client, err := rpc.Dial(endpoint)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return client.CallContext(ctx, result, method, args...)
+}
+
+// deployCross deploys the cross contract on the chain of endpoint and registers
+// the remote chain crossed by the anchors.
+func deployCross(endpoint string, key *ecdsa.PrivateKey, signer types.Signer, bin []byte,
+	remoteChainID *big.Int, confirms uint8, anchors []common.Address, deadline time.Time) (common.Address, error) {
+	client, err := ethclient.Dial(endpoint)
+	if err != nil {
+		return common.Address{}, err
+	}
+	defer client.Close()
+
+	receipt, err := transact(client, key, signer, nil, bin, 8000000, deadline)
+	if err != nil {
+		return common.Address{}, err
+	}
+	contract := receipt.ContractAddress
+
+	crossAbi, err := abi.JSON(bytes.NewReader(hexutil.MustDecode(params.CrossDemoAbi)))
+	if err != nil {
+		return common.Address{}, err
+	}
+	maxValue := new(big.Int).Mul(big.NewInt(params.Ether), big.NewInt(1e4))
+	input, err := crossAbi.Pack("chainRegister", remoteChainID, maxValue, confirms, anchors)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if _, err := transact(client, key, signer, &contract, input, 1000000, deadline); err != nil {
+		return common.Address{}, err
+	}
+	return contract, nil
+}
+
+// transact sends a transaction signed by key and waits for its successful receipt.
+func transact(client *ethclient.Client, key *ecdsa.PrivateKey, signer types.Signer, to *common.Address, data []byte,
+	gas uint64, deadline time.Time) (*types.Receipt, error) {
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+
+	nonce, err := client.PendingNonceAt(ctx, crypto.PubkeyToAddress(key.PublicKey))
+	if err != nil {
+		return nil, err
+	}
+	gasPrice, err := client.SuggestGasPrice(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tx := types.NewContractCreation(nonce, common.Big0, gas, gasPrice, data)
+	if to != nil {
+		tx = types.NewTransaction(nonce, *to, common.Big0, gas, gasPrice, data)
+	}
+	if tx, err = types.SignTx(tx, signer, key); err != nil {
+		return nil, err
+	}
+	if err := client.SendTransaction(ctx, tx); err != nil {
+		return nil, err
+	}
+	for {
+		receipt, err := client.TransactionReceipt(ctx, tx.Hash())
+		if receipt != nil {
+			if receipt.Status != types.ReceiptStatusSuccessful {
+				return nil, fmt.Errorf("transaction %s failed", tx.Hash().Hex())
+			}
+			return receipt, nil
+		}
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("transaction %s not mined, %v", tx.Hash().Hex(), err)
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+// makeMainGenesis creates a clique main chain sealed by signer.
+func makeMainGenesis(signer common.Address, accs []accounts.Account) *core.Genesis {
+	genesis := &core.Genesis{
+		Config: &params.ChainConfig{
+			ChainID:          big.NewInt(mainChainID),
+			SingularityBlock: big.NewInt(0),
+			Clique:           &params.CliqueConfig{Period: 1, Epoch: 30000},
+		},
+		Timestamp:  uint64(time.Now().Unix()),
+		GasLimit:   crossGasLimit,
+		Difficulty: big.NewInt(1),
+		ExtraData:  make([]byte, 32+common.AddressLength+65),
+		Alloc:      make(core.GenesisAlloc),
+	}
+	copy(genesis.ExtraData[32:], signer[:])
+	fundGenesis(genesis, accs)
+
+	return genesis
+}
+
+func fundGenesis(genesis *core.Genesis, accs []accounts.Account) {
+	for _, acc := range accs {
+		genesis.Alloc[acc.Address] = core.GenesisAccount{Balance: new(big.Int).Mul(big.NewInt(params.Ether), big.NewInt(1e4))}
+	}
+}
@@ -30,6 +30,7 @@ var app = cli.NewApp()
 func init() {
 	app.Commands = []cli.Command{
 		dposCommand, raftCommand, pbftCommand,
+		upCommand, downCommand, statusCommand,
 	}
 }
 
@@ -145,6 +146,12 @@ const (
 	GenesisPbftFile = "genesis_pbft.json"
 )
 
+var genesisFiles = map[ConsensusType]string{
+	DPOS: GenesisDPoSFile,
+	RAFT: GenesisRaftFile,
+	PBFT: GenesisPbftFile,
+}
+
 func mkdir(dir string) error {
 	stat, err := os.Stat(dir)
 	if os.IsNotExist(err) {
@@ -201,19 +208,40 @@ func writeFile(dir, name string, content []byte) error {
 }
 
 func writeGenesis(consensus ConsensusType, addresses []accounts.Account, dir, file string) error {
+	genesis, err := makeGenesis(consensus, addresses, file)
+	if err != nil {
+		return err
+	}
+
+	return writeGenesisFile(dir, genesisFiles[consensus], genesis)
+}
+
+func writeGenesisFile(dir, name string, genesis *core.Genesis) error {
+	marshaled, err := json.Marshal(genesis)
+	if err != nil {
+		return fmt.Errorf("marshal genesis file failed, %s", err.Error())
+	}
+	if err = writeFile(dir, name, marshaled); err != nil {
+		return fmt.Errorf("write genesis file failed, %s", err.Error())
+	}
+	return nil
+}
+
+func makeGenesis(consensus ConsensusType, addresses []accounts.Account, file string) (*core.Genesis, error) {
 	gf, err := os.Open(file)
 	if err != nil {
-		return fmt.Errorf("open genesis file failed, %s", err.Error())
+		return nil, fmt.Errorf("open genesis file failed, %s", err.Error())
 	}
+	defer gf.Close()
 
 	b, err := ioutil.ReadAll(gf)
 	if err != nil {
-		return fmt.Errorf("read genesis file failed, %s", err.Error())
+		return nil, fmt.Errorf("read genesis file failed, %s", err.Error())
 	}
 
 	var genesis core.Genesis
 	if err := json.Unmarshal(b, &genesis); err != nil {
-		return fmt.Errorf("unmarshal genesis file failed, %s", err.Error())
+		return nil, fmt.Errorf("unmarshal genesis file failed, %s", err.Error())
 	}
 
 	if consensus == RAFT || consensus == DPOS {
@@ -222,28 +250,16 @@ func writeGenesis(consensus ConsensusType, addresses []accounts.Account, dir, fi
 		}
 	}
 
-	var GenesisFile string
 	switch consensus {
-	case RAFT:
-		GenesisFile = GenesisRaftFile
 	case PBFT:
-		GenesisFile = GenesisPbftFile
-		genesis.ExtraData, err = makeIstanbulExtra(addresses)
+		if genesis.ExtraData, err = makeIstanbulExtra(addresses); err != nil {
+			return nil, fmt.Errorf("make istanbul extra failed, %s", err.Error())
+		}
 	case DPOS:
-		GenesisFile = GenesisDPoSFile
 		makeDPoSSigners(&genesis, addresses)
 	}
 
-	marshaled, err := json.Marshal(genesis)
-	if err != nil {
-		return fmt.Errorf("marshal genesis file failed, %s", err.Error())
-	}
-
-	if err = writeFile(dir, GenesisFile, marshaled); err != nil {
-		return fmt.Errorf("write genesis file failed, %s", err.Error())
-	}
-
-	return nil
+	return &genesis, nil
 }
 
 func writeKeystore(privKeys []*ecdsa.PrivateKey, dir string) ([]accounts.Account, error) {
@@ -25,6 +25,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/simplechain-org/go-simplechain/core"
 	"github.com/simplechain-org/go-simplechain/log"
 )
 
@@ -150,3 +151,17 @@ func checkPort(host string, port int) error {
 	conn.Close()
 	return nil
 }
+
+// roleFlag returns the flag selecting the chain role a node needs to run the
+// consensus engine of a genesis spec. Istanbul, raft and dpos networks can only
+// be run as subchains.
+func roleFlag(genesis []byte) string {
+	spec := new(core.Genesis)
+	if err := json.Unmarshal(genesis, spec); err != nil || spec.Config == nil {
+		return ""
+	}
+	if config := spec.Config; config.Istanbul != nil || config.Raft || config.DPoS != nil {
+		return "--role=subchain"
+	}
+	return ""
+}
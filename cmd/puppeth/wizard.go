@@ -18,6 +18,7 @@ package main
 
 import (
 	"bufio"
+	"crypto/ecdsa"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -33,7 +34,9 @@ import (
 
 	"github.com/simplechain-org/go-simplechain/common"
 	"github.com/simplechain-org/go-simplechain/core"
+	"github.com/simplechain-org/go-simplechain/crypto"
 	"github.com/simplechain-org/go-simplechain/log"
+	"github.com/simplechain-org/go-simplechain/p2p/enode"
 	"golang.org/x/crypto/ssh/terminal"
 )
 
@@ -44,8 +47,9 @@ type config struct {
 	bootnodes []string // Bootnodes to always connect to by all nodes
 	ethstats  string   // Ethstats settings to cache for node deploys
 
-	Genesis *core.Genesis     `json:"genesis,omitempty"` // Genesis block to cache for node deploys
-	Servers map[string][]byte `json:"servers,omitempty"`
+	Genesis   *core.Genesis     `json:"genesis,omitempty"`   // Genesis block to cache for node deploys
+	RaftNodes []string          `json:"raftnodes,omitempty"` // Initial raft cluster members of the genesis
+	Servers   map[string][]byte `json:"servers,omitempty"`
 }
 
 // servers retrieves an alphabetically sorted list of servers.
@@ -366,3 +370,51 @@ func (w *wizard) readIPAddress() string {
 		return text
 	}
 }
+
+// readEnode reads a single line from stdin, trimming if from spaces and
+// converts it to an enode URL carrying a raft port. If an empty line is
+// entered, nil is returned.
+func (w *wizard) readEnode() *enode.Node {
+	for {
+		// Read the enode URL from the user
+		fmt.Printf("> ")
+		text, err := w.in.ReadString('\n')
+		if err != nil {
+			log.Crit("Failed to read user input", "err", err)
+		}
+		if text = strings.TrimSpace(text); text == "" {
+			return nil
+		}
+		// Make sure it looks ok and return it if so
+		node, err := enode.ParseV4(text)
+		if err != nil {
+			log.Error("Invalid enode URL, please retry", "err", err)
+			continue
+		}
+		if !node.HasRaftPort() {
+			log.Error("Enode URL misses the raftport parameter, please retry")
+			continue
+		}
+		return node
+	}
+}
+
+// readNodeKey reads a single line from stdin, trimming if from spaces and
+// converts it to a hex encoded node private key.
+func (w *wizard) readNodeKey() *ecdsa.PrivateKey {
+	for {
+		// Read the node key from the user
+		fmt.Printf("> ")
+		text, err := w.in.ReadString('\n')
+		if err != nil {
+			log.Crit("Failed to read user input", "err", err)
+		}
+		// Make sure it looks ok and return it if so
+		key, err := crypto.HexToECDSA(strings.TrimPrefix(strings.TrimSpace(text), "0x"))
+		if err != nil {
+			log.Error("Invalid node key, please retry", "err", err)
+			continue
+		}
+		return key
+	}
+}
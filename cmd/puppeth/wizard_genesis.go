@@ -30,9 +30,12 @@ import (
 	"time"
 
 	"github.com/simplechain-org/go-simplechain/common"
+	"github.com/simplechain-org/go-simplechain/consensus/istanbul"
 	"github.com/simplechain-org/go-simplechain/core"
+	"github.com/simplechain-org/go-simplechain/core/types"
 	"github.com/simplechain-org/go-simplechain/log"
 	"github.com/simplechain-org/go-simplechain/params"
+	"github.com/simplechain-org/go-simplechain/rlp"
 )
 
 // makeGenesis creates a new genesis struct based on some user input.
@@ -52,11 +55,14 @@ func (w *wizard) makeGenesis() {
 	fmt.Println("Which consensus engine to use? (default = clique)")
 	fmt.Println(" 1. Scrypt - proof-of-work")
 	fmt.Println(" 2. Clique - proof-of-authority")
+	fmt.Println(" 3. Istanbul - byzantine fault tolerant")
+	fmt.Println(" 4. Raft - crash fault tolerant")
+	fmt.Println(" 5. DPoS - delegated proof-of-stake")
 
 	choice := w.read()
 	switch {
 	case choice == "1":
-		// In case of ethash, we're pretty much done
+		// In case of scrypt, we're pretty much done
 		genesis.Config.Scrypt = new(params.ScryptConfig)
 		genesis.ExtraData = make([]byte, 32)
 
@@ -98,9 +104,107 @@ func (w *wizard) makeGenesis() {
 			copy(genesis.ExtraData[32+i*common.AddressLength:], signer[:])
 		}
 
+	case choice == "3":
+		// In the case of istanbul, configure the consensus parameters
+		genesis.Difficulty = big.NewInt(1)
+		genesis.Mixhash = types.IstanbulDigest
+		genesis.Config.Istanbul = &params.IstanbulConfig{
+			Epoch:          30000,
+			ProposerPolicy: uint64(istanbul.RoundRobin),
+		}
+		fmt.Println()
+		fmt.Println("Should the proposer stay the same until a round change (y/n)? (default = no)")
+		if w.readDefaultYesNo(false) {
+			genesis.Config.Istanbul.ProposerPolicy = uint64(istanbul.Sticky)
+		}
+		// We also need the initial list of validators, sealing with their node keys
+		fmt.Println()
+		fmt.Println("Which node addresses are allowed to validate? (mandatory at least one)")
+
+		var validators []common.Address
+		for {
+			if address := w.readAddress(); address != nil {
+				validators = append(validators, *address)
+				continue
+			}
+			if len(validators) > 0 {
+				break
+			}
+		}
+		extra, err := rlp.EncodeToBytes(&types.IstanbulExtra{
+			Validators:    validators,
+			Seal:          []byte{},
+			CommittedSeal: [][]byte{},
+		})
+		if err != nil {
+			log.Crit("Failed to encode istanbul extra-data", "err", err)
+		}
+		genesis.ExtraData = append(make([]byte, types.IstanbulExtraVanity), extra...)
+
+	case choice == "4":
+		// In the case of raft, the cluster is defined by its initial members
+		genesis.Difficulty = new(big.Int)
+		genesis.Config.Raft = true
+		genesis.ExtraData = make([]byte, 32)
+
+		fmt.Println()
+		fmt.Println("Which enode URLs (with raftport) form the initial cluster? (mandatory at least one)")
+
+		w.conf.RaftNodes = w.conf.RaftNodes[:0]
+		for {
+			if node := w.readEnode(); node != nil {
+				w.conf.RaftNodes = append(w.conf.RaftNodes, node.URLv4())
+				continue
+			}
+			if len(w.conf.RaftNodes) > 0 {
+				break
+			}
+		}
+
+	case choice == "5":
+		// In the case of dpos, configure the consensus parameters
+		genesis.Difficulty = big.NewInt(1)
+		genesis.Config.DPoS = &params.DPoSConfig{
+			Period:           3,
+			Epoch:            300,
+			MaxSignerCount:   21,
+			MinVoterBalance:  new(big.Int).Mul(big.NewInt(100), big.NewInt(params.Ether)),
+			GenesisTimestamp: genesis.Timestamp,
+			VoterReward:      true,
+		}
+		fmt.Println()
+		fmt.Println("How many seconds should blocks take? (default = 3)")
+		genesis.Config.DPoS.Period = uint64(w.readDefaultInt(3))
+
+		fmt.Println()
+		fmt.Println("How many signers should seal blocks at most? (default = 21)")
+		genesis.Config.DPoS.MaxSignerCount = uint64(w.readDefaultInt(21))
+
+		// We also need the initial signers, voting for themselves
+		fmt.Println()
+		fmt.Println("Which accounts are voted to seal by themselves? (mandatory at least one)")
+		for {
+			if address := w.readAddress(); address != nil {
+				genesis.Config.DPoS.SelfVoteSigners = append(genesis.Config.DPoS.SelfVoteSigners, common.UnprefixedAddress(*address))
+
+				// Self-voting signers need funds to back their votes
+				genesis.Alloc[*address] = core.GenesisAccount{
+					Balance: new(big.Int).Lsh(big.NewInt(1), 256-7),
+				}
+				continue
+			}
+			if len(genesis.Config.DPoS.SelfVoteSigners) > 0 {
+				break
+			}
+		}
+		genesis.ExtraData = make([]byte, 32+65)
+
 	default:
 		log.Crit("Invalid consensus engine choice", "choice", choice)
 	}
+	if !genesis.Config.Raft {
+		w.conf.RaftNodes = nil
+	}
 	// Consensus all set, just ask for initial funds and go
 	fmt.Println()
 	fmt.Println("Which accounts should be pre-funded? (advisable at least one)")
@@ -17,13 +17,17 @@
 package main
 
 import (
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"time"
 
 	"github.com/simplechain-org/go-simplechain/accounts/keystore"
 	"github.com/simplechain-org/go-simplechain/common"
+	"github.com/simplechain-org/go-simplechain/core/types"
+	"github.com/simplechain-org/go-simplechain/crypto"
 	"github.com/simplechain-org/go-simplechain/log"
+	"github.com/simplechain-org/go-simplechain/p2p/enode"
 )
 
 // deployNode creates a new node configuration based on some user input.
@@ -57,6 +61,7 @@ func (w *wizard) deployNode(boot bool) {
 
 	infos.genesis, _ = json.MarshalIndent(w.conf.Genesis, "", "  ")
 	infos.network = w.conf.Genesis.Config.ChainID.Int64()
+	infos.raftNodes = w.conf.RaftNodes
 
 	// Figure out where the user wants to store the persistent data
 	fmt.Println()
@@ -103,8 +108,8 @@ func (w *wizard) deployNode(boot bool) {
 	}
 	// If the node is a miner/signer, load up needed credentials
 	if !boot {
-		if w.conf.Genesis.Config.Ethash != nil {
-			// Ethash based miners only need an etherbase to mine against
+		if w.conf.Genesis.Config.Ethash != nil || w.conf.Genesis.Config.Scrypt != nil {
+			// Ethash and scrypt based miners only need an etherbase to mine against
 			fmt.Println()
 			if infos.etherbase == "" {
 				fmt.Printf("What address should the miner use?\n")
@@ -118,7 +123,7 @@ func (w *wizard) deployNode(boot bool) {
 				fmt.Printf("What address should the miner use? (default = %s)\n", infos.etherbase)
 				infos.etherbase = w.readDefaultAddress(common.HexToAddress(infos.etherbase)).Hex()
 			}
-		} else if w.conf.Genesis.Config.Clique != nil {
+		} else if w.conf.Genesis.Config.Clique != nil || w.conf.Genesis.Config.DPoS != nil {
 			// If a previous signer was already set, offer to reuse it
 			if infos.keyJSON != "" {
 				if key, err := keystore.DecryptKey([]byte(infos.keyJSON), infos.keyPass); err != nil {
@@ -131,7 +136,7 @@ func (w *wizard) deployNode(boot bool) {
 					}
 				}
 			}
-			// Clique based signers need a keyfile and unlock password, ask if unavailable
+			// Clique and dpos based signers need a keyfile and unlock password, ask if unavailable
 			if infos.keyJSON == "" {
 				fmt.Println()
 				fmt.Println("Please paste the signer's key JSON:")
@@ -146,6 +151,43 @@ func (w *wizard) deployNode(boot bool) {
 					return
 				}
 			}
+		} else if w.conf.Genesis.Config.Istanbul != nil || w.conf.Genesis.Config.Raft {
+			// If a previous node key was already set, offer to reuse it
+			if infos.nodeKey != "" {
+				if key, err := crypto.HexToECDSA(infos.nodeKey); err != nil {
+					infos.nodeKey = ""
+				} else {
+					fmt.Println()
+					fmt.Printf("Reuse previous (%s) node account (y/n)? (default = yes)\n", crypto.PubkeyToAddress(key.PublicKey).Hex())
+					if !w.readDefaultYesNo(true) {
+						infos.nodeKey = ""
+					}
+				}
+			}
+			// Istanbul validators and raft members seal with their node key, ask if unavailable
+			if infos.nodeKey == "" {
+				fmt.Println()
+				fmt.Println("Please paste the node's private key (hex):")
+				infos.nodeKey = hex.EncodeToString(crypto.FromECDSA(w.readNodeKey()))
+			}
+			key, _ := crypto.HexToECDSA(infos.nodeKey)
+			if w.conf.Genesis.Config.Istanbul != nil {
+				if !w.isValidator(crypto.PubkeyToAddress(key.PublicKey)) {
+					log.Error("Node key is not a genesis validator")
+					return
+				}
+			} else {
+				infos.raftPort = 0
+				for _, url := range w.conf.RaftNodes {
+					if node, err := enode.ParseV4(url); err == nil && node.ID() == enode.PubkeyToIDV4(&key.PublicKey) {
+						infos.raftPort = node.RaftPort()
+					}
+				}
+				if infos.raftPort == 0 {
+					log.Error("Node key is not a raft cluster member")
+					return
+				}
+			}
 		}
 		// Establish the gas dynamics to be enforced by the signer
 		fmt.Println()
@@ -180,3 +222,18 @@ func (w *wizard) deployNode(boot bool) {
 
 	w.networkStats()
 }
+
+// isValidator checks whether an address is in the initial istanbul validator
+// set embedded in the genesis extra-data.
+func (w *wizard) isValidator(address common.Address) bool {
+	extra, err := types.ExtractIstanbulExtra(&types.Header{Extra: w.conf.Genesis.ExtraData})
+	if err != nil {
+		return false
+	}
+	for _, validator := range extra.Validators {
+		if validator == address {
+			return true
+		}
+	}
+	return false
+}
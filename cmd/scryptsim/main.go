@@ -0,0 +1,215 @@
+// Copyright 2019 The go-simplechain Authors
+// This file is part of go-simplechain.
+//
+// go-simplechain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-simplechain is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-simplechain. If not, see <http://www.gnu.org/licenses/>.
+
+// scryptsim simulates block production under a hashrate schedule using the
+// scrypt difficulty retarget rule and dumps the resulting time series.
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"strconv"
+
+	"github.com/simplechain-org/go-simplechain/cmd/utils"
+	"github.com/simplechain-org/go-simplechain/consensus/scrypt"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	// Git SHA1 commit hash of the release (set via linker flags)
+	gitCommit = ""
+	gitDate   = ""
+)
+
+var app *cli.App
+
+func init() {
+	app = utils.NewApp(gitCommit, gitDate, "scrypt difficulty retarget simulator")
+	app.Flags = []cli.Flag{
+		blocksFlag,
+		difficultyFlag,
+		scheduleFlag,
+		hashrateFlag,
+		targetFlag,
+		atFlag,
+		durationFlag,
+		halfLifeFlag,
+		uncleRateFlag,
+		seedFlag,
+		formatFlag,
+		outputFlag,
+	}
+	app.Action = simulate
+}
+
+var (
+	blocksFlag = cli.IntFlag{
+		Name:  "blocks",
+		Usage: "Number of blocks to simulate",
+		Value: 10000,
+	}
+	difficultyFlag = cli.StringFlag{
+		Name:  "difficulty",
+		Usage: "Difficulty of the starting block (genesis difficulty if empty)",
+	}
+	scheduleFlag = cli.StringFlag{
+		Name:  "schedule",
+		Usage: "Hashrate schedule (constant, step, spike, decay)",
+		Value: "constant",
+	}
+	hashrateFlag = cli.Float64Flag{
+		Name:  "hashrate",
+		Usage: "Base network hashrate in hashes per second",
+		Value: 100000,
+	}
+	targetFlag = cli.Float64Flag{
+		Name:  "target",
+		Usage: "Hashrate after a step, at the top of a spike or at the floor of a decay",
+		Value: 1000000,
+	}
+	atFlag = cli.Float64Flag{
+		Name:  "at",
+		Usage: "Seconds into the simulation when the schedule kicks in",
+		Value: 3600,
+	}
+	durationFlag = cli.Float64Flag{
+		Name:  "duration",
+		Usage: "Length of a spike in seconds",
+		Value: 3600,
+	}
+	halfLifeFlag = cli.Float64Flag{
+		Name:  "halflife",
+		Usage: "Half life of a decay in seconds",
+		Value: 3600,
+	}
+	uncleRateFlag = cli.Float64Flag{
+		Name:  "unclerate",
+		Usage: "Probability that a block includes uncles",
+	}
+	seedFlag = cli.Int64Flag{
+		Name:  "seed",
+		Usage: "Seed of the block time sampler",
+		Value: 1,
+	}
+	formatFlag = cli.StringFlag{
+		Name:  "format",
+		Usage: "Output format (csv, json)",
+		Value: "csv",
+	}
+	outputFlag = cli.StringFlag{
+		Name:  "output",
+		Usage: "File to write the time series to (stdout if empty)",
+	}
+)
+
+func main() {
+	if err := app.Run(os.Args); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// simulate runs the difficulty simulation configured by the command line flags.
+func simulate(ctx *cli.Context) error {
+	schedule, err := makeSchedule(ctx)
+	if err != nil {
+		return err
+	}
+	config := scrypt.SimConfig{
+		Blocks:    ctx.Int(blocksFlag.Name),
+		Schedule:  schedule,
+		UncleRate: ctx.Float64(uncleRateFlag.Name),
+		Seed:      ctx.Int64(seedFlag.Name),
+	}
+	if s := ctx.String(difficultyFlag.Name); s != "" {
+		difficulty, ok := new(big.Int).SetString(s, 0)
+		if !ok || difficulty.Sign() <= 0 {
+			return fmt.Errorf("invalid difficulty %q", s)
+		}
+		config.Difficulty = difficulty
+	}
+	blocks, err := scrypt.Simulate(config)
+	if err != nil {
+		return err
+	}
+	out := io.Writer(os.Stdout)
+	if path := ctx.String(outputFlag.Name); path != "" {
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	switch format := ctx.String(formatFlag.Name); format {
+	case "csv":
+		return writeCSV(out, blocks)
+	case "json":
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(blocks)
+	default:
+		return fmt.Errorf("unknown output format %q", format)
+	}
+}
+
+// makeSchedule assembles the hashrate schedule requested on the command line.
+func makeSchedule(ctx *cli.Context) (scrypt.HashrateSchedule, error) {
+	var (
+		base   = ctx.Float64(hashrateFlag.Name)
+		target = ctx.Float64(targetFlag.Name)
+		at     = ctx.Float64(atFlag.Name)
+	)
+	switch name := ctx.String(scheduleFlag.Name); name {
+	case "constant":
+		return scrypt.ConstantSchedule{Rate: base}, nil
+	case "step":
+		return scrypt.StepSchedule{Base: base, Rate: target, At: at}, nil
+	case "spike":
+		return scrypt.SpikeSchedule{Base: base, Peak: target, At: at, Duration: ctx.Float64(durationFlag.Name)}, nil
+	case "decay":
+		return scrypt.DecaySchedule{Base: base, Floor: target, At: at, HalfLife: ctx.Float64(halfLifeFlag.Name)}, nil
+	default:
+		return nil, fmt.Errorf("unknown hashrate schedule %q", name)
+	}
+}
+
+// writeCSV dumps the simulated blocks as a CSV time series.
+func writeCSV(out io.Writer, blocks []scrypt.SimBlock) error {
+	w := csv.NewWriter(out)
+	if err := w.Write([]string{"number", "time", "interval", "difficulty", "hashrate", "uncles"}); err != nil {
+		return err
+	}
+	for _, block := range blocks {
+		record := []string{
+			strconv.FormatUint(block.Number, 10),
+			strconv.FormatUint(block.Time, 10),
+			strconv.FormatUint(block.Interval, 10),
+			block.Difficulty.String(),
+			strconv.FormatFloat(block.Hashrate, 'f', -1, 64),
+			strconv.FormatBool(block.Uncles),
+		}
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
@@ -28,10 +28,12 @@ import (
 
 	"github.com/simplechain-org/go-simplechain/cmd/utils"
 	"github.com/simplechain-org/go-simplechain/common"
+	raftBackend "github.com/simplechain-org/go-simplechain/consensus/raft/backend"
 	"github.com/simplechain-org/go-simplechain/console"
 	"github.com/simplechain-org/go-simplechain/core"
 	"github.com/simplechain-org/go-simplechain/core/rawdb"
 	"github.com/simplechain-org/go-simplechain/core/state"
+	"github.com/simplechain-org/go-simplechain/core/state/pruner"
 	"github.com/simplechain-org/go-simplechain/core/types"
 	"github.com/simplechain-org/go-simplechain/eth/downloader"
 	"github.com/simplechain-org/go-simplechain/event"
@@ -70,6 +72,7 @@ It expects the genesis file as argument.`,
 			utils.GCModeFlag,
 			utils.CacheDatabaseFlag,
 			utils.CacheGCFlag,
+			utils.ParallelTxsFlag,
 		},
 		Category: "BLOCKCHAIN COMMANDS",
 		Description: `
@@ -186,6 +189,32 @@ Use "ethereum dump 0" to dump the genesis block.`,
 		},
 		Category: "BLOCKCHAIN COMMANDS",
 	}
+	pruneStateCommand = cli.Command{
+		Action:    utils.MigrateFlags(pruneState),
+		Name:      "prune-state",
+		Usage:     "Delete the state data unreachable from the recent states",
+		ArgsUsage: " ",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.AncientFlag,
+			utils.CacheFlag,
+			utils.CacheDatabaseFlag,
+			utils.TestnetFlag,
+			utils.PruneRetainFlag,
+			utils.PruneBloomSizeFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+The prune-state command walks the head state and the states of the last
+--prune.retain blocks, marks every reachable trie node and contract code in a
+bloom filter of --prune.bloomsize megabytes and deletes all other state data
+from the chain database. The genesis state, the private states of the retained
+blocks and the state raft needs to recover from its latest snapshot are kept
+too, snapshots of the consensus engines are never touched.
+
+The node must be stopped while pruning. An interrupted pruning is safe to run
+again.`,
+	}
 )
 
 // initGenesis will initialise the given JSON format genesis file and writes it as
@@ -607,6 +636,32 @@ func inspect(ctx *cli.Context) error {
 	return rawdb.InspectDatabase(chainDb)
 }
 
+// pruneState deletes the state data unreachable from the retained states.
+func pruneState(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	chainDb := utils.MakeChainDatabase(ctx, stack)
+	defer chainDb.Close()
+
+	config := pruner.Config{
+		Retain:    ctx.GlobalUint64(utils.PruneRetainFlag.Name),
+		BloomSize: ctx.GlobalUint64(utils.PruneBloomSizeFlag.Name),
+	}
+	// Raft replays the chain from the head of its latest snapshot on recovery
+	raftHead, err := raftBackend.ReadSnapshotHead(ctx.GlobalString(utils.DataDirFlag.Name))
+	if err != nil {
+		utils.Fatalf("Failed to read raft snapshot: %v", err)
+	}
+	if raftHead != (common.Hash{}) {
+		config.Blocks = append(config.Blocks, raftHead)
+	}
+	if err := pruner.NewPruner(chainDb, config).Prune(); err != nil {
+		utils.Fatalf("Failed to prune state: %v", err)
+	}
+	return nil
+}
+
 // hashish returns true for strings that look like hashes.
 func hashish(x string) bool {
 	_, err := strconv.Atoi(x)
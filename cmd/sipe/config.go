@@ -23,7 +23,7 @@ import (
 	"math/big"
 	"os"
 	"reflect"
-	"time"
+	"strings"
 	"unicode"
 
 	"github.com/simplechain-org/go-simplechain/cmd/utils"
@@ -32,9 +32,11 @@ import (
 	"github.com/simplechain-org/go-simplechain/cross/trigger/simpletrigger"
 	"github.com/simplechain-org/go-simplechain/cross/trigger/simpletrigger/executor"
 	"github.com/simplechain-org/go-simplechain/eth"
+	"github.com/simplechain-org/go-simplechain/log"
 	"github.com/simplechain-org/go-simplechain/node"
 	"github.com/simplechain-org/go-simplechain/p2p/enode"
 	"github.com/simplechain-org/go-simplechain/params"
+	"github.com/simplechain-org/go-simplechain/stratum"
 	"github.com/simplechain-org/go-simplechain/sub"
 	whisper "github.com/simplechain-org/go-simplechain/whisper/whisperv6"
 
@@ -184,6 +186,10 @@ func makeFullNode(ctx *cli.Context) *node.Node {
 	if ctx.GlobalBool(utils.RaftModeFlag.Name) {
 		RegisterRaftService(stack, ctx, cfg, raftChan)
 	}
+	if ctx.GlobalString(utils.MinerType.Name) == "stratum" {
+		RegisterStratumService(stack, ctx)
+	}
+	utils.RegisterPermissionService(stack, ctx)
 
 	// Whisper must be explicitly enabled by specifying at least 1 whisper flag or in dev mode
 	shhEnabled := enableWhisper(ctx)
@@ -217,23 +223,120 @@ func makeFullNode(ctx *cli.Context) *node.Node {
 	return stack
 }
 
+// RegisterStratumService adds the stratum server and its share ledger to the
+// node. The miner agent serving it is registered once the node is started.
+func RegisterStratumService(stack *node.Node, ctx *cli.Context) {
+	port := ctx.GlobalString(utils.StratumPort.Name)
+	log.Info("[stratum]Server port", "port", port)
+	mode, err := stratum.ParseProtocolMode(ctx.GlobalString(utils.StratumMode.Name))
+	if err != nil {
+		utils.Fatalf("%v", err)
+	}
+	type listener struct {
+		address string
+		mode    stratum.ProtocolMode
+	}
+	var listeners []listener
+	if spec := ctx.GlobalString(utils.StratumListeners.Name); spec != "" {
+		for _, entry := range strings.Split(spec, ",") {
+			parts := strings.SplitN(strings.TrimSpace(entry), "=", 2)
+			if len(parts) != 2 {
+				utils.Fatalf("Invalid stratum listener %q, want address=mode", entry)
+			}
+			mode, err := stratum.ParseProtocolMode(parts[1])
+			if err != nil {
+				utils.Fatalf("%v", err)
+			}
+			listeners = append(listeners, listener{parts[0], mode})
+		}
+	}
+	var auth stratum.Auth
+	switch backend := ctx.GlobalString(utils.StratumAuth.Name); backend {
+	case "password":
+		auth = stratum.NewSimpleAuth(ctx.GlobalString(utils.StratumPassword.Name))
+	case "signature":
+		auth = stratum.NewSignatureAuth()
+	case "allowlist":
+		allowlist, err := stratum.NewAllowlistAuth(ctx.GlobalString(utils.StratumAllowlist.Name))
+		if err != nil {
+			utils.Fatalf("Failed to load the stratum allowlist: %v", err)
+		}
+		auth = allowlist
+	default:
+		utils.Fatalf("Unknown stratum authentication backend: %s", backend)
+	}
+	maxConn := ctx.GlobalInt(utils.StratumMaxConn.Name)
+	calcHashRate := ctx.GlobalBool(utils.StratumHashRate.Name)
+	if calcHashRate {
+		log.Info("calc stratum miner's hashRate")
+	}
+	fanOut := ctx.GlobalBool(utils.StratumFanout.Name)
+	maxConnPerIP := ctx.GlobalInt(utils.StratumMaxConnPerIP.Name)
+	certFile := ctx.GlobalString(utils.StratumTLSCert.Name)
+	keyFile := ctx.GlobalString(utils.StratumTLSKey.Name)
+	vardiff := stratum.DefaultVardiffConfig
+	vardiff.TargetTime = ctx.GlobalDuration(utils.StratumVardiffTarget.Name)
+	vardiff.MinDifficulty = ctx.GlobalUint64(utils.StratumVardiffMin.Name)
+	vardiff.MaxDifficulty = ctx.GlobalUint64(utils.StratumVardiffMax.Name)
+	vardiff.RetargetPeriod = ctx.GlobalDuration(utils.StratumVardiffRetarget.Name)
+	scheme := ctx.GlobalString(utils.StratumPayout.Name)
+	window := ctx.GlobalUint64(utils.StratumPPLNSWindow.Name)
+
+	if err := stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
+		server, err := stratum.NewServer(port, uint(maxConn), auth, calcHashRate, fanOut)
+		if err != nil {
+			return nil, err
+		}
+		server.SetProtocolMode(mode)
+		for _, l := range listeners {
+			if err := server.AddListener(l.address, l.mode); err != nil {
+				return nil, err
+			}
+		}
+		server.SetMaxConnPerIP(uint(maxConnPerIP))
+		server.SetVardiff(vardiff)
+		if certFile != "" {
+			if err := server.SetTLS(certFile, keyFile); err != nil {
+				return nil, err
+			}
+		}
+		db, err := ctx.OpenDatabase("stratum", 16, 16, "stratum/db/")
+		if err != nil {
+			return nil, err
+		}
+		return stratum.NewService(server, db, scheme, window)
+	}); err != nil {
+		utils.Fatalf("Failed to register the stratum service: %v", err)
+	}
+}
+
 func RegisterRaftService(stack *node.Node, ctx *cli.Context, cfg gethConfig, subChan <-chan *sub.Ethereum) {
 	datadir := ctx.GlobalString(utils.DataDirFlag.Name)
 	joinExistingId := ctx.GlobalInt(utils.RaftJoinExistingFlag.Name)
 	raftPort := uint16(ctx.GlobalInt(utils.RaftPortFlag.Name))
-	blockTimeMillis := ctx.GlobalInt(utils.RaftBlockTimeFlag.Name)
+	raftConfig := params.RaftConfig{
+		BlockTime:         uint64(ctx.GlobalInt(utils.RaftBlockTimeFlag.Name)),
+		MinBatchTxs:       ctx.GlobalUint64(utils.RaftMinBatchTxsFlag.Name),
+		MaxBatchTxs:       ctx.GlobalUint64(utils.RaftMaxBatchTxsFlag.Name),
+		MaxLatency:        ctx.GlobalUint64(utils.RaftMaxLatencyFlag.Name),
+		GasTarget:         ctx.GlobalUint64(utils.RaftGasTargetFlag.Name),
+		HeartbeatInterval: ctx.GlobalUint64(utils.RaftHeartbeatFlag.Name),
+	}
 	useDns := ctx.GlobalBool(utils.RaftDNSEnabledFlag.Name)
+	useP2P := ctx.GlobalBool(utils.RaftP2PTransportFlag.Name)
 
 	if err := stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
 		privkey := cfg.Node.NodeKey()
 		strId := enode.PubkeyToIDV4(&privkey.PublicKey).String()
 		peers := cfg.Node.StaticNodes()
-		blockTimeNanos := time.Duration(blockTimeMillis) * time.Millisecond
 
 		var myId uint16
 		var joinExisting bool
 
 		if joinExistingId > 0 {
+			if useP2P && len(peers) == 0 {
+				utils.Fatalf("Joining a raft cluster over the p2p transport requires the existing members to be listed in static-nodes.json, as only they are trusted before this node learns the cluster membership.")
+			}
 			myId = uint16(joinExistingId)
 			joinExisting = true
 		} else if len(peers) == 0 {
@@ -242,7 +345,7 @@ func RegisterRaftService(stack *node.Node, ctx *cli.Context, cfg gethConfig, sub
 			peerIds := make([]string, len(peers))
 
 			for peerIdx, peer := range peers {
-				if !peer.HasRaftPort() {
+				if !useP2P && !peer.HasRaftPort() {
 					utils.Fatalf("raftport querystring parameter not specified in static-node enode ID: %v. please check your static-nodes.json file.", peer.String())
 				}
 
@@ -259,7 +362,7 @@ func RegisterRaftService(stack *node.Node, ctx *cli.Context, cfg gethConfig, sub
 		}
 
 		ethereum := <-subChan
-		return raftBackend.New(ctx, myId, raftPort, joinExisting, ethereum, peers, datadir, blockTimeNanos, useDns)
+		return raftBackend.New(ctx, myId, raftPort, joinExisting, ethereum, peers, datadir, raftConfig, useDns, useP2P)
 	}); err != nil {
 		utils.Fatalf("Failed to register the Raft service: %v", err)
 	}
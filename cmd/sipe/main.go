@@ -79,6 +79,7 @@ var (
 		utils.EthashDatasetDirFlag,
 		utils.EthashDatasetsInMemoryFlag,
 		utils.EthashDatasetsOnDiskFlag,
+		utils.ScryptWorkHistoryFlag,
 		utils.TxPoolLocalsFlag,
 		utils.TxPoolNoLocalsFlag,
 		utils.TxPoolJournalFlag,
@@ -109,6 +110,9 @@ var (
 		utils.CacheTrieFlag,
 		utils.CacheGCFlag,
 		utils.CacheNoPrefetchFlag,
+		utils.CacheSnapshotFlag,
+		utils.SnapshotFlag,
+		utils.ParallelTxsFlag,
 		utils.ListenPortFlag,
 		utils.MaxPeersFlag,
 		utils.MaxPendingPeersFlag,
@@ -116,10 +120,23 @@ var (
 		utils.MinerThreadsFlag,
 		utils.MinerType,
 		utils.StratumPort,
+		utils.StratumMode,
+		utils.StratumListeners,
 		utils.StratumMaxConn,
 		utils.StratumFanout,
 		utils.StratumPassword,
 		utils.StratumHashRate,
+		utils.StratumPayout,
+		utils.StratumPPLNSWindow,
+		utils.StratumTLSCert,
+		utils.StratumTLSKey,
+		utils.StratumAuth,
+		utils.StratumAllowlist,
+		utils.StratumMaxConnPerIP,
+		utils.StratumVardiffTarget,
+		utils.StratumVardiffMin,
+		utils.StratumVardiffMax,
+		utils.StratumVardiffRetarget,
 		utils.CPUAgentOff,
 		utils.MinerLegacyThreadsFlag,
 		utils.MinerNotifyFlag,
@@ -138,6 +155,9 @@ var (
 		utils.NoDiscoverFlag,
 		utils.DiscoveryV5Flag,
 		utils.NetrestrictFlag,
+		utils.PermissionNodesFlag,
+		utils.PermissionContractFlag,
+		utils.PermissionRefreshFlag,
 		utils.NodeKeyFileFlag,
 		utils.NodeKeyHexFlag,
 		utils.DeveloperFlag,
@@ -152,15 +172,23 @@ var (
 		utils.GpoPercentileFlag,
 		utils.EWASMInterpreterFlag,
 		utils.EVMInterpreterFlag,
+		utils.TracerJSFlag,
 		utils.RoleFlag,
 		utils.ContractMainFlag,
 		utils.ContractSubFlag,
+		utils.PrivateTxManagerFlag,
 		configFileFlag,
 		utils.RaftModeFlag,
 		utils.RaftJoinExistingFlag,
 		utils.RaftPortFlag,
 		utils.RaftBlockTimeFlag,
+		utils.RaftMinBatchTxsFlag,
+		utils.RaftMaxBatchTxsFlag,
+		utils.RaftMaxLatencyFlag,
+		utils.RaftGasTargetFlag,
+		utils.RaftHeartbeatFlag,
 		utils.RaftDNSEnabledFlag,
+		utils.RaftP2PTransportFlag,
 		utils.RaftEmitCheckpointsFlag,
 		utils.IstanbulRequestTimeoutFlag,
 		utils.IstanbulBlockPeriodFlag,
@@ -243,6 +271,9 @@ func init() {
 		removedbCommand,
 		dumpCommand,
 		inspectCommand,
+		pruneStateCommand,
+		// See snapshotcmd.go:
+		snapshotCommand,
 		// See accountcmd.go:
 		accountCommand,
 		walletCommand,
@@ -474,23 +505,12 @@ func startNode(ctx *cli.Context, stack *node.Node) {
 			//Use stratum if requested
 			if ctx.GlobalString(utils.MinerType.Name) == "stratum" {
 				log.Info("MinerType", "MinerType", ctx.GlobalString(utils.MinerType.Name))
-				port := ctx.GlobalString(utils.StratumPort.Name)
-				log.Info("[stratum]Server port", "port", port)
-				auth := stratum.NewSimpleAuth(ctx.GlobalString(utils.StratumPassword.Name))
-				maxConn := ctx.GlobalInt(utils.StratumMaxConn.Name)
-				var calcHashRate bool = false
-				if ctx.GlobalBool(utils.StratumHashRate.Name) {
-					calcHashRate = true
-					log.Info("calc stratum miner's hashRate")
-				}
-				fanOut := ctx.GlobalBool(utils.StratumFanout.Name)
-				stratumServer, err := stratum.NewServer(port, uint(maxConn), auth, calcHashRate, fanOut)
-				if err != nil {
-					log.Info("[stratum]Server init error", "err", err.Error())
-					return
+				var stratumService *stratum.Service
+				if err := stack.Service(&stratumService); err != nil {
+					utils.Fatalf("Stratum service not running: %v", err)
 				}
 				stratumAgent := miner.NewStratumAgent(ethereum.BlockChain(), ethereum.Engine())
-				stratumAgent.Register(stratumServer)
+				stratumAgent.Register(stratumService.Server())
 				if !ctx.GlobalBool(utils.CPUAgentOff.Name) {
 					cpuMinerAgent := miner.NewCpuAgent(ethereum.BlockChain(), ethereum.Engine())
 					ethereum.Miner().Register(cpuMinerAgent)
@@ -525,23 +545,12 @@ func startNode(ctx *cli.Context, stack *node.Node) {
 			//Use stratum if requested
 			if ctx.GlobalString(utils.MinerType.Name) == "stratum" {
 				log.Info("MinerType", "MinerType", ctx.GlobalString(utils.MinerType.Name))
-				port := ctx.GlobalString(utils.StratumPort.Name)
-				log.Info("[stratum]Server port", "port", port)
-				auth := stratum.NewSimpleAuth(ctx.GlobalString(utils.StratumPassword.Name))
-				maxConn := ctx.GlobalInt(utils.StratumMaxConn.Name)
-				var calcHashRate bool = false
-				if ctx.GlobalBool(utils.StratumHashRate.Name) {
-					calcHashRate = true
-					log.Info("calc stratum miner's hashRate")
-				}
-				fanOut := ctx.GlobalBool(utils.StratumFanout.Name)
-				stratumServer, err := stratum.NewServer(port, uint(maxConn), auth, calcHashRate, fanOut)
-				if err != nil {
-					log.Info("[stratum]Server init error", "err", err.Error())
-					return
+				var stratumService *stratum.Service
+				if err := stack.Service(&stratumService); err != nil {
+					utils.Fatalf("Stratum service not running: %v", err)
 				}
 				stratumAgent := miner.NewStratumAgent(ethereum.BlockChain(), ethereum.Engine())
-				stratumAgent.Register(stratumServer)
+				stratumAgent.Register(stratumService.Server())
 				if !ctx.GlobalBool(utils.CPUAgentOff.Name) {
 					cpuMinerAgent := miner.NewCpuAgent(ethereum.BlockChain(), ethereum.Engine())
 					ethereum.Miner().Register(cpuMinerAgent)
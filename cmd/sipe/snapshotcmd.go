@@ -0,0 +1,100 @@
+// Copyright 2020 The go-simplechain Authors
+// This file is part of go-simplechain.
+//
+// go-simplechain is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-simplechain is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-simplechain. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"time"
+
+	"github.com/simplechain-org/go-simplechain/cmd/utils"
+	"github.com/simplechain-org/go-simplechain/common"
+	"github.com/simplechain-org/go-simplechain/common/hexutil"
+	"github.com/simplechain-org/go-simplechain/core/rawdb"
+	"github.com/simplechain-org/go-simplechain/core/state/snapshot"
+	"github.com/simplechain-org/go-simplechain/log"
+	"github.com/simplechain-org/go-simplechain/trie"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	snapshotCommand = cli.Command{
+		Name:      "snapshot",
+		Usage:     "Manage the state snapshot",
+		ArgsUsage: "",
+		Category:  "BLOCKCHAIN COMMANDS",
+		Description: `
+The state snapshot is a flat dump of the accounts and storage slots of a recent
+state, enabled with --snapshot. The commands below operate on the persisted
+snapshot of a stopped node.`,
+		Subcommands: []cli.Command{
+			{
+				Name:      "verify",
+				Usage:     "Verify the state snapshot against the state trie",
+				ArgsUsage: "[<root>]",
+				Action:    utils.MigrateFlags(verifySnapshot),
+				Category:  "BLOCKCHAIN COMMANDS",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.CacheFlag,
+					utils.CacheDatabaseFlag,
+				},
+				Description: `
+    sipe snapshot verify [<root>]
+
+Walks the state trie and the persisted snapshot side by side, checking that
+every account and storage slot of the trie has an identical snapshot entry and
+that the snapshot contains nothing else.
+
+The persisted snapshot is verified against the state it was generated for, if
+a state root is given the snapshot must belong to that state.`,
+			},
+		},
+	}
+)
+
+// verifySnapshot checks the persisted state snapshot against the state trie.
+func verifySnapshot(ctx *cli.Context) error {
+	if len(ctx.Args()) > 1 {
+		utils.Fatalf("This command accepts at most one argument")
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	chaindb := utils.MakeChainDatabase(ctx, stack)
+	defer chaindb.Close()
+
+	root := rawdb.ReadSnapshotRoot(chaindb)
+	if len(ctx.Args()) == 1 {
+		blob, err := hexutil.Decode(ctx.Args()[0])
+		if err != nil || len(blob) != common.HashLength {
+			utils.Fatalf("Invalid state root: %s", ctx.Args()[0])
+		}
+		root = common.BytesToHash(blob)
+	}
+	if root == (common.Hash{}) {
+		utils.Fatalf("No complete state snapshot found")
+	}
+	start := time.Now()
+	stats, err := snapshot.Verify(chaindb, trie.NewDatabase(chaindb), root)
+	if err != nil {
+		if stats != nil {
+			log.Error("State snapshot verification failed", "root", root, "accounts", stats.Accounts, "slots", stats.Slots, "err", err)
+		}
+		return err
+	}
+	log.Info("Verified state snapshot", "root", root, "accounts", stats.Accounts, "slots", stats.Slots, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
@@ -113,6 +113,12 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.AnchorSyncModeFlag,
 		},
 	},
+	{
+		Name: "PRIVATE TRANSACTIONS",
+		Flags: []cli.Flag{
+			utils.PrivateTxManagerFlag,
+		},
+	},
 	{
 		Name: "ETHASH",
 		Flags: []cli.Flag{
@@ -124,6 +130,12 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.EthashDatasetsOnDiskFlag,
 		},
 	},
+	{
+		Name: "SCRYPT",
+		Flags: []cli.Flag{
+			utils.ScryptWorkHistoryFlag,
+		},
+	},
 	{
 		Name: "TRANSACTION POOL",
 		Flags: []cli.Flag{
@@ -148,6 +160,9 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.CacheTrieFlag,
 			utils.CacheGCFlag,
 			utils.CacheNoPrefetchFlag,
+			utils.CacheSnapshotFlag,
+			utils.SnapshotFlag,
+			utils.ParallelTxsFlag,
 		},
 	},
 	{
@@ -157,7 +172,13 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.RaftJoinExistingFlag,
 			utils.RaftPortFlag,
 			utils.RaftBlockTimeFlag,
+			utils.RaftMinBatchTxsFlag,
+			utils.RaftMaxBatchTxsFlag,
+			utils.RaftMaxLatencyFlag,
+			utils.RaftGasTargetFlag,
+			utils.RaftHeartbeatFlag,
 			utils.RaftDNSEnabledFlag,
+			utils.RaftP2PTransportFlag,
 		},
 	},
 	{
@@ -222,6 +243,9 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.NoDiscoverFlag,
 			utils.DiscoveryV5Flag,
 			utils.NetrestrictFlag,
+			utils.PermissionNodesFlag,
+			utils.PermissionContractFlag,
+			utils.PermissionRefreshFlag,
 			utils.NodeKeyFileFlag,
 			utils.NodeKeyHexFlag,
 		},
@@ -254,6 +278,7 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.VMEnableDebugFlag,
 			utils.EVMInterpreterFlag,
 			utils.EWASMInterpreterFlag,
+			utils.TracerJSFlag,
 		},
 	},
 	{
@@ -287,9 +312,22 @@ var AppHelpFlagGroups = []flagGroup{
 		Name: "STRATUM",
 		Flags: []cli.Flag{
 			utils.StratumPort,
+			utils.StratumMode,
+			utils.StratumListeners,
 			utils.StratumPassword,
 			utils.StratumMaxConn,
 			utils.StratumHashRate,
+			utils.StratumPayout,
+			utils.StratumPPLNSWindow,
+			utils.StratumTLSCert,
+			utils.StratumTLSKey,
+			utils.StratumAuth,
+			utils.StratumAllowlist,
+			utils.StratumMaxConnPerIP,
+			utils.StratumVardiffTarget,
+			utils.StratumVardiffMin,
+			utils.StratumVardiffMax,
+			utils.StratumVardiffRetarget,
 			utils.StratumFanout,
 			utils.MinerType,
 			utils.CPUAgentOff,
@@ -64,7 +64,10 @@ import (
 	"github.com/simplechain-org/go-simplechain/p2p/nat"
 	"github.com/simplechain-org/go-simplechain/p2p/netutil"
 	"github.com/simplechain-org/go-simplechain/params"
+	"github.com/simplechain-org/go-simplechain/permission"
+	"github.com/simplechain-org/go-simplechain/private"
 	"github.com/simplechain-org/go-simplechain/rpc"
+	"github.com/simplechain-org/go-simplechain/stratum"
 	"github.com/simplechain-org/go-simplechain/sub"
 	whisper "github.com/simplechain-org/go-simplechain/whisper/whisperv6"
 	cli "gopkg.in/urfave/cli.v1"
@@ -308,6 +311,12 @@ var (
 		Usage: "Number of recent ethash mining DAGs to keep on disk (1+GB each)",
 		Value: eth.DefaultConfig.Ethash.DatasetsOnDisk,
 	}
+	// Scrypt settings
+	ScryptWorkHistoryFlag = cli.IntFlag{
+		Name:  "scrypt.workhistory",
+		Usage: "Number of recent scrypt work packages remote miners can submit solutions for",
+		Value: eth.DefaultConfig.Scrypt.WorkHistory,
+	}
 	// Transaction pool settings
 	TxPoolLocalsFlag = cli.StringFlag{
 		Name:  "txpool.locals",
@@ -387,6 +396,29 @@ var (
 		Name:  "cache.noprefetch",
 		Usage: "Disable heuristic state prefetch during block import (less CPU and disk IO, more time waiting for data)",
 	}
+	CacheSnapshotFlag = cli.IntFlag{
+		Name:  "cache.snapshot",
+		Usage: "Percentage of cache memory allowance to use for snapshot caching (requires --snapshot)",
+		Value: 10,
+	}
+	SnapshotFlag = cli.BoolFlag{
+		Name:  "snapshot",
+		Usage: "Enables the flat state snapshot for faster state reads (experimental)",
+	}
+	ParallelTxsFlag = cli.BoolFlag{
+		Name:  "parallel.txs",
+		Usage: "Execute the transactions of imported blocks in parallel (experimental)",
+	}
+	PruneRetainFlag = cli.Uint64Flag{
+		Name:  "prune.retain",
+		Usage: "Number of recent block states below the head to keep when pruning",
+		Value: 127,
+	}
+	PruneBloomSizeFlag = cli.Uint64Flag{
+		Name:  "prune.bloomsize",
+		Usage: "Megabytes of memory allocated to the bloom filter of reachable state when pruning",
+		Value: 2048,
+	}
 	// Miner settings
 	MiningEnabledFlag = cli.BoolFlag{
 		Name:  "mine",
@@ -441,7 +473,66 @@ var (
 	}
 	StratumHashRate = cli.BoolFlag{
 		Name:  "stratum.hashrate",
-		Usage: "calc stratum miner's hashRate , if turn on,sipe can estimate stratum miner's HashRate",
+		Usage: "Estimate and report the hash rate of stratum miners (variable difficulty works without it)",
+	}
+	StratumMode = cli.StringFlag{
+		Name:  "stratum.mode",
+		Usage: "Protocol of the stratum.port listener (legacy, standard)",
+		Value: "legacy",
+	}
+	StratumListeners = cli.StringFlag{
+		Name:  "stratum.listeners",
+		Usage: "Comma separated additional stratum listeners as address=mode (e.g. :3333=standard)",
+	}
+	StratumTLSCert = cli.StringFlag{
+		Name:  "stratum.tlscert",
+		Usage: "PEM certificate of the stratum TLS listener, plain TCP if not set",
+	}
+	StratumTLSKey = cli.StringFlag{
+		Name:  "stratum.tlskey",
+		Usage: "PEM private key of the stratum TLS listener",
+	}
+	StratumAuth = cli.StringFlag{
+		Name:  "stratum.auth",
+		Usage: "Stratum authentication backend (password, signature, allowlist)",
+		Value: "password",
+	}
+	StratumAllowlist = cli.StringFlag{
+		Name:  "stratum.allowlist",
+		Usage: "File of the usernames admitted by the allowlist authentication, reloaded on change",
+	}
+	StratumMaxConnPerIP = cli.IntFlag{
+		Name:  "stratum.maxperip",
+		Usage: "Maximum number of stratum connections from a single IP (0 = no limit)",
+	}
+	StratumVardiffTarget = cli.DurationFlag{
+		Name:  "stratum.vardiff.target",
+		Usage: "Share interval the variable difficulty of stratum sessions aims for",
+		Value: stratum.DefaultVardiffConfig.TargetTime,
+	}
+	StratumVardiffMin = cli.Uint64Flag{
+		Name:  "stratum.vardiff.min",
+		Usage: "Minimum difficulty of stratum sessions",
+		Value: stratum.DefaultVardiffConfig.MinDifficulty,
+	}
+	StratumVardiffMax = cli.Uint64Flag{
+		Name:  "stratum.vardiff.max",
+		Usage: "Maximum difficulty of stratum sessions (0 = network difficulty)",
+	}
+	StratumVardiffRetarget = cli.DurationFlag{
+		Name:  "stratum.vardiff.retarget",
+		Usage: "Minimum time between two difficulty retargets of a stratum session",
+		Value: stratum.DefaultVardiffConfig.RetargetPeriod,
+	}
+	StratumPayout = cli.StringFlag{
+		Name:  "stratum.payout",
+		Usage: "Payout scheme sharing the rewards of found blocks between stratum workers (pplns, prop)",
+		Value: stratum.PayoutPPLNS,
+	}
+	StratumPPLNSWindow = cli.Uint64Flag{
+		Name:  "stratum.pplns",
+		Usage: "Number of last accepted shares a PPLNS payout is shared over",
+		Value: stratum.DefaultPPLNSWindow,
 	}
 	MinerLegacyGasTargetFlag = cli.Uint64Flag{
 		Name:  "targetgaslimit",
@@ -743,6 +834,19 @@ var (
 		Name:  "netrestrict",
 		Usage: "Restricts network communication to the given IP networks (CIDR masks)",
 	}
+	PermissionNodesFlag = cli.StringFlag{
+		Name:  "permission.nodes",
+		Usage: "JSON file listing the enode URLs allowed to connect (enables node permissioning)",
+	}
+	PermissionContractFlag = cli.StringFlag{
+		Name:  "permission.contract",
+		Usage: "Address of the contract listing the enode URLs allowed to connect (enables node permissioning)",
+	}
+	PermissionRefreshFlag = cli.DurationFlag{
+		Name:  "permission.refresh",
+		Usage: "Interval at which the permissioned node list is reloaded",
+		Value: permission.DefaultRefreshPeriod,
+	}
 
 	// ATM the url is left to the user and deployment to
 	JSpathFlag = cli.StringFlag{
@@ -801,10 +905,39 @@ var (
 		Usage: "Amount of time between raft block creations in milliseconds",
 		Value: 50,
 	}
+	RaftMinBatchTxsFlag = cli.Uint64Flag{
+		Name:  "raftminbatch",
+		Usage: "Number of pending transactions worth minting a raft block for",
+		Value: params.DefaultRaftConfig.MinBatchTxs,
+	}
+	RaftMaxBatchTxsFlag = cli.Uint64Flag{
+		Name:  "raftmaxbatch",
+		Usage: "Maximum number of transactions in a raft block (0 = unlimited)",
+		Value: params.DefaultRaftConfig.MaxBatchTxs,
+	}
+	RaftMaxLatencyFlag = cli.Uint64Flag{
+		Name:  "raftmaxlatency",
+		Usage: "Milliseconds a transaction may wait for its raft batch to fill up (0 = no deadline)",
+		Value: params.DefaultRaftConfig.MaxLatency,
+	}
+	RaftGasTargetFlag = cli.Uint64Flag{
+		Name:  "raftgastarget",
+		Usage: "Gas limit of pending transactions worth minting a raft block for (0 = disabled)",
+		Value: params.DefaultRaftConfig.GasTarget,
+	}
+	RaftHeartbeatFlag = cli.Uint64Flag{
+		Name:  "raftheartbeat",
+		Usage: "Milliseconds without raft blocks before an empty heartbeat block is minted (0 = never)",
+		Value: params.DefaultRaftConfig.HeartbeatInterval,
+	}
 	RaftDNSEnabledFlag = cli.BoolFlag{
 		Name:  "raftdnsenable",
 		Usage: "Enable DNS resolution of peers",
 	}
+	RaftP2PTransportFlag = cli.BoolFlag{
+		Name:  "raftp2p",
+		Usage: "Carry raft messages over the p2p connection instead of a separate raft port",
+	}
 	RaftEmitCheckpointsFlag = cli.BoolFlag{
 		Name:  "raftcheckpoints",
 		Usage: "If enabled, emit specially formatted logging checkpoints",
@@ -875,6 +1008,10 @@ var (
 		Usage: "External EVM configuration (default = built-in interpreter)",
 		Value: "",
 	}
+	TracerJSFlag = cli.BoolFlag{
+		Name:  "tracer.js",
+		Usage: "Run the built-in transaction tracers in the JavaScript interpreter instead of natively",
+	}
 	role = eth.DefaultConfig.Role
 
 	RoleFlag = TextMarshalerFlag{
@@ -890,6 +1027,10 @@ var (
 		Name:  "contract.sub",
 		Usage: "The address of sub contract",
 	}
+	PrivateTxManagerFlag = cli.StringFlag{
+		Name:  "private.manager",
+		Usage: "URL or IPC socket path of the private transaction manager (default = no private transactions)",
+	}
 	AnchorSignerFlag = cli.StringFlag{
 		Name:  "anchor.signer",
 		Usage: "public address of anchor signer",
@@ -1449,6 +1590,28 @@ func setEthash(ctx *cli.Context, cfg *eth.Config) {
 	}
 }
 
+func setScrypt(ctx *cli.Context, cfg *eth.Config) {
+	if ctx.GlobalIsSet(ScryptWorkHistoryFlag.Name) {
+		cfg.Scrypt.WorkHistory = ctx.GlobalInt(ScryptWorkHistoryFlag.Name)
+	}
+}
+
+// setPrivateTxManager connects to the private transaction manager configured
+// on the command line or in the config file.
+func setPrivateTxManager(ctx *cli.Context, cfg *eth.Config) {
+	if ctx.GlobalIsSet(PrivateTxManagerFlag.Name) {
+		cfg.PrivateTxManagerURL = ctx.GlobalString(PrivateTxManagerFlag.Name)
+	}
+	if cfg.PrivateTxManagerURL == "" {
+		return
+	}
+	manager, err := private.NewHTTPManager(cfg.PrivateTxManagerURL)
+	if err != nil {
+		Fatalf("Failed to connect to the private transaction manager: %v", err)
+	}
+	cfg.PrivateTxManager = manager
+}
+
 func setMiner(ctx *cli.Context, cfg *miner.Config) {
 	if ctx.GlobalIsSet(MinerNotifyFlag.Name) {
 		cfg.Notify = strings.Split(ctx.GlobalString(MinerNotifyFlag.Name), ",")
@@ -1583,12 +1746,14 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 	setGPO(ctx, &cfg.GPO)
 	setTxPool(ctx, &cfg.TxPool)
 	setEthash(ctx, cfg)
+	setScrypt(ctx, cfg)
 	setMiner(ctx, &cfg.Miner)
 	setIstanbul(ctx, cfg)
 	setWhitelist(ctx, cfg)
 	setLes(ctx, cfg)
 	setAnchorSign(ctx, ks, cfg)
 	setAnchorSync(ctx, cfg)
+	setPrivateTxManager(ctx, cfg)
 
 	if ctx.GlobalIsSet(SyncModeFlag.Name) {
 		cfg.SyncMode = *GlobalTextMarshaler(ctx, SyncModeFlag.Name).(*downloader.SyncMode)
@@ -1619,6 +1784,12 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheGCFlag.Name) {
 		cfg.TrieDirtyCache = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheGCFlag.Name) / 100
 	}
+	if ctx.GlobalBool(SnapshotFlag.Name) {
+		cfg.SnapshotCache = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheSnapshotFlag.Name) / 100
+	}
+	if ctx.GlobalIsSet(ParallelTxsFlag.Name) {
+		cfg.ParallelTxs = ctx.GlobalBool(ParallelTxsFlag.Name)
+	}
 	if ctx.GlobalIsSet(DocRootFlag.Name) {
 		cfg.DocRoot = ctx.GlobalString(DocRootFlag.Name)
 	}
@@ -1634,6 +1805,9 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *eth.Config) {
 	if ctx.GlobalIsSet(EVMInterpreterFlag.Name) {
 		cfg.EVMInterpreter = ctx.GlobalString(EVMInterpreterFlag.Name)
 	}
+	if ctx.GlobalIsSet(TracerJSFlag.Name) {
+		cfg.JSTracers = ctx.GlobalBool(TracerJSFlag.Name)
+	}
 	if ctx.GlobalIsSet(RPCGlobalGasCap.Name) {
 		cfg.RPCGasCap = new(big.Int).SetUint64(ctx.GlobalUint64(RPCGlobalGasCap.Name))
 	}
@@ -1790,6 +1964,43 @@ func RegisterGraphQLService(stack *node.Node, endpoint string, cors, vhosts []st
 	}
 }
 
+// RegisterPermissionService configures node permissioning if requested and
+// registers the enforcing service against the node.
+func RegisterPermissionService(stack *node.Node, ctx *cli.Context) {
+	var (
+		file     = ctx.GlobalString(PermissionNodesFlag.Name)
+		contract = ctx.GlobalString(PermissionContractFlag.Name)
+		period   = ctx.GlobalDuration(PermissionRefreshFlag.Name)
+	)
+	switch {
+	case file == "" && contract == "":
+		return
+	case file != "" && contract != "":
+		Fatalf("Flags --%s and --%s are mutually exclusive", PermissionNodesFlag.Name, PermissionContractFlag.Name)
+	case contract != "" && !common.IsHexAddress(contract):
+		Fatalf("Option %q: invalid address %q", PermissionContractFlag.Name, contract)
+	}
+	if err := stack.Register(func(sctx *node.ServiceContext) (node.Service, error) {
+		if file != "" {
+			return permission.New(permission.NewFileSource(file), period), nil
+		}
+		// Query the permission contract through whichever chain backend is running
+		address := common.HexToAddress(contract)
+
+		var ethServ *eth.Ethereum
+		if err := sctx.Service(&ethServ); err == nil {
+			return permission.New(permission.NewContractSource(address, permission.NewBackendCaller(ethServ.APIBackend)), period), nil
+		}
+		var lesServ *les.LightEthereum
+		if err := sctx.Service(&lesServ); err == nil {
+			return permission.New(permission.NewContractSource(address, permission.NewBackendCaller(lesServ.ApiBackend)), period), nil
+		}
+		return nil, errors.New("no Ethereum service")
+	}); err != nil {
+		Fatalf("Failed to register the permission service: %v", err)
+	}
+}
+
 func SetupMetrics(ctx *cli.Context) {
 	if metrics.Enabled {
 		log.Info("Enabling metrics collection")
@@ -1898,7 +2109,13 @@ func MakeChain(ctx *cli.Context, stack *node.Node) (chain *core.BlockChain, chai
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheGCFlag.Name) {
 		cache.TrieDirtyLimit = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheGCFlag.Name) / 100
 	}
-	vmcfg := vm.Config{EnablePreimageRecording: ctx.GlobalBool(VMEnableDebugFlag.Name)}
+	if ctx.GlobalBool(SnapshotFlag.Name) {
+		cache.SnapshotLimit = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheSnapshotFlag.Name) / 100
+	}
+	vmcfg := vm.Config{
+		EnablePreimageRecording: ctx.GlobalBool(VMEnableDebugFlag.Name),
+		ParallelTxs:             ctx.GlobalBool(ParallelTxsFlag.Name),
+	}
 	chain, err = core.NewBlockChain(chainDb, cache, config, engine, vmcfg, nil)
 	if err != nil {
 		Fatalf("Can't create BlockChain: %v", err)
@@ -19,12 +19,23 @@
 package dpos
 
 import (
+	"bytes"
+	"errors"
+	"math/big"
+	"sort"
+
 	"github.com/simplechain-org/go-simplechain/common"
+	"github.com/simplechain-org/go-simplechain/common/hexutil"
 	"github.com/simplechain-org/go-simplechain/consensus"
 	"github.com/simplechain-org/go-simplechain/core/types"
 	"github.com/simplechain-org/go-simplechain/rpc"
 )
 
+var (
+	errInvalidRewardsRange  = errors.New("fromBlock is after toBlock")
+	errRewardsRangeTooLarge = errors.New("block range too large")
+)
+
 // API is a user facing RPC API to allow controlling the signer and voting
 // mechanisms of the delegated-proof-of-stake scheme.
 type API struct {
@@ -66,3 +77,112 @@ func (api *API) GetSnapshotAtNumber(number uint64) (*Snapshot, error) {
 	}
 	return api.dpos.snapshot(api.chain, header.Number.Uint64(), header.Hash(), nil, nil, defaultLoopCntRecalculateSigners)
 }
+
+// maxRewardsRange is the maximum number of blocks a reward query may span.
+const maxRewardsRange = 10000
+
+// RewardEntry is a payout of a block to an account.
+type RewardEntry struct {
+	BlockNumber uint64       `json:"blockNumber"`
+	BlockHash   common.Hash  `json:"blockHash"`
+	Type        string       `json:"type"` // miner, voter, proposalRefund or gasRefund
+	Amount      *hexutil.Big `json:"amount"`
+}
+
+// Rewards is the reward history of an account over a block range.
+type Rewards struct {
+	Address common.Address `json:"address"`
+	Total   *hexutil.Big   `json:"total"`
+	Rewards []RewardEntry  `json:"rewards"`
+	Missing []uint64       `json:"missing,omitempty"` // Blocks without a reward record, e.g. imported before the index existed
+}
+
+// GetRewards retrieves the rewards paid to an address by the canonical blocks
+// within the given range.
+func (api *API) GetRewards(address common.Address, fromBlock rpc.BlockNumber, toBlock rpc.BlockNumber) (*Rewards, error) {
+	from, to := api.resolveNumber(fromBlock), api.resolveNumber(toBlock)
+	if from > to {
+		return nil, errInvalidRewardsRange
+	}
+	if to-from >= maxRewardsRange {
+		return nil, errRewardsRangeTooLarge
+	}
+	result := &Rewards{
+		Address: address,
+		Rewards: []RewardEntry{},
+	}
+	total := new(big.Int)
+	for number := from; number <= to; number++ {
+		header := api.chain.GetHeaderByNumber(number)
+		if header == nil {
+			break
+		}
+		if number == 0 {
+			continue // genesis pays no rewards
+		}
+		record, err := loadBlockRewards(api.dpos.db, header.Hash())
+		if err != nil {
+			result.Missing = append(result.Missing, number)
+			continue
+		}
+		rewards := record.rewardsOf(address)
+		for _, typ := range []string{rewardTypeMiner, rewardTypeVoter, rewardTypeProposalRefund, rewardTypeGasRefund} {
+			if amount, ok := rewards[typ]; ok {
+				result.Rewards = append(result.Rewards, RewardEntry{
+					BlockNumber: number,
+					BlockHash:   header.Hash(),
+					Type:        typ,
+					Amount:      (*hexutil.Big)(new(big.Int).Set(amount)),
+				})
+				total.Add(total, amount)
+			}
+		}
+	}
+	result.Total = (*hexutil.Big)(total)
+	return result, nil
+}
+
+// VoterInfo is a vote counted for a candidate.
+type VoterInfo struct {
+	Voter  common.Address `json:"voter"`
+	Stake  *hexutil.Big   `json:"stake"`
+	Number uint64         `json:"number"` // Block number of the vote
+}
+
+// GetVoters retrieves the voters of a candidate at a given block, by
+// descending stake.
+func (api *API) GetVoters(candidate common.Address, number *rpc.BlockNumber) ([]*VoterInfo, error) {
+	snap, err := api.GetSnapshot(number)
+	if err != nil {
+		return nil, err
+	}
+	voters := []*VoterInfo{}
+	for voter, vote := range snap.Votes {
+		if vote.Candidate != candidate {
+			continue
+		}
+		info := &VoterInfo{
+			Voter: voter,
+			Stake: (*hexutil.Big)(new(big.Int).Set(vote.Stake)),
+		}
+		if voteNumber, ok := snap.Voters[voter]; ok {
+			info.Number = voteNumber.Uint64()
+		}
+		voters = append(voters, info)
+	}
+	sort.Slice(voters, func(i, j int) bool {
+		if cmp := voters[i].Stake.ToInt().Cmp(voters[j].Stake.ToInt()); cmp != 0 {
+			return cmp > 0
+		}
+		return bytes.Compare(voters[i].Voter[:], voters[j].Voter[:]) < 0
+	})
+	return voters, nil
+}
+
+// resolveNumber maps an RPC block number onto the local chain.
+func (api *API) resolveNumber(number rpc.BlockNumber) uint64 {
+	if number == rpc.LatestBlockNumber || number == rpc.PendingBlockNumber {
+		return api.chain.CurrentHeader().Number.Uint64()
+	}
+	return uint64(number.Int64())
+}
@@ -32,6 +32,7 @@ import (
 	"github.com/simplechain-org/go-simplechain/core/types"
 	"github.com/simplechain-org/go-simplechain/crypto"
 	"github.com/simplechain-org/go-simplechain/ethdb"
+	"github.com/simplechain-org/go-simplechain/event"
 	"github.com/simplechain-org/go-simplechain/log"
 	"github.com/simplechain-org/go-simplechain/params"
 	"github.com/simplechain-org/go-simplechain/rlp"
@@ -53,7 +54,7 @@ var (
 	totalBlockReward                 = new(big.Int).Mul(big.NewInt(1e+18), big.NewInt(2.5e+8)) // Block reward in wei
 	defaultEpochLength               = uint64(201600)                                          // Default number of blocks after which vote's period of validity, About one week if period is 3
 	defaultBlockPeriod               = uint64(3)                                               // Default minimum difference between two consecutive block's timestamps
-	defaultMaxSignerCount            = uint64(21)                                              //
+	DefaultMaxSignerCount            = uint64(21)                                              // Default number of signers in the queue, and so of blocks in a loop
 	minVoterBalance                  = new(big.Int).Mul(big.NewInt(100), big.NewInt(1e+18))
 	extraVanity                      = 32                                                    // Fixed number of extra-data prefix bytes reserved for signer vanity
 	extraSeal                        = 65                                                    // Fixed number of extra-data suffix bytes reserved for signer seal
@@ -127,6 +128,14 @@ var (
 
 	// errLastLoopHeaderFail is returned when try to get header of last loop fail
 	errLastLoopHeaderFail = errors.New("get last loop header fail")
+
+	// errInvalidLoopHeader is returned if a light client is handed a header not
+	// opening the loop after the trusted one
+	errInvalidLoopHeader = errors.New("invalid loop header")
+
+	// errInvalidLoopStartTime is returned if a header opening a loop doesn't start
+	// it one loop of periods after the loop before it
+	errInvalidLoopStartTime = errors.New("invalid loop start time")
 )
 
 // DPoS is the delegated-proof-of-stake consensus engine.
@@ -138,6 +147,9 @@ type DPoS struct {
 	signer     common.Address     // Ethereum address of the signing key
 	signFn     SignerFn           // Signer function to authorize hashes with
 	lock       sync.RWMutex       // Protects the signer fields
+
+	rewards    *lru.ARCCache      // Rewards of recently finalized blocks, until they turn canonical
+	rewardsSub event.Subscription // Subscription to the canonical blocks to index the rewards of
 }
 
 // SignerFn is a signer callback function to request a hash to be signed by a
@@ -229,7 +241,7 @@ func New(config *params.DPoSConfig, db ethdb.Database) *DPoS {
 		conf.Period = defaultBlockPeriod
 	}
 	if conf.MaxSignerCount == 0 {
-		conf.MaxSignerCount = defaultMaxSignerCount
+		conf.MaxSignerCount = DefaultMaxSignerCount
 	}
 	if conf.MinVoterBalance.Uint64() > 0 {
 		minVoterBalance = conf.MinVoterBalance
@@ -238,12 +250,14 @@ func New(config *params.DPoSConfig, db ethdb.Database) *DPoS {
 	// Allocate the snapshot caches and create the engine
 	recents, _ := lru.NewARC(inMemorySnapshots)
 	signatures, _ := lru.NewARC(inMemorySignatures)
+	rewards, _ := lru.NewARC(inMemoryRewards)
 
 	return &DPoS{
 		config:     &conf,
 		db:         db,
 		recents:    recents,
 		signatures: signatures,
+		rewards:    rewards,
 	}
 }
 
@@ -361,8 +375,9 @@ func (d *DPoS) snapshot(chain consensus.ChainReader, number uint64, hash common.
 			snap = s.(*Snapshot)
 			break
 		}
-		// If an on-disk checkpoint snapshot can be found, use that
-		if number%checkpointInterval == 0 {
+		// If an on-disk checkpoint snapshot can be found, use that, light clients
+		// storing theirs at the loop header they start from
+		if number%checkpointInterval == 0 || number%d.config.MaxSignerCount == 0 {
 			if s, err := loadSnapshot(d.config, d.signatures, d.db, hash); err == nil {
 				log.Trace("Loaded voting snapshot from disk", "number", number, "hash", hash)
 				snap = s
@@ -487,11 +502,18 @@ func (d *DPoS) verifySeal(chain consensus.ChainReader, header *types.Header, par
 		}
 		// verify signerqueue
 		if number%d.config.MaxSignerCount == 0 {
-			err := snap.verifySignerQueue(currentHeaderExtra.SignerQueue)
-			if err != nil {
+			if snap.LightCheckpoint != 0 {
+				// The tallies before a light checkpoint are unknown, so the queue
+				// sealed by the in-turn signer is trusted instead of recreated
+				if len(currentHeaderExtra.SignerQueue) == 0 || len(currentHeaderExtra.SignerQueue) > int(d.config.MaxSignerCount) {
+					return errInvalidSignerQueue
+				}
+				if currentHeaderExtra.LoopStartTime != parentHeaderExtra.LoopStartTime+d.config.Period*d.config.MaxSignerCount {
+					return errInvalidLoopStartTime
+				}
+			} else if err := snap.verifySignerQueue(currentHeaderExtra.SignerQueue); err != nil {
 				return err
 			}
-
 		} else {
 			for i := 0; i < int(d.config.MaxSignerCount); i++ {
 				if parentHeaderExtra.SignerQueue[i] != currentHeaderExtra.SignerQueue[i] {
@@ -505,7 +527,10 @@ func (d *DPoS) verifySeal(chain consensus.ChainReader, header *types.Header, par
 		}
 
 		// verify missing signer for punish
-		var grandParentHeaderExtra HeaderExtra
+		var (
+			grandParentHeaderExtra HeaderExtra
+			checkMissing           = true
+		)
 		if number%d.config.MaxSignerCount == 1 {
 			var grandParent *types.Header
 			if len(parents) > 1 {
@@ -513,24 +538,32 @@ func (d *DPoS) verifySeal(chain consensus.ChainReader, header *types.Header, par
 			} else {
 				grandParent = chain.GetHeader(parent.ParentHash, number-2)
 			}
-			if grandParent == nil {
+			switch {
+			case grandParent != nil:
+				err := decodeHeaderExtra(grandParent.Extra[extraVanity:len(grandParent.Extra)-extraSeal], &grandParentHeaderExtra)
+				if err != nil {
+					log.Info("Fail to decode parent header", "err", err)
+					return err
+				}
+			case snap.LightCheckpoint == number-1:
+				// The loop before a light checkpoint is unknown, so are the
+				// signers missing at its end
+				checkMissing = false
+			default:
 				return errLastLoopHeaderFail
 			}
-			err := decodeHeaderExtra(grandParent.Extra[extraVanity:len(grandParent.Extra)-extraSeal], &grandParentHeaderExtra)
-			if err != nil {
-				log.Info("Fail to decode parent header", "err", err)
-				return err
-			}
 		}
-		parentSignerMissing := getSignerMissingTrantor(parent.Coinbase, header.Coinbase, &parentHeaderExtra, &grandParentHeaderExtra)
+		if checkMissing {
+			parentSignerMissing := getSignerMissingTrantor(parent.Coinbase, header.Coinbase, &parentHeaderExtra, &grandParentHeaderExtra)
 
-		if len(parentSignerMissing) != len(currentHeaderExtra.SignerMissing) {
-			return errPunishedMissing
-		}
-		for i, signerMissing := range currentHeaderExtra.SignerMissing {
-			if parentSignerMissing[i] != signerMissing {
+			if len(parentSignerMissing) != len(currentHeaderExtra.SignerMissing) {
 				return errPunishedMissing
 			}
+			for i, signerMissing := range currentHeaderExtra.SignerMissing {
+				if parentSignerMissing[i] != signerMissing {
+					return errPunishedMissing
+				}
+			}
 		}
 	}
 
@@ -541,6 +574,105 @@ func (d *DPoS) verifySeal(chain consensus.ChainReader, header *types.Header, par
 	return nil
 }
 
+// loopExtra returns the header extra of the given header, holding the signer
+// queue and the loop start time handed to the blocks after it. The genesis has
+// none, its blocks after it running the self voted signers from the genesis
+// timestamp on.
+func (d *DPoS) loopExtra(header *types.Header) (*HeaderExtra, error) {
+	extra := new(HeaderExtra)
+	if header.Number.Uint64() == 0 {
+		extra.LoopStartTime = d.config.GenesisTimestamp
+		for i := 0; i < int(d.config.MaxSignerCount) && len(d.config.SelfVoteSigners) > 0; i++ {
+			extra.SignerQueue = append(extra.SignerQueue, common.Address(d.config.SelfVoteSigners[i%len(d.config.SelfVoteSigners)]))
+		}
+		return extra, nil
+	}
+	if len(header.Extra) < extraVanity+extraSeal {
+		return nil, errMissingSignature
+	}
+	if err := decodeHeaderExtra(header.Extra[extraVanity:len(header.Extra)-extraSeal], extra); err != nil {
+		return nil, err
+	}
+	return extra, nil
+}
+
+// VerifyLoopHeader checks the header opening a loop against a trusted header of
+// the loop before it, without the chain of headers leading to it. The header has
+// to be sealed by the signer in turn in the trusted signer queue and start its
+// loop one loop of periods after the trusted one. Its own signer queue can't be
+// recreated without the tallies, so it's trusted as sealed, a signer sealing
+// conflicting queues being punished by the double signing evidences.
+func (d *DPoS) VerifyLoopHeader(trusted, header *types.Header) error {
+	loop := d.config.MaxSignerCount
+	if header.Number.Uint64() != (trusted.Number.Uint64()/loop+1)*loop {
+		return errInvalidLoopHeader
+	}
+	if header.Time > uint64(time.Now().Unix()) {
+		return consensus.ErrFutureBlock
+	}
+	prev, err := d.loopExtra(trusted)
+	if err != nil {
+		return err
+	}
+	if len(prev.SignerQueue) == 0 {
+		return errSignerQueueEmpty
+	}
+	if header.Time < trusted.Time || header.Time < prev.LoopStartTime {
+		return ErrInvalidTimestamp
+	}
+	signer, err := ecrecover(header, d.signatures)
+	if err != nil {
+		return err
+	}
+	slot := (header.Time - prev.LoopStartTime) / d.config.Period
+	if signer != header.Coinbase || prev.SignerQueue[slot%uint64(len(prev.SignerQueue))] != signer {
+		return ErrUnauthorized
+	}
+	extra, err := d.loopExtra(header)
+	if err != nil {
+		return err
+	}
+	if len(extra.SignerQueue) == 0 || len(extra.SignerQueue) > int(loop) {
+		return errInvalidSignerQueue
+	}
+	if extra.LoopStartTime != prev.LoopStartTime+d.config.Period*loop {
+		return errInvalidLoopStartTime
+	}
+	return nil
+}
+
+// TrustLoopHeader stores the snapshot at a loop header verified by a light
+// client, from which the headers after it can be verified without the history
+// before it. The snapshot starts the signer queue and the loop of the header
+// with no votes tallied, marked as a light checkpoint to trust the signer queues
+// of the next loops as sealed.
+func (d *DPoS) TrustLoopHeader(header *types.Header) error {
+	number := header.Number.Uint64()
+	if number == 0 || number%d.config.MaxSignerCount != 0 {
+		return errInvalidLoopHeader
+	}
+	extra, err := d.loopExtra(header)
+	if err != nil {
+		return err
+	}
+	snap := newSnapshot(d.config, d.signatures, header.Hash(), nil, defaultLoopCntRecalculateSigners)
+	snap.Number = number
+	snap.LightCheckpoint = number
+	snap.ConfirmedNumber = extra.ConfirmedBlockNumber
+	snap.HeaderTime = header.Time
+	snap.LoopStartTime = extra.LoopStartTime
+	snap.Signers = nil
+	for i := range extra.SignerQueue {
+		snap.Signers = append(snap.Signers, &extra.SignerQueue[i])
+	}
+	if err := snap.store(d.db); err != nil {
+		return err
+	}
+	d.recents.Add(snap.Hash, snap)
+	log.Trace("Stored light checkpoint snapshot to disk", "number", number, "hash", snap.Hash)
+	return nil
+}
+
 // Prepare implements consensus.Engine, preparing all the consensus fields of the
 // header for running the transactions on top.
 func (d *DPoS) Prepare(chain consensus.ChainReader, header *types.Header) error {
@@ -673,7 +805,8 @@ func (d *DPoS) Finalize(chain consensus.ChainReader, header *types.Header, state
 	}
 
 	// Accumulate any block rewards and commit the final state root
-	if err := accumulateRewards(chain.Config(), state, header, snap, refundGas); err != nil {
+	rewards, err := accumulateRewards(chain.Config(), state, header, snap, refundGas)
+	if err != nil {
 		return ErrUnauthorized
 	}
 
@@ -693,6 +826,8 @@ func (d *DPoS) Finalize(chain consensus.ChainReader, header *types.Header, state
 	// No uncle block
 	header.UncleHash = types.CalcUncleHash(nil)
 
+	// Keep the rewards until the block turns canonical and they get indexed
+	d.rewards.Add(pendingRewardsKey(header), rewards)
 	return nil
 }
 
@@ -826,54 +961,76 @@ func (d *DPoS) APIs(chain consensus.ChainReader) []rpc.API {
 	}}
 }
 
+// Close implements consensus.Engine, stopping the rewards index.
 func (d *DPoS) Close() error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if d.rewardsSub != nil {
+		d.rewardsSub.Unsubscribe()
+		d.rewardsSub = nil
+	}
 	return nil
 }
 
-// AccumulateRewards credits the coinbase of the given block with the mining reward.
-func accumulateRewards(config *params.ChainConfig, state *state.StateDB, header *types.Header, snap *Snapshot, refundGas RefundGas) error {
+// blockRewards splits the reward of the given block between the miner and the
+// voters of the miner.
+func blockRewards(config *params.DPoSConfig, number uint64, snap *Snapshot) (minerReward *big.Int, votersReward *big.Int) {
 	// Calculate the block reword by year
-	blockNumPerYear := secondsPerYear / config.DPoS.Period
+	blockNumPerYear := secondsPerYear / config.Period
 	initSignerBlockReward := new(big.Int).Div(totalBlockReward, big.NewInt(int64(2*blockNumPerYear)))
-	yearCount := header.Number.Uint64() / blockNumPerYear
+	yearCount := number / blockNumPerYear
 	blockReward := new(big.Int).Rsh(initSignerBlockReward, uint(yearCount))
 
-	minerReward := new(big.Int).Set(blockReward)
-
-	if config.DPoS.VoterReward {
+	minerReward = new(big.Int).Set(blockReward)
+	votersReward = new(big.Int)
 
+	if config.VoterReward {
 		minerReward.Mul(minerReward, new(big.Int).SetUint64(snap.MinerReward))
 		minerReward.Div(minerReward, big.NewInt(1000)) // cause the reward is calculate by cnt per thousand
-		votersReward := blockReward.Sub(blockReward, minerReward)
+		votersReward.Sub(blockReward, minerReward)
+	}
+	return minerReward, votersReward
+}
 
-		// rewards for the voters
+// AccumulateRewards credits the coinbase of the given block with the mining reward,
+// returning what was paid to whom.
+func accumulateRewards(config *params.ChainConfig, state *state.StateDB, header *types.Header, snap *Snapshot, refundGas RefundGas) (*BlockRewards, error) {
+	minerReward, votersReward := blockRewards(config.DPoS, header.Number.Uint64(), snap)
+	rewards := newBlockRewards(header.Number.Uint64(), header.Coinbase)
 
+	if config.DPoS.VoterReward {
+		// rewards for the voters
 		voteRewardMap, err := snap.calculateVoteReward(header.Coinbase, votersReward)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		for voter, reward := range voteRewardMap {
 			state.AddBalance(voter, reward)
+			rewards.VoterRewards[voter] = new(big.Int).Set(reward)
 		}
 	}
 
 	// calculate for proposal refund
 	for proposer, refund := range snap.calculateProposalRefund() {
 		state.AddBalance(proposer, refund)
+		rewards.ProposalRefunds[proposer] = new(big.Int).Set(refund)
 	}
 
 	// refund gas for custom txs (confirm event)
 	for sender, gas := range refundGas {
 		state.AddBalance(sender, gas)
 		minerReward.Sub(minerReward, gas)
+		rewards.GasRefunds[sender] = new(big.Int).Set(gas)
 	}
 
 	// rewards for the miner, check minerReward value for refund gas
 	if minerReward.Cmp(big.NewInt(0)) > 0 {
 		state.AddBalance(header.Coinbase, minerReward)
+		rewards.MinerReward.Set(minerReward)
 	}
 
-	return nil
+	return rewards, nil
 }
 
 // Get the signer missing from last signer till header.Coinbase
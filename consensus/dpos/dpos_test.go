@@ -17,9 +17,13 @@
 package dpos
 
 import (
+	"math/big"
 	"testing"
 
 	"github.com/simplechain-org/go-simplechain/common"
+	"github.com/simplechain-org/go-simplechain/core/rawdb"
+	"github.com/simplechain-org/go-simplechain/core/types"
+	"github.com/simplechain-org/go-simplechain/params"
 )
 
 func Test_PenaltyTrantor(t *testing.T) {
@@ -235,3 +239,98 @@ func Test_Penalty(t *testing.T) {
 
 	}
 }
+
+// testerLoopReader is a chain reader serving the headers a light client synced.
+type testerLoopReader struct {
+	testerChainReader
+	headers map[common.Hash]*types.Header
+}
+
+func (r *testerLoopReader) GetHeader(hash common.Hash, number uint64) *types.Header {
+	return r.headers[hash]
+}
+
+// Tests that light clients verify the loop headers by the signer queue of the
+// loop before them, and the headers after a trusted loop header without the
+// history before it.
+func TestVerifyLoopHeader(t *testing.T) {
+	accounts := newTesterAccountPool()
+	config := &params.DPoSConfig{
+		Period:           3,
+		MaxSignerCount:   3,
+		GenesisTimestamp: 1000,
+		MinVoterBalance:  big.NewInt(1),
+	}
+	for _, signer := range []string{"A", "B", "C"} {
+		config.SelfVoteSigners = append(config.SelfVoteSigners, common.UnprefixedAddress(accounts.address(signer)))
+	}
+	header := func(number, time uint64, signer, coinbase string, queue []string, loopStart uint64) *types.Header {
+		extra := HeaderExtra{LoopStartTime: loopStart}
+		for _, name := range queue {
+			extra.SignerQueue = append(extra.SignerQueue, accounts.address(name))
+		}
+		enc, err := encodeHeaderExtra(extra)
+		if err != nil {
+			t.Fatalf("failed to encode header extra: %v", err)
+		}
+		h := &types.Header{
+			Number:     new(big.Int).SetUint64(number),
+			Time:       time,
+			Coinbase:   accounts.address(coinbase),
+			Difficulty: big.NewInt(1),
+			Extra:      append(append(make([]byte, extraVanity), enc...), make([]byte, extraSeal)...),
+		}
+		accounts.sign(h, signer)
+		return h
+	}
+	var (
+		genesis = &types.Header{Number: big.NewInt(0), Time: 1000}
+		first   = header(3, 1009, "A", "A", []string{"B", "C", "A"}, 1009)
+		second  = header(6, 1018, "B", "B", []string{"C", "A", "B"}, 1018)
+	)
+	tests := []struct {
+		trusted *types.Header
+		header  *types.Header
+		err     error
+	}{
+		// The first loop runs the self voted signers from the genesis timestamp on
+		{genesis, first, nil},
+		// The next loops run the signer queue of the loop before them
+		{first, second, nil},
+		// The header has to be sealed by the signer in turn
+		{genesis, header(3, 1009, "B", "B", []string{"B", "C", "A"}, 1009), ErrUnauthorized},
+		{genesis, header(3, 1012, "A", "A", []string{"B", "C", "A"}, 1009), ErrUnauthorized},
+		{genesis, header(3, 1009, "A", "B", []string{"B", "C", "A"}, 1009), ErrUnauthorized},
+		// The header has to open the loop after the trusted one
+		{genesis, second, errInvalidLoopHeader},
+		{first, header(4, 1012, "B", "B", []string{"C", "A", "B"}, 1018), errInvalidLoopHeader},
+		// The header has to start its loop one loop of periods after the trusted one
+		{genesis, header(3, 1009, "A", "A", []string{"B", "C", "A"}, 1012), errInvalidLoopStartTime},
+		// The signer queue can't be empty or longer than the loop
+		{genesis, header(3, 1009, "A", "A", nil, 1009), errInvalidSignerQueue},
+		{genesis, header(3, 1009, "A", "A", []string{"B", "C", "A", "B"}, 1009), errInvalidSignerQueue},
+	}
+	for i, tt := range tests {
+		engine := New(config, rawdb.NewMemoryDatabase())
+		if err := engine.VerifyLoopHeader(tt.trusted, tt.header); err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+	// Headers after a trusted loop header verify without the history before it,
+	// from the light checkpoint stored to disk
+	db := rawdb.NewMemoryDatabase()
+	if err := New(config, db).TrustLoopHeader(second); err != nil {
+		t.Fatalf("failed to trust loop header: %v", err)
+	}
+	next := header(7, 1021, "A", "A", []string{"C", "A", "B"}, 1018)
+	next.ParentHash = second.Hash()
+	accounts.sign(next, "A")
+
+	chain := &testerLoopReader{headers: map[common.Hash]*types.Header{second.Hash(): second}}
+	if err := New(config, db).VerifySeal(chain, next); err != nil {
+		t.Errorf("header after light checkpoint: verification failed: %v", err)
+	}
+	if err := New(config, rawdb.NewMemoryDatabase()).VerifySeal(chain, next); err == nil {
+		t.Errorf("header without light checkpoint: verification passed")
+	}
+}
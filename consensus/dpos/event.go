@@ -171,6 +171,7 @@ type HeaderExtra struct {
 	SignerQueue               []common.Address
 	SignerMissing             []common.Address
 	ConfirmedBlockNumber      uint64
+	CurrentBlockEvidences     []Evidence `rlp:"tail"` // empty before the system contract fork, headers without evidence encode as before
 }
 
 // Encode HeaderExtra
@@ -202,47 +203,28 @@ func (d *DPoS) processTxEvent(headerExtra HeaderExtra, chain consensus.ChainRead
 		}
 	}
 
-	for _, tx := range txs {
+	systemContract := d.config.IsSystemContract(header.Number)
+	emitted := make(map[int][]*types.Log)
+
+	for i, tx := range txs {
 
 		txSender, err := types.Sender(types.NewEIP155Signer(tx.ChainId()), tx)
 		if err != nil {
 			continue
 		}
 
-		if len(string(tx.Data())) >= len(dposPrefix) {
-			txData := string(tx.Data())
-			txDataInfo := strings.Split(txData, ":")
-			if len(txDataInfo) >= dposMinSplitLen {
-				if txDataInfo[pPrefix] == dposPrefix {
-					if txDataInfo[pVersion] == dposVersion {
-						// process vote event
-						if txDataInfo[pCategory] == dposCategoryEvent {
-							if len(txDataInfo) > dposMinSplitLen {
-								// check is vote or not
-								if txDataInfo[pEventVote] == dposEventVote && (!candidateNeedPD || snap.isCandidate(*tx.To())) && state.GetBalance(txSender).Cmp(snap.MinVB) > 0 {
-									headerExtra.CurrentBlockVotes = d.processEventVote(headerExtra.CurrentBlockVotes, state, tx, txSender)
-
-								} else if txDataInfo[pEventVote] == dposEventDeVote && snap.isVoter(txSender) {
-									headerExtra.CurrentBlockVotes = d.processEventDeVote(headerExtra.CurrentBlockVotes, txSender)
-
-								} else if txDataInfo[pEventConfirm] == dposEventConfirm && snap.isCandidate(txSender) {
-									headerExtra.CurrentBlockConfirmations, refundHash = d.processEventConfirm(headerExtra.CurrentBlockConfirmations, chain, txDataInfo, number, tx, txSender, refundHash)
-
-								} else if txDataInfo[pEventProposal] == dposEventProposal {
-									headerExtra.CurrentBlockProposals = d.processEventProposal(headerExtra.CurrentBlockProposals, txDataInfo, state, tx, txSender, snap)
-
-								} else if txDataInfo[pEventDeclare] == dposEventDeclare && snap.isCandidate(txSender) {
-									headerExtra.CurrentBlockDeclares = d.processEventDeclare(headerExtra.CurrentBlockDeclares, txDataInfo, tx, txSender)
-								}
-							} else {
-								// todo : something wrong, leave this transaction to process as normal transaction
-							}
-						} else if txDataInfo[pCategory] == dposCategoryLog {
-							// todo :
-						}
-					}
+		if systemContract {
+			// After the fork governance goes through the system contract only,
+			// string encoded events are left as normal transactions
+			if tx.To() != nil && *tx.To() == SystemContractAddress {
+				var logs []*types.Log
+				headerExtra, refundHash, logs = d.processSystemCall(headerExtra, chain, number, state, tx, txSender, snap, refundHash)
+				if len(logs) > 0 {
+					emitted[i] = logs
 				}
 			}
+		} else {
+			headerExtra, refundHash = d.processStringEvent(headerExtra, chain, number, state, tx, txSender, snap, refundHash)
 		}
 		// check each address
 		if number > 1 {
@@ -251,6 +233,7 @@ func (d *DPoS) processTxEvent(headerExtra HeaderExtra, chain consensus.ChainRead
 		}
 
 	}
+	attachSystemLogs(receipts, number, emitted)
 
 	for _, receipt := range receipts {
 		if pair, ok := refundHash[receipt.TxHash]; ok && receipt.Status == 1 {
@@ -261,6 +244,51 @@ func (d *DPoS) processTxEvent(headerExtra HeaderExtra, chain consensus.ChainRead
 	return headerExtra, refundGas, nil
 }
 
+// processStringEvent applies a governance event encoded in the transaction data
+// like "dpos:1:event:vote", the format used before the system contract fork.
+func (d *DPoS) processStringEvent(headerExtra HeaderExtra, chain consensus.ChainReader, number uint64, state *state.StateDB, tx *types.Transaction, txSender common.Address, snap *Snapshot, refundHash RefundHash) (HeaderExtra, RefundHash) {
+	if len(string(tx.Data())) < len(dposPrefix) {
+		return headerExtra, refundHash
+	}
+	txDataInfo := strings.Split(string(tx.Data()), ":")
+	if len(txDataInfo) < dposMinSplitLen || txDataInfo[pPrefix] != dposPrefix || txDataInfo[pVersion] != dposVersion {
+		return headerExtra, refundHash
+	}
+	// process vote event
+	if txDataInfo[pCategory] == dposCategoryEvent {
+		if len(txDataInfo) > dposMinSplitLen {
+			// check is vote or not
+			if txDataInfo[pEventVote] == dposEventVote && (!candidateNeedPD || snap.isCandidate(*tx.To())) && state.GetBalance(txSender).Cmp(snap.MinVB) > 0 {
+				headerExtra.CurrentBlockVotes = d.processEventVote(headerExtra.CurrentBlockVotes, state, *tx.To(), txSender)
+
+			} else if txDataInfo[pEventVote] == dposEventDeVote && snap.isVoter(txSender) {
+				headerExtra.CurrentBlockVotes = d.processEventDeVote(headerExtra.CurrentBlockVotes, txSender)
+
+			} else if txDataInfo[pEventConfirm] == dposEventConfirm && snap.isCandidate(txSender) {
+				if len(txDataInfo) > pEventConfirmNumber {
+					confirmedBlockNumber := new(big.Int)
+					if err := confirmedBlockNumber.UnmarshalText([]byte(txDataInfo[pEventConfirmNumber])); err == nil {
+						headerExtra.CurrentBlockConfirmations, refundHash = d.processEventConfirm(headerExtra.CurrentBlockConfirmations, chain, confirmedBlockNumber, number, tx, txSender, refundHash)
+					}
+				}
+
+			} else if txDataInfo[pEventProposal] == dposEventProposal {
+				if proposal, ok := d.parseEventProposal(txDataInfo, tx, txSender); ok {
+					headerExtra.CurrentBlockProposals = d.processEventProposal(headerExtra.CurrentBlockProposals, proposal, state, txSender)
+				}
+
+			} else if txDataInfo[pEventDeclare] == dposEventDeclare && snap.isCandidate(txSender) {
+				headerExtra.CurrentBlockDeclares = d.processEventDeclare(headerExtra.CurrentBlockDeclares, txDataInfo, tx, txSender)
+			}
+		} else {
+			// todo : something wrong, leave this transaction to process as normal transaction
+		}
+	} else if txDataInfo[pCategory] == dposCategoryLog {
+		// todo :
+	}
+	return headerExtra, refundHash
+}
+
 func (d *DPoS) refundAddGas(refundGas RefundGas, address common.Address, value *big.Int) RefundGas {
 	if _, ok := refundGas[address]; ok {
 		refundGas[address].Add(refundGas[address], value)
@@ -271,14 +299,10 @@ func (d *DPoS) refundAddGas(refundGas RefundGas, address common.Address, value *
 	return refundGas
 }
 
-func (d *DPoS) processEventProposal(currentBlockProposals []Proposal, txDataInfo []string, state *state.StateDB, tx *types.Transaction, proposer common.Address, snap *Snapshot) []Proposal {
-	// sample for declare
-	// eth.sendTransaction({from:eth.accounts[0],to:eth.accounts[0],value:0,data:web3.toHex("dpos:1:event:declare:hash:0x853e10706e6b9d39c5f4719018aa2417e8b852dec8ad18f9c592d526db64c725:decision:yes")})
-	if len(txDataInfo) <= pEventProposal+2 {
-		return currentBlockProposals
-	}
-
-	proposal := Proposal{
+// newProposal returns a proposal of the transaction holding the default
+// parameters.
+func newProposal(tx *types.Transaction, proposer common.Address) Proposal {
+	return Proposal{
 		Hash:                   tx.Hash(),
 		ReceivedNumber:         big.NewInt(0),
 		CurrentDeposit:         proposalDeposit, // for all type of deposit
@@ -291,6 +315,17 @@ func (d *DPoS) processEventProposal(currentBlockProposals []Proposal, txDataInfo
 		MinVoterBalance:        new(big.Int).Div(minVoterBalance, big.NewInt(1e+18)).Uint64(),
 		ProposalDeposit:        new(big.Int).Div(proposalDeposit, big.NewInt(1e+18)).Uint64(), // default value
 	}
+}
+
+// parseEventProposal builds the proposal of a string encoded proposal event,
+// reporting false if any of its parameters is invalid.
+func (d *DPoS) parseEventProposal(txDataInfo []string, tx *types.Transaction, proposer common.Address) (Proposal, bool) {
+	// sample for declare
+	// eth.sendTransaction({from:eth.accounts[0],to:eth.accounts[0],value:0,data:web3.toHex("dpos:1:event:declare:hash:0x853e10706e6b9d39c5f4719018aa2417e8b852dec8ad18f9c592d526db64c725:decision:yes")})
+	if len(txDataInfo) <= pEventProposal+2 {
+		return Proposal{}, false
+	}
+	proposal := newProposal(tx, proposer)
 
 	for i := 0; i < len(txDataInfo[pEventProposal+1:])/2; i++ {
 		k, v := txDataInfo[pEventProposal+1+i*2], txDataInfo[pEventProposal+2+i*2]
@@ -298,13 +333,13 @@ func (d *DPoS) processEventProposal(currentBlockProposals []Proposal, txDataInfo
 		case "vlcnt":
 			// If vlcnt is missing then user default value, but if the vlcnt is beyond the min/max value then ignore this proposal
 			if validationLoopCnt, err := strconv.Atoi(v); err != nil || validationLoopCnt < minValidationLoopCnt || validationLoopCnt > maxValidationLoopCnt {
-				return currentBlockProposals
+				return Proposal{}, false
 			} else {
 				proposal.ValidationLoopCnt = uint64(validationLoopCnt)
 			}
 		case "proposal_type":
 			if proposalType, err := strconv.Atoi(v); err != nil {
-				return currentBlockProposals
+				return Proposal{}, false
 			} else {
 				proposal.ProposalType = uint64(proposalType)
 			}
@@ -314,27 +349,31 @@ func (d *DPoS) processEventProposal(currentBlockProposals []Proposal, txDataInfo
 		case "mrpt":
 			// miner reward per thousand
 			if mrpt, err := strconv.Atoi(v); err != nil || mrpt <= 0 || mrpt > 1000 {
-				return currentBlockProposals
+				return Proposal{}, false
 			} else {
 				proposal.MinerRewardPerThousand = uint64(mrpt)
 			}
 		case "mvb":
 			// minVoterBalance
 			if mvb, err := strconv.Atoi(v); err != nil || mvb <= 0 {
-				return currentBlockProposals
+				return Proposal{}, false
 			} else {
 				proposal.MinVoterBalance = uint64(mvb)
 			}
 		case "mpd":
 			// proposalDeposit
 			if mpd, err := strconv.Atoi(v); err != nil || mpd <= 0 || mpd > maxProposalDeposit {
-				return currentBlockProposals
+				return Proposal{}, false
 			} else {
 				proposal.ProposalDeposit = uint64(mpd)
 			}
 		}
 	}
-	// now the proposal is built
+	return proposal, true
+}
+
+// processEventProposal collects the deposit of a proposal and records it.
+func (d *DPoS) processEventProposal(currentBlockProposals []Proposal, proposal Proposal, state *state.StateDB, proposer common.Address) []Proposal {
 	currentProposalPay := new(big.Int).Set(proposalDeposit)
 	// check enough balance for deposit
 	if state.GetBalance(proposer).Cmp(currentProposalPay) < 0 {
@@ -374,14 +413,14 @@ func (d *DPoS) processEventDeclare(currentBlockDeclares []Declare, txDataInfo []
 	return append(currentBlockDeclares, declare)
 }
 
-func (d *DPoS) processEventVote(currentBlockVotes []Vote, state *state.StateDB, tx *types.Transaction, voter common.Address) []Vote {
+func (d *DPoS) processEventVote(currentBlockVotes []Vote, state *state.StateDB, candidate common.Address, voter common.Address) []Vote {
 	d.lock.RLock()
 	stake := state.GetBalance(voter)
 	d.lock.RUnlock()
 
 	return append(currentBlockVotes, Vote{
 		Voter:     voter,
-		Candidate: *tx.To(),
+		Candidate: candidate,
 		Stake:     stake,
 	})
 }
@@ -393,37 +432,33 @@ func (d *DPoS) processEventDeVote(currentBlockVotes []Vote, voter common.Address
 	})
 }
 
-func (d *DPoS) processEventConfirm(currentBlockConfirmations []Confirmation, chain consensus.ChainReader, txDataInfo []string, number uint64, tx *types.Transaction, confirmer common.Address, refundHash RefundHash) ([]Confirmation, RefundHash) {
-	if len(txDataInfo) > pEventConfirmNumber {
-		confirmedBlockNumber := new(big.Int)
-		err := confirmedBlockNumber.UnmarshalText([]byte(txDataInfo[pEventConfirmNumber]))
-		if err != nil || number-confirmedBlockNumber.Uint64() > d.config.MaxSignerCount || number-confirmedBlockNumber.Uint64() < 0 {
-			return currentBlockConfirmations, refundHash
-		}
-		// check if the voter is in block
-		confirmedHeader := chain.GetHeaderByNumber(confirmedBlockNumber.Uint64())
-		if confirmedHeader == nil {
-			//log.Info("Fail to get confirmedHeader")
-			return currentBlockConfirmations, refundHash
-		}
-		confirmedHeaderExtra := HeaderExtra{}
-		if extraVanity+extraSeal > len(confirmedHeader.Extra) {
-			return currentBlockConfirmations, refundHash
-		}
-		err = decodeHeaderExtra(confirmedHeader.Extra[extraVanity:len(confirmedHeader.Extra)-extraSeal], &confirmedHeaderExtra)
-		if err != nil {
-			log.Info("Fail to decode parent header", "err", err)
-			return currentBlockConfirmations, refundHash
-		}
-		for _, s := range confirmedHeaderExtra.SignerQueue {
-			if s == confirmer {
-				currentBlockConfirmations = append(currentBlockConfirmations, Confirmation{
-					Signer:      confirmer,
-					BlockNumber: new(big.Int).Set(confirmedBlockNumber),
-				})
-				refundHash[tx.Hash()] = RefundPair{confirmer, tx.GasPrice()}
-				break
-			}
+func (d *DPoS) processEventConfirm(currentBlockConfirmations []Confirmation, chain consensus.ChainReader, confirmedBlockNumber *big.Int, number uint64, tx *types.Transaction, confirmer common.Address, refundHash RefundHash) ([]Confirmation, RefundHash) {
+	if number-confirmedBlockNumber.Uint64() > d.config.MaxSignerCount || number-confirmedBlockNumber.Uint64() < 0 {
+		return currentBlockConfirmations, refundHash
+	}
+	// check if the voter is in block
+	confirmedHeader := chain.GetHeaderByNumber(confirmedBlockNumber.Uint64())
+	if confirmedHeader == nil {
+		//log.Info("Fail to get confirmedHeader")
+		return currentBlockConfirmations, refundHash
+	}
+	confirmedHeaderExtra := HeaderExtra{}
+	if extraVanity+extraSeal > len(confirmedHeader.Extra) {
+		return currentBlockConfirmations, refundHash
+	}
+	err := decodeHeaderExtra(confirmedHeader.Extra[extraVanity:len(confirmedHeader.Extra)-extraSeal], &confirmedHeaderExtra)
+	if err != nil {
+		log.Info("Fail to decode parent header", "err", err)
+		return currentBlockConfirmations, refundHash
+	}
+	for _, s := range confirmedHeaderExtra.SignerQueue {
+		if s == confirmer {
+			currentBlockConfirmations = append(currentBlockConfirmations, Confirmation{
+				Signer:      confirmer,
+				BlockNumber: new(big.Int).Set(confirmedBlockNumber),
+			})
+			refundHash[tx.Hash()] = RefundPair{confirmer, tx.GasPrice()}
+			break
 		}
 	}
 
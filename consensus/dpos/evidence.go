@@ -0,0 +1,202 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package dpos
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"sort"
+
+	"github.com/simplechain-org/go-simplechain/common"
+	"github.com/simplechain-org/go-simplechain/consensus"
+	"github.com/simplechain-org/go-simplechain/core/state"
+	"github.com/simplechain-org/go-simplechain/core/types"
+	"github.com/simplechain-org/go-simplechain/rlp"
+)
+
+const (
+	doubleSignSlashPerThousand = 100 // Part of the signer's balance burnt for signing two blocks at one height
+)
+
+var (
+	// errEvidenceMalformed is returned if the headers of an evidence can't be
+	// decoded or carry no seal.
+	errEvidenceMalformed = errors.New("malformed evidence header")
+
+	// errEvidenceNotConflicting is returned if the headers of an evidence are
+	// at different heights or seal the same block.
+	errEvidenceNotConflicting = errors.New("evidence headers do not conflict")
+
+	// errEvidenceSignerMismatch is returned if the headers of an evidence are
+	// sealed by different signers or by another account than their coinbase.
+	errEvidenceSignerMismatch = errors.New("evidence headers signed by different signers")
+
+	// errEvidenceNotInTurn is returned if the headers of an evidence fill
+	// different slots of the producer schedule, or a slot of another signer.
+	errEvidenceNotInTurn = errors.New("evidence headers not sealed in the signer's slot")
+
+	// errEvidenceOutOfRange is returned if the evidence is for a future block
+	// or older than one epoch.
+	errEvidenceOutOfRange = errors.New("evidence out of range")
+
+	// errEvidenceKnown is returned if the signer is already jailed for an earlier
+	// evidence.
+	errEvidenceKnown = errors.New("signer already slashed")
+)
+
+// Evidence :
+// evidence come from a submitEvidence call of the system contract, which carries
+// two different headers sealed by the same signer at the same height, in its slot
+// SignerSlash is burnt from the balance of the signer, VoterSlash from its voters
+type Evidence struct {
+	Signer         common.Address
+	Number         uint64        // block number of the conflicting headers
+	Hashes         []common.Hash // hashes of the conflicting headers
+	Reporter       common.Address
+	SignerSlash    *big.Int
+	VoterSlash     *big.Int
+	ReportedNumber uint64 // block number of evidence received (always zero in block header)
+}
+
+func (e *Evidence) copy() *Evidence {
+	cpy := *e
+	cpy.Hashes = make([]common.Hash, len(e.Hashes))
+	copy(cpy.Hashes, e.Hashes)
+	cpy.SignerSlash = new(big.Int).Set(e.SignerSlash)
+	cpy.VoterSlash = new(big.Int).Set(e.VoterSlash)
+	return &cpy
+}
+
+// jailedUntil returns the last block number the evidence keeps its signer out
+// of the signer queue.
+func (e *Evidence) jailedUntil(epoch uint64) uint64 {
+	return e.ReportedNumber + epoch
+}
+
+// verifyEvidence checks that the RLP encoded headers are two different blocks
+// sealed by the same signer at the same height, in the same slot of the producer
+// schedule, which is the signer's own, returning the signer and the headers.
+func (d *DPoS) verifyEvidence(chain consensus.ChainReader, blobA, blobB []byte) (common.Address, *types.Header, *types.Header, error) {
+	var headerA, headerB types.Header
+	if err := rlp.DecodeBytes(blobA, &headerA); err != nil {
+		return common.Address{}, nil, nil, errEvidenceMalformed
+	}
+	if err := rlp.DecodeBytes(blobB, &headerB); err != nil {
+		return common.Address{}, nil, nil, errEvidenceMalformed
+	}
+	if headerA.Number == nil || headerB.Number == nil || headerA.Number.Sign() <= 0 ||
+		len(headerA.Extra) < extraVanity+extraSeal || len(headerB.Extra) < extraVanity+extraSeal {
+		return common.Address{}, nil, nil, errEvidenceMalformed
+	}
+	if headerA.Number.Cmp(headerB.Number) != 0 || bytes.Equal(DposRLP(&headerA), DposRLP(&headerB)) {
+		return common.Address{}, nil, nil, errEvidenceNotConflicting
+	}
+	signerA, err := ecrecover(&headerA, d.signatures)
+	if err != nil {
+		return common.Address{}, nil, nil, errEvidenceMalformed
+	}
+	signerB, err := ecrecover(&headerB, d.signatures)
+	if err != nil {
+		return common.Address{}, nil, nil, errEvidenceMalformed
+	}
+	if signerA != signerB || signerA != headerA.Coinbase || signerB != headerB.Coinbase {
+		return common.Address{}, nil, nil, errEvidenceSignerMismatch
+	}
+	// Only the signer in turn could seal a valid block in that slot, anyone
+	// else's header would have been rejected without a double sign
+	snap, err := d.snapshot(chain, headerA.Number.Uint64()-1, headerA.ParentHash, nil, nil, defaultLoopCntRecalculateSigners)
+	if err != nil {
+		return common.Address{}, nil, nil, err
+	}
+	if headerA.Time < snap.LoopStartTime || headerB.Time < snap.LoopStartTime ||
+		snap.slot(headerA.Time) != snap.slot(headerB.Time) || !snap.inturn(signerA, headerA.Time) {
+		return common.Address{}, nil, nil, errEvidenceNotInTurn
+	}
+	return signerA, &headerA, &headerB, nil
+}
+
+// processEventEvidence verifies a double signing evidence and slashes the
+// signer: part of its balance is burnt and its voters lose the voter reward
+// of one block they got from it. The signer is jailed by the snapshot once
+// the evidence is in a block.
+func (d *DPoS) processEventEvidence(headerExtra HeaderExtra, chain consensus.ChainReader, state *state.StateDB, number uint64, blobA, blobB []byte, reporter common.Address, snap *Snapshot) (HeaderExtra, *Evidence, error) {
+	signer, headerA, headerB, err := d.verifyEvidence(chain, blobA, blobB)
+	if err != nil {
+		return headerExtra, nil, err
+	}
+	height := headerA.Number.Uint64()
+	if height >= number || height+d.config.Epoch < number || !snap.isCandidate(signer) {
+		return headerExtra, nil, errEvidenceOutOfRange
+	}
+	if snap.isJailed(signer, number) {
+		return headerExtra, nil, errEvidenceKnown
+	}
+	for _, evidence := range headerExtra.CurrentBlockEvidences {
+		if evidence.Signer == signer {
+			return headerExtra, nil, errEvidenceKnown
+		}
+	}
+	evidence := Evidence{
+		Signer:      signer,
+		Number:      height,
+		Hashes:      []common.Hash{headerA.Hash(), headerB.Hash()},
+		Reporter:    reporter,
+		SignerSlash: new(big.Int),
+		VoterSlash:  new(big.Int),
+	}
+	// Burn the stake of the signer
+	evidence.SignerSlash.Mul(state.GetBalance(signer), big.NewInt(doubleSignSlashPerThousand))
+	evidence.SignerSlash.Div(evidence.SignerSlash, big.NewInt(1000))
+	state.SubBalance(signer, evidence.SignerSlash)
+	if snap.isVoter(signer) {
+		headerExtra.ModifyPredecessorVotes = append(headerExtra.ModifyPredecessorVotes, PredecessorVoter{
+			Voter: signer,
+			Stake: state.GetBalance(signer),
+		})
+	}
+	// Claw back the voter reward of the double signed height from the voters
+	if d.config.VoterReward {
+		_, votersReward := blockRewards(d.config, height, snap)
+		voteRewardMap, err := snap.calculateVoteReward(signer, votersReward)
+		if err == nil {
+			voters := make([]common.Address, 0, len(voteRewardMap))
+			for voter := range voteRewardMap {
+				if voter != signer {
+					voters = append(voters, voter)
+				}
+			}
+			sort.Slice(voters, func(i, j int) bool { return bytes.Compare(voters[i][:], voters[j][:]) < 0 })
+
+			for _, voter := range voters {
+				reward := voteRewardMap[voter]
+				if balance := state.GetBalance(voter); balance.Cmp(reward) < 0 {
+					reward = new(big.Int).Set(balance)
+				}
+				state.SubBalance(voter, reward)
+				evidence.VoterSlash.Add(evidence.VoterSlash, reward)
+
+				headerExtra.ModifyPredecessorVotes = append(headerExtra.ModifyPredecessorVotes, PredecessorVoter{
+					Voter: voter,
+					Stake: state.GetBalance(voter),
+				})
+			}
+		}
+	}
+	headerExtra.CurrentBlockEvidences = append(headerExtra.CurrentBlockEvidences, evidence)
+	return headerExtra, &evidence, nil
+}
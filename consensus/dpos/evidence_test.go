@@ -0,0 +1,172 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package dpos
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/simplechain-org/go-simplechain/common"
+	"github.com/simplechain-org/go-simplechain/core/rawdb"
+	"github.com/simplechain-org/go-simplechain/core/types"
+	"github.com/simplechain-org/go-simplechain/params"
+	"github.com/simplechain-org/go-simplechain/rlp"
+)
+
+func TestVerifyEvidence(t *testing.T) {
+	accounts := newTesterAccountPool()
+	config := &params.DPoSConfig{
+		Period:          3,
+		MaxSignerCount:  2,
+		MinVoterBalance: big.NewInt(0),
+		SelfVoteSigners: []common.UnprefixedAddress{
+			common.UnprefixedAddress(accounts.address("A")),
+			common.UnprefixedAddress(accounts.address("B")),
+		},
+	}
+	engine := New(config, rawdb.NewMemoryDatabase())
+
+	// The schedule of the parent gives A the slots starting at 0 and 6, B the
+	// one starting at 3
+	parent := newSnapshot(config, engine.signatures, common.Hash{}, nil, defaultLoopCntRecalculateSigners)
+	parent.LoopStartTime = 0
+	engine.recents.Add(parent.Hash, parent)
+
+	// sealed builds the RLP of a header at the given height and time sealed by
+	// signer
+	sealed := func(number int64, time uint64, coinbase, signer string, root byte) []byte {
+		header := &types.Header{
+			Number:   big.NewInt(number),
+			Time:     time,
+			Coinbase: accounts.address(coinbase),
+			Root:     common.Hash{root},
+			Extra:    make([]byte, extraVanity+extraSeal),
+		}
+		accounts.sign(header, signer)
+		blob, _ := rlp.EncodeToBytes(header)
+		return blob
+	}
+	tests := []struct {
+		a, b []byte
+		err  error
+	}{
+		{sealed(5, 0, "A", "A", 1), sealed(5, 0, "A", "A", 2), nil},
+		{sealed(5, 6, "A", "A", 1), sealed(5, 8, "A", "A", 2), nil},
+		{sealed(5, 0, "A", "A", 1), sealed(5, 0, "A", "A", 1), errEvidenceNotConflicting},
+		{sealed(5, 0, "A", "A", 1), sealed(6, 0, "A", "A", 2), errEvidenceNotConflicting},
+		{sealed(5, 0, "A", "A", 1), sealed(5, 0, "B", "B", 2), errEvidenceSignerMismatch},
+		{sealed(5, 0, "A", "A", 1), sealed(5, 0, "A", "B", 2), errEvidenceSignerMismatch},
+		{sealed(5, 0, "A", "A", 1), []byte{0x01}, errEvidenceMalformed},
+		// Both headers must fill the same slot, which must be the signer's
+		{sealed(5, 0, "A", "A", 1), sealed(5, 6, "A", "A", 2), errEvidenceNotInTurn},
+		{sealed(5, 3, "A", "A", 1), sealed(5, 4, "A", "A", 2), errEvidenceNotInTurn},
+	}
+	for i, tt := range tests {
+		signer, _, _, err := engine.verifyEvidence(nil, tt.a, tt.b)
+		if err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+			continue
+		}
+		if err == nil && signer != accounts.address("A") {
+			t.Errorf("test %d: signer mismatch: have %x, want %x", i, signer, accounts.address("A"))
+		}
+	}
+}
+
+func TestSnapshotJail(t *testing.T) {
+	accounts := newTesterAccountPool()
+	config := &params.DPoSConfig{
+		Period:          3,
+		Epoch:           10,
+		MaxSignerCount:  2,
+		MinVoterBalance: big.NewInt(0),
+		SelfVoteSigners: []common.UnprefixedAddress{
+			common.UnprefixedAddress(accounts.address("A")),
+			common.UnprefixedAddress(accounts.address("B")),
+		},
+	}
+	snap := newSnapshot(config, nil, common.Hash{}, nil, defaultLoopCntRecalculateSigners)
+	snap.LoopStartTime = 0
+	snap.Number = 3
+
+	if !snap.inturn(accounts.address("A"), 0) {
+		t.Fatalf("signer A not in turn before evidence")
+	}
+	snap.updateSnapshotByEvidences([]Evidence{{
+		Signer:      accounts.address("A"),
+		Number:      2,
+		Hashes:      []common.Hash{{1}, {2}},
+		SignerSlash: new(big.Int),
+		VoterSlash:  new(big.Int),
+	}}, big.NewInt(4))
+
+	if !snap.isJailed(accounts.address("A"), 5) || snap.isJailed(accounts.address("B"), 5) {
+		t.Fatalf("jail mismatch after evidence")
+	}
+	if snap.inturn(accounts.address("A"), 0) {
+		t.Errorf("jailed signer A still in turn")
+	}
+	if cpy := snap.copy(); cpy.Evidences[accounts.address("A")] == nil || cpy.Evidences[accounts.address("A")].ReportedNumber != 4 {
+		t.Errorf("evidence not carried by snapshot copy")
+	}
+	// The jail ends one epoch after the evidence got in
+	snap.updateSnapshotForExpired(big.NewInt(14))
+	if !snap.isJailed(accounts.address("A"), 14) {
+		t.Errorf("signer A released before the end of the epoch")
+	}
+	snap.updateSnapshotForExpired(big.NewInt(15))
+	if snap.isJailed(accounts.address("A"), 15) || len(snap.Evidences) != 0 {
+		t.Errorf("signer A still jailed after the epoch")
+	}
+}
+
+func TestHeaderExtraEvidenceCompatibility(t *testing.T) {
+	// Header extras without evidence must keep their encoding
+	type legacyHeaderExtra struct {
+		CurrentBlockConfirmations []Confirmation
+		CurrentBlockVotes         []Vote
+		CurrentBlockProposals     []Proposal
+		CurrentBlockDeclares      []Declare
+		ModifyPredecessorVotes    []PredecessorVoter
+		LoopStartTime             uint64
+		SignerQueue               []common.Address
+		SignerMissing             []common.Address
+		ConfirmedBlockNumber      uint64
+	}
+	legacy, _ := rlp.EncodeToBytes(legacyHeaderExtra{LoopStartTime: 7, ConfirmedBlockNumber: 3})
+	current, _ := encodeHeaderExtra(HeaderExtra{LoopStartTime: 7, ConfirmedBlockNumber: 3})
+	if string(legacy) != string(current) {
+		t.Fatalf("encoding changed: have %x, want %x", current, legacy)
+	}
+	var extra HeaderExtra
+	if err := decodeHeaderExtra(legacy, &extra); err != nil || len(extra.CurrentBlockEvidences) != 0 {
+		t.Fatalf("failed to decode legacy header extra: %v", err)
+	}
+	// And with evidence round trip
+	extra.CurrentBlockEvidences = []Evidence{{Signer: common.Address{1}, Number: 2, Hashes: []common.Hash{{3}, {4}}, SignerSlash: big.NewInt(5), VoterSlash: big.NewInt(6)}}
+	blob, err := encodeHeaderExtra(extra)
+	if err != nil {
+		t.Fatalf("failed to encode header extra: %v", err)
+	}
+	var decoded HeaderExtra
+	if err := decodeHeaderExtra(blob, &decoded); err != nil {
+		t.Fatalf("failed to decode header extra: %v", err)
+	}
+	if len(decoded.CurrentBlockEvidences) != 1 || decoded.CurrentBlockEvidences[0].SignerSlash.Int64() != 5 || decoded.CurrentBlockEvidences[0].Hashes[1] != (common.Hash{4}) {
+		t.Errorf("evidence mismatch: %+v", decoded.CurrentBlockEvidences)
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package dpos
+
+import (
+	"encoding/json"
+	"math/big"
+
+	"github.com/simplechain-org/go-simplechain/common"
+	"github.com/simplechain-org/go-simplechain/core"
+	"github.com/simplechain-org/go-simplechain/core/types"
+	"github.com/simplechain-org/go-simplechain/crypto"
+	"github.com/simplechain-org/go-simplechain/ethdb"
+	"github.com/simplechain-org/go-simplechain/event"
+	"github.com/simplechain-org/go-simplechain/log"
+)
+
+const (
+	inMemoryRewards    = 256 // Number of finalized blocks to keep the rewards of until they turn canonical
+	chainEventChanSize = 10  // Size of the channel listening to canonical blocks
+)
+
+// Reward types of a BlockRewards entry.
+const (
+	rewardTypeMiner          = "miner"
+	rewardTypeVoter          = "voter"
+	rewardTypeProposalRefund = "proposalRefund"
+	rewardTypeGasRefund      = "gasRefund"
+)
+
+// BlockRewards is the breakdown of what a block paid out. It is computed when
+// the block is finalized and recorded once the block is canonical, so the
+// rewards can be queried without replaying the state.
+type BlockRewards struct {
+	Number          uint64                      `json:"number"`          // Block number of the rewards
+	Miner           common.Address              `json:"miner"`           // Coinbase of the block
+	MinerReward     *big.Int                    `json:"minerReward"`     // Reward of the miner, after the gas refunds
+	VoterRewards    map[common.Address]*big.Int `json:"voterRewards"`    // Rewards of the voters of the miner
+	ProposalRefunds map[common.Address]*big.Int `json:"proposalRefunds"` // Proposal deposits paid back
+	GasRefunds      map[common.Address]*big.Int `json:"gasRefunds"`      // Gas paid back for confirmation and evidence txs
+}
+
+func newBlockRewards(number uint64, miner common.Address) *BlockRewards {
+	return &BlockRewards{
+		Number:          number,
+		Miner:           miner,
+		MinerReward:     new(big.Int),
+		VoterRewards:    make(map[common.Address]*big.Int),
+		ProposalRefunds: make(map[common.Address]*big.Int),
+		GasRefunds:      make(map[common.Address]*big.Int),
+	}
+}
+
+// rewardsKey returns the database key of the rewards of a block.
+func rewardsKey(hash common.Hash) []byte {
+	return append([]byte("dpos-rewards-"), hash[:]...)
+}
+
+// pendingRewardsKey identifies the rewards of a finalized block until it turns
+// canonical. The block hash isn't known yet when the miner finalizes a block,
+// but its parent, coinbase and state root are, and they stay the same once the
+// block is sealed or when it is imported.
+func pendingRewardsKey(header *types.Header) common.Hash {
+	return crypto.Keccak256Hash(header.ParentHash[:], header.Coinbase[:], header.Root[:])
+}
+
+// loadBlockRewards loads the rewards of a block from the database.
+func loadBlockRewards(db ethdb.Database, hash common.Hash) (*BlockRewards, error) {
+	blob, err := db.Get(rewardsKey(hash))
+	if err != nil {
+		return nil, err
+	}
+	rewards := new(BlockRewards)
+	if err := json.Unmarshal(blob, rewards); err != nil {
+		return nil, err
+	}
+	return rewards, nil
+}
+
+// store inserts the rewards of a block into the database.
+func (r *BlockRewards) store(db ethdb.Database, hash common.Hash) error {
+	blob, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return db.Put(rewardsKey(hash), blob)
+}
+
+// rewardsChain is the blockchain whose canonical blocks get their rewards
+// indexed.
+type rewardsChain interface {
+	SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subscription
+}
+
+// IndexRewards starts recording the rewards of the blocks the chain makes
+// canonical, for GetRewards to serve. Blocks that are only finalized, like
+// those the miner assembles, are never recorded.
+func (d *DPoS) IndexRewards(chain rewardsChain) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	ch := make(chan core.ChainEvent, chainEventChanSize)
+	d.rewardsSub = chain.SubscribeChainEvent(ch)
+	go d.indexRewardsLoop(ch, d.rewardsSub)
+}
+
+func (d *DPoS) indexRewardsLoop(ch chan core.ChainEvent, sub event.Subscription) {
+	for {
+		select {
+		case ev := <-ch:
+			d.storeRewards(ev.Block.Header())
+		case <-sub.Err():
+			return
+		}
+	}
+}
+
+// storeRewards writes the rewards of a canonical block to the database, the
+// index is not part of consensus so failures are only logged.
+func (d *DPoS) storeRewards(header *types.Header) {
+	rewards, ok := d.rewards.Get(pendingRewardsKey(header))
+	if !ok {
+		log.Debug("No rewards to index for block", "number", header.Number, "hash", header.Hash())
+		return
+	}
+	if err := rewards.(*BlockRewards).store(d.db, header.Hash()); err != nil {
+		log.Warn("Failed to store block rewards", "number", header.Number, "hash", header.Hash(), "err", err)
+	}
+}
+
+// rewardsOf returns the amounts the block paid to the given address, by reward
+// type.
+func (r *BlockRewards) rewardsOf(address common.Address) map[string]*big.Int {
+	rewards := make(map[string]*big.Int)
+	if address == r.Miner && r.MinerReward.Sign() > 0 {
+		rewards[rewardTypeMiner] = r.MinerReward
+	}
+	if reward, ok := r.VoterRewards[address]; ok {
+		rewards[rewardTypeVoter] = reward
+	}
+	if refund, ok := r.ProposalRefunds[address]; ok {
+		rewards[rewardTypeProposalRefund] = refund
+	}
+	if refund, ok := r.GasRefunds[address]; ok {
+		rewards[rewardTypeGasRefund] = refund
+	}
+	return rewards
+}
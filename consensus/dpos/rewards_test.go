@@ -0,0 +1,155 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package dpos
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/simplechain-org/go-simplechain/common"
+	"github.com/simplechain-org/go-simplechain/core"
+	"github.com/simplechain-org/go-simplechain/core/rawdb"
+	"github.com/simplechain-org/go-simplechain/core/types"
+	"github.com/simplechain-org/go-simplechain/core/vm"
+	"github.com/simplechain-org/go-simplechain/crypto"
+	"github.com/simplechain-org/go-simplechain/params"
+	"github.com/simplechain-org/go-simplechain/rpc"
+)
+
+// makeRewardsBlock finalizes a block on top of the chain head the way the miner
+// does, and seals it with the given key.
+func makeRewardsBlock(t *testing.T, chain *core.BlockChain, engine *DPoS, key *ecdsa.PrivateKey) *types.Block {
+	parent := chain.CurrentBlock()
+	statedb, err := chain.StateAt(parent.Root())
+	if err != nil {
+		t.Fatalf("failed to load parent state: %v", err)
+	}
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Number:     new(big.Int).Add(parent.Number(), common.Big1),
+		GasLimit:   parent.GasLimit(),
+		Coinbase:   crypto.PubkeyToAddress(key.PublicKey),
+		Time:       parent.Time() + engine.config.Period,
+	}
+	if err := engine.Prepare(chain, header); err != nil {
+		t.Fatalf("failed to prepare header: %v", err)
+	}
+	block, err := engine.FinalizeAndAssemble(chain, header, statedb, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to finalize block: %v", err)
+	}
+	header = block.Header()
+	sig, err := crypto.Sign(SealHash(header).Bytes(), key)
+	if err != nil {
+		t.Fatalf("failed to seal block: %v", err)
+	}
+	copy(header.Extra[len(header.Extra)-extraSeal:], sig)
+	return block.WithSeal(header)
+}
+
+// Tests that the rewards of a block are recorded once it is inserted into the
+// chain, not when the miner finalizes it, and served by GetRewards.
+func TestGetRewards(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	miner := crypto.PubkeyToAddress(key.PublicKey)
+
+	config := *params.AllDPoSProtocolChanges
+	config.DPoS = &params.DPoSConfig{
+		Period:           1,
+		Epoch:            30000,
+		MaxSignerCount:   3,
+		MinVoterBalance:  big.NewInt(0),
+		GenesisTimestamp: uint64(time.Now().Unix()) - 100,
+		SelfVoteSigners:  []common.UnprefixedAddress{common.UnprefixedAddress(miner)},
+	}
+	genesis := &core.Genesis{
+		Config:    &config,
+		Timestamp: config.DPoS.GenesisTimestamp,
+		ExtraData: make([]byte, extraVanity+extraSeal),
+		GasLimit:  params.GenesisGasLimit,
+		Alloc:     core.GenesisAlloc{miner: {Balance: big.NewInt(1000000000)}},
+	}
+	db := rawdb.NewMemoryDatabase()
+	genesis.MustCommit(db)
+
+	engine := New(config.DPoS, db)
+	chain, err := core.NewBlockChain(db, &core.CacheConfig{TrieDirtyDisabled: true}, &config, engine, vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+	engine.IndexRewards(chain)
+	defer engine.Close()
+
+	// Finalizing alone must not record anything
+	speculative := makeRewardsBlock(t, chain, engine, key)
+	if _, err := loadBlockRewards(db, speculative.Hash()); err == nil {
+		t.Fatalf("rewards of a finalized block recorded before insertion")
+	}
+	for i := 0; i < 2; i++ {
+		// Blocks are never sealed ahead of the clock, wait for their slot
+		block := makeRewardsBlock(t, chain, engine, key)
+		for uint64(time.Now().Unix()) < block.Time() {
+			time.Sleep(100 * time.Millisecond)
+		}
+		if _, err := chain.InsertChain(types.Blocks{block}); err != nil {
+			t.Fatalf("failed to insert block %d: %v", i+1, err)
+		}
+	}
+	api := &API{chain: chain, dpos: engine}
+
+	var result *Rewards
+	for start := time.Now(); time.Since(start) < time.Second; time.Sleep(10 * time.Millisecond) {
+		if result, err = api.GetRewards(miner, 0, rpc.LatestBlockNumber); err != nil {
+			t.Fatalf("failed to get rewards: %v", err)
+		}
+		if len(result.Missing) == 0 {
+			break
+		}
+	}
+	if len(result.Missing) != 0 {
+		t.Fatalf("canonical blocks without rewards: %v", result.Missing)
+	}
+	if len(result.Rewards) != 2 {
+		t.Fatalf("rewards mismatch: %+v", result.Rewards)
+	}
+	state, _ := chain.State()
+	paid := new(big.Int).Sub(state.GetBalance(miner), big.NewInt(1000000000))
+	for i, reward := range result.Rewards {
+		if reward.Type != rewardTypeMiner || reward.BlockHash != chain.GetHeaderByNumber(uint64(i+1)).Hash() {
+			t.Errorf("reward %d mismatch: %+v", i, reward)
+		}
+	}
+	if result.Total.ToInt().Cmp(paid) != 0 {
+		t.Errorf("total mismatch: have %v, want %v", result.Total, paid)
+	}
+	result, err = api.GetRewards(miner, 2, 2)
+	if err != nil {
+		t.Fatalf("failed to get rewards: %v", err)
+	}
+	if len(result.Rewards) != 1 || result.Rewards[0].BlockNumber != 2 {
+		t.Errorf("ranged rewards mismatch: %+v", result.Rewards)
+	}
+	if _, err := api.GetRewards(miner, 2, 1); err != errInvalidRewardsRange {
+		t.Errorf("reversed range error mismatch: have %v, want %v", err, errInvalidRewardsRange)
+	}
+	if _, err := api.GetRewards(miner, 0, maxRewardsRange); err != errRewardsRangeTooLarge {
+		t.Errorf("large range error mismatch: have %v, want %v", err, errRewardsRangeTooLarge)
+	}
+}
@@ -115,7 +115,13 @@ func (s *Snapshot) createSignerQueue() ([]common.Address, error) {
 
 		// only recalculate signers from to tally per 10 loop,
 		// other loop end just reset the order of signers by block hash (nearly random)
-		tallySlice := s.buildTallySlice()
+		var tallySlice TallySlice
+		for _, tallyItem := range s.buildTallySlice() {
+			// signers jailed for double signing are left out
+			if !s.isJailed(tallyItem.addr, s.Number+1) {
+				tallySlice = append(tallySlice, tallyItem)
+			}
+		}
 		sort.Sort(tallySlice)
 		queueLength := int(s.config.MaxSignerCount)
 		if queueLength > len(tallySlice) {
@@ -159,6 +165,9 @@ func (s *Snapshot) createSignerQueue() ([]common.Address, error) {
 
 	} else {
 		for i, signer := range s.Signers {
+			if s.isJailed(*signer, s.Number+1) {
+				continue
+			}
 			signerSlice = append(signerSlice, SignerItem{*signer, s.HistoryHash[len(s.HistoryHash)-1-i]})
 		}
 	}
@@ -81,6 +81,8 @@ type Snapshot struct {
 	ProposalRefund  map[uint64]map[common.Address]*big.Int `json:"proposalRefund"`  // Refund proposal deposit
 	MinerReward     uint64                                 `json:"minerReward"`     // miner reward per thousand
 	MinVB           *big.Int                               `json:"minVoterBalance"` // min voter balance
+	Evidences       map[common.Address]*Evidence           `json:"evidences"`       // Double signing evidence of the signers jailed out of the signer queue
+	LightCheckpoint uint64                                 `json:"lightCheckpoint"` // Loop header a light client started from without the tallies before it
 }
 
 // newSnapshot creates a new snapshot with the specified startup parameters. only ever use if for
@@ -108,6 +110,7 @@ func newSnapshot(config *params.DPoSConfig, sigcache *lru.ARCCache, hash common.
 		ProposalRefund:  make(map[uint64]map[common.Address]*big.Int),
 		MinerReward:     minerRewardPerThousand,
 		MinVB:           config.MinVoterBalance,
+		Evidences:       make(map[common.Address]*Evidence),
 	}
 	snap.HistoryHash = append(snap.HistoryHash, hash)
 
@@ -160,6 +163,9 @@ func loadSnapshot(config *params.DPoSConfig, sigcache *lru.ARCCache, db ethdb.Da
 	if snap.MinVB == nil {
 		snap.MinVB = new(big.Int).Set(minVoterBalance)
 	}
+	if snap.Evidences == nil {
+		snap.Evidences = make(map[common.Address]*Evidence)
+	}
 	return snap, nil
 }
 
@@ -197,8 +203,10 @@ func (s *Snapshot) copy() *Snapshot {
 		LoopStartTime:  s.LoopStartTime,
 		ProposalRefund: make(map[uint64]map[common.Address]*big.Int),
 
-		MinerReward: s.MinerReward,
-		MinVB:       nil,
+		MinerReward:     s.MinerReward,
+		MinVB:           nil,
+		Evidences:       make(map[common.Address]*Evidence),
+		LightCheckpoint: s.LightCheckpoint,
 	}
 	copy(cpy.HistoryHash, s.HistoryHash)
 	copy(cpy.Signers, s.Signers)
@@ -229,6 +237,10 @@ func (s *Snapshot) copy() *Snapshot {
 		cpy.Proposals[txHash] = proposal.copy()
 	}
 
+	for signer, evidence := range s.Evidences {
+		cpy.Evidences[signer] = evidence.copy()
+	}
+
 	for number, refund := range s.ProposalRefund {
 		cpy.ProposalRefund[number] = make(map[common.Address]*big.Int)
 		for proposer, deposit := range refund {
@@ -315,6 +327,9 @@ func (s *Snapshot) apply(headers []*types.Header) (*Snapshot, error) {
 		// deal declares
 		snap.updateSnapshotByDeclares(headerExtra.CurrentBlockDeclares, header.Number)
 
+		// deal double signing evidences
+		snap.updateSnapshotByEvidences(headerExtra.CurrentBlockEvidences, header.Number)
+
 		// deal trantor upgrade
 		if snap.Period == 0 {
 			snap.Period = snap.config.Period
@@ -504,6 +519,13 @@ func (s *Snapshot) updateSnapshotForExpired(headerNumber *big.Int) {
 		}
 	}
 
+	// release the jailed signers
+	for signer, evidence := range s.Evidences {
+		if evidence.jailedUntil(s.config.Epoch) < headerNumber.Uint64() {
+			delete(s.Evidences, signer)
+		}
+	}
+
 	// deal the expired confirmation
 	for blockNumber := range s.Confirmations {
 		if headerNumber.Uint64()-blockNumber > s.config.MaxSignerCount {
@@ -519,6 +541,14 @@ func (s *Snapshot) updateSnapshotForExpired(headerNumber *big.Int) {
 	}
 }
 
+func (s *Snapshot) updateSnapshotByEvidences(evidences []Evidence, headerNumber *big.Int) {
+	for _, evidence := range evidences {
+		evidence := evidence.copy()
+		evidence.ReportedNumber = headerNumber.Uint64()
+		s.Evidences[evidence.Signer] = evidence
+	}
+}
+
 func (s *Snapshot) updateSnapshotByConfirmations(confirmations []Confirmation) {
 	for _, confirmation := range confirmations {
 		_, ok := s.Confirmations[confirmation.BlockNumber.Uint64()]
@@ -619,11 +649,20 @@ func (s *Snapshot) updateSnapshotForPunish(signerMissing []common.Address, heade
 	}
 }
 
+// slot returns the index of the producer slot a block sealed at headerTime
+// fills, counted from the start of the current loop.
+func (s *Snapshot) slot(headerTime uint64) uint64 {
+	return (headerTime - s.LoopStartTime) / s.config.Period
+}
+
 // inturn returns if a signer at a given block height is in-turn or not.
 func (s *Snapshot) inturn(signer common.Address, headerTime uint64) bool {
 	// if all node stop more than period of one loop
+	if s.isJailed(signer, s.Number+1) {
+		return false
+	}
 	if signersCount := len(s.Signers); signersCount > 0 {
-		if loopIndex := ((headerTime - s.LoopStartTime) / s.config.Period) % uint64(signersCount); *s.Signers[loopIndex] == signer {
+		if loopIndex := s.slot(headerTime) % uint64(signersCount); *s.Signers[loopIndex] == signer {
 			return true
 		}
 	}
@@ -639,6 +678,15 @@ func (s *Snapshot) isVoter(address common.Address) bool {
 	return false
 }
 
+// check if signer is kept out of the signer queue at the given block number
+// for double signing
+func (s *Snapshot) isJailed(signer common.Address, number uint64) bool {
+	if evidence, ok := s.Evidences[signer]; ok {
+		return evidence.jailedUntil(s.config.Epoch) >= number
+	}
+	return false
+}
+
 // check if address belong to candidate
 func (s *Snapshot) isCandidate(address common.Address) bool {
 	if _, ok := s.Candidates[address]; ok {
@@ -0,0 +1,293 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package dpos
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+
+	"github.com/simplechain-org/go-simplechain/accounts/abi"
+	"github.com/simplechain-org/go-simplechain/common"
+	"github.com/simplechain-org/go-simplechain/consensus"
+	"github.com/simplechain-org/go-simplechain/core/state"
+	"github.com/simplechain-org/go-simplechain/core/types"
+	"github.com/simplechain-org/go-simplechain/log"
+	"github.com/simplechain-org/go-simplechain/params"
+)
+
+// SystemContractAddress is the address governance transactions are sent to
+// once the system contract fork is active. No code lives there: calls are
+// decoded against SystemContractABI and applied by the engine when the block
+// is finalized, the way the string encoded events were before the fork.
+//
+// Transactions carrying value to it are rejected, as there is nothing to hold
+// or refund the value.
+var SystemContractAddress = params.DPoSSystemContractAddress
+
+// SystemContractABI is the JSON ABI of the DPoS system contract, suitable for
+// abigen and for abi.JSON.
+//
+// Zero proposal parameters select the engine defaults, as omitting the key did
+// in the string encoded proposal.
+const SystemContractABI = `[
+	{"type":"function","name":"vote","inputs":[{"name":"candidate","type":"address"}],"outputs":[]},
+	{"type":"function","name":"devote","inputs":[],"outputs":[]},
+	{"type":"function","name":"confirm","inputs":[{"name":"number","type":"uint256"}],"outputs":[]},
+	{"type":"function","name":"propose","inputs":[{"name":"proposalType","type":"uint64"},{"name":"validationLoopCnt","type":"uint64"},{"name":"candidate","type":"address"},{"name":"minerRewardPerThousand","type":"uint64"},{"name":"minVoterBalance","type":"uint64"},{"name":"proposalDeposit","type":"uint64"}],"outputs":[]},
+	{"type":"function","name":"declare","inputs":[{"name":"proposalHash","type":"bytes32"},{"name":"decision","type":"bool"}],"outputs":[]},
+	{"type":"function","name":"submitEvidence","inputs":[{"name":"headerA","type":"bytes"},{"name":"headerB","type":"bytes"}],"outputs":[]},
+	{"type":"event","name":"Voted","inputs":[{"name":"voter","type":"address","indexed":true},{"name":"candidate","type":"address","indexed":true},{"name":"stake","type":"uint256","indexed":false}],"anonymous":false},
+	{"type":"event","name":"Devoted","inputs":[{"name":"voter","type":"address","indexed":true}],"anonymous":false},
+	{"type":"event","name":"Confirmed","inputs":[{"name":"signer","type":"address","indexed":true},{"name":"number","type":"uint256","indexed":true}],"anonymous":false},
+	{"type":"event","name":"Proposed","inputs":[{"name":"hash","type":"bytes32","indexed":true},{"name":"proposer","type":"address","indexed":true},{"name":"proposalType","type":"uint64","indexed":false},{"name":"candidate","type":"address","indexed":false}],"anonymous":false},
+	{"type":"event","name":"Declared","inputs":[{"name":"proposalHash","type":"bytes32","indexed":true},{"name":"declarer","type":"address","indexed":true},{"name":"decision","type":"bool","indexed":false}],"anonymous":false},
+	{"type":"event","name":"Slashed","inputs":[{"name":"signer","type":"address","indexed":true},{"name":"number","type":"uint256","indexed":true},{"name":"reporter","type":"address","indexed":false},{"name":"signerSlash","type":"uint256","indexed":false},{"name":"voterSlash","type":"uint256","indexed":false}],"anonymous":false}
+]`
+
+// systemABI is the parsed SystemContractABI.
+var systemABI abi.ABI
+
+func init() {
+	var err error
+	if systemABI, err = abi.JSON(strings.NewReader(SystemContractABI)); err != nil {
+		panic(err)
+	}
+}
+
+var (
+	// errInvalidSystemCall is returned if a transaction to the system contract
+	// doesn't decode against its ABI.
+	errInvalidSystemCall = errors.New("invalid system contract call")
+)
+
+// PackSystemCall encodes a call of the named system contract method.
+func PackSystemCall(method string, args ...interface{}) ([]byte, error) {
+	return systemABI.Pack(method, args...)
+}
+
+// systemCall is a decoded call of the system contract.
+type systemCall struct {
+	method string
+	args   []interface{}
+}
+
+// decodeSystemCall decodes the data of a transaction sent to the system
+// contract.
+func decodeSystemCall(data []byte) (*systemCall, error) {
+	if len(data) < 4 {
+		return nil, errInvalidSystemCall
+	}
+	method, err := systemABI.MethodById(data[:4])
+	if err != nil {
+		return nil, errInvalidSystemCall
+	}
+	args, err := method.Inputs.UnpackValues(data[4:])
+	if err != nil {
+		return nil, errInvalidSystemCall
+	}
+	return &systemCall{method: method.Name, args: args}, nil
+}
+
+// systemLog builds the log of a system contract event. The indexed arguments
+// go into the topics in order, the rest are ABI encoded as the log data.
+func systemLog(event string, args ...interface{}) *types.Log {
+	ev := systemABI.Events[event]
+
+	var (
+		topics    = []common.Hash{ev.ID()}
+		nonIndex  []interface{}
+		nonFields abi.Arguments
+	)
+	for i, arg := range ev.Inputs {
+		if !arg.Indexed {
+			nonIndex = append(nonIndex, args[i])
+			nonFields = append(nonFields, arg)
+			continue
+		}
+		switch v := args[i].(type) {
+		case common.Address:
+			topics = append(topics, v.Hash())
+		case common.Hash:
+			topics = append(topics, v)
+		case *big.Int:
+			topics = append(topics, common.BigToHash(v))
+		}
+	}
+	data, err := nonFields.Pack(nonIndex...)
+	if err != nil {
+		// The arguments are built by the engine, any mismatch is a bug
+		panic(err)
+	}
+	return &types.Log{
+		Address: SystemContractAddress,
+		Topics:  topics,
+		Data:    data,
+	}
+}
+
+// attachSystemLogs appends the logs emitted by system contract calls to the
+// receipts of their transactions and renumbers the log indexes of the block.
+// Log slices and renumbered logs are copied rather than modified in place, as
+// the miner shares them between the blocks it assembles.
+func attachSystemLogs(receipts []*types.Receipt, number uint64, emitted map[int][]*types.Log) {
+	if len(emitted) == 0 {
+		return
+	}
+	var index uint
+	for i, receipt := range receipts {
+		logs := make([]*types.Log, 0, len(receipt.Logs)+len(emitted[i]))
+		for _, l := range receipt.Logs {
+			if l.Index != index {
+				cpy := *l
+				cpy.Index = index
+				l = &cpy
+			}
+			logs = append(logs, l)
+			index++
+		}
+		for _, l := range emitted[i] {
+			l.TxHash = receipt.TxHash
+			l.TxIndex = uint(i)
+			l.BlockNumber = number
+			l.Index = index
+			logs = append(logs, l)
+			index++
+		}
+		receipt.Logs = logs
+		if len(emitted[i]) > 0 {
+			receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+		}
+	}
+}
+
+// processSystemCall applies a governance call of the system contract, the
+// typed counterpart of processStringEvent. It returns the logs of the events
+// the call emitted, none if the call had no effect.
+func (d *DPoS) processSystemCall(headerExtra HeaderExtra, chain consensus.ChainReader, number uint64, state *state.StateDB, tx *types.Transaction, txSender common.Address, snap *Snapshot, refundHash RefundHash) (HeaderExtra, RefundHash, []*types.Log) {
+	if snap == nil {
+		return headerExtra, refundHash, nil
+	}
+	call, err := decodeSystemCall(tx.Data())
+	if err != nil {
+		log.Debug("Ignored system contract call", "tx", tx.Hash(), "err", err)
+		return headerExtra, refundHash, nil
+	}
+	var logs []*types.Log
+
+	switch call.method {
+	case "vote":
+		candidate := call.args[0].(common.Address)
+		if (!candidateNeedPD || snap.isCandidate(candidate)) && state.GetBalance(txSender).Cmp(snap.MinVB) > 0 {
+			headerExtra.CurrentBlockVotes = d.processEventVote(headerExtra.CurrentBlockVotes, state, candidate, txSender)
+			vote := headerExtra.CurrentBlockVotes[len(headerExtra.CurrentBlockVotes)-1]
+			logs = append(logs, systemLog("Voted", txSender, candidate, vote.Stake))
+		}
+
+	case "devote":
+		if snap.isVoter(txSender) {
+			headerExtra.CurrentBlockVotes = d.processEventDeVote(headerExtra.CurrentBlockVotes, txSender)
+			logs = append(logs, systemLog("Devoted", txSender))
+		}
+
+	case "confirm":
+		confirmedBlockNumber := call.args[0].(*big.Int)
+		if snap.isCandidate(txSender) && confirmedBlockNumber.IsUint64() {
+			confirmed := len(headerExtra.CurrentBlockConfirmations)
+			headerExtra.CurrentBlockConfirmations, refundHash = d.processEventConfirm(headerExtra.CurrentBlockConfirmations, chain, confirmedBlockNumber, number, tx, txSender, refundHash)
+			if len(headerExtra.CurrentBlockConfirmations) > confirmed {
+				logs = append(logs, systemLog("Confirmed", txSender, confirmedBlockNumber))
+			}
+		}
+
+	case "propose":
+		proposal, ok := systemProposal(call.args, tx, txSender)
+		if !ok {
+			break
+		}
+		proposed := len(headerExtra.CurrentBlockProposals)
+		headerExtra.CurrentBlockProposals = d.processEventProposal(headerExtra.CurrentBlockProposals, proposal, state, txSender)
+		if len(headerExtra.CurrentBlockProposals) > proposed {
+			logs = append(logs, systemLog("Proposed", tx.Hash(), txSender, proposal.ProposalType, proposal.TargetAddress))
+		}
+
+	case "declare":
+		if snap.isCandidate(txSender) {
+			declare := Declare{
+				ProposalHash: common.Hash(call.args[0].([32]byte)),
+				Declarer:     txSender,
+				Decision:     call.args[1].(bool),
+			}
+			headerExtra.CurrentBlockDeclares = append(headerExtra.CurrentBlockDeclares, declare)
+			logs = append(logs, systemLog("Declared", declare.ProposalHash, txSender, declare.Decision))
+		}
+
+	case "submitEvidence":
+		var evidence *Evidence
+		headerExtra, evidence, err = d.processEventEvidence(headerExtra, chain, state, number, call.args[0].([]byte), call.args[1].([]byte), txSender, snap)
+		if err != nil {
+			log.Debug("Ignored double signing evidence", "tx", tx.Hash(), "err", err)
+			break
+		}
+		log.Info("Slashed double signing signer", "signer", evidence.Signer, "number", evidence.Number, "reporter", txSender)
+		refundHash[tx.Hash()] = RefundPair{txSender, tx.GasPrice()}
+		logs = append(logs, systemLog("Slashed", evidence.Signer, new(big.Int).SetUint64(evidence.Number), txSender, evidence.SignerSlash, evidence.VoterSlash))
+	}
+	return headerExtra, refundHash, logs
+}
+
+// systemProposal builds the proposal of a propose call, reporting false if any
+// of its parameters is out of range. The bounds are those of the string
+// encoded proposal.
+func systemProposal(args []interface{}, tx *types.Transaction, proposer common.Address) (Proposal, bool) {
+	var (
+		proposalType           = args[0].(uint64)
+		validationLoopCnt      = args[1].(uint64)
+		candidate              = args[2].(common.Address)
+		minerRewardPerThousand = args[3].(uint64)
+		minVoterBalance        = args[4].(uint64)
+		proposalDeposit        = args[5].(uint64)
+	)
+	proposal := newProposal(tx, proposer)
+	proposal.TargetAddress = candidate
+
+	if proposalType != 0 {
+		proposal.ProposalType = proposalType
+	}
+	if validationLoopCnt != 0 {
+		if validationLoopCnt < minValidationLoopCnt || validationLoopCnt > maxValidationLoopCnt {
+			return Proposal{}, false
+		}
+		proposal.ValidationLoopCnt = validationLoopCnt
+	}
+	if minerRewardPerThousand != 0 {
+		if minerRewardPerThousand > 1000 {
+			return Proposal{}, false
+		}
+		proposal.MinerRewardPerThousand = minerRewardPerThousand
+	}
+	if minVoterBalance != 0 {
+		proposal.MinVoterBalance = minVoterBalance
+	}
+	if proposalDeposit != 0 {
+		if proposalDeposit > maxProposalDeposit {
+			return Proposal{}, false
+		}
+		proposal.ProposalDeposit = proposalDeposit
+	}
+	return proposal, true
+}
@@ -0,0 +1,141 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package dpos
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/simplechain-org/go-simplechain/common"
+	"github.com/simplechain-org/go-simplechain/core/types"
+)
+
+func TestDecodeSystemCall(t *testing.T) {
+	candidate := common.HexToAddress("0x0102030405060708090a0b0c0d0e0f1011121314")
+	hash := common.HexToHash("0x853e10706e6b9d39c5f4719018aa2417e8b852dec8ad18f9c592d526db64c725")
+
+	tests := []struct {
+		method string
+		args   []interface{}
+	}{
+		{"vote", []interface{}{candidate}},
+		{"devote", nil},
+		{"confirm", []interface{}{big.NewInt(123)}},
+		{"propose", []interface{}{uint64(2), uint64(100), candidate, uint64(500), uint64(10), uint64(20)}},
+		{"declare", []interface{}{[32]byte(hash), true}},
+	}
+	for i, tt := range tests {
+		data, err := PackSystemCall(tt.method, tt.args...)
+		if err != nil {
+			t.Fatalf("test %d: failed to pack %s: %v", i, tt.method, err)
+		}
+		call, err := decodeSystemCall(data)
+		if err != nil {
+			t.Fatalf("test %d: failed to decode %s: %v", i, tt.method, err)
+		}
+		if call.method != tt.method {
+			t.Errorf("test %d: method mismatch: have %s, want %s", i, call.method, tt.method)
+		}
+		if len(call.args) != len(tt.args) {
+			t.Fatalf("test %d: argument count mismatch: have %d, want %d", i, len(call.args), len(tt.args))
+		}
+		for j, arg := range tt.args {
+			if want, ok := arg.(*big.Int); ok {
+				if have := call.args[j].(*big.Int); have.Cmp(want) != 0 {
+					t.Errorf("test %d: argument %d mismatch: have %v, want %v", i, j, have, want)
+				}
+			} else if call.args[j] != arg {
+				t.Errorf("test %d: argument %d mismatch: have %v, want %v", i, j, call.args[j], arg)
+			}
+		}
+	}
+	// Data that doesn't match the ABI must be refused
+	for i, data := range [][]byte{nil, {0x01, 0x02}, {0xde, 0xad, 0xbe, 0xef}, []byte("dpos:1:event:vote")} {
+		if _, err := decodeSystemCall(data); err != errInvalidSystemCall {
+			t.Errorf("invalid call %d: error mismatch: have %v, want %v", i, err, errInvalidSystemCall)
+		}
+	}
+}
+
+func TestSystemProposal(t *testing.T) {
+	tx := types.NewTransaction(0, SystemContractAddress, new(big.Int), 0, new(big.Int), nil)
+	proposer := common.HexToAddress("0x01")
+
+	// Zero parameters select the defaults
+	proposal, ok := systemProposal([]interface{}{uint64(0), uint64(0), common.Address{}, uint64(0), uint64(0), uint64(0)}, tx, proposer)
+	if !ok {
+		t.Fatalf("default proposal refused")
+	}
+	if want := newProposal(tx, proposer); proposal.ValidationLoopCnt != want.ValidationLoopCnt || proposal.ProposalType != want.ProposalType ||
+		proposal.MinerRewardPerThousand != want.MinerRewardPerThousand || proposal.ProposalDeposit != want.ProposalDeposit {
+		t.Errorf("default proposal mismatch: have %+v, want %+v", proposal, want)
+	}
+	// Out of range parameters reject the proposal
+	tests := []struct {
+		args []interface{}
+		ok   bool
+	}{
+		{[]interface{}{uint64(3), uint64(minValidationLoopCnt), common.Address{}, uint64(1000), uint64(1), uint64(maxProposalDeposit)}, true},
+		{[]interface{}{uint64(0), uint64(minValidationLoopCnt - 1), common.Address{}, uint64(0), uint64(0), uint64(0)}, false},
+		{[]interface{}{uint64(0), uint64(maxValidationLoopCnt + 1), common.Address{}, uint64(0), uint64(0), uint64(0)}, false},
+		{[]interface{}{uint64(0), uint64(0), common.Address{}, uint64(1001), uint64(0), uint64(0)}, false},
+		{[]interface{}{uint64(0), uint64(0), common.Address{}, uint64(0), uint64(0), uint64(maxProposalDeposit + 1)}, false},
+	}
+	for i, tt := range tests {
+		if _, ok := systemProposal(tt.args, tx, proposer); ok != tt.ok {
+			t.Errorf("test %d: validity mismatch: have %v, want %v", i, ok, tt.ok)
+		}
+	}
+}
+
+func TestAttachSystemLogs(t *testing.T) {
+	voter, candidate := common.HexToAddress("0x01"), common.HexToAddress("0x02")
+
+	shared := &types.Log{Address: common.HexToAddress("0x03"), Index: 0}
+	later := &types.Log{Address: common.HexToAddress("0x04"), Index: 1}
+	receipts := []*types.Receipt{
+		{TxHash: common.HexToHash("0xa1"), Logs: []*types.Log{shared}},
+		{TxHash: common.HexToHash("0xa2")},
+		{TxHash: common.HexToHash("0xa3"), Logs: []*types.Log{later}},
+	}
+	voted := systemLog("Voted", voter, candidate, big.NewInt(100))
+	attachSystemLogs(receipts, 7, map[int][]*types.Log{1: {voted}})
+
+	if len(receipts[1].Logs) != 1 || receipts[1].Logs[0] != voted {
+		t.Fatalf("system log not attached: %v", receipts[1].Logs)
+	}
+	if voted.TxHash != receipts[1].TxHash || voted.TxIndex != 1 || voted.BlockNumber != 7 || voted.Index != 1 {
+		t.Errorf("system log fields mismatch: %+v", voted)
+	}
+	if want := []common.Hash{systemABI.Events["Voted"].ID(), voter.Hash(), candidate.Hash()}; len(voted.Topics) != len(want) ||
+		voted.Topics[0] != want[0] || voted.Topics[1] != want[1] || voted.Topics[2] != want[2] {
+		t.Errorf("system log topics mismatch: have %v, want %v", voted.Topics, want)
+	}
+	if !types.BloomLookup(receipts[1].Bloom, SystemContractAddress) {
+		t.Errorf("receipt bloom misses the system contract")
+	}
+	// Logs after the inserted one are renumbered on copies
+	if receipts[0].Logs[0] != shared {
+		t.Errorf("log with unchanged index was copied")
+	}
+	if have := receipts[2].Logs[0].Index; have != 2 {
+		t.Errorf("renumbered index mismatch: have %d, want %d", have, 2)
+	}
+	if later.Index != 1 {
+		t.Errorf("original log modified: index %d", later.Index)
+	}
+}
@@ -82,6 +82,12 @@ var (
 	errEmptyCommittedSeals = errors.New("zero committed seals")
 	// errMismatchTxhashes is returned if the TxHash in header is mismatch.
 	errMismatchTxhashes = errors.New("mismatch transactions hashes")
+	// errInvalidTransitionValidators is returned if the first block sealed by Istanbul
+	// after another engine doesn't list the scheduled validators in its extra-data.
+	errInvalidTransitionValidators = errors.New("invalid transition validators")
+	// errUntrustedCommittedSeals is returned if too few of the committed seals of an
+	// epoch header are signed by the validators trusted before it.
+	errUntrustedCommittedSeals = errors.New("untrusted committed seals")
 )
 var (
 	defaultDifficulty = big.NewInt(1)
@@ -206,6 +212,20 @@ func (sb *backend) verifyCascadingFields(chain consensus.ChainReader, header *ty
 	for i, validator := range snap.validators() {
 		copy(validators[i*common.AddressLength:], validator[:])
 	}
+	// The first block after switching from another engine sets the validators
+	if sb.config.TransitionBlock > 0 && number == sb.config.TransitionBlock {
+		extra, err := types.ExtractIstanbulExtra(header)
+		if err != nil {
+			return err
+		}
+		listed := make([]byte, len(extra.Validators)*common.AddressLength)
+		for i, validator := range extra.Validators {
+			copy(listed[i*common.AddressLength:], validator[:])
+		}
+		if !bytes.Equal(listed, validators) {
+			return errInvalidTransitionValidators
+		}
+	}
 	if err := sb.verifySigner(chain, header, parents); err != nil {
 		return err
 	}
@@ -570,13 +590,19 @@ func (sb *backend) snapshot(chain consensus.ChainReader, number uint64, hash com
 		snap    *Snapshot
 	)
 	for snap == nil {
+		// If we're before the switch from another engine, make a snapshot of the
+		// validators scheduled for the first Istanbul block
+		if number < sb.config.TransitionBlock {
+			snap = newSnapshot(sb.config.Epoch, number, hash, validator.NewSet(sb.config.TransitionValidators, sb.config.ProposerPolicy))
+			break
+		}
 		// If an in-memory snapshot was found, use that
 		if s, ok := sb.recents.Get(hash); ok {
 			snap = s.(*Snapshot)
 			break
 		}
-		// If an on-disk checkpoint snapshot can be found, use that
-		if number%checkpointInterval == 0 {
+		// If an on-disk checkpoint or trusted epoch snapshot can be found, use that
+		if number%checkpointInterval == 0 || number%sb.config.Epoch == 0 {
 			if s, err := loadSnapshot(sb.config.Epoch, sb.db, hash); err == nil {
 				log.Trace("Loaded voting snapshot form disk", "number", number, "hash", hash)
 				snap = s
@@ -639,6 +665,90 @@ func (sb *backend) snapshot(chain consensus.ChainReader, number uint64, hash com
 	return snap, err
 }
 
+// VerifyEpochHeader checks the header of a validator handoff against the given
+// trusted validators, without the chain of headers leading to it. The header has
+// to be proposed and committed by its own validators, and more than a third of
+// the trusted validators have to be among the committers, one of them honest at
+// least. The validators of the header are returned to be trusted for the next.
+func (sb *backend) VerifyEpochHeader(validators []common.Address, header *types.Header) ([]common.Address, error) {
+	extra, err := types.ExtractIstanbulExtra(header)
+	if err != nil {
+		return nil, errInvalidExtraDataFormat
+	}
+	if header.MixDigest != types.IstanbulDigest {
+		return nil, errInvalidMixDigest
+	}
+	if header.Difficulty == nil || header.Difficulty.Cmp(defaultDifficulty) != 0 {
+		return nil, errInvalidDifficulty
+	}
+	listed := validator.NewSet(extra.Validators, sb.config.ProposerPolicy)
+	trusted := validator.NewSet(validators, sb.config.ProposerPolicy)
+
+	// The proposer has to be one of the validators of the header
+	proposer, err := ecrecover(header)
+	if err != nil {
+		return nil, err
+	}
+	if _, v := listed.GetByAddress(proposer); v == nil {
+		return nil, errUnauthorized
+	}
+	// Every committer has to be a distinct validator of the header
+	if len(extra.CommittedSeal) == 0 {
+		return nil, errEmptyCommittedSeals
+	}
+	committers, err := sb.Signers(header)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		uncommitted = listed.Copy()
+		untrusted   = trusted.Copy()
+		validSeal   int
+		trustedSeal int
+	)
+	for _, addr := range committers {
+		if !uncommitted.RemoveValidator(addr) {
+			return nil, errInvalidCommittedSeals
+		}
+		validSeal++
+		if untrusted.RemoveValidator(addr) {
+			trustedSeal++
+		}
+	}
+	if validSeal <= listed.F() {
+		return nil, errInvalidCommittedSeals
+	}
+	if trustedSeal <= trusted.F() {
+		return nil, errUntrustedCommittedSeals
+	}
+	return extra.Validators, nil
+}
+
+// TrustEpochHeader stores the snapshot at an epoch header verified by a light
+// client, from which the headers after it can be verified without the history
+// before it. The votes are reset at epoch headers, so the validators listed in
+// the header and its own vote make up the whole snapshot.
+func (sb *backend) TrustEpochHeader(header *types.Header) error {
+	number := header.Number.Uint64()
+	if number == 0 || number%sb.config.Epoch != 0 {
+		return errUnknownBlock
+	}
+	extra, err := types.ExtractIstanbulExtra(header)
+	if err != nil {
+		return errInvalidExtraDataFormat
+	}
+	snap := newSnapshot(sb.config.Epoch, number-1, header.ParentHash, validator.NewSet(extra.Validators, sb.config.ProposerPolicy))
+	if snap, err = snap.apply([]*types.Header{header}); err != nil {
+		return err
+	}
+	if err := snap.store(sb.db); err != nil {
+		return err
+	}
+	sb.recents.Add(snap.Hash, snap)
+	log.Trace("Stored trusted epoch snapshot to disk", "number", snap.Number, "hash", snap.Hash)
+	return nil
+}
+
 // FIXME: Need to update this for Istanbul
 // sigHash returns the hash which is used as input for the Istanbul
 // signing. It is the hash of the entire header apart from the 65 byte signature
@@ -450,6 +450,81 @@ OUT3:
 	}
 }
 
+func TestTransitionValidators(t *testing.T) {
+	chain, engine := newBlockChain(1)
+
+	// Switch to Istanbul at block 1 with a validator other than the genesis one
+	key, _ := crypto.GenerateKey()
+	validator := crypto.PubkeyToAddress(key.PublicKey)
+
+	config := *engine.config
+	config.TransitionBlock = 1
+	config.TransitionValidators = []common.Address{validator}
+	engine.config = &config
+	engine.recents.Purge()
+
+	snap, err := engine.snapshot(chain, 0, chain.Genesis().Hash(), nil)
+	if err != nil {
+		t.Fatalf("failed to get snapshot: %v", err)
+	}
+	if validators := snap.validators(); !reflect.DeepEqual(validators, config.TransitionValidators) {
+		t.Errorf("validators mismatch: have %v, want %v", validators, config.TransitionValidators)
+	}
+	// The transition header has to list the scheduled validators
+	header := makeHeader(chain.Genesis(), engine.config)
+	if err := engine.verifyCascadingFields(chain, header, nil); err != errInvalidTransitionValidators {
+		t.Errorf("error mismatch: have %v, want %v", err, errInvalidTransitionValidators)
+	}
+	if err := engine.Prepare(chain, header); err != nil {
+		t.Fatalf("failed to prepare header: %v", err)
+	}
+	if err := engine.verifyCascadingFields(chain, header, nil); err == errInvalidTransitionValidators {
+		t.Errorf("error mismatch: have %v, want not %v", err, errInvalidTransitionValidators)
+	}
+}
+
+func TestEpochHeaders(t *testing.T) {
+	chain, engine := newBlockChain(1)
+
+	// Make every block an epoch header
+	config := *engine.config
+	config.Epoch = 1
+	engine.config = &config
+
+	block := makeBlock(chain, engine, chain.Genesis())
+	header := block.Header()
+
+	validators, err := engine.VerifyEpochHeader([]common.Address{engine.address}, header)
+	if err != nil {
+		t.Fatalf("failed to verify epoch header: %v", err)
+	}
+	if !reflect.DeepEqual(validators, []common.Address{engine.address}) {
+		t.Errorf("validators mismatch: have %v, want %v", validators, []common.Address{engine.address})
+	}
+	// Committers unknown to the trusted validators don't hand off
+	untrusted := make([]common.Address, 4)
+	for i := range untrusted {
+		key, _ := crypto.GenerateKey()
+		untrusted[i] = crypto.PubkeyToAddress(key.PublicKey)
+	}
+	if _, err := engine.VerifyEpochHeader(untrusted, header); err != errUntrustedCommittedSeals {
+		t.Errorf("error mismatch: have %v, want %v", err, errUntrustedCommittedSeals)
+	}
+	// The snapshot of a trusted epoch header survives without its ancestors
+	if err := engine.TrustEpochHeader(header); err != nil {
+		t.Fatalf("failed to trust epoch header: %v", err)
+	}
+	engine.recents.Purge()
+
+	snap, err := engine.snapshot(chain, header.Number.Uint64(), header.Hash(), nil)
+	if err != nil {
+		t.Fatalf("failed to get snapshot: %v", err)
+	}
+	if have := snap.validators(); !reflect.DeepEqual(have, validators) {
+		t.Errorf("snapshot validators mismatch: have %v, want %v", have, validators)
+	}
+}
+
 func TestPrepareExtra(t *testing.T) {
 	validators := make([]common.Address, 4)
 	validators[0] = common.BytesToAddress(hexutil.MustDecode("0x44add0ec310f115a0e603b2d7db9f067778eaf8a"))
@@ -16,6 +16,8 @@
 
 package istanbul
 
+import "github.com/simplechain-org/go-simplechain/common"
+
 type ProposerPolicy uint64
 
 const (
@@ -28,6 +30,10 @@ type Config struct {
 	BlockPeriod    uint64         `toml:",omitempty"` // Default minimum difference between two consecutive block's timestamps in second
 	ProposerPolicy ProposerPolicy `toml:",omitempty"` // The policy for proposer selection
 	Epoch          uint64         `toml:",omitempty"` // The number of blocks after which to checkpoint and reset the pending votes
+
+	// Switch from another consensus engine, set from the chain configuration
+	TransitionBlock      uint64           `toml:"-"` // The first block sealed by Istanbul (0 = genesis)
+	TransitionValidators []common.Address `toml:"-"` // The validators of the first block
 }
 
 var DefaultConfig = &Config{
@@ -0,0 +1,319 @@
+// Copyright 2020 The go-simplechain Authors
+// This file is part of the go-simplechain library.
+//
+// The go-simplechain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-simplechain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-simplechain library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package multi implements a consensus engine switching between other engines
+// at fork blocks, which lets a chain migrate its consensus without a regenesis.
+package multi
+
+import (
+	"math/big"
+
+	"github.com/simplechain-org/go-simplechain/common"
+	"github.com/simplechain-org/go-simplechain/consensus"
+	"github.com/simplechain-org/go-simplechain/core/state"
+	"github.com/simplechain-org/go-simplechain/core/types"
+	"github.com/simplechain-org/go-simplechain/p2p"
+	"github.com/simplechain-org/go-simplechain/params"
+	"github.com/simplechain-org/go-simplechain/rpc"
+)
+
+// Engine is a consensus engine delegating every block to the engine scheduled
+// for its number.
+type Engine struct {
+	engines []consensus.Engine // Engines in the order of the schedule
+	starts  []uint64           // First block sealed by each engine, starts[0] being 0
+}
+
+// New creates an engine running engines[i] from block starts[i] on, up to the
+// start of the next engine. The starts have to be ascending, the first one zero.
+//
+// If any engine is istanbul, the returned engine implements consensus.Istanbul,
+// forwarding the istanbul specific calls to it.
+func New(engines []consensus.Engine, starts []uint64) consensus.Engine {
+	e := &Engine{engines: engines, starts: starts}
+	for _, engine := range engines {
+		if istanbul, ok := engine.(consensus.Istanbul); ok {
+			return &IstanbulEngine{Engine: e, istanbul: istanbul}
+		}
+	}
+	return e
+}
+
+// EngineAt returns the engine sealing the block with the given number. Engines
+// other than a multi engine seal every block.
+func EngineAt(engine consensus.Engine, number uint64) consensus.Engine {
+	switch e := engine.(type) {
+	case *Engine:
+		return e.engineAt(number)
+	case *IstanbulEngine:
+		return e.engineAt(number)
+	}
+	return engine
+}
+
+// Engines returns the engines of a multi engine in the order of the schedule,
+// or the engine itself if it's not a multi engine.
+func Engines(engine consensus.Engine) []consensus.Engine {
+	switch e := engine.(type) {
+	case *Engine:
+		return e.engines
+	case *IstanbulEngine:
+		return e.engines
+	}
+	return []consensus.Engine{engine}
+}
+
+// reader returns the chain reader handed to the engine at the given position in
+// the schedule, which sees the chain config of that engine and the given batch
+// of headers as part of the chain.
+func (e *Engine) reader(chain consensus.ChainReader, index int, batch []*types.Header) consensus.ChainReader {
+	r := &chainReader{
+		ChainReader: chain,
+		config:      chain.Config().ConsensusAt(new(big.Int).SetUint64(e.starts[index])),
+	}
+	if len(batch) > 0 {
+		r.headers = make(map[common.Hash]*types.Header, len(batch))
+		for _, header := range batch {
+			r.headers[header.Hash()] = header
+		}
+	}
+	return r
+}
+
+// index returns the position in the schedule of the engine sealing the block
+// with the given number.
+func (e *Engine) index(number uint64) int {
+	i := len(e.starts) - 1
+	for i > 0 && e.starts[i] > number {
+		i--
+	}
+	return i
+}
+
+// engineAt returns the engine sealing the block with the given number.
+func (e *Engine) engineAt(number uint64) consensus.Engine {
+	return e.engines[e.index(number)]
+}
+
+// Author implements consensus.Engine, returning the author according to the
+// engine which sealed the header.
+func (e *Engine) Author(header *types.Header) (common.Address, error) {
+	return e.engineAt(header.Number.Uint64()).Author(header)
+}
+
+// VerifyHeader implements consensus.Engine, verifying the header against the
+// rules of the engine scheduled for it.
+func (e *Engine) VerifyHeader(chain consensus.ChainReader, header *types.Header, seal bool) error {
+	index := e.index(header.Number.Uint64())
+	return e.engines[index].VerifyHeader(e.reader(chain, index, nil), header, seal)
+}
+
+// VerifyHeaders implements consensus.Engine. The batch is split into runs of
+// headers sealed by the same engine, verified one run after the other. The
+// engine of a run sees the headers preceding it in the batch as if they were
+// part of the chain already, so it can check the run against its ancestors
+// across the boundary.
+func (e *Engine) VerifyHeaders(chain consensus.ChainReader, headers []*types.Header, seals []bool) (chan<- struct{}, <-chan error) {
+	abort := make(chan struct{})
+	results := make(chan error, len(headers))
+
+	go func() {
+		for start := 0; start < len(headers); {
+			index := e.index(headers[start].Number.Uint64())
+			end := start + 1
+			for end < len(headers) && e.index(headers[end].Number.Uint64()) == index {
+				end++
+			}
+			cancel, errs := e.engines[index].VerifyHeaders(e.reader(chain, index, headers[:start]), headers[start:end], seals[start:end])
+			for i := start; i < end; i++ {
+				select {
+				case <-abort:
+					close(cancel)
+					return
+				case err := <-errs:
+					results <- err
+				}
+			}
+			close(cancel)
+			start = end
+		}
+	}()
+	return abort, results
+}
+
+// VerifyUncles implements consensus.Engine, verifying the uncles against the
+// rules of the engine scheduled for the block.
+func (e *Engine) VerifyUncles(chain consensus.ChainReader, block *types.Block) error {
+	index := e.index(block.NumberU64())
+	return e.engines[index].VerifyUncles(e.reader(chain, index, nil), block)
+}
+
+// VerifySeal implements consensus.Engine, verifying the seal against the rules
+// of the engine scheduled for the header.
+func (e *Engine) VerifySeal(chain consensus.ChainReader, header *types.Header) error {
+	index := e.index(header.Number.Uint64())
+	return e.engines[index].VerifySeal(e.reader(chain, index, nil), header)
+}
+
+// Prepare implements consensus.Engine, preparing the header for the engine
+// scheduled for it.
+func (e *Engine) Prepare(chain consensus.ChainReader, header *types.Header) error {
+	index := e.index(header.Number.Uint64())
+	return e.engines[index].Prepare(e.reader(chain, index, nil), header)
+}
+
+// Finalize implements consensus.Engine, applying the post-transaction state
+// modifications of the engine scheduled for the header.
+func (e *Engine) Finalize(chain consensus.ChainReader, header *types.Header, state *state.StateDB, txs []*types.Transaction,
+	uncles []*types.Header, receipts []*types.Receipt) error {
+	index := e.index(header.Number.Uint64())
+	return e.engines[index].Finalize(e.reader(chain, index, nil), header, state, txs, uncles, receipts)
+}
+
+// FinalizeAndAssemble implements consensus.Engine, finalizing and assembling the
+// block with the engine scheduled for the header.
+func (e *Engine) FinalizeAndAssemble(chain consensus.ChainReader, header *types.Header, state *state.StateDB, txs []*types.Transaction,
+	uncles []*types.Header, receipts []*types.Receipt) (*types.Block, error) {
+	index := e.index(header.Number.Uint64())
+	return e.engines[index].FinalizeAndAssemble(e.reader(chain, index, nil), header, state, txs, uncles, receipts)
+}
+
+// Seal implements consensus.Engine, sealing the block with the engine scheduled
+// for it.
+func (e *Engine) Seal(chain consensus.ChainReader, block *types.Block, results chan<- *types.Block, stop <-chan struct{}) error {
+	index := e.index(block.NumberU64())
+	return e.engines[index].Seal(e.reader(chain, index, nil), block, results, stop)
+}
+
+// SealHash implements consensus.Engine, returning the seal hash of the engine
+// scheduled for the header.
+func (e *Engine) SealHash(header *types.Header) common.Hash {
+	return e.engineAt(header.Number.Uint64()).SealHash(header)
+}
+
+// CalcDifficulty implements consensus.Engine, returning the difficulty the
+// engine scheduled for the child of parent requires.
+func (e *Engine) CalcDifficulty(chain consensus.ChainReader, time uint64, parent *types.Header) *big.Int {
+	index := e.index(parent.Number.Uint64() + 1)
+	return e.engines[index].CalcDifficulty(e.reader(chain, index, nil), time, parent)
+}
+
+// APIs implements consensus.Engine, returning the APIs of all the engines.
+func (e *Engine) APIs(chain consensus.ChainReader) []rpc.API {
+	var apis []rpc.API
+	for i, engine := range e.engines {
+		apis = append(apis, engine.APIs(e.reader(chain, i, nil))...)
+	}
+	return apis
+}
+
+// Close implements consensus.Engine, terminating all the engines.
+func (e *Engine) Close() error {
+	var err error
+	for _, engine := range e.engines {
+		if cerr := engine.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// SetThreads updates the number of mining threads of the engines supporting it.
+func (e *Engine) SetThreads(threads int) {
+	type threaded interface {
+		SetThreads(threads int)
+	}
+	for _, engine := range e.engines {
+		if th, ok := engine.(threaded); ok {
+			th.SetThreads(threads)
+		}
+	}
+}
+
+// Hashrate returns the sum of the mining hashrates of the proof-of-work engines.
+func (e *Engine) Hashrate() float64 {
+	var rate float64
+	for _, engine := range e.engines {
+		if pow, ok := engine.(consensus.PoW); ok {
+			rate += pow.Hashrate()
+		}
+	}
+	return rate
+}
+
+// IstanbulEngine is a multi engine switching to istanbul at some point, which
+// has to be driven by the protocol handler and the miner like a plain istanbul
+// engine.
+type IstanbulEngine struct {
+	*Engine
+	istanbul consensus.Istanbul
+}
+
+// Start implements consensus.Istanbul, starting the istanbul engine.
+func (e *IstanbulEngine) Start(chain consensus.ChainReader, currentBlock func() *types.Block, hasBadBlock func(hash common.Hash) bool) error {
+	return e.istanbul.Start(chain, currentBlock, hasBadBlock)
+}
+
+// Stop implements consensus.Istanbul, stopping the istanbul engine.
+func (e *IstanbulEngine) Stop() error {
+	return e.istanbul.Stop()
+}
+
+// SetBroadcaster implements consensus.Istanbul.
+func (e *IstanbulEngine) SetBroadcaster(broadcaster consensus.Broadcaster) {
+	e.istanbul.SetBroadcaster(broadcaster)
+}
+
+// HandleMsg implements consensus.Istanbul, handing the message to the istanbul
+// engine.
+func (e *IstanbulEngine) HandleMsg(addr common.Address, msg p2p.Msg) (bool, error) {
+	return e.istanbul.HandleMsg(addr, msg)
+}
+
+// NewChainHead implements consensus.Istanbul, notifying the istanbul engine.
+func (e *IstanbulEngine) NewChainHead() error {
+	return e.istanbul.NewChainHead()
+}
+
+// chainReader is the chain as seen by one of the engines: its chain config has
+// the consensus engine fields of that engine, and the headers of a batch under
+// verification are served as if they were part of the chain.
+type chainReader struct {
+	consensus.ChainReader
+	config  *params.ChainConfig
+	headers map[common.Hash]*types.Header
+}
+
+// Config retrieves the chain config of the engine.
+func (r *chainReader) Config() *params.ChainConfig {
+	return r.config
+}
+
+// GetHeader retrieves a header from the batch or the chain by hash and number.
+func (r *chainReader) GetHeader(hash common.Hash, number uint64) *types.Header {
+	if header, ok := r.headers[hash]; ok && header.Number.Uint64() == number {
+		return header
+	}
+	return r.ChainReader.GetHeader(hash, number)
+}
+
+// GetHeaderByHash retrieves a header from the batch or the chain by hash.
+func (r *chainReader) GetHeaderByHash(hash common.Hash) *types.Header {
+	if header, ok := r.headers[hash]; ok {
+		return header
+	}
+	return r.ChainReader.GetHeaderByHash(hash)
+}
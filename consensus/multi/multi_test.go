@@ -0,0 +1,97 @@
+// Copyright 2020 The go-simplechain Authors
+// This file is part of the go-simplechain library.
+//
+// The go-simplechain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-simplechain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-simplechain library. If not, see <http://www.gnu.org/licenses/>.
+
+package multi
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/simplechain-org/go-simplechain/consensus"
+	"github.com/simplechain-org/go-simplechain/consensus/ethash"
+	"github.com/simplechain-org/go-simplechain/core"
+	"github.com/simplechain-org/go-simplechain/core/rawdb"
+	"github.com/simplechain-org/go-simplechain/core/types"
+	"github.com/simplechain-org/go-simplechain/core/vm"
+	"github.com/simplechain-org/go-simplechain/params"
+)
+
+// Tests that a header batch spanning a switch of engines is verified by the
+// engine scheduled for each header, with the engine after the switch seeing
+// the headers before it in the batch.
+func TestVerifyHeadersAcrossSwitch(t *testing.T) {
+	const switchBlock = 4
+
+	db := rawdb.NewMemoryDatabase()
+	genesis := new(core.Genesis).MustCommit(db)
+	blocks, _ := core.GenerateChain(params.TestChainConfig, genesis, ethash.NewFaker(), db, 8, nil)
+
+	headers := make([]*types.Header, len(blocks))
+	seals := make([]bool, len(blocks))
+	for i, block := range blocks {
+		headers[i] = block.Header()
+		seals[i] = true
+	}
+	tests := []struct {
+		fail uint64 // Block the second engine fails to verify
+		err  int    // Index of the header expected to fail, -1 for none
+	}{
+		{fail: 2, err: -1}, // Sealed by the first engine, not failing
+		{fail: 4, err: 3},  // First header of the second engine
+		{fail: 6, err: 5},
+	}
+	for i, tt := range tests {
+		chain, _ := core.NewBlockChain(db, nil, params.TestChainConfig, ethash.NewFaker(), vm.Config{}, nil)
+		engine := New([]consensus.Engine{ethash.NewFaker(), ethash.NewFakeFailer(tt.fail)}, []uint64{0, switchBlock})
+
+		_, results := engine.VerifyHeaders(chain, headers, seals)
+		for j := range headers {
+			err := <-results
+			if j == tt.err && err == nil {
+				t.Errorf("test %d: header %d: verification succeeded, want failure", i, j)
+			}
+			if j != tt.err && err != nil {
+				t.Errorf("test %d: header %d: verification failed: %v", i, j, err)
+			}
+		}
+		chain.Stop()
+	}
+}
+
+// Tests that every engine is handed the chain config of its own consensus.
+func TestChainReaderConfig(t *testing.T) {
+	config := *params.TestChainConfig
+	config.ConsensusForks = []*params.ConsensusFork{
+		{Block: big.NewInt(10), Clique: &params.CliqueConfig{Period: 1, Epoch: 30000}},
+	}
+	db := rawdb.NewMemoryDatabase()
+	(&core.Genesis{Config: &config}).MustCommit(db)
+	chain, _ := core.NewBlockChain(db, nil, &config, ethash.NewFaker(), vm.Config{}, nil)
+	defer chain.Stop()
+
+	engine := New([]consensus.Engine{ethash.NewFaker(), ethash.NewFaker()}, []uint64{0, 10}).(*Engine)
+	if cfg := engine.reader(chain, 0, nil).Config(); cfg.Ethash == nil || cfg.Clique != nil {
+		t.Errorf("first engine: have ethash %v clique %v, want ethash", cfg.Ethash, cfg.Clique)
+	}
+	if cfg := engine.reader(chain, 1, nil).Config(); cfg.Ethash != nil || cfg.Clique == nil {
+		t.Errorf("second engine: have ethash %v clique %v, want clique", cfg.Ethash, cfg.Clique)
+	}
+	for number, want := range map[uint64]int{0: 0, 9: 0, 10: 1, 100: 1} {
+		if have := engine.index(number); have != want {
+			t.Errorf("block %d: engine index mismatch: have %d, want %d", number, have, want)
+		}
+	}
+}
@@ -2,8 +2,6 @@ package backend
 
 import (
 	"errors"
-
-	"github.com/coreos/etcd/pkg/types"
 )
 
 type RaftNodeInfo struct {
@@ -114,7 +112,11 @@ func (s *PublicRaftAPI) Cluster() ([]ClusterInfo, error) {
 				role = "verifier"
 			}
 		}
-		clustInfo[i] = ClusterInfo{*a, role, s.checkIfNodeIsActive(a.RaftId)}
+		clustInfo[i] = ClusterInfo{Address: *a, Role: role, NodeActive: s.checkIfNodeIsActive(a.RaftId)}
+		if a.RaftId == s.raftService.raftProtocolManager.raftId {
+			strategy := s.raftService.config
+			clustInfo[i].MintingStrategy = &strategy
+		}
 	}
 	return clustInfo, nil
 }
@@ -125,7 +127,7 @@ func (s *PublicRaftAPI) checkIfNodeIsActive(raftId uint16) bool {
 	if raftId == s.raftService.raftProtocolManager.raftId {
 		return true
 	}
-	activeSince := s.raftService.raftProtocolManager.transport.ActiveSince(types.ID(raftId))
+	activeSince := s.raftService.raftProtocolManager.transport.ActiveSince(raftId)
 	return !activeSince.IsZero()
 }
 
@@ -17,6 +17,7 @@ import (
 	"github.com/simplechain-org/go-simplechain/node"
 	"github.com/simplechain-org/go-simplechain/p2p"
 	"github.com/simplechain-org/go-simplechain/p2p/enode"
+	"github.com/simplechain-org/go-simplechain/params"
 	"github.com/simplechain-org/go-simplechain/rpc"
 	"github.com/simplechain-org/go-simplechain/sub"
 )
@@ -36,9 +37,11 @@ type RaftService struct {
 	eventMux *event.TypeMux
 	minter   *miner.Miner
 	nodeKey  *ecdsa.PrivateKey
+
+	config params.RaftConfig // Minting strategy of the local minter
 }
 
-func New(ctx *node.ServiceContext, raftId, raftPort uint16, joinExisting bool, e *sub.Ethereum, startPeers []*enode.Node, datadir string, blockTime time.Duration, useDns bool) (*RaftService, error) {
+func New(ctx *node.ServiceContext, raftId, raftPort uint16, joinExisting bool, e *sub.Ethereum, startPeers []*enode.Node, datadir string, config params.RaftConfig, useDns bool, useP2P bool) (*RaftService, error) {
 	service := &RaftService{
 		eventMux:       ctx.EventMux,
 		chainDb:        e.ChainDb(),
@@ -48,6 +51,7 @@ func New(ctx *node.ServiceContext, raftId, raftPort uint16, joinExisting bool, e
 		downloader:     e.Downloader(),
 		startPeers:     startPeers,
 		nodeKey:        ctx.NodeKey(),
+		config:         config,
 	}
 
 	engine, ok := e.Engine().(*raft.Raft)
@@ -58,7 +62,8 @@ func New(ctx *node.ServiceContext, raftId, raftPort uint16, joinExisting bool, e
 
 	minerConfig := &e.Config().Miner
 	// Reuse Recommit
-	minerConfig.Recommit = blockTime
+	minerConfig.Recommit = time.Duration(config.BlockTime) * time.Millisecond
+	minerConfig.Raft = config
 
 	// Configure the local mining address
 	eb, _ := e.Etherbase()
@@ -67,7 +72,7 @@ func New(ctx *node.ServiceContext, raftId, raftPort uint16, joinExisting bool, e
 	service.minter.SetEtherbase(eb)
 
 	var err error
-	if service.raftProtocolManager, err = NewProtocolManager(raftId, raftPort, service.blockchain, service.eventMux, startPeers, joinExisting, datadir, service.minter, service.downloader, useDns); err != nil {
+	if service.raftProtocolManager, err = NewProtocolManager(raftId, raftPort, service.blockchain, service.eventMux, startPeers, joinExisting, datadir, service.minter, service.downloader, useDns, useP2P); err != nil {
 		return nil, err
 	}
 
@@ -84,7 +89,9 @@ func (service *RaftService) TxPool() *core.TxPool              { return service.
 
 // node.Service interface methods:
 
-func (service *RaftService) Protocols() []p2p.Protocol { return []p2p.Protocol{} }
+func (service *RaftService) Protocols() []p2p.Protocol {
+	return service.raftProtocolManager.transport.Protocols()
+}
 func (service *RaftService) APIs() []rpc.API {
 	return []rpc.API{
 		{
@@ -4,11 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"net"
-	"net/http"
-	"net/url"
 	"os"
-	"strconv"
 	"sync"
 	"time"
 
@@ -24,12 +20,9 @@ import (
 	"github.com/simplechain-org/go-simplechain/p2p/enr"
 	"github.com/simplechain-org/go-simplechain/rlp"
 
-	"github.com/coreos/etcd/etcdserver/stats"
 	"github.com/coreos/etcd/pkg/fileutil"
-	raftTypes "github.com/coreos/etcd/pkg/types"
 	etcdRaft "github.com/coreos/etcd/raft"
 	"github.com/coreos/etcd/raft/raftpb"
-	"github.com/coreos/etcd/rafthttp"
 	"github.com/coreos/etcd/snap"
 	"github.com/coreos/etcd/wal"
 	mapset "github.com/deckarep/golang-set"
@@ -61,6 +54,7 @@ type ProtocolManager struct {
 	// P2P transport
 	p2pServer *p2p.Server // Initialized in start()
 	useDns    bool
+	useP2P    bool // Whether raft messages are carried over devp2p instead of the raft port
 
 	// Blockchain services
 	blockchain *core.BlockChain
@@ -77,9 +71,7 @@ type ProtocolManager struct {
 
 	// Raft transport
 	unsafeRawNode etcdRaft.Node
-	transport     *rafthttp.Transport
-	httpstopc     chan struct{}
-	httpdonec     chan struct{}
+	transport     transport
 
 	// Raft snapshotting
 	snapshotter *snap.Snapshotter
@@ -101,7 +93,7 @@ var errNoLeaderElected = errors.New("no leader is currently elected")
 // Public interface
 //
 
-func NewProtocolManager(raftId uint16, raftPort uint16, blockchain *core.BlockChain, mux *event.TypeMux, bootstrapNodes []*enode.Node, joinExisting bool, datadir string, minter *miner.Miner, downloader *downloader.Downloader, useDns bool) (*ProtocolManager, error) {
+func NewProtocolManager(raftId uint16, raftPort uint16, blockchain *core.BlockChain, mux *event.TypeMux, bootstrapNodes []*enode.Node, joinExisting bool, datadir string, minter *miner.Miner, downloader *downloader.Downloader, useDns bool, useP2P bool) (*ProtocolManager, error) {
 	waldir := fmt.Sprintf("%s/raft-wal", datadir)
 	snapdir := fmt.Sprintf("%s/raft-snap", datadir)
 	raftDbLoc := fmt.Sprintf("%s/raft-state", datadir)
@@ -116,8 +108,6 @@ func NewProtocolManager(raftId uint16, raftPort uint16, blockchain *core.BlockCh
 		eventMux:            mux,
 		blockProposalC:      make(chan *types.Block, 10),
 		confChangeProposalC: make(chan raftpb.ConfChange),
-		httpstopc:           make(chan struct{}),
-		httpdonec:           make(chan struct{}),
 		waldir:              waldir,
 		snapdir:             snapdir,
 		snapshotter:         snap.New(snapdir),
@@ -128,6 +118,16 @@ func NewProtocolManager(raftId uint16, raftPort uint16, blockchain *core.BlockCh
 		minter:              minter,
 		downloader:          downloader,
 		useDns:              useDns,
+		useP2P:              useP2P,
+	}
+	if useP2P {
+		var bootstrap []*enode.Node
+		if joinExisting {
+			bootstrap = bootstrapNodes
+		}
+		manager.transport = newP2PTransport(raftId, manager, bootstrap)
+	} else {
+		manager.transport = newHttpTransport(raftId, raftPort, manager)
 	}
 
 	if db, err := openRaftDb(raftDbLoc); err != nil {
@@ -168,12 +168,7 @@ func (pm *ProtocolManager) Stop() {
 
 	pm.minedBlockSub.Unsubscribe()
 
-	if pm.transport != nil {
-		pm.transport.Stop()
-	}
-
-	close(pm.httpstopc)
-	<-pm.httpdonec
+	pm.transport.Stop()
 	close(pm.quitSync)
 
 	if pm.unsafeRawNode != nil {
@@ -302,7 +297,7 @@ func (pm *ProtocolManager) isNodeAlreadyInCluster(node *enode.Node) error {
 		if peerNode.IP().Equal(node.IP()) {
 			if peerNode.TCP() == node.TCP() {
 				return fmt.Errorf("existing node %v with raft ID %v is already using eth p2p at %v:%v", peerNode.ID(), peerRaftId, node.IP(), node.TCP())
-			} else if peer.Address.RaftPort == enr.RaftPort(node.RaftPort()) {
+			} else if !pm.useP2P && peer.Address.RaftPort == enr.RaftPort(node.RaftPort()) {
 				return fmt.Errorf("existing node %v with raft ID %v is already using raft at %v:%v", peerNode.ID(), peerRaftId, node.IP(), node.RaftPort())
 			}
 		}
@@ -315,6 +310,17 @@ func (pm *ProtocolManager) peerExist(node *enode.Node) bool {
 	pm.mu.RLock()
 	defer pm.mu.RUnlock()
 
+	// Over the p2p transport a joining node learns its fellow members from the
+	// log or snapshot the leader sends, so until then it lets in the existing
+	// members listed in its static nodes.
+	if pm.useP2P && pm.joinExisting && len(pm.peers) == 0 {
+		for _, n := range pm.bootstrapNodes {
+			if node.ID() == n.ID() {
+				return true
+			}
+		}
+		return false
+	}
 	for _, p := range pm.peers {
 		if node.ID() == p.P2pNode.ID() {
 			return true
@@ -342,7 +348,7 @@ func (pm *ProtocolManager) ProposeNewPeer(enodeId string, isLearner bool) (uint1
 		}
 	}
 
-	if !node.HasRaftPort() {
+	if !pm.useP2P && !node.HasRaftPort() {
 		return 0, fmt.Errorf("enodeId is missing raftport querystring parameter: %v", enodeId)
 	}
 
@@ -448,17 +454,9 @@ func (pm *ProtocolManager) startRaft() {
 	walExisted := wal.Exist(pm.waldir)
 	lastAppliedIndex := pm.loadAppliedIndex()
 
-	id := raftTypes.ID(pm.raftId).String()
-	ss := stats.NewServerStats(id, id)
-	pm.transport = &rafthttp.Transport{
-		ID:          raftTypes.ID(pm.raftId),
-		ClusterID:   0x1000,
-		Raft:        pm,
-		ServerStats: ss,
-		LeaderStats: stats.NewLeaderStats(strconv.Itoa(int(pm.raftId))),
-		ErrorC:      make(chan error),
+	if err := pm.transport.Start(); err != nil {
+		raft.Fatalf("Failed to start raft transport (%v)", err)
 	}
-	pm.transport.Start()
 
 	// We load the snapshot to connect to prev peers before replaying the WAL,
 	// which typically goes further into the future than the snapshot.
@@ -583,7 +581,7 @@ func (pm *ProtocolManager) startRaft() {
 
 	log.Info("raft node started")
 
-	go pm.serveRaft()
+	pm.transport.Serve()
 	go pm.serveLocalProposals()
 	go pm.eventLoop()
 	go pm.handleRoleChange(pm.rawNode().RoleChan().Out())
@@ -594,36 +592,11 @@ func (pm *ProtocolManager) setLocalAddress(addr *Address) {
 	pm.address = addr
 	pm.mu.Unlock()
 
-	// By setting `URLs` on the raft transport, we advertise our URL (in an HTTP
-	// header) to any recipient. This is necessary for a newcomer to the cluster
-	// to be able to accept a snapshot from us to bootstrap them.
-	if urls, err := raftTypes.NewURLs([]string{pm.raftUrl(addr)}); err == nil {
-		pm.transport.URLs = urls
-	} else {
-		panic(fmt.Sprintf("error: could not create URL from local address: %v", addr))
+	if err := pm.transport.SetLocalAddress(addr); err != nil {
+		panic(fmt.Sprintf("error: %v", err))
 	}
 }
 
-func (pm *ProtocolManager) serveRaft() {
-	urlString := fmt.Sprintf("http://0.0.0.0:%d", pm.raftPort)
-	url, err := url.Parse(urlString)
-	if err != nil {
-		raft.Fatalf("Failed parsing URL (%v)", err)
-	}
-
-	listener, err := raft.NewStoppableListener(url.Host, pm.httpstopc)
-	if err != nil {
-		raft.Fatalf("Failed to listen rafthttp (%v)", err)
-	}
-	err = (&http.Server{Handler: pm.transport.Handler()}).Serve(listener)
-	select {
-	case <-pm.httpstopc:
-	default:
-		raft.Fatalf("Failed to serve rafthttp (%v)", err)
-	}
-	close(pm.httpdonec)
-}
-
 func (pm *ProtocolManager) isLearner(rid uint16) bool {
 	pm.mu.RLock()
 	defer pm.mu.RUnlock()
@@ -760,18 +733,6 @@ func (pm *ProtocolManager) entriesToApply(allEntries []raftpb.Entry) (entriesToA
 	return
 }
 
-func (pm *ProtocolManager) raftUrl(address *Address) string {
-	if parsedIp := net.ParseIP(address.Hostname); parsedIp != nil {
-		if ipv4 := parsedIp.To4(); ipv4 != nil {
-			//this is an IPv4 address
-			return fmt.Sprintf("http://%s:%d", ipv4, address.RaftPort)
-		}
-		//this is an IPv6 address
-		return fmt.Sprintf("http://[%s]:%d", parsedIp, address.RaftPort)
-	}
-	return fmt.Sprintf("http://%s:%d", address.Hostname, address.RaftPort)
-}
-
 func (pm *ProtocolManager) addPeer(address *Address) {
 	pm.mu.Lock()
 	defer pm.mu.Unlock()
@@ -790,13 +751,14 @@ func (pm *ProtocolManager) addPeer(address *Address) {
 	pm.p2pServer.AddPeer(p2pNode)
 
 	// Add raft transport connection:
-	pm.transport.AddPeer(raftTypes.ID(raftId), []string{pm.raftUrl(address)})
-	pm.peers[raftId] = &Peer{Address: address, P2pNode: p2pNode}
+	peer := &Peer{Address: address, P2pNode: p2pNode}
+	pm.transport.AddPeer(peer)
+	pm.peers[raftId] = peer
 }
 
 func (pm *ProtocolManager) disconnectFromPeer(raftId uint16, peer *Peer) {
 	pm.p2pServer.RemovePeer(peer.P2pNode)
-	pm.transport.RemovePeer(raftTypes.ID(raftId))
+	pm.transport.RemovePeer(raftId)
 }
 
 func (pm *ProtocolManager) removePeer(raftId uint16) {
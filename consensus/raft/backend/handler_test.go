@@ -54,7 +54,7 @@ func TestProtocolManager_whenAppliedIndexOutOfSync(t *testing.T) {
 	}
 	raftNodes := make([]*RaftService, count)
 	for i := 0; i < count; i++ {
-		if s, err := startRaftNode(uint16(i+1), ports[i], tmpWorkingDir, nodeKeys[i], peers); err != nil {
+		if s, err := startRaftNode(uint16(i+1), ports[i], tmpWorkingDir, nodeKeys[i], peers, false); err != nil {
 			t.Fatal(err)
 		} else {
 			raftNodes[i] = s
@@ -92,7 +92,7 @@ func TestProtocolManager_whenAppliedIndexOutOfSync(t *testing.T) {
 	//time.Sleep(3 * time.Second)
 	logger.Debug("restart the cluster")
 	for i := 0; i < count; i++ {
-		if s, err := startRaftNode(uint16(i+1), ports[i], tmpWorkingDir, nodeKeys[i], peers); err != nil {
+		if s, err := startRaftNode(uint16(i+1), ports[i], tmpWorkingDir, nodeKeys[i], peers, false); err != nil {
 			t.Fatal(err)
 		} else {
 			raftNodes[i] = s
@@ -139,6 +139,7 @@ func nextPort(t *testing.T) uint16 {
 	if err != nil {
 		t.Fatal(err)
 	}
+	defer listener.Close()
 	return uint16(listener.Addr().(*net.TCPAddr).Port)
 }
 
@@ -166,7 +167,7 @@ func prepareServiceContext(key *ecdsa.PrivateKey, am *accounts.Manager) (ctx *no
 	return
 }
 
-func startRaftNode(id, port uint16, tmpWorkingDir string, key *ecdsa.PrivateKey, nodes []*enode.Node) (*RaftService, error) {
+func startRaftNode(id, port uint16, tmpWorkingDir string, key *ecdsa.PrivateKey, nodes []*enode.Node, useP2P bool) (*RaftService, error) {
 	datadir := fmt.Sprintf("%s/node%d", tmpWorkingDir, id)
 
 	ks := keystore.NewKeyStore(datadir, keystore.StandardScryptN, keystore.StandardScryptP)
@@ -184,7 +185,7 @@ func startRaftNode(id, port uint16, tmpWorkingDir string, key *ecdsa.PrivateKey,
 		return nil, err
 	}
 
-	s, err := New(ctx, id, port, false, e, nodes, datadir, 100*time.Millisecond, false)
+	s, err := New(ctx, id, port, false, e, nodes, datadir, params.RaftConfig{BlockTime: 100, MinBatchTxs: 1}, false, useP2P)
 	if err != nil {
 		return nil, err
 	}
@@ -194,6 +195,13 @@ func startRaftNode(id, port uint16, tmpWorkingDir string, key *ecdsa.PrivateKey,
 			PrivateKey: key,
 		},
 	}
+	if useP2P {
+		// raft messages travel over devp2p, so the port is the p2p listener
+		srv.ListenAddr = fmt.Sprintf("127.0.0.1:%d", port)
+		srv.MaxPeers = len(nodes)
+		srv.NoDiscovery = true
+		srv.Protocols = s.Protocols()
+	}
 	if err := srv.Start(); err != nil {
 		return nil, fmt.Errorf("could not start: %v", err)
 	}
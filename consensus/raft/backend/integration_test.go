@@ -1,8 +1,13 @@
 package backend
 
 import (
+	"context"
+	"crypto/ecdsa"
 	"fmt"
+	"io/ioutil"
 	"math/big"
+	"net"
+	"os"
 	"strings"
 	"testing"
 	"time"
@@ -13,9 +18,12 @@ import (
 	"github.com/simplechain-org/go-simplechain/core/types"
 	"github.com/simplechain-org/go-simplechain/crypto"
 	"github.com/simplechain-org/go-simplechain/node"
+	"github.com/simplechain-org/go-simplechain/p2p"
 	"github.com/simplechain-org/go-simplechain/p2p/enode"
+	"github.com/simplechain-org/go-simplechain/p2p/enr"
 	"github.com/simplechain-org/go-simplechain/rlp"
 
+	etcdRaft "github.com/coreos/etcd/raft"
 	"github.com/coreos/etcd/raft/raftpb"
 	mapset "github.com/deckarep/golang-set"
 )
@@ -203,3 +211,221 @@ func newTestRaftService(t *testing.T, raftId uint16, nodes []uint64, learners []
 
 	return raftService
 }
+
+func TestProposeNewPeer_withoutRaftPortOverP2P(t *testing.T) {
+	url := strings.TrimSuffix(TEST_URL, "&raftport=50404")
+
+	raftService := newTestRaftService(t, 1, []uint64{1}, []uint64{})
+	if _, err := raftService.raftProtocolManager.ProposeNewPeer(url, false); err == nil || !strings.Contains(err.Error(), "missing raftport") {
+		t.Fatalf("expected missing raftport error over the http transport, got %v", err)
+	}
+
+	raftService.raftProtocolManager.useP2P = true
+	go func() {
+		if _, err := raftService.raftProtocolManager.ProposeNewPeer(url, false); err != nil {
+			t.Errorf("propose new peer failed %v\n", err)
+		}
+	}()
+	select {
+	case confChange := <-raftService.raftProtocolManager.confChangeProposalC:
+		if confChange.Type != raftpb.ConfChangeAddNode {
+			t.Errorf("expected ConfChangeAddNode but got %s", confChange.Type.String())
+		}
+		if address := BytesToAddress(confChange.Context); address.RaftPort != 0 {
+			t.Errorf("expected no raft port, got %d", address.RaftPort)
+		}
+	case <-time.After(time.Millisecond * 200):
+		t.Errorf("add peer conf change not received")
+	}
+}
+
+type testRaftNode struct {
+	msgs    chan raftpb.Message
+	removed map[uint64]bool
+}
+
+func newTestRaftNode(removed ...uint64) *testRaftNode {
+	node := &testRaftNode{msgs: make(chan raftpb.Message, 16), removed: make(map[uint64]bool)}
+	for _, id := range removed {
+		node.removed[id] = true
+	}
+	return node
+}
+
+func (n *testRaftNode) Process(ctx context.Context, m raftpb.Message) error {
+	n.msgs <- m
+	return nil
+}
+func (n *testRaftNode) IsIDRemoved(id uint64) bool                               { return n.removed[id] }
+func (n *testRaftNode) ReportUnreachable(id uint64)                              {}
+func (n *testRaftNode) ReportSnapshot(id uint64, status etcdRaft.SnapshotStatus) {}
+
+// testEnodeID returns the node identity the tests bind to a raft ID.
+func testEnodeID(raftId uint16) (id enode.ID) {
+	id[0] = byte(raftId)
+	return id
+}
+
+// addTestMember makes the transport expect the given raft ID on the node the
+// tests bind to it.
+func addTestMember(t *p2pTransport, raftId uint16) {
+	t.members[raftId] = testEnodeID(raftId)
+}
+
+// connectP2PTransports runs a raft session between two transports over an
+// in-memory devp2p pipe, returning the errors the sessions end with.
+func connectP2PTransports(t1, t2 *p2pTransport) (chan error, chan error) {
+	return connectP2PTransportsAs(t1, t2, testEnodeID(t1.raftId), testEnodeID(t2.raftId))
+}
+
+// connectP2PTransportsAs is like connectP2PTransports, with the node identities
+// of both ends given explicitly.
+func connectP2PTransportsAs(t1, t2 *p2pTransport, id1, id2 enode.ID) (chan error, chan error) {
+	rw1, rw2 := p2p.MsgPipe()
+	errc1, errc2 := make(chan error, 1), make(chan error, 1)
+	go func() { errc1 <- t1.handle(p2p.NewPeer(id2, "raft2", nil), rw1) }()
+	go func() { errc2 <- t2.handle(p2p.NewPeer(id1, "raft1", nil), rw2) }()
+	return errc1, errc2
+}
+
+// expectP2PSessionError waits for a raft session to end with the given error.
+func expectP2PSessionError(t *testing.T, errc chan error, want error) {
+	t.Helper()
+
+	select {
+	case err := <-errc:
+		if err == nil || !strings.Contains(err.Error(), want.Error()) {
+			t.Errorf("expected %v, got %v", want, err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("raft session not rejected, expected %v", want)
+	}
+}
+
+func TestP2PTransport_deliversRaftMessages(t *testing.T) {
+	node1, node2 := newTestRaftNode(), newTestRaftNode()
+	t1, t2 := newP2PTransport(1, node1, nil), newP2PTransport(2, node2, nil)
+	defer t1.Stop()
+	defer t2.Stop()
+	addTestMember(t1, 2)
+	addTestMember(t2, 1)
+
+	connectP2PTransports(t1, t2)
+	for t1.ActiveSince(2).IsZero() || t2.ActiveSince(1).IsZero() {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	sent := raftpb.Message{Type: raftpb.MsgHeartbeat, From: 1, To: 2, Term: 3, Commit: 7}
+	t1.Send([]raftpb.Message{sent})
+	select {
+	case m := <-node2.msgs:
+		if m.Type != sent.Type || m.From != sent.From || m.To != sent.To || m.Term != sent.Term || m.Commit != sent.Commit {
+			t.Errorf("raft message mismatch: have %v, want %v", m, sent)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("raft message not delivered")
+	}
+}
+
+func TestP2PTransport_rejectsRemovedPeer(t *testing.T) {
+	t1, t2 := newP2PTransport(1, newTestRaftNode(2), nil), newP2PTransport(2, newTestRaftNode(), nil)
+	defer t1.Stop()
+	defer t2.Stop()
+	addTestMember(t2, 1)
+
+	errc1, _ := connectP2PTransports(t1, t2)
+	expectP2PSessionError(t, errc1, errRaftIdRemoved)
+}
+
+func TestP2PTransport_rejectsNonMember(t *testing.T) {
+	t1, t2 := newP2PTransport(1, newTestRaftNode(), nil), newP2PTransport(2, newTestRaftNode(), nil)
+	defer t1.Stop()
+	defer t2.Stop()
+	addTestMember(t1, 3)
+	addTestMember(t2, 1)
+
+	errc1, _ := connectP2PTransports(t1, t2)
+	expectP2PSessionError(t, errc1, errRaftNotMember)
+}
+
+func TestP2PTransport_rejectsRaftIdOfAnotherNode(t *testing.T) {
+	t1, t2 := newP2PTransport(1, newTestRaftNode(), nil), newP2PTransport(2, newTestRaftNode(), nil)
+	defer t1.Stop()
+	defer t2.Stop()
+	addTestMember(t1, 2)
+	addTestMember(t2, 1)
+
+	// The remote end claims raft ID 2 from the key of an unrelated node.
+	errc1, _ := connectP2PTransportsAs(t1, t2, testEnodeID(1), testEnodeID(9))
+	expectP2PSessionError(t, errc1, errRaftIdConflict)
+}
+
+func TestP2PTransport_joiningNodeTrustsBootstrapNodes(t *testing.T) {
+	leader := enode.SignNull(new(enr.Record), testEnodeID(1))
+	stranger := enode.SignNull(new(enr.Record), testEnodeID(3))
+
+	// Before the membership is known, only the bootstrap nodes are let in.
+	joiner, t1 := newP2PTransport(2, newTestRaftNode(), []*enode.Node{leader}), newP2PTransport(1, newTestRaftNode(), nil)
+	defer joiner.Stop()
+	defer t1.Stop()
+	addTestMember(t1, 2)
+
+	_, errc1 := connectP2PTransports(t1, joiner)
+	for joiner.ActiveSince(1).IsZero() || t1.ActiveSince(2).IsZero() {
+		time.Sleep(10 * time.Millisecond)
+	}
+	t3 := newP2PTransport(3, newTestRaftNode(), nil)
+	defer t3.Stop()
+	addTestMember(t3, 2)
+
+	errc, _ := connectP2PTransportsAs(joiner, t3, testEnodeID(2), stranger.ID())
+	expectP2PSessionError(t, errc, errRaftNotMember)
+
+	// Once raft ID 1 turns out to belong to another node, the session that
+	// claimed it is dropped.
+	joiner.AddPeer(&Peer{Address: &Address{RaftId: 1}, P2pNode: stranger})
+	t1.Send([]raftpb.Message{{Type: raftpb.MsgHeartbeat, From: 1, To: 2}})
+	expectP2PSessionError(t, errc1, errRaftIdConflict)
+}
+
+func TestProtocolManager_electsMinterOverP2P(t *testing.T) {
+	tmpWorkingDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpWorkingDir)
+
+	count := 3
+	ports := make([]uint16, count)
+	nodeKeys := make([]*ecdsa.PrivateKey, count)
+	peers := make([]*enode.Node, count)
+	for i := 0; i < count; i++ {
+		ports[i] = nextPort(t)
+		nodeKeys[i] = mustNewNodeKey(t)
+		// no raft port: the raft transport shares the p2p listener
+		peers[i] = enode.NewV4Hostname(&(nodeKeys[i].PublicKey), net.IPv4(127, 0, 0, 1).String(), int(ports[i]), 0, 0)
+	}
+	raftNodes := make([]*RaftService, count)
+	for i := 0; i < count; i++ {
+		s, err := startRaftNode(uint16(i+1), ports[i], tmpWorkingDir, nodeKeys[i], peers, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		raftNodes[i] = s
+		defer s.Stop()
+	}
+
+	timeout := time.After(30 * time.Second)
+	for {
+		for i := 0; i < count; i++ {
+			if raftNodes[i].raftProtocolManager.NodeInfo().Role == "minter" {
+				return
+			}
+		}
+		select {
+		case <-timeout:
+			t.Fatalf("no minter elected over the p2p transport")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/simplechain-org/go-simplechain/p2p/enode"
 	"github.com/simplechain-org/go-simplechain/p2p/enr"
+	"github.com/simplechain-org/go-simplechain/params"
 	"github.com/simplechain-org/go-simplechain/rlp"
 )
 
@@ -30,6 +31,9 @@ type ClusterInfo struct {
 	Address
 	Role       string `json:"role"`
 	NodeActive bool   `json:"nodeActive"`
+
+	// Minting strategy, only known for the local node
+	MintingStrategy *params.RaftConfig `json:"mintingStrategy,omitempty"`
 }
 
 func NewAddress(raftId uint16, raftPort int, node *enode.Node, useDns bool) *Address {
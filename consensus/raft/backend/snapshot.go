@@ -18,6 +18,7 @@ import (
 	"github.com/simplechain-org/go-simplechain/p2p/enr"
 	"github.com/simplechain-org/go-simplechain/rlp"
 
+	"github.com/coreos/etcd/pkg/fileutil"
 	"github.com/coreos/etcd/raft/raftpb"
 	"github.com/coreos/etcd/snap"
 	"github.com/coreos/etcd/wal/walpb"
@@ -317,6 +318,24 @@ func (pm *ProtocolManager) readRaftSnapshot() *raftpb.Snapshot {
 	return snapshot
 }
 
+// ReadSnapshotHead returns the hash of the head block recorded in the latest raft
+// snapshot of the given data directory, or the zero hash if there is none. The
+// state of this block is needed to recover the node from the snapshot.
+func ReadSnapshotHead(datadir string) (common.Hash, error) {
+	snapdir := fmt.Sprintf("%s/raft-snap", datadir)
+	if !fileutil.Exist(snapdir) {
+		return common.Hash{}, nil
+	}
+	raftSnapshot, err := snap.New(snapdir).Load()
+	if err == snap.ErrNoSnapshot {
+		return common.Hash{}, nil
+	}
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return bytesToSnapshot(raftSnapshot.Data).HeadBlockHash, nil
+}
+
 func (pm *ProtocolManager) applyRaftSnapshot(raftSnapshot raftpb.Snapshot) {
 	log.Info("applying snapshot to raft storage")
 	if err := pm.raftStorage.ApplySnapshot(raftSnapshot); err != nil {
@@ -0,0 +1,56 @@
+package backend
+
+import (
+	"context"
+	"time"
+
+	"github.com/simplechain-org/go-simplechain/p2p"
+
+	etcdRaft "github.com/coreos/etcd/raft"
+	"github.com/coreos/etcd/raft/raftpb"
+)
+
+// The cluster ID every raft transport announces to its peers.
+const raftClusterId = 0x1000
+
+// raftNode is the part of the ProtocolManager a transport hands inbound raft
+// traffic and delivery reports to. It mirrors rafthttp.Raft.
+type raftNode interface {
+	Process(ctx context.Context, m raftpb.Message) error
+	IsIDRemoved(id uint64) bool
+	ReportUnreachable(id uint64)
+	ReportSnapshot(id uint64, status etcdRaft.SnapshotStatus)
+}
+
+// transport carries raft messages between the members of the cluster.
+type transport interface {
+	// Start prepares the transport for delivering messages to peers.
+	Start() error
+
+	// Serve begins accepting inbound raft messages, once the local raft node
+	// and its address are set up.
+	Serve()
+
+	// Stop closes all peer connections and stops serving.
+	Stop()
+
+	// SetLocalAddress advertises the local node to the rest of the cluster.
+	SetLocalAddress(address *Address) error
+
+	// AddPeer starts delivering messages to the given cluster member.
+	AddPeer(peer *Peer)
+
+	// RemovePeer stops delivering messages to the given cluster member.
+	RemovePeer(raftId uint16)
+
+	// Send delivers the messages to the peers named in their To field. Messages
+	// for unknown peers are dropped.
+	Send(msgs []raftpb.Message)
+
+	// ActiveSince returns the time the connection to the given peer became
+	// active, or the zero time if it is not connected.
+	ActiveSince(raftId uint16) time.Time
+
+	// Protocols returns the devp2p protocols the transport runs on, if any.
+	Protocols() []p2p.Protocol
+}
@@ -0,0 +1,124 @@
+package backend
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/simplechain-org/go-simplechain/consensus/raft"
+	"github.com/simplechain-org/go-simplechain/p2p"
+
+	"github.com/coreos/etcd/etcdserver/stats"
+	raftTypes "github.com/coreos/etcd/pkg/types"
+	"github.com/coreos/etcd/raft/raftpb"
+	"github.com/coreos/etcd/rafthttp"
+)
+
+// httpTransport carries raft messages over etcd's rafthttp transport, served
+// on a dedicated raft port.
+type httpTransport struct {
+	raftId   uint16
+	raftPort uint16
+
+	transport *rafthttp.Transport
+	httpstopc chan struct{}
+	httpdonec chan struct{}
+}
+
+func newHttpTransport(raftId uint16, raftPort uint16, node raftNode) *httpTransport {
+	id := raftTypes.ID(raftId).String()
+	return &httpTransport{
+		raftId:   raftId,
+		raftPort: raftPort,
+		transport: &rafthttp.Transport{
+			ID:          raftTypes.ID(raftId),
+			ClusterID:   raftClusterId,
+			Raft:        node,
+			ServerStats: stats.NewServerStats(id, id),
+			LeaderStats: stats.NewLeaderStats(strconv.Itoa(int(raftId))),
+			ErrorC:      make(chan error),
+		},
+		httpstopc: make(chan struct{}),
+		httpdonec: make(chan struct{}),
+	}
+}
+
+func (t *httpTransport) Start() error {
+	return t.transport.Start()
+}
+
+func (t *httpTransport) Serve() {
+	go t.serveRaft()
+}
+
+func (t *httpTransport) Stop() {
+	t.transport.Stop()
+
+	close(t.httpstopc)
+	<-t.httpdonec
+}
+
+func (t *httpTransport) SetLocalAddress(addr *Address) error {
+	// By setting `URLs` on the raft transport, we advertise our URL (in an HTTP
+	// header) to any recipient. This is necessary for a newcomer to the cluster
+	// to be able to accept a snapshot from us to bootstrap them.
+	urls, err := raftTypes.NewURLs([]string{raftUrl(addr)})
+	if err != nil {
+		return fmt.Errorf("could not create URL from local address %v: %v", addr, err)
+	}
+	t.transport.URLs = urls
+	return nil
+}
+
+func (t *httpTransport) AddPeer(peer *Peer) {
+	t.transport.AddPeer(raftTypes.ID(peer.Address.RaftId), []string{raftUrl(peer.Address)})
+}
+
+func (t *httpTransport) RemovePeer(raftId uint16) {
+	t.transport.RemovePeer(raftTypes.ID(raftId))
+}
+
+func (t *httpTransport) Send(msgs []raftpb.Message) {
+	t.transport.Send(msgs)
+}
+
+func (t *httpTransport) ActiveSince(raftId uint16) time.Time {
+	return t.transport.ActiveSince(raftTypes.ID(raftId))
+}
+
+func (t *httpTransport) Protocols() []p2p.Protocol { return nil }
+
+func (t *httpTransport) serveRaft() {
+	urlString := fmt.Sprintf("http://0.0.0.0:%d", t.raftPort)
+	url, err := url.Parse(urlString)
+	if err != nil {
+		raft.Fatalf("Failed parsing URL (%v)", err)
+	}
+
+	listener, err := raft.NewStoppableListener(url.Host, t.httpstopc)
+	if err != nil {
+		raft.Fatalf("Failed to listen rafthttp (%v)", err)
+	}
+	err = (&http.Server{Handler: t.transport.Handler()}).Serve(listener)
+	select {
+	case <-t.httpstopc:
+	default:
+		raft.Fatalf("Failed to serve rafthttp (%v)", err)
+	}
+	close(t.httpdonec)
+}
+
+func raftUrl(address *Address) string {
+	if parsedIp := net.ParseIP(address.Hostname); parsedIp != nil {
+		if ipv4 := parsedIp.To4(); ipv4 != nil {
+			//this is an IPv4 address
+			return fmt.Sprintf("http://%s:%d", ipv4, address.RaftPort)
+		}
+		//this is an IPv6 address
+		return fmt.Sprintf("http://[%s]:%d", parsedIp, address.RaftPort)
+	}
+	return fmt.Sprintf("http://%s:%d", address.Hostname, address.RaftPort)
+}
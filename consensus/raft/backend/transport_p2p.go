@@ -0,0 +1,384 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/simplechain-org/go-simplechain/log"
+	"github.com/simplechain-org/go-simplechain/p2p"
+	"github.com/simplechain-org/go-simplechain/p2p/enode"
+
+	etcdRaft "github.com/coreos/etcd/raft"
+	"github.com/coreos/etcd/raft/raftpb"
+)
+
+const (
+	raftProtocolName    = "raft"
+	raftProtocolVersion = 1
+	raftProtocolLength  = 2                // Number of implemented message codes
+	raftMaxMsgSize      = 10 * 1024 * 1024 // Maximum cap on the size of a raft message
+
+	raftHandshakeTimeout = 5 * time.Second
+	raftPeerQueueSize    = 4096 // Outbound raft messages buffered per peer, as rafthttp's stream
+)
+
+// raft protocol message codes
+const (
+	raftStatusMsg  = 0x00
+	raftMessageMsg = 0x01
+)
+
+var (
+	errRaftClusterMismatch = errors.New("raft cluster id mismatch")
+	errRaftIdConflict      = errors.New("raft id is held by another node")
+	errRaftIdRemoved       = errors.New("raft id has been removed from the cluster")
+	errRaftNotMember       = errors.New("raft id is not a cluster member")
+	errRaftPeerConnected   = errors.New("raft peer is already connected")
+	errRaftMsgTooLarge     = errors.New("raft message too large")
+)
+
+// raftStatusData is the network packet exchanged when a raft session starts
+// on a devp2p connection.
+type raftStatusData struct {
+	ClusterId uint64
+	RaftId    uint16
+}
+
+// p2pTransport carries raft messages as a devp2p sub-protocol, reusing the node
+// identity and the encrypted connections of the p2p server instead of serving
+// a separate raft port.
+type p2pTransport struct {
+	raftId uint16
+	node   raftNode
+
+	mu        sync.RWMutex
+	members   map[uint16]enode.ID   // Expected node identity of every known cluster member
+	peers     map[uint16]*raftPeer  // Members with a live raft session
+	bootstrap map[enode.ID]struct{} // Nodes trusted to introduce a joining node to the cluster
+
+	quit chan struct{}
+}
+
+// raftPeer is a live raft session with a cluster member.
+type raftPeer struct {
+	*p2p.Peer
+	raftId uint16
+	rw     p2p.MsgReadWriter
+	since  time.Time
+
+	queue chan raftpb.Message // Outbound messages waiting to be written
+	term  chan struct{}       // Termination channel to stop the writer
+}
+
+// newP2PTransport creates a devp2p raft transport. The bootstrap nodes are let
+// in under any raft ID as long as no cluster member is known, so that a node
+// joining an existing cluster can hear from the leader before it learns the
+// membership from the log or snapshot the leader sends it.
+func newP2PTransport(raftId uint16, node raftNode, bootstrap []*enode.Node) *p2pTransport {
+	t := &p2pTransport{
+		raftId:    raftId,
+		node:      node,
+		members:   make(map[uint16]enode.ID),
+		peers:     make(map[uint16]*raftPeer),
+		bootstrap: make(map[enode.ID]struct{}),
+		quit:      make(chan struct{}),
+	}
+	for _, n := range bootstrap {
+		t.bootstrap[n.ID()] = struct{}{}
+	}
+	return t
+}
+
+func (t *p2pTransport) Start() error { return nil }
+
+// Serve is a no-op: inbound sessions are run by the p2p server.
+func (t *p2pTransport) Serve() {}
+
+func (t *p2pTransport) Stop() {
+	close(t.quit)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, peer := range t.peers {
+		peer.Disconnect(p2p.DiscQuitting)
+	}
+}
+
+// SetLocalAddress is a no-op: peers learn our identity from the devp2p
+// handshake.
+func (t *p2pTransport) SetLocalAddress(address *Address) error { return nil }
+
+func (t *p2pTransport) AddPeer(peer *Peer) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	raftId, id := peer.Address.RaftId, peer.P2pNode.ID()
+	t.members[raftId] = id
+
+	// A session let in before the membership was known may have claimed the
+	// raft ID of another node.
+	if live := t.peers[raftId]; live != nil && live.ID() != id {
+		live.Disconnect(p2p.DiscUnexpectedIdentity)
+	}
+}
+
+func (t *p2pTransport) RemovePeer(raftId uint16) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.members, raftId)
+	if peer := t.peers[raftId]; peer != nil {
+		peer.Disconnect(p2p.DiscRequested)
+	}
+}
+
+func (t *p2pTransport) Send(msgs []raftpb.Message) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	for _, m := range msgs {
+		if m.To == 0 {
+			continue
+		}
+		peer := t.peers[uint16(m.To)]
+		if peer == nil {
+			log.Debug("ignored message send request; unknown remote raft peer", "type", m.Type, "to", m.To)
+			if m.Type == raftpb.MsgSnap {
+				t.node.ReportSnapshot(m.To, etcdRaft.SnapshotFailure)
+			}
+			continue
+		}
+		select {
+		case peer.queue <- m:
+		default:
+			// Like rafthttp, drop the message instead of blocking the raft loop
+			// and let raft retry once the peer catches up.
+			log.Debug("dropped raft message, peer queue is full", "type", m.Type, "to", m.To)
+			t.node.ReportUnreachable(m.To)
+			if m.Type == raftpb.MsgSnap {
+				t.node.ReportSnapshot(m.To, etcdRaft.SnapshotFailure)
+			}
+		}
+	}
+}
+
+func (t *p2pTransport) ActiveSince(raftId uint16) time.Time {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if peer := t.peers[raftId]; peer != nil {
+		return peer.since
+	}
+	return time.Time{}
+}
+
+func (t *p2pTransport) Protocols() []p2p.Protocol {
+	return []p2p.Protocol{{
+		Name:    raftProtocolName,
+		Version: raftProtocolVersion,
+		Length:  raftProtocolLength,
+		Run:     t.handle,
+	}}
+}
+
+// handle runs a raft session over a freshly connected devp2p peer until the
+// connection is torn down.
+func (t *p2pTransport) handle(p *p2p.Peer, rw p2p.MsgReadWriter) error {
+	status, err := t.handshake(p, rw)
+	if err != nil {
+		p.Log().Debug("raft handshake failed", "err", err)
+		return err
+	}
+	peer := &raftPeer{
+		Peer:   p,
+		raftId: status.RaftId,
+		rw:     rw,
+		since:  time.Now(),
+		queue:  make(chan raftpb.Message, raftPeerQueueSize),
+		term:   make(chan struct{}),
+	}
+	if err := t.register(peer); err != nil {
+		p.Log().Debug("raft peer registration failed", "raftId", peer.raftId, "err", err)
+		return err
+	}
+	defer t.unregister(peer)
+
+	go t.writeLoop(peer)
+
+	for {
+		if err := t.handleMsg(peer); err != nil {
+			p.Log().Debug("raft message handling failed", "raftId", peer.raftId, "err", err)
+			return err
+		}
+	}
+}
+
+// handshake exchanges the cluster and raft IDs of both ends of the connection.
+func (t *p2pTransport) handshake(p *p2p.Peer, rw p2p.MsgReadWriter) (*raftStatusData, error) {
+	errc := make(chan error, 2)
+	status := new(raftStatusData)
+
+	go func() {
+		errc <- p2p.Send(rw, raftStatusMsg, &raftStatusData{ClusterId: raftClusterId, RaftId: t.raftId})
+	}()
+	go func() {
+		errc <- readRaftStatus(rw, status)
+	}()
+	timeout := time.NewTimer(raftHandshakeTimeout)
+	defer timeout.Stop()
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-errc:
+			if err != nil {
+				return nil, err
+			}
+		case <-timeout.C:
+			return nil, p2p.DiscReadTimeout
+		}
+	}
+
+	if status.ClusterId != raftClusterId {
+		return nil, fmt.Errorf("%v: %d (!= %d)", errRaftClusterMismatch, status.ClusterId, raftClusterId)
+	}
+	if status.RaftId == t.raftId {
+		return nil, fmt.Errorf("%v: %d", errRaftIdConflict, status.RaftId)
+	}
+	if t.node.IsIDRemoved(uint64(status.RaftId)) {
+		return nil, fmt.Errorf("%v: %d", errRaftIdRemoved, status.RaftId)
+	}
+	return status, nil
+}
+
+func readRaftStatus(rw p2p.MsgReadWriter, status *raftStatusData) error {
+	msg, err := rw.ReadMsg()
+	if err != nil {
+		return err
+	}
+	defer msg.Discard()
+
+	if msg.Code != raftStatusMsg {
+		return fmt.Errorf("first msg has code %x (!= %x)", msg.Code, raftStatusMsg)
+	}
+	if msg.Size > raftMaxMsgSize {
+		return fmt.Errorf("%v: %v > %v", errRaftMsgTooLarge, msg.Size, raftMaxMsgSize)
+	}
+	return msg.Decode(status)
+}
+
+func (t *p2pTransport) register(peer *raftPeer) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// A member must come from the node it was added with. Until a joining node
+	// learns the cluster, only its bootstrap nodes are let in.
+	if id, ok := t.members[peer.raftId]; ok {
+		if id != peer.ID() {
+			return fmt.Errorf("%v: %d", errRaftIdConflict, peer.raftId)
+		}
+	} else if _, trusted := t.bootstrap[peer.ID()]; !trusted || len(t.members) > 0 {
+		return fmt.Errorf("%v: %d", errRaftNotMember, peer.raftId)
+	}
+	if _, ok := t.peers[peer.raftId]; ok {
+		return errRaftPeerConnected
+	}
+	t.peers[peer.raftId] = peer
+	return nil
+}
+
+func (t *p2pTransport) unregister(peer *raftPeer) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.peers[peer.raftId] == peer {
+		delete(t.peers, peer.raftId)
+	}
+	close(peer.term)
+}
+
+func (t *p2pTransport) handleMsg(peer *raftPeer) error {
+	msg, err := peer.rw.ReadMsg()
+	if err != nil {
+		return err
+	}
+	defer msg.Discard()
+
+	if msg.Size > raftMaxMsgSize {
+		return fmt.Errorf("%v: %v > %v", errRaftMsgTooLarge, msg.Size, raftMaxMsgSize)
+	}
+	switch msg.Code {
+	case raftMessageMsg:
+		var data []byte
+		if err := msg.Decode(&data); err != nil {
+			return fmt.Errorf("msg %v: %v", msg, err)
+		}
+		var m raftpb.Message
+		if err := m.Unmarshal(data); err != nil {
+			return fmt.Errorf("msg %v: %v", msg, err)
+		}
+		if m.From != uint64(peer.raftId) {
+			return fmt.Errorf("raft message from %d on the session of %d", m.From, peer.raftId)
+		}
+		if err := t.checkMember(peer); err != nil {
+			return err
+		}
+		if t.node.IsIDRemoved(m.From) {
+			return fmt.Errorf("%v: %d", errRaftIdRemoved, m.From)
+		}
+		if err := t.node.Process(context.TODO(), m); err != nil {
+			peer.Log().Warn("failed to process raft message", "type", m.Type, "err", err)
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("invalid raft message code %d", msg.Code)
+	}
+}
+
+// checkMember verifies that the raft ID of a session is still bound to the
+// node on the other end, as the membership may have changed since it started.
+func (t *p2pTransport) checkMember(peer *raftPeer) error {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	id, ok := t.members[peer.raftId]
+	switch {
+	case ok && id != peer.ID():
+		return fmt.Errorf("%v: %d", errRaftIdConflict, peer.raftId)
+	case !ok && len(t.members) > 0:
+		return fmt.Errorf("%v: %d", errRaftNotMember, peer.raftId)
+	}
+	return nil
+}
+
+// writeLoop writes the queued raft messages of a peer to its connection.
+func (t *p2pTransport) writeLoop(peer *raftPeer) {
+	for {
+		select {
+		case m := <-peer.queue:
+			data, err := m.Marshal()
+			if err == nil {
+				err = p2p.Send(peer.rw, raftMessageMsg, data)
+			}
+			if err != nil {
+				peer.Log().Debug("failed to send raft message", "type", m.Type, "err", err)
+				t.node.ReportUnreachable(m.To)
+				if m.Type == raftpb.MsgSnap {
+					t.node.ReportSnapshot(m.To, etcdRaft.SnapshotFailure)
+				}
+				continue
+			}
+			if m.Type == raftpb.MsgSnap {
+				t.node.ReportSnapshot(m.To, etcdRaft.SnapshotFinish)
+			}
+
+		case <-peer.term:
+			return
+		case <-t.quit:
+			return
+		}
+	}
+}
@@ -65,8 +65,15 @@ func (api *API) GetWork() ([2]string, error) {
 // It returns an indication if the work was accepted.
 // Note either an invalid solution, a stale work a non-existent work will return false.
 func (api *API) SubmitWork(nonce types.BlockNonce, hash common.Hash) bool {
+	accepted, _ := api.SubmitSolution(nonce, hash)
+	return accepted
+}
+
+// SubmitSolution is SubmitWork returning the reason of a rejection: unknown
+// work package, stale work package or proof-of-work below the difficulty.
+func (api *API) SubmitSolution(nonce types.BlockNonce, hash common.Hash) (bool, error) {
 	if api.powScrypt.config.PowMode != ModeNormal && api.powScrypt.config.PowMode != ModeTest {
-		return false
+		return false, errors.New("not supported")
 	}
 
 	var errc = make(chan error, 1)
@@ -81,11 +88,28 @@ func (api *API) SubmitWork(nonce types.BlockNonce, hash common.Hash) bool {
 		errc:      errc,
 	}:
 	case <-api.powScrypt.exitCh:
-		return false
+		return false, errScryptStopped
+	}
+
+	if err := <-errc; err != nil {
+		return false, err
 	}
+	return true, nil
+}
 
-	err := <-errc
-	return err == nil
+// GetWorkStats returns the counters of the solutions submitted by remote
+// miners, by outcome.
+func (api *API) GetWorkStats() (WorkStats, error) {
+	if api.powScrypt.config.PowMode != ModeNormal && api.powScrypt.config.PowMode != ModeTest {
+		return WorkStats{}, errors.New("not supported")
+	}
+	req := make(chan WorkStats, 1)
+	select {
+	case api.powScrypt.fetchStatsCh <- req:
+	case <-api.powScrypt.exitCh:
+		return WorkStats{}, errScryptStopped
+	}
+	return <-req, nil
 }
 
 // SubmitHashrate can be used for remote miners to submit their hash rate.
@@ -476,6 +476,13 @@ func accumulateRewards(config *params.ChainConfig, state *state.StateDB, header
 
 }
 
+// MinerReward returns the static reward the coinbase of a block at the given
+// height is credited with, without uncle inclusion rewards and fees.
+func MinerReward(blockNumber *big.Int) *big.Int {
+	reward := calculateFixedRewards(blockNumber)
+	return reward.Sub(reward, calculateFoundationRewards(blockNumber, reward))
+}
+
 func calculateFixedRewards(blockNumber *big.Int) *big.Int {
 	reward := new(big.Int).Set(BlockReward)
 	number := new(big.Int).Set(blockNumber)
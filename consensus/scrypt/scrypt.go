@@ -49,11 +49,13 @@ const (
 
 // Config are the configuration parameters of the scrypt.
 type Config struct {
-	PowMode Mode
+	PowMode     Mode
+	WorkHistory int // Number of recent work packages the remote sealer accepts solutions for
 }
 
 // sealTask wraps a seal block with relative result channel for remote sealer thread.
 type sealTask struct {
+	chain   consensus.ChainReader
 	block   *types.Block
 	results chan<- *types.Block
 }
@@ -94,11 +96,12 @@ type PowScrypt struct {
 	hashrate metrics.Meter // Meter tracking the average hashrate
 
 	// Remote sealer related fields
-	workCh       chan *sealTask   // Notification channel to push new work and relative result channel to remote sealer
-	fetchWorkCh  chan *sealWork   // Channel used for remote sealer to fetch mining work
-	submitWorkCh chan *mineResult // Channel used for remote sealer to submit their mining result
-	fetchRateCh  chan chan uint64 // Channel used to gather submitted hash rate for local or remote sealer.
-	submitRateCh chan *hashrate   // Channel used for remote sealer to submit their mining hashrate
+	workCh       chan *sealTask      // Notification channel to push new work and relative result channel to remote sealer
+	fetchWorkCh  chan *sealWork      // Channel used for remote sealer to fetch mining work
+	submitWorkCh chan *mineResult    // Channel used for remote sealer to submit their mining result
+	fetchRateCh  chan chan uint64    // Channel used to gather submitted hash rate for local or remote sealer.
+	submitRateCh chan *hashrate      // Channel used for remote sealer to submit their mining hashrate
+	fetchStatsCh chan chan WorkStats // Channel used to gather the counters of the submitted solutions
 
 	// The fields below are hooks for testing
 	fakeFail  uint64        // Block number which fails PoW check even in fake mode
@@ -123,6 +126,7 @@ func NewScrypt(config Config, notify []string, noverify bool) *PowScrypt {
 		submitWorkCh: make(chan *mineResult),
 		fetchRateCh:  make(chan chan uint64),
 		submitRateCh: make(chan *hashrate),
+		fetchStatsCh: make(chan chan WorkStats),
 		exitCh:       make(chan chan error),
 	}
 
@@ -143,6 +147,7 @@ func NewTester(notify []string, noverify bool) *PowScrypt {
 		submitWorkCh: make(chan *mineResult),
 		fetchRateCh:  make(chan chan uint64),
 		submitRateCh: make(chan *hashrate),
+		fetchStatsCh: make(chan chan WorkStats),
 		exitCh:       make(chan chan error),
 	}
 	go pow.remote(notify, noverify)
@@ -34,18 +34,63 @@ import (
 	"github.com/simplechain-org/go-simplechain/consensus"
 	"github.com/simplechain-org/go-simplechain/core/types"
 	"github.com/simplechain-org/go-simplechain/log"
+	"github.com/simplechain-org/go-simplechain/metrics"
 )
 
 const (
 	// staleThreshold is the maximum depth of the acceptable stale but valid scrypt solution.
 	staleThreshold = 7
+
+	// DefaultWorkHistory is the number of recent work packages the remote sealer
+	// accepts solutions for, if not configured.
+	DefaultWorkHistory = 16
 )
 
 var (
 	errNoMiningWork      = errors.New("no mining work available yet")
 	errInvalidSealResult = errors.New("invalid or stale proof-of-work solution")
+	errUnknownWork       = errors.New("unknown work package")
+	errStaleWork         = errors.New("stale work package, the chain moved on")
+	errLowDifficulty     = errors.New("proof-of-work below the block difficulty")
+	errResultDropped     = errors.New("solution not read by the miner")
+
+	remoteAcceptedMeter = metrics.NewRegisteredMeter("scrypt/remote/accepted", nil)
+	remoteStaleMeter    = metrics.NewRegisteredMeter("scrypt/remote/stale", nil)
+	remoteUnknownMeter  = metrics.NewRegisteredMeter("scrypt/remote/unknown", nil)
+	remoteInvalidMeter  = metrics.NewRegisteredMeter("scrypt/remote/invalid", nil)
+	remoteDroppedMeter  = metrics.NewRegisteredMeter("scrypt/remote/dropped", nil)
 )
 
+// WorkStats counts the solutions submitted to the remote sealer by outcome.
+type WorkStats struct {
+	Accepted hexutil.Uint64 `json:"accepted"` // Solutions handed to the miner
+	Stale    hexutil.Uint64 `json:"stale"`    // Solutions for packages the chain moved past
+	Unknown  hexutil.Uint64 `json:"unknown"`  // Solutions for packages never sent or already evicted
+	Invalid  hexutil.Uint64 `json:"invalid"`  // Solutions failing the proof-of-work check
+	Dropped  hexutil.Uint64 `json:"dropped"`  // Valid solutions the miner did not take
+}
+
+// count accounts the outcome of a submitted solution.
+func (s *WorkStats) count(err error) {
+	switch err {
+	case nil:
+		s.Accepted++
+		remoteAcceptedMeter.Mark(1)
+	case errStaleWork:
+		s.Stale++
+		remoteStaleMeter.Mark(1)
+	case errUnknownWork, errNoMiningWork:
+		s.Unknown++
+		remoteUnknownMeter.Mark(1)
+	case errResultDropped, errInvalidSealResult:
+		s.Dropped++
+		remoteDroppedMeter.Mark(1)
+	default:
+		s.Invalid++
+		remoteInvalidMeter.Mark(1)
+	}
+}
+
 // Seal implements consensus.Engine, attempting to find a nonce that satisfies
 // the block's difficulty requirements.
 func (powScrypt *PowScrypt) Seal(chain consensus.ChainReader, block *types.Block, results chan<- *types.Block, stop <-chan struct{}) error {
@@ -84,7 +129,7 @@ func (powScrypt *PowScrypt) Seal(chain consensus.ChainReader, block *types.Block
 
 	// Push new work to remote sealer
 	if powScrypt.workCh != nil {
-		powScrypt.workCh <- &sealTask{block: block, results: results}
+		powScrypt.workCh <- &sealTask{chain: chain, block: block, results: results}
 	}
 	var (
 		pend   sync.WaitGroup
@@ -182,10 +227,17 @@ search:
 
 // remote is a standalone goroutine to handle remote mining related stuff.
 func (powScrypt *PowScrypt) remote(notify []string, noverify bool) {
+	history := powScrypt.config.WorkHistory
+	if history <= 0 {
+		history = DefaultWorkHistory
+	}
 	var (
-		works = make(map[common.Hash]*types.Block)
-		rates = make(map[common.Hash]hashrate)
+		works     = make(map[common.Hash]*types.Block)
+		workOrder []common.Hash // Seal hashes of the works, oldest first
+		rates     = make(map[common.Hash]hashrate)
+		stats     WorkStats
 
+		chain        consensus.ChainReader
 		results      chan<- *types.Block
 		currentBlock *types.Block
 		currentWork  [2]string
@@ -242,23 +294,44 @@ func (powScrypt *PowScrypt) remote(notify []string, noverify bool) {
 		)
 		currentWork[1] = fmt.Sprintf("%x", block.Difficulty())
 
-		// Trace the seal work fetched by remote sealer.
+		// Trace the seal work fetched by remote sealer, keeping the last packages
+		// for late solutions.
 		currentBlock = block
+		if _, ok := works[hash]; !ok {
+			workOrder = append(workOrder, hash)
+		}
 		works[hash] = block
+		for len(workOrder) > history {
+			delete(works, workOrder[0])
+			workOrder = workOrder[1:]
+		}
 	}
-	// submitWork verifies the submitted pow solution, returning
-	// whether the solution was accepted or not (not can be both a bad pow as well as
-	// any other error, like no pending work or stale mining result).
-	submitWork := func(nonce types.BlockNonce, mixDigest common.Hash, sealhash common.Hash) bool {
+	// submitWork verifies the submitted pow solution, returning nil if it was
+	// accepted or the reason of the rejection: no such pending work, a stale
+	// package, a bad pow or a miner not taking the result.
+	submitWork := func(nonce types.BlockNonce, mixDigest common.Hash, sealhash common.Hash) error {
 		if currentBlock == nil {
 			log.Error("Pending work without block", "sealhash", sealhash)
-			return false
+			return errNoMiningWork
 		}
 		// Make sure the work submitted is present
 		block := works[sealhash]
 		if block == nil {
 			log.Warn("Work submitted but none pending", "sealhash", sealhash, "curnumber", currentBlock.NumberU64())
-			return false
+			return errUnknownWork
+		}
+		// Older packages are only worth sealing while they still extend the head,
+		// or, without a chain to check, within the stale threshold.
+		if block != currentBlock {
+			if chain != nil {
+				if head := chain.CurrentHeader(); head != nil && block.ParentHash() != head.Hash() {
+					log.Warn("Work submitted for a stale parent", "number", block.NumberU64(), "sealhash", sealhash, "head", head.Number)
+					return errStaleWork
+				}
+			} else if block.NumberU64()+staleThreshold <= currentBlock.NumberU64() {
+				log.Warn("Work submitted is too old", "number", block.NumberU64(), "sealhash", sealhash)
+				return errStaleWork
+			}
 		}
 		// Verify the correctness of submitted result.
 		header := block.Header()
@@ -269,33 +342,29 @@ func (powScrypt *PowScrypt) remote(notify []string, noverify bool) {
 		if !noverify {
 			if err := powScrypt.verifySeal(nil, header); err != nil {
 				log.Warn("Invalid proof-of-work submitted", "sealhash", sealhash, "elapsed", time.Since(start), "err", err)
-				return false
+				if err == errInvalidPoW {
+					return errLowDifficulty
+				}
+				return err
 			}
 		}
 		// Make sure the result channel is assigned.
 		if results == nil {
 			log.Warn("Scrypt result channel is empty, submitted mining result is rejected")
-			return false
+			return errInvalidSealResult
 		}
 		log.Trace("Verified correct proof-of-work", "sealhash", sealhash, "elapsed", time.Since(start))
 
 		// Solutions seems to be valid, return to the miner and notify acceptance.
 		solution := block.WithSeal(header)
-
-		// The submitted solution is within the scope of acceptance.
-		if solution.NumberU64()+staleThreshold > currentBlock.NumberU64() {
-			select {
-			case results <- solution:
-				log.Debug("Work submitted is acceptable", "number", solution.NumberU64(), "sealhash", sealhash, "hash", solution.Hash())
-				return true
-			default:
-				log.Warn("Sealing result is not read by miner", "mode", "remote", "sealhash", sealhash)
-				return false
-			}
+		select {
+		case results <- solution:
+			log.Debug("Work submitted is acceptable", "number", solution.NumberU64(), "sealhash", sealhash, "hash", solution.Hash())
+			return nil
+		default:
+			log.Warn("Sealing result is not read by miner", "mode", "remote", "sealhash", sealhash)
+			return errResultDropped
 		}
-		// The submitted block is too old to accept, drop it.
-		log.Warn("Work submitted is too old", "number", solution.NumberU64(), "sealhash", sealhash, "hash", solution.Hash())
-		return false
 	}
 
 	ticker := time.NewTicker(5 * time.Second)
@@ -307,6 +376,7 @@ func (powScrypt *PowScrypt) remote(notify []string, noverify bool) {
 			// Update current work with new received block.
 			// Note same work can be past twice, happens when changing CPU threads.
 			results = work.results
+			chain = work.chain
 
 			makeWork(work.block)
 
@@ -323,11 +393,13 @@ func (powScrypt *PowScrypt) remote(notify []string, noverify bool) {
 
 		case result := <-powScrypt.submitWorkCh:
 			// Verify submitted PoW solution based on maintained mining blocks.
-			if submitWork(result.nonce, result.mixDigest, result.hash) {
-				result.errc <- nil
-			} else {
-				result.errc <- errInvalidSealResult
-			}
+			err := submitWork(result.nonce, result.mixDigest, result.hash)
+			stats.count(err)
+			result.errc <- err
+
+		case req := <-powScrypt.fetchStatsCh:
+			// Return the counters of the submitted solutions.
+			req <- stats
 
 		case result := <-powScrypt.submitRateCh:
 			// Trace remote sealer's hash rate by submitted value.
@@ -352,11 +424,15 @@ func (powScrypt *PowScrypt) remote(notify []string, noverify bool) {
 			}
 			// Clear stale pending blocks
 			if currentBlock != nil {
-				for hash, block := range works {
-					if block.NumberU64()+staleThreshold <= currentBlock.NumberU64() {
+				kept := workOrder[:0]
+				for _, hash := range workOrder {
+					if works[hash].NumberU64()+staleThreshold <= currentBlock.NumberU64() {
 						delete(works, hash)
+					} else {
+						kept = append(kept, hash)
 					}
 				}
+				workOrder = kept
 			}
 
 		case errc := <-powScrypt.exitCh:
@@ -12,6 +12,7 @@ import (
 
 	"github.com/simplechain-org/go-simplechain/common"
 	"github.com/simplechain-org/go-simplechain/common/hexutil"
+	"github.com/simplechain-org/go-simplechain/consensus"
 	"github.com/simplechain-org/go-simplechain/core/types"
 )
 
@@ -211,3 +212,74 @@ func TestStaleSubmission(t *testing.T) {
 		}
 	}
 }
+
+// headChain is a chain reader serving only its current header.
+type headChain struct {
+	consensus.ChainReader
+	head *types.Header
+}
+
+func (c *headChain) CurrentHeader() *types.Header { return c.head }
+
+// Tests that late solutions are accepted while their parent is the head, and
+// that rejections carry their reason.
+func TestSubmitSolution(t *testing.T) {
+	scrypt := NewTester(nil, true)
+	defer scrypt.Close()
+	api := &API{scrypt}
+
+	var (
+		head    = &types.Header{Number: big.NewInt(4), Difficulty: big.NewInt(100)}
+		chain   = &headChain{head: head}
+		results = make(chan *types.Block, 16)
+		nonce   = types.BlockNonce{0x01}
+	)
+	older := &types.Header{ParentHash: head.Hash(), Number: big.NewInt(5), Difficulty: big.NewInt(100), Time: 1}
+	latest := &types.Header{ParentHash: head.Hash(), Number: big.NewInt(5), Difficulty: big.NewInt(100), Time: 2}
+	scrypt.Seal(chain, types.NewBlockWithHeader(older), results, nil)
+	scrypt.Seal(chain, types.NewBlockWithHeader(latest), results, nil)
+
+	// An older package on the same parent is still good
+	if ok, err := api.SubmitSolution(nonce, scrypt.SealHash(older)); !ok || err != nil {
+		t.Fatalf("late solution rejected: %v", err)
+	}
+	if block := <-results; block.Time() != 1 {
+		t.Errorf("sealed block mismatch: have time %d, want 1", block.Time())
+	}
+	// Once the head moves, it is stale even if recent
+	chain.head = &types.Header{ParentHash: head.Hash(), Number: big.NewInt(5), Difficulty: big.NewInt(100)}
+	if _, err := api.SubmitSolution(nonce, scrypt.SealHash(older)); err != errStaleWork {
+		t.Errorf("stale error mismatch: have %v, want %v", err, errStaleWork)
+	}
+	if _, err := api.SubmitSolution(nonce, common.Hash{0xff}); err != errUnknownWork {
+		t.Errorf("unknown error mismatch: have %v, want %v", err, errUnknownWork)
+	}
+	stats, err := api.GetWorkStats()
+	if err != nil {
+		t.Fatalf("failed to get work stats: %v", err)
+	}
+	if stats.Accepted != 1 || stats.Stale != 1 || stats.Unknown != 1 || stats.Invalid != 0 {
+		t.Errorf("work stats mismatch: %+v", stats)
+	}
+}
+
+// Tests that only the configured number of work packages is kept.
+func TestWorkHistory(t *testing.T) {
+	scrypt := NewTester(nil, true)
+	defer scrypt.Close()
+	api := &API{scrypt}
+
+	results := make(chan *types.Block, DefaultWorkHistory+1)
+	var headers []*types.Header
+	for i := 0; i <= DefaultWorkHistory; i++ {
+		header := &types.Header{Number: big.NewInt(1), Difficulty: big.NewInt(100), Time: uint64(i)}
+		headers = append(headers, header)
+		scrypt.Seal(nil, types.NewBlockWithHeader(header), results, nil)
+	}
+	if _, err := api.SubmitSolution(types.BlockNonce{}, scrypt.SealHash(headers[0])); err != errUnknownWork {
+		t.Errorf("evicted package error mismatch: have %v, want %v", err, errUnknownWork)
+	}
+	if ok, err := api.SubmitSolution(types.BlockNonce{}, scrypt.SealHash(headers[1])); !ok {
+		t.Errorf("oldest kept package rejected: %v", err)
+	}
+}
@@ -0,0 +1,206 @@
+// Copyright (c) 2019 Simplechain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package scrypt
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"math/rand"
+
+	"github.com/simplechain-org/go-simplechain/core/types"
+	"github.com/simplechain-org/go-simplechain/params"
+)
+
+// maxSimulatedInterval caps the simulated time a single block may take to be
+// found, protecting the simulator from schedules that starve the network.
+const maxSimulatedInterval = 7 * 24 * 3600
+
+var (
+	errNoSchedule      = errors.New("no hashrate schedule")
+	errNoHashrate      = errors.New("hashrate schedule reached zero")
+	errSimulationStall = errors.New("block interval exceeds simulation limit")
+)
+
+// HashrateSchedule describes the network hashrate (hashes per second) as a
+// function of the seconds elapsed since the start of the simulation.
+type HashrateSchedule interface {
+	Hashrate(elapsed float64) float64
+}
+
+// ConstantSchedule keeps the hashrate fixed for the whole simulation.
+type ConstantSchedule struct {
+	Rate float64
+}
+
+func (s ConstantSchedule) Hashrate(elapsed float64) float64 { return s.Rate }
+
+// StepSchedule switches from Base to Rate once At seconds have elapsed.
+type StepSchedule struct {
+	Base float64
+	Rate float64
+	At   float64
+}
+
+func (s StepSchedule) Hashrate(elapsed float64) float64 {
+	if elapsed < s.At {
+		return s.Base
+	}
+	return s.Rate
+}
+
+// SpikeSchedule raises the hashrate from Base to Peak for Duration seconds
+// starting at At, then falls back to Base.
+type SpikeSchedule struct {
+	Base     float64
+	Peak     float64
+	At       float64
+	Duration float64
+}
+
+func (s SpikeSchedule) Hashrate(elapsed float64) float64 {
+	if elapsed >= s.At && elapsed < s.At+s.Duration {
+		return s.Peak
+	}
+	return s.Base
+}
+
+// DecaySchedule holds the hashrate at Base until At, then decays it
+// exponentially towards Floor, halving the distance every HalfLife seconds.
+type DecaySchedule struct {
+	Base     float64
+	Floor    float64
+	At       float64
+	HalfLife float64
+}
+
+func (s DecaySchedule) Hashrate(elapsed float64) float64 {
+	if elapsed < s.At || s.HalfLife <= 0 {
+		return s.Base
+	}
+	return s.Floor + (s.Base-s.Floor)*math.Pow(0.5, (elapsed-s.At)/s.HalfLife)
+}
+
+// SimConfig is the set of parameters of a difficulty simulation.
+type SimConfig struct {
+	Config     *params.ChainConfig // Chain config passed to CalcDifficulty (mainnet if nil)
+	Difficulty *big.Int            // Difficulty of the starting block (genesis difficulty if nil)
+	Blocks     int                 // Number of blocks to produce
+	Schedule   HashrateSchedule    // Network hashrate over time
+	UncleRate  float64             // Probability that a block includes uncles
+	Seed       int64               // Seed of the block time sampler
+}
+
+// SimBlock is a single block produced by the simulator.
+type SimBlock struct {
+	Number     uint64   `json:"number"`
+	Time       uint64   `json:"time"`
+	Interval   uint64   `json:"interval"`
+	Difficulty *big.Int `json:"difficulty"`
+	Hashrate   float64  `json:"hashrate"`
+	Uncles     bool     `json:"uncles"`
+}
+
+// Simulate produces config.Blocks blocks on top of a starting block with the
+// configured difficulty, retargeting each one with CalcDifficulty. The work
+// needed to seal a block is drawn from an exponential distribution with a mean
+// of the block difficulty and is consumed second by second at the hashrate the
+// schedule yields, so hashrate changes in the middle of a block are honoured.
+func Simulate(config SimConfig) ([]SimBlock, error) {
+	if config.Schedule == nil {
+		return nil, errNoSchedule
+	}
+	chainConfig := config.Config
+	if chainConfig == nil {
+		chainConfig = params.MainnetChainConfig
+	}
+	difficulty := config.Difficulty
+	if difficulty == nil {
+		difficulty = params.GenesisDifficulty
+	}
+	rng := rand.New(rand.NewSource(config.Seed))
+
+	parent := &types.Header{
+		Number:     new(big.Int),
+		Difficulty: new(big.Int).Set(difficulty),
+		UncleHash:  types.EmptyUncleHash,
+	}
+	var (
+		blocks  = make([]SimBlock, 0, config.Blocks)
+		elapsed float64
+	)
+	for i := 0; i < config.Blocks; i++ {
+		// Draw the work of the next block under the parent's successor
+		// difficulty, which depends on the block time itself. Mining is
+		// simulated against the difficulty a block found right now would
+		// have, as a real miner refreshes its work on every new head.
+		work := rng.ExpFloat64()
+
+		// The miner sleeps rather than sealing too far in the future
+		if elapsed < float64(parent.Time) {
+			elapsed = float64(parent.Time)
+		}
+		start := elapsed
+		for {
+			rate := config.Schedule.Hashrate(elapsed)
+			if rate <= 0 || math.IsNaN(rate) {
+				return blocks, fmt.Errorf("%w at %.0fs", errNoHashrate, elapsed)
+			}
+			now := uint64(elapsed)
+			if now <= parent.Time {
+				now = parent.Time + 1
+			}
+			diff, _ := new(big.Float).SetInt(CalcDifficulty(chainConfig, now, parent)).Float64()
+
+			// Consume up to the next whole second at the current rate
+			step := math.Floor(elapsed) + 1 - elapsed
+			if done := rate * step / diff; done < work {
+				work -= done
+				elapsed += step
+			} else {
+				elapsed += work * diff / rate
+				break
+			}
+			if elapsed-start > maxSimulatedInterval {
+				return blocks, fmt.Errorf("%w at block %d", errSimulationStall, parent.Number.Uint64()+1)
+			}
+		}
+		header := &types.Header{
+			ParentHash: parent.Hash(),
+			Number:     new(big.Int).Add(parent.Number, big1),
+			Time:       uint64(elapsed),
+			UncleHash:  types.EmptyUncleHash,
+		}
+		if header.Time <= parent.Time {
+			header.Time = parent.Time + 1
+		}
+		header.Difficulty = CalcDifficulty(chainConfig, header.Time, parent)
+		if config.UncleRate > 0 && rng.Float64() < config.UncleRate {
+			header.UncleHash = types.CalcUncleHash([]*types.Header{parent})
+		}
+		blocks = append(blocks, SimBlock{
+			Number:     header.Number.Uint64(),
+			Time:       header.Time,
+			Interval:   header.Time - parent.Time,
+			Difficulty: new(big.Int).Set(header.Difficulty),
+			Hashrate:   config.Schedule.Hashrate(elapsed),
+			Uncles:     header.UncleHash != types.EmptyUncleHash,
+		})
+		parent = header
+	}
+	return blocks, nil
+}
@@ -0,0 +1,99 @@
+// Copyright (c) 2019 Simplechain
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program. If not, see <http://www.gnu.org/licenses/>.
+
+package scrypt
+
+import (
+	"errors"
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+// Tests that the simulator is deterministic for a given seed and that every
+// produced block carries the difficulty CalcDifficulty yields for it.
+func TestSimulateDeterministic(t *testing.T) {
+	config := SimConfig{
+		Difficulty: big.NewInt(1000000),
+		Blocks:     200,
+		Schedule:   ConstantSchedule{Rate: 100000},
+		UncleRate:  0.1,
+		Seed:       42,
+	}
+	first, err := Simulate(config)
+	if err != nil {
+		t.Fatalf("simulation failed: %v", err)
+	}
+	second, err := Simulate(config)
+	if err != nil {
+		t.Fatalf("simulation failed: %v", err)
+	}
+	if !reflect.DeepEqual(first, second) {
+		t.Fatalf("simulation not deterministic")
+	}
+	if len(first) != config.Blocks {
+		t.Fatalf("block count mismatch: have %d, want %d", len(first), config.Blocks)
+	}
+	for i, block := range first {
+		if block.Number != uint64(i+1) {
+			t.Fatalf("block %d: number mismatch: have %d", i, block.Number)
+		}
+		if block.Interval == 0 {
+			t.Fatalf("block %d: zero interval", block.Number)
+		}
+	}
+}
+
+// Tests that a sustained hashrate increase drives the difficulty up and a
+// sustained decrease drives it down.
+func TestSimulateStep(t *testing.T) {
+	base := SimConfig{
+		Difficulty: big.NewInt(1000000),
+		Blocks:     2000,
+		Schedule:   ConstantSchedule{Rate: 100000},
+		Seed:       1,
+	}
+	steady, err := Simulate(base)
+	if err != nil {
+		t.Fatalf("steady simulation failed: %v", err)
+	}
+	up := base
+	up.Schedule = StepSchedule{Base: 100000, Rate: 1000000, At: 0}
+	raised, err := Simulate(up)
+	if err != nil {
+		t.Fatalf("step up simulation failed: %v", err)
+	}
+	down := base
+	down.Schedule = StepSchedule{Base: 100000, Rate: 10000, At: 0}
+	lowered, err := Simulate(down)
+	if err != nil {
+		t.Fatalf("step down simulation failed: %v", err)
+	}
+	last := base.Blocks - 1
+	if raised[last].Difficulty.Cmp(steady[last].Difficulty) <= 0 {
+		t.Errorf("difficulty not raised: have %v, steady %v", raised[last].Difficulty, steady[last].Difficulty)
+	}
+	if lowered[last].Difficulty.Cmp(steady[last].Difficulty) >= 0 {
+		t.Errorf("difficulty not lowered: have %v, steady %v", lowered[last].Difficulty, steady[last].Difficulty)
+	}
+}
+
+// Tests that schedules starving the network are reported instead of looping.
+func TestSimulateNoHashrate(t *testing.T) {
+	_, err := Simulate(SimConfig{Blocks: 1000, Schedule: StepSchedule{Base: 100000, Rate: 0, At: 60}})
+	if !errors.Is(err, errNoHashrate) {
+		t.Fatalf("error mismatch: have %v, want %v", err, errNoHashrate)
+	}
+}
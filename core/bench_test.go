@@ -70,6 +70,12 @@ func BenchmarkInsertChain_ring1000_memdb(b *testing.B) {
 func BenchmarkInsertChain_ring1000_diskdb(b *testing.B) {
 	benchInsertChain(b, true, genTxRing(1000))
 }
+func BenchmarkInsertChain_transfers_serial(b *testing.B) {
+	benchInsertTransfers(b, false)
+}
+func BenchmarkInsertChain_transfers_parallel(b *testing.B) {
+	benchInsertTransfers(b, true)
+}
 
 var (
 	// This is the content of the genesis block used by the benchmarks.
@@ -184,6 +190,38 @@ func benchInsertChain(b *testing.B, disk bool, gen func(int, *BlockGen)) {
 	}
 }
 
+// benchInsertTransfers measures the import of blocks full of independent value
+// transfers, executing their transactions in order or in parallel.
+func benchInsertTransfers(b *testing.B, parallel bool) {
+	alloc := make(GenesisAlloc)
+	for _, addr := range ringAddrs {
+		alloc[addr] = GenesisAccount{Balance: benchRootFunds}
+	}
+	gspec := Genesis{
+		Config:   params.TestChainConfig,
+		GasLimit: uint64(len(ringKeys)) * params.TxGas,
+		Alloc:    alloc,
+	}
+	db := rawdb.NewMemoryDatabase()
+	genesis := gspec.MustCommit(db)
+
+	// Every account sends to a fresh recipient in every block
+	chain, _ := GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, b.N, func(i int, gen *BlockGen) {
+		for j, key := range ringKeys {
+			to := common.BigToAddress(big.NewInt(int64(i*len(ringKeys) + j + 1)))
+			tx, _ := types.SignTx(types.NewTransaction(gen.TxNonce(ringAddrs[j]), to, big.NewInt(1), params.TxGas, nil, nil), types.HomesteadSigner{}, key)
+			gen.AddTx(tx)
+		}
+	})
+	chainman, _ := NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{ParallelTxs: parallel}, nil)
+	defer chainman.Stop()
+	b.ReportAllocs()
+	b.ResetTimer()
+	if i, err := chainman.InsertChain(chain); err != nil {
+		b.Fatalf("insert error (block %d): %v\n", i, err)
+	}
+}
+
 func BenchmarkChainRead_header_10k(b *testing.B) {
 	benchReadChain(b, false, 10000)
 }
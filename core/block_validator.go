@@ -70,7 +70,7 @@ func (v *BlockValidator) ValidateBody(block *types.Block) error {
 		}
 		return consensus.ErrPrunedAncestor
 	}
-	return nil
+	return v.bc.validateTypedTxs(block)
 }
 
 // ValidateState validates the various changes that happen after a state
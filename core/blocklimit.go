@@ -0,0 +1,204 @@
+// Copyright 2020 The go-simplechain Authors
+// This file is part of the go-simplechain library.
+//
+// The go-simplechain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-simplechain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-simplechain library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+
+	"github.com/simplechain-org/go-simplechain/common"
+	"github.com/simplechain-org/go-simplechain/core/rawdb"
+	"github.com/simplechain-org/go-simplechain/core/types"
+	"github.com/simplechain-org/go-simplechain/ethdb"
+	"github.com/simplechain-org/go-simplechain/params"
+)
+
+var (
+	// ErrBlockLimitExpired is returned if a block-limit transaction is included
+	// in, or submitted for, a block past its block limit.
+	ErrBlockLimitExpired = errors.New("block limit expired")
+
+	// ErrBlockLimitTooHigh is returned if the block limit of a transaction lies
+	// further ahead than the block-limit window.
+	ErrBlockLimitTooHigh = errors.New("block limit too far in the future")
+
+	// ErrTxIDUsed is returned if a block-limit transaction reuses the ID of one
+	// the sender already got included within the block-limit window.
+	ErrTxIDUsed = errors.New("transaction id already used")
+
+	// ErrBlockLimitCreation is returned if a block-limit transaction attempts to
+	// create a contract. The address of the contract would derive from the nonce
+	// of the sender, which the transaction doesn't carry.
+	ErrBlockLimitCreation = errors.New("contract creation by block-limit transaction")
+)
+
+// txID identifies a block-limit transaction within the block-limit window.
+type txID struct {
+	sender common.Address
+	id     uint64
+}
+
+// checkBlockLimit verifies that the block limit of a transaction admits the
+// block with the given number.
+func checkBlockLimit(tx *types.Transaction, number uint64) error {
+	switch {
+	case tx.To() == nil:
+		return ErrBlockLimitCreation
+	case tx.BlockLimit() < number:
+		return ErrBlockLimitExpired
+	case tx.BlockLimit() >= number+params.BlockLimitWindow:
+		return ErrBlockLimitTooHigh
+	}
+	return nil
+}
+
+// CheckBlockLimit verifies that a block-limit transaction of the given sender
+// can be included in the block with the given number on top of the canonical
+// chain.
+func (bc *BlockChain) CheckBlockLimit(tx *types.Transaction, from common.Address, number uint64) error {
+	if err := checkBlockLimit(tx, number); err != nil {
+		return err
+	}
+	if bc.hasTxID(txID{from, tx.ID()}, number-1, number) {
+		return ErrTxIDUsed
+	}
+	return nil
+}
+
+// hasTxID reports whether the canonical chain up to and including block fork
+// contains a block-limit transaction with the given ID, recently enough for
+// it to be ineligible for block number.
+func (bc *BlockChain) hasTxID(id txID, fork uint64, number uint64) bool {
+	entry := rawdb.ReadTxIDEntry(bc.db, id.sender, id.id)
+	if entry == nil || *entry > fork || *entry+params.BlockLimitWindow <= number {
+		return false
+	}
+	// Entries aren't removed when the chain is rewound, confirm the inclusion
+	block := bc.GetBlockByNumber(*entry)
+	if block == nil {
+		return false
+	}
+	signer := types.MakeSigner(bc.chainConfig)
+	for _, tx := range block.Transactions() {
+		if tx.Type() != types.BlockLimitTxType || tx.ID() != id.id {
+			continue
+		}
+		if from, err := types.Sender(signer, tx); err == nil && from == id.sender {
+			return true
+		}
+	}
+	return false
+}
+
+// sideTxIDs collects the block-limit transaction IDs of the non-canonical
+// ancestors of a block within the block-limit window. It also returns the number
+// of the ancestor the canonical index takes over from.
+func (bc *BlockChain) sideTxIDs(block *types.Block) (map[txID]struct{}, uint64) {
+	var (
+		ids    = make(map[txID]struct{})
+		signer = types.MakeSigner(bc.chainConfig)
+		number = block.NumberU64()
+	)
+	parent := bc.GetBlock(block.ParentHash(), number-1)
+	for parent != nil && parent.NumberU64()+params.BlockLimitWindow > number {
+		if rawdb.ReadCanonicalHash(bc.db, parent.NumberU64()) == parent.Hash() {
+			return ids, parent.NumberU64()
+		}
+		for _, tx := range parent.Transactions() {
+			if tx.Type() != types.BlockLimitTxType {
+				continue
+			}
+			if from, err := types.Sender(signer, tx); err == nil {
+				ids[txID{from, tx.ID()}] = struct{}{}
+			}
+		}
+		parent = bc.GetBlock(parent.ParentHash(), parent.NumberU64()-1)
+	}
+	if parent == nil {
+		return ids, 0
+	}
+	return ids, parent.NumberU64()
+}
+
+// validateTypedTxs verifies the typed transactions of a block: their type has to
+// be activated and for block-limit transactions, their block limit has to admit
+// the block and their IDs mustn't be used by the ancestors within the block-limit
+// window, nor the block itself.
+func (bc *BlockChain) validateTypedTxs(block *types.Block) error {
+	var (
+		number = block.NumberU64()
+		signer = types.MakeSigner(bc.chainConfig)
+		side   map[txID]struct{}
+		fork   uint64
+		seen   = make(map[txID]struct{})
+	)
+	for _, tx := range block.Transactions() {
+		if tx.Type() == types.LegacyTxType {
+			continue
+		}
+		if !bc.chainConfig.IsTypedTx(block.Number()) {
+			return types.ErrTxTypeNotSupported
+		}
+		if tx.Type() != types.BlockLimitTxType {
+			continue
+		}
+		if err := checkBlockLimit(tx, number); err != nil {
+			return err
+		}
+		from, err := types.Sender(signer, tx)
+		if err != nil {
+			return err
+		}
+		if side == nil {
+			side, fork = bc.sideTxIDs(block)
+		}
+		id := txID{from, tx.ID()}
+		if _, ok := seen[id]; ok {
+			return ErrTxIDUsed
+		}
+		if _, ok := side[id]; ok || bc.hasTxID(id, fork, number) {
+			return ErrTxIDUsed
+		}
+		seen[id] = struct{}{}
+	}
+	return nil
+}
+
+// writeTxIDEntries indexes the block-limit transactions of a canonical block by
+// sender and ID.
+func writeTxIDEntries(db ethdb.KeyValueWriter, signer types.Signer, block *types.Block) {
+	for _, tx := range block.Transactions() {
+		if tx.Type() != types.BlockLimitTxType {
+			continue
+		}
+		if from, err := types.Sender(signer, tx); err == nil {
+			rawdb.WriteTxIDEntry(db, from, tx.ID(), block.NumberU64())
+		}
+	}
+}
+
+// deleteTxIDEntries removes the index entries of block-limit transactions which
+// left the canonical chain.
+func deleteTxIDEntries(db ethdb.KeyValueWriter, signer types.Signer, txs types.Transactions) {
+	for _, tx := range txs {
+		if tx.Type() != types.BlockLimitTxType {
+			continue
+		}
+		if from, err := types.Sender(signer, tx); err == nil {
+			rawdb.DeleteTxIDEntry(db, from, tx.ID())
+		}
+	}
+}
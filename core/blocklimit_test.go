@@ -0,0 +1,160 @@
+// Copyright 2020 The go-simplechain Authors
+// This file is part of the go-simplechain library.
+//
+// The go-simplechain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-simplechain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-simplechain library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/simplechain-org/go-simplechain/common"
+	"github.com/simplechain-org/go-simplechain/consensus/ethash"
+	"github.com/simplechain-org/go-simplechain/core/rawdb"
+	"github.com/simplechain-org/go-simplechain/core/state"
+	"github.com/simplechain-org/go-simplechain/core/types"
+	"github.com/simplechain-org/go-simplechain/core/vm"
+	"github.com/simplechain-org/go-simplechain/crypto"
+	"github.com/simplechain-org/go-simplechain/params"
+)
+
+// Tests that block-limit transactions are only accepted within their block
+// limit and that their IDs can't be replayed, neither on the canonical chain
+// nor on a side chain.
+func TestBlockLimitTransactions(t *testing.T) {
+	var (
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr   = crypto.PubkeyToAddress(key.PublicKey)
+		to     = common.Address{0x01}
+	)
+	config := *params.TestChainConfig
+	config.TypedTxBlock = big.NewInt(0)
+	gspec := &Genesis{Config: &config, Alloc: GenesisAlloc{addr: {Balance: big.NewInt(10000000000000)}}}
+	signer := types.NewEIP155Signer(config.ChainID)
+
+	newTx := func(id, limit uint64) *types.Transaction {
+		tx, err := types.SignTx(types.NewBlockLimitTransaction(config.ChainID, id, limit, &to, big.NewInt(1), params.TxGas, new(big.Int), nil), signer, key)
+		if err != nil {
+			t.Fatalf("failed to sign tx: %v", err)
+		}
+		return tx
+	}
+	gendb := rawdb.NewMemoryDatabase()
+	genesis := gspec.MustCommit(gendb)
+
+	// makeChain generates a chain on top of genesis with a block-limit transaction
+	// of the given ID in each block
+	makeChain := func(ids ...uint64) []*types.Block {
+		blocks, _ := GenerateChain(&config, genesis, ethash.NewFaker(), gendb, len(ids), func(i int, gen *BlockGen) {
+			gen.AddTx(newTx(ids[i], 10))
+		})
+		return blocks
+	}
+	db := rawdb.NewMemoryDatabase()
+	gspec.MustCommit(db)
+
+	chain, _ := NewBlockChain(db, nil, &config, ethash.NewFaker(), vm.Config{}, nil)
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(makeChain(7, 8)); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	if state, _ := chain.State(); state.GetBalance(to).Uint64() != 2 {
+		t.Errorf("recipient balance mismatch: have %v, want 2", state.GetBalance(to))
+	} else if state.GetNonce(addr) != 0 {
+		t.Errorf("sender nonce mismatch: have %d, want 0", state.GetNonce(addr))
+	}
+	for i, tt := range []struct {
+		tx   *types.Transaction
+		want error
+	}{
+		{newTx(9, 3), nil},
+		{newTx(7, 10), ErrTxIDUsed},
+		{newTx(9, 2), ErrBlockLimitExpired},
+		{newTx(9, 3+params.BlockLimitWindow), ErrBlockLimitTooHigh},
+		{types.NewBlockLimitTransaction(config.ChainID, 9, 3, nil, new(big.Int), params.TxGas, new(big.Int), nil), ErrBlockLimitCreation},
+	} {
+		if err := chain.CheckBlockLimit(tt.tx, addr, 3); err != tt.want {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.want)
+		}
+	}
+	// Replays on top of the canonical chain and on a side chain must be rejected
+	for i, ids := range [][]uint64{{7, 8, 8}, {7, 8, 7}, {9, 9}} {
+		if _, err := chain.InsertChain(makeChain(ids...)); err != ErrTxIDUsed {
+			t.Errorf("test %d: replay error mismatch: have %v, want %v", i, err, ErrTxIDUsed)
+		}
+	}
+	// And so must replays within a single block
+	blocks, _ := GenerateChain(&config, genesis, ethash.NewFaker(), gendb, 1, func(i int, gen *BlockGen) {
+		gen.AddTx(newTx(9, 9))
+		gen.AddTx(newTx(9, 10))
+	})
+	if _, err := chain.InsertChain(blocks); err != ErrTxIDUsed {
+		t.Errorf("in-block replay error mismatch: have %v, want %v", err, ErrTxIDUsed)
+	}
+}
+
+// Tests that block-limit transactions leave the nonce of their sender alone, so
+// they don't invalidate its nonce-ordered transactions, and that they are subject
+// to account permissioning like any other transaction.
+func TestBlockLimitTransactionState(t *testing.T) {
+	var (
+		key, _         = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr           = crypto.PubkeyToAddress(key.PublicKey)
+		strangerKey, _ = crypto.HexToECDSA("8a1f9a8f95be41cd7ccb6168179afb4504aefe388d1e14474d32c45c72ce7b7a")
+		stranger       = crypto.PubkeyToAddress(strangerKey.PublicKey)
+		to             = common.Address{0x01}
+	)
+	config := *params.TestChainConfig
+	config.TypedTxBlock = big.NewInt(0)
+	config.PermissionBlock = big.NewInt(0)
+	config.Permission = &params.PermissionConfig{
+		Accounts: map[common.Address]params.AccountRole{addr: params.RoleTransact},
+	}
+	signer := types.NewEIP155Signer(config.ChainID)
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()))
+	statedb.SetBalance(addr, big.NewInt(10000000000000))
+	statedb.SetBalance(stranger, big.NewInt(10000000000000))
+
+	header := &types.Header{Number: big.NewInt(1), GasLimit: 8000000, Difficulty: big.NewInt(1)}
+	apply := func(tx *types.Transaction, key *ecdsa.PrivateKey) error {
+		tx, err := types.SignTx(tx, signer, key)
+		if err != nil {
+			t.Fatalf("failed to sign tx: %v", err)
+		}
+		var used uint64
+		_, err = ApplyTransaction(&config, nil, &header.Coinbase, new(GasPool).AddGas(header.GasLimit), statedb, header, tx, &used, vm.Config{})
+		return err
+	}
+	// A nonce-ordered transaction still goes through after a block-limit one
+	if err := apply(types.NewBlockLimitTransaction(config.ChainID, 1, 10, &to, big.NewInt(1), params.TxGas, new(big.Int), nil), key); err != nil {
+		t.Fatalf("failed to apply block-limit tx: %v", err)
+	}
+	if nonce := statedb.GetNonce(addr); nonce != 0 {
+		t.Errorf("nonce bumped by block-limit tx: have %d, want 0", nonce)
+	}
+	if err := apply(types.NewTransaction(0, to, big.NewInt(1), params.TxGas, new(big.Int), nil), key); err != nil {
+		t.Fatalf("failed to apply legacy tx after block-limit tx: %v", err)
+	}
+	if nonce := statedb.GetNonce(addr); nonce != 1 {
+		t.Errorf("nonce mismatch after legacy tx: have %d, want 1", nonce)
+	}
+	// Block-limit transactions of readonly accounts are rejected
+	if err := apply(types.NewBlockLimitTransaction(config.ChainID, 2, 10, &to, big.NewInt(1), params.TxGas, new(big.Int), nil), strangerKey); err != ErrTransactNotPermitted {
+		t.Errorf("readonly block-limit tx error mismatch: have %v, want %v", err, ErrTransactNotPermitted)
+	}
+}
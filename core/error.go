@@ -36,6 +36,10 @@ var (
 	// ErrNoGenesis is returned when there is no Genesis Block.
 	ErrNoGenesis = errors.New("genesis not found in chain")
 
+	// ErrSystemContractValue is returned if a transaction sends value to the
+	// DPoS system contract, which has no code to hold or refund it.
+	ErrSystemContractValue = errors.New("value transfer to the system contract")
+
 	// Quorum
 	// ErrAbortBlocksProcessing is returned if bc.insertChain is interrupted under raft mode
 	ErrAbortBlocksProcessing = errors.New("abort during blocks processing")
@@ -223,6 +223,12 @@ func gatherForks(config *params.ChainConfig) []uint64 {
 			forks = append(forks, rule.Uint64())
 		}
 	}
+	// Switching consensus engines forks the chain too
+	for _, fork := range config.ConsensusForks {
+		if fork.Block != nil {
+			forks = append(forks, fork.Block.Uint64())
+		}
+	}
 	// Sort the fork block numbers to permit chronologival XOR
 	for i := 0; i < len(forks); i++ {
 		for j := i + 1; j < len(forks); j++ {
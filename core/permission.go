@@ -0,0 +1,95 @@
+// Copyright 2020 The go-simplechain Authors
+// This file is part of the go-simplechain library.
+//
+// The go-simplechain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-simplechain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-simplechain library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/simplechain-org/go-simplechain/common"
+	"github.com/simplechain-org/go-simplechain/crypto"
+	"github.com/simplechain-org/go-simplechain/params"
+)
+
+var (
+	// ErrTransactNotPermitted is returned if a read only account sends a
+	// transaction while account permissioning is active.
+	ErrTransactNotPermitted = errors.New("account not permitted to transact")
+
+	// ErrDeployNotPermitted is returned if an account without the deploy role
+	// creates a contract while account permissioning is active.
+	ErrDeployNotPermitted = errors.New("account not permitted to deploy contracts")
+)
+
+// roleSlot is the storage slot of the role table in the governance contract.
+var roleSlot = common.Hash{}
+
+// contractRoles maps the values of the governance contract role table to
+// roles, zero meaning unset.
+var contractRoles = map[byte]params.AccountRole{
+	1: params.RoleReadOnly,
+	2: params.RoleTransact,
+	3: params.RoleDeploy,
+}
+
+// permissionState is the part of the state account permissioning reads.
+type permissionState interface {
+	GetState(common.Address, common.Hash) common.Hash
+}
+
+// AccountRoleOf returns the role of addr under the given permission config,
+// consulting the governance contract first and the genesis roles after.
+func AccountRoleOf(config *params.PermissionConfig, statedb permissionState, addr common.Address) params.AccountRole {
+	if config.Contract != nil {
+		key := crypto.Keccak256Hash(common.LeftPadBytes(addr.Bytes(), 32), roleSlot.Bytes())
+		value := statedb.GetState(*config.Contract, key)
+		if role, ok := contractRoles[value[common.HashLength-1]]; ok {
+			return role
+		}
+	}
+	if role, ok := config.Accounts[addr]; ok {
+		return role
+	}
+	if config.DefaultRole != "" {
+		return config.DefaultRole
+	}
+	return params.RoleReadOnly
+}
+
+// CheckAccountPermission verifies that the sender may send a transaction to the
+// given recipient, nil meaning a contract creation, in the block with the given
+// number. It always succeeds before the account permissioning fork.
+func CheckAccountPermission(config *params.ChainConfig, number *big.Int, statedb permissionState, from common.Address, to *common.Address) error {
+	if !config.IsPermission(number) {
+		return nil
+	}
+	return checkAccountRole(config.Permission, statedb, from, to)
+}
+
+// checkAccountRole verifies that the role of the sender allows the transaction.
+func checkAccountRole(config *params.PermissionConfig, statedb permissionState, from common.Address, to *common.Address) error {
+	switch role := AccountRoleOf(config, statedb, from); {
+	case role == params.RoleDeploy:
+		return nil
+	case role == params.RoleTransact && to != nil:
+		return nil
+	case role == params.RoleTransact:
+		return ErrDeployNotPermitted
+	default:
+		return ErrTransactNotPermitted
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright 2020 The go-simplechain Authors
+// This file is part of the go-simplechain library.
+//
+// The go-simplechain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-simplechain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-simplechain library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/simplechain-org/go-simplechain/common"
+	"github.com/simplechain-org/go-simplechain/core/rawdb"
+	"github.com/simplechain-org/go-simplechain/core/state"
+	"github.com/simplechain-org/go-simplechain/core/types"
+	"github.com/simplechain-org/go-simplechain/core/vm"
+	"github.com/simplechain-org/go-simplechain/crypto"
+	"github.com/simplechain-org/go-simplechain/params"
+)
+
+// Tests that account roles are resolved from the governance contract first,
+// then the genesis and the default role, and enforced from the fork block on.
+func TestAccountPermission(t *testing.T) {
+	var (
+		deployer = common.HexToAddress("0x01")
+		sender   = common.HexToAddress("0x02")
+		stranger = common.HexToAddress("0x03")
+		contract = common.HexToAddress("0x0c")
+		to       = common.HexToAddress("0xff")
+	)
+	config := &params.ChainConfig{
+		ChainID:         big.NewInt(1),
+		PermissionBlock: big.NewInt(10),
+		Permission: &params.PermissionConfig{
+			Contract: &contract,
+			Accounts: map[common.Address]params.AccountRole{
+				deployer: params.RoleDeploy,
+				sender:   params.RoleTransact,
+			},
+		},
+	}
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()))
+
+	tests := []struct {
+		number *big.Int
+		from   common.Address
+		to     *common.Address
+		err    error
+	}{
+		// Everything goes before the fork
+		{big.NewInt(9), stranger, nil, nil},
+		// Genesis roles and the readonly default afterwards
+		{big.NewInt(10), deployer, nil, nil},
+		{big.NewInt(10), deployer, &to, nil},
+		{big.NewInt(10), sender, &to, nil},
+		{big.NewInt(10), sender, nil, ErrDeployNotPermitted},
+		{big.NewInt(10), stranger, &to, ErrTransactNotPermitted},
+	}
+	for i, tt := range tests {
+		if err := CheckAccountPermission(config, tt.number, statedb, tt.from, tt.to); err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+	// Grant the stranger the deploy role and revoke the sender's in the contract
+	slot := func(addr common.Address) common.Hash {
+		return crypto.Keccak256Hash(common.LeftPadBytes(addr.Bytes(), 32), common.Hash{}.Bytes())
+	}
+	statedb.SetState(contract, slot(stranger), common.BigToHash(big.NewInt(3)))
+	statedb.SetState(contract, slot(sender), common.BigToHash(big.NewInt(1)))
+
+	if err := CheckAccountPermission(config, big.NewInt(10), statedb, stranger, nil); err != nil {
+		t.Errorf("contract granted deploy role rejected: %v", err)
+	}
+	if err := CheckAccountPermission(config, big.NewInt(10), statedb, sender, &to); err != ErrTransactNotPermitted {
+		t.Errorf("contract revoked role error mismatch: have %v, want %v", err, ErrTransactNotPermitted)
+	}
+	// Unlisted accounts take the default role if configured
+	config.Permission.DefaultRole = params.RoleTransact
+	other := common.HexToAddress("0x04")
+	if err := CheckAccountPermission(config, big.NewInt(10), statedb, other, &to); err != nil {
+		t.Errorf("default role rejected: %v", err)
+	}
+}
+
+// Tests that readonly accounts and calls without a sender can still make calls
+// once permissioning is active, while their transactions are rejected.
+func TestReadonlyCalls(t *testing.T) {
+	var (
+		readonly = common.HexToAddress("0x03")
+		to       = common.HexToAddress("0xff")
+	)
+	config := *params.TestChainConfig
+	config.PermissionBlock = big.NewInt(0)
+	config.Permission = &params.PermissionConfig{}
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()))
+	statedb.SetBalance(readonly, big.NewInt(1000000000))
+
+	for i, tt := range []struct {
+		from       common.Address
+		checkNonce bool
+		err        error
+	}{
+		{readonly, false, nil},
+		{common.Address{}, false, nil},
+		{readonly, true, ErrTransactNotPermitted},
+	} {
+		msg := types.NewMessage(tt.from, &to, statedb.GetNonce(tt.from), new(big.Int), params.TxGas, new(big.Int), nil, tt.checkNonce)
+		context := vm.Context{
+			CanTransfer: CanTransfer,
+			Transfer:    Transfer,
+			GasLimit:    params.TxGas,
+			BlockNumber: big.NewInt(1),
+			Time:        new(big.Int),
+			Difficulty:  new(big.Int),
+			GasPrice:    msg.GasPrice(),
+		}
+		evm := vm.NewEVM(context, statedb, &config, vm.Config{})
+		if _, _, _, err := ApplyMessage(evm, msg, new(GasPool).AddGas(params.TxGas)); err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright 2020 The go-simplechain Authors
+// This file is part of the go-simplechain library.
+//
+// The go-simplechain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-simplechain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-simplechain library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+
+	"github.com/simplechain-org/go-simplechain/common"
+	"github.com/simplechain-org/go-simplechain/core/rawdb"
+	"github.com/simplechain-org/go-simplechain/core/state"
+	"github.com/simplechain-org/go-simplechain/core/types"
+	"github.com/simplechain-org/go-simplechain/core/vm"
+	"github.com/simplechain-org/go-simplechain/log"
+	"github.com/simplechain-org/go-simplechain/private"
+)
+
+// SetPrivateTransactionManager sets the manager supplying the payloads of the
+// private transactions this node participates in. It has to be called before
+// any block is imported.
+func (bc *BlockChain) SetPrivateTransactionManager(ptm private.PrivateTransactionManager) {
+	bc.privateTxManager = ptm
+}
+
+// PrivateTransactionManager returns the private transaction manager of the
+// chain, or nil if the node doesn't participate in private transactions.
+func (bc *BlockChain) PrivateTransactionManager() private.PrivateTransactionManager {
+	return bc.privateTxManager
+}
+
+// PrivateStateAt returns the private state of the node after the block with the
+// given hash. Blocks without private state yield an empty one.
+func (bc *BlockChain) PrivateStateAt(hash common.Hash) (*state.StateDB, error) {
+	return state.New(rawdb.ReadPrivateStateRoot(bc.db, hash), bc.privateStateCache)
+}
+
+// writePrivateState executes the private transactions of the block the node
+// participates in on top of the parent's private state and stores the root of
+// the result. Public state is never touched: there the transactions are opaque.
+func (bc *BlockChain) writePrivateState(block *types.Block) error {
+	if bc.privateTxManager == nil || !bc.chainConfig.IsPrivacy(block.Number()) {
+		return nil
+	}
+	statedb, err := bc.PrivateStateAt(block.ParentHash())
+	if err != nil {
+		return err
+	}
+	var (
+		header = block.Header()
+		signer = types.MakeSigner(bc.chainConfig)
+	)
+	for i, tx := range block.Transactions() {
+		hash, ok := types.PrivatePayloadHash(tx.Data())
+		if !ok {
+			continue
+		}
+		payload, err := bc.privateTxManager.Receive(private.BytesToPayloadHash(hash))
+		if err != nil {
+			return err
+		}
+		if payload == nil {
+			continue // Not a participant
+		}
+		from, err := types.Sender(signer, tx)
+		if err != nil {
+			return err
+		}
+		// Private execution is free and doesn't track nonces, the public
+		// transaction has already been paid for and ordered.
+		msg := types.NewMessage(from, tx.To(), 0, new(big.Int), tx.Gas(), new(big.Int), payload, false)
+		statedb.Prepare(tx.Hash(), block.Hash(), i)
+
+		evm := vm.NewEVM(NewEVMContext(msg, header, bc, nil), statedb, bc.chainConfig, bc.vmConfig)
+		if _, _, failed, err := ApplyMessage(evm, msg, new(GasPool).AddGas(tx.Gas())); err != nil || failed {
+			log.Debug("Private transaction failed", "hash", tx.Hash(), "failed", failed, "err", err)
+		}
+	}
+	root, err := statedb.Commit(bc.chainConfig.IsSingularity(header.Number))
+	if err != nil {
+		return err
+	}
+	if err := bc.privateStateCache.TrieDB().Commit(root, false); err != nil {
+		return err
+	}
+	rawdb.WritePrivateStateRoot(bc.db, block.Hash(), root)
+	return nil
+}
@@ -0,0 +1,99 @@
+// Copyright 2020 The go-simplechain Authors
+// This file is part of the go-simplechain library.
+//
+// The go-simplechain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-simplechain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-simplechain library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/simplechain-org/go-simplechain/common"
+	"github.com/simplechain-org/go-simplechain/consensus/ethash"
+	"github.com/simplechain-org/go-simplechain/core/rawdb"
+	"github.com/simplechain-org/go-simplechain/core/types"
+	"github.com/simplechain-org/go-simplechain/core/vm"
+	"github.com/simplechain-org/go-simplechain/crypto"
+	"github.com/simplechain-org/go-simplechain/params"
+	"github.com/simplechain-org/go-simplechain/private"
+)
+
+// Tests that private transactions are only executed by their participants, on
+// a private state separate from the public one.
+func TestPrivateTransactions(t *testing.T) {
+	var (
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr    = crypto.PubkeyToAddress(key.PublicKey)
+		network = private.NewMemoryNetwork()
+		sender  = network.NewManager("sender")
+		outside = network.NewManager("outside")
+		// Stores 1 in slot 0 of the created contract
+		code = common.Hex2Bytes("600160005500")
+	)
+	network.NewManager("recipient")
+
+	config := *params.TestChainConfig
+	config.PrivacyBlock = big.NewInt(0)
+	gspec := &Genesis{Config: &config, Alloc: GenesisAlloc{addr: {Balance: big.NewInt(10000000000000)}}}
+	signer := types.NewEIP155Signer(config.ChainID)
+
+	hash, err := sender.Send(code, "", []string{"recipient"})
+	if err != nil {
+		t.Fatalf("failed to send private payload: %v", err)
+	}
+	gendb := rawdb.NewMemoryDatabase()
+	genesis := gspec.MustCommit(gendb)
+	blocks, _ := GenerateChain(&config, genesis, ethash.NewFaker(), gendb, 1, func(i int, gen *BlockGen) {
+		tx, err := types.SignTx(types.NewContractCreation(gen.TxNonce(addr), new(big.Int), 100000, new(big.Int), types.NewPrivatePayload(hash[:])), signer, key)
+		if err != nil {
+			t.Fatalf("failed to create tx: %v", err)
+		}
+		gen.AddTx(tx)
+	})
+	contract := crypto.CreateAddress(addr, 0)
+
+	for _, tt := range []struct {
+		ptm  private.PrivateTransactionManager
+		want common.Hash
+	}{
+		{sender, common.BigToHash(big.NewInt(1))},
+		{outside, common.Hash{}},
+	} {
+		db := rawdb.NewMemoryDatabase()
+		gspec.MustCommit(db)
+
+		chain, _ := NewBlockChain(db, nil, &config, ethash.NewFaker(), vm.Config{}, nil)
+		chain.SetPrivateTransactionManager(tt.ptm)
+
+		if _, err := chain.InsertChain(blocks); err != nil {
+			t.Fatalf("failed to insert chain: %v", err)
+		}
+		public, _ := chain.State()
+		if public.Exist(contract) {
+			t.Errorf("private contract created in public state")
+		}
+		if public.GetNonce(addr) != 1 {
+			t.Errorf("public nonce mismatch: have %d, want 1", public.GetNonce(addr))
+		}
+		privateState, err := chain.PrivateStateAt(blocks[0].Hash())
+		if err != nil {
+			t.Fatalf("failed to open private state: %v", err)
+		}
+		if have := privateState.GetState(contract, common.Hash{}); have != tt.want {
+			t.Errorf("private storage mismatch: have %x, want %x", have, tt.want)
+		}
+		chain.Stop()
+	}
+}
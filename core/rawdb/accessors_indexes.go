@@ -17,6 +17,7 @@
 package rawdb
 
 import (
+	"encoding/binary"
 	"math/big"
 
 	"github.com/simplechain-org/go-simplechain/common"
@@ -68,6 +69,30 @@ func DeleteTxLookupEntry(db ethdb.KeyValueWriter, hash common.Hash) {
 	db.Delete(txLookupKey(hash))
 }
 
+// ReadTxIDEntry retrieves the number of the block which included the block-limit
+// transaction with the given sender and ID.
+func ReadTxIDEntry(db ethdb.Reader, sender common.Address, id uint64) *uint64 {
+	data, _ := db.Get(txIDKey(sender, id))
+	if len(data) != 8 {
+		return nil
+	}
+	number := binary.BigEndian.Uint64(data)
+	return &number
+}
+
+// WriteTxIDEntry stores the number of the block which included the block-limit
+// transaction with the given sender and ID, rejecting replays of the ID.
+func WriteTxIDEntry(db ethdb.KeyValueWriter, sender common.Address, id uint64, number uint64) {
+	if err := db.Put(txIDKey(sender, id), encodeBlockNumber(number)); err != nil {
+		log.Crit("Failed to store transaction ID entry", "err", err)
+	}
+}
+
+// DeleteTxIDEntry removes the block-limit transaction ID entry of a sender.
+func DeleteTxIDEntry(db ethdb.KeyValueWriter, sender common.Address, id uint64) {
+	db.Delete(txIDKey(sender, id))
+}
+
 // ReadTransaction retrieves a specific transaction from the database, along with
 // its added positional metadata.
 func ReadTransaction(db ethdb.Reader, hash common.Hash) (*types.Transaction, common.Hash, uint64, uint64) {
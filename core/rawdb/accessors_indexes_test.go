@@ -106,3 +106,24 @@ func TestLookupStorage(t *testing.T) {
 		})
 	}
 }
+
+// Tests that block-limit transaction IDs are indexed per sender.
+func TestTxIDStorage(t *testing.T) {
+	db := NewMemoryDatabase()
+
+	alice, bob := common.Address{0xa1}, common.Address{0xb0}
+	if entry := ReadTxIDEntry(db, alice, 1); entry != nil {
+		t.Fatalf("non existent entry returned: %d", *entry)
+	}
+	WriteTxIDEntry(db, alice, 1, 314)
+	if entry := ReadTxIDEntry(db, alice, 1); entry == nil || *entry != 314 {
+		t.Fatalf("entry mismatch: have %v, want 314", entry)
+	}
+	if entry := ReadTxIDEntry(db, bob, 1); entry != nil {
+		t.Fatalf("entry of other sender returned: %d", *entry)
+	}
+	DeleteTxIDEntry(db, alice, 1)
+	if entry := ReadTxIDEntry(db, alice, 1); entry != nil {
+		t.Fatalf("deleted entry returned: %d", *entry)
+	}
+}
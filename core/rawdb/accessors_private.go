@@ -0,0 +1,38 @@
+// Copyright 2020 The go-simplechain Authors
+// This file is part of the go-simplechain library.
+//
+// The go-simplechain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-simplechain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-simplechain library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"github.com/simplechain-org/go-simplechain/common"
+	"github.com/simplechain-org/go-simplechain/ethdb"
+	"github.com/simplechain-org/go-simplechain/log"
+)
+
+// ReadPrivateStateRoot retrieves the root of the private state after the block
+// with the given hash, or the empty hash if none is stored.
+func ReadPrivateStateRoot(db ethdb.KeyValueReader, hash common.Hash) common.Hash {
+	data, _ := db.Get(privateRootKey(hash))
+	return common.BytesToHash(data)
+}
+
+// WritePrivateStateRoot stores the root of the private state after the block
+// with the given hash.
+func WritePrivateStateRoot(db ethdb.KeyValueWriter, hash common.Hash, root common.Hash) {
+	if err := db.Put(privateRootKey(hash), root.Bytes()); err != nil {
+		log.Crit("Failed to store private state root", "err", err)
+	}
+}
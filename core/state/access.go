@@ -0,0 +1,187 @@
+// Copyright 2020 The go-simplechain Authors
+// This file is part of the go-simplechain library.
+//
+// The go-simplechain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-simplechain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-simplechain library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"math/big"
+
+	"github.com/simplechain-org/go-simplechain/common"
+	"github.com/simplechain-org/go-simplechain/core/types"
+)
+
+// storageKey identifies a storage slot of an account.
+type storageKey struct {
+	addr common.Address
+	slot common.Hash
+}
+
+// AccessSet is the record of the state read and modified while executing
+// transactions with access tracking enabled.
+//
+// The execution of a transaction only depends on the values it read. If none of
+// them were modified in between, executing it on an older state performs the very
+// same modifications, which can be replayed on the current state instead of
+// executing the transaction again.
+type AccessSet struct {
+	accounts map[common.Address]struct{} // Accounts whose fields or existence were read
+	slots    map[storageKey]struct{}     // Storage slots read
+	storages map[common.Address]struct{} // Accounts whose whole storage was iterated
+
+	dirtyAccounts map[common.Address]struct{} // Accounts whose fields or existence were modified
+	dirtySlots    map[storageKey]struct{}     // Storage slots modified
+	resets        map[common.Address]struct{} // Accounts whose storage was wiped
+
+	ops []func(*StateDB) // Modifications in execution order, reverted ones dropped
+}
+
+// NewAccessSet creates an empty access set.
+func NewAccessSet() *AccessSet {
+	return &AccessSet{
+		accounts:      make(map[common.Address]struct{}),
+		slots:         make(map[storageKey]struct{}),
+		storages:      make(map[common.Address]struct{}),
+		dirtyAccounts: make(map[common.Address]struct{}),
+		dirtySlots:    make(map[storageKey]struct{}),
+		resets:        make(map[common.Address]struct{}),
+	}
+}
+
+// Conflicts reports whether any state read by the recorded execution was
+// modified by the executions recorded in the given set.
+func (a *AccessSet) Conflicts(prev *AccessSet) bool {
+	for addr := range a.accounts {
+		if _, ok := prev.dirtyAccounts[addr]; ok {
+			return true
+		}
+	}
+	for key := range a.slots {
+		if _, ok := prev.dirtySlots[key]; ok {
+			return true
+		}
+		if _, ok := prev.resets[key.addr]; ok {
+			return true
+		}
+	}
+	for addr := range a.storages {
+		if _, ok := prev.resets[addr]; ok {
+			return true
+		}
+		for key := range prev.dirtySlots {
+			if key.addr == addr {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Merge adds the modifications recorded in the given set to this one. Reads and
+// replayable operations aren't merged.
+func (a *AccessSet) Merge(other *AccessSet) {
+	for addr := range other.dirtyAccounts {
+		a.dirtyAccounts[addr] = struct{}{}
+	}
+	for key := range other.dirtySlots {
+		a.dirtySlots[key] = struct{}{}
+	}
+	for addr := range other.resets {
+		a.resets[addr] = struct{}{}
+	}
+}
+
+// Replay applies the recorded modifications to the given state.
+func (a *AccessSet) Replay(s *StateDB) {
+	for _, op := range a.ops {
+		op(s)
+	}
+}
+
+// readAccount records a read of the fields or the existence of an account.
+func (a *AccessSet) readAccount(addr common.Address) {
+	a.accounts[addr] = struct{}{}
+}
+
+// readSlot records a read of a storage slot.
+func (a *AccessSet) readSlot(addr common.Address, slot common.Hash) {
+	a.slots[storageKey{addr, slot}] = struct{}{}
+}
+
+// readStorage records a read of the whole storage of an account.
+func (a *AccessSet) readStorage(addr common.Address) {
+	a.storages[addr] = struct{}{}
+}
+
+// modifyAccount records a modification of the fields or the existence of an
+// account, along with the operation replaying it.
+func (a *AccessSet) modifyAccount(addr common.Address, op func(*StateDB)) {
+	a.dirtyAccounts[addr] = struct{}{}
+	a.ops = append(a.ops, op)
+}
+
+// modifySlot records a modification of a storage slot, along with the operation
+// replaying it.
+func (a *AccessSet) modifySlot(addr common.Address, slot common.Hash, op func(*StateDB)) {
+	a.dirtySlots[storageKey{addr, slot}] = struct{}{}
+	a.ops = append(a.ops, op)
+}
+
+// resetAccount records an operation recreating or destructing an account, which
+// wipes its storage too.
+func (a *AccessSet) resetAccount(addr common.Address, op func(*StateDB)) {
+	a.resets[addr] = struct{}{}
+	a.modifyAccount(addr, op)
+}
+
+// addBalanceOp returns the operation replaying a balance increase.
+func addBalanceOp(addr common.Address, amount *big.Int) func(*StateDB) {
+	amount = new(big.Int).Set(amount)
+	return func(s *StateDB) { s.AddBalance(addr, amount) }
+}
+
+// subBalanceOp returns the operation replaying a balance decrease.
+func subBalanceOp(addr common.Address, amount *big.Int) func(*StateDB) {
+	amount = new(big.Int).Set(amount)
+	return func(s *StateDB) { s.SubBalance(addr, amount) }
+}
+
+// setBalanceOp returns the operation replaying a balance assignment.
+func setBalanceOp(addr common.Address, amount *big.Int) func(*StateDB) {
+	amount = new(big.Int).Set(amount)
+	return func(s *StateDB) { s.SetBalance(addr, amount) }
+}
+
+// addLogOp returns the operation replaying the emission of a log. The fields
+// derived from the state are filled in again when replayed.
+func addLogOp(log *types.Log) func(*StateDB) {
+	address, topics, data, number := log.Address, log.Topics, log.Data, log.BlockNumber
+	return func(s *StateDB) {
+		s.AddLog(&types.Log{Address: address, Topics: topics, Data: data, BlockNumber: number})
+	}
+}
+
+// StartAccessTracking starts recording the state accesses, discarding any
+// previous record.
+func (s *StateDB) StartAccessTracking() {
+	s.access = NewAccessSet()
+}
+
+// StopAccessTracking stops recording the state accesses and returns the record.
+func (s *StateDB) StopAccessTracking() *AccessSet {
+	access := s.access
+	s.access = nil
+	return access
+}
@@ -0,0 +1,65 @@
+// Copyright 2020 The go-simplechain Authors
+// This file is part of the go-simplechain library.
+//
+// The go-simplechain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-simplechain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-simplechain library. If not, see <http://www.gnu.org/licenses/>.
+
+package pruner
+
+import (
+	"encoding/binary"
+
+	"github.com/simplechain-org/go-simplechain/common"
+	"github.com/simplechain-org/go-simplechain/log"
+	"github.com/steakknife/bloomfilter"
+)
+
+// stateBloomHasher is a wrapper around a byte blob to satisfy the interface API
+// requirements of the bloom library used. It's used to convert a trie hash or
+// contract code hash into a 64 bit mini hash.
+type stateBloomHasher []byte
+
+func (f stateBloomHasher) Write(p []byte) (n int, err error) { panic("not implemented") }
+func (f stateBloomHasher) Sum(b []byte) []byte               { panic("not implemented") }
+func (f stateBloomHasher) Reset()                            { panic("not implemented") }
+func (f stateBloomHasher) BlockSize() int                    { panic("not implemented") }
+func (f stateBloomHasher) Size() int                         { return 8 }
+func (f stateBloomHasher) Sum64() uint64                     { return binary.BigEndian.Uint64(f) }
+
+// stateBloom is a bloom filter tracking the trie nodes and contract codes which
+// are reachable from the retained states. False positives only cause garbage to
+// survive the pruning, there are no false negatives which could delete live data.
+type stateBloom struct {
+	bloom *bloomfilter.Filter
+}
+
+// newStateBloom creates a new bloom filter of the given size (in megabytes). The
+// bloom is hard coded to use 4 filters.
+func newStateBloom(size uint64) (*stateBloom, error) {
+	bloom, err := bloomfilter.New(size*1024*1024*8, 4)
+	if err != nil {
+		return nil, err
+	}
+	log.Info("Allocated state bloom", "size", common.StorageSize(size*1024*1024))
+	return &stateBloom{bloom: bloom}, nil
+}
+
+// add marks the given hash as reachable.
+func (b *stateBloom) add(hash common.Hash) {
+	b.bloom.Add(stateBloomHasher(hash[:]))
+}
+
+// contain reports whether the given key might belong to a reachable entry.
+func (b *stateBloom) contain(key []byte) bool {
+	return b.bloom.Contains(stateBloomHasher(key))
+}
@@ -0,0 +1,219 @@
+// Copyright 2020 The go-simplechain Authors
+// This file is part of the go-simplechain library.
+//
+// The go-simplechain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-simplechain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-simplechain library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package pruner implements offline pruning of the state tries on disk.
+package pruner
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/simplechain-org/go-simplechain/common"
+	"github.com/simplechain-org/go-simplechain/core/rawdb"
+	"github.com/simplechain-org/go-simplechain/core/state"
+	"github.com/simplechain-org/go-simplechain/ethdb"
+	"github.com/simplechain-org/go-simplechain/log"
+)
+
+// errMissingHead is returned if the database has no head block to prune at.
+var errMissingHead = errors.New("head block missing")
+
+// Config contains the settings of the state pruning.
+type Config struct {
+	Retain    uint64        // Number of recent blocks below the head whose state to keep
+	BloomSize uint64        // Memory allowance (MB) of the bloom filter tracking reachable nodes
+	Blocks    []common.Hash // Additional blocks whose state to keep, e.g. consensus recovery points
+}
+
+// Pruner deletes every trie node and contract code from the database which is
+// not reachable from a retained state: the head state, the states of the last
+// few blocks, the genesis state and any explicitly requested blocks. Both the
+// public and the private state of these blocks are kept.
+//
+// Only 32 byte keys are ever deleted, all other data (chain, snapshots of the
+// consensus engines, state snapshot, preimages) is left untouched. Pruning must
+// be done on a stopped node and is safe to rerun if interrupted: the retained
+// states are never touched, any leftover garbage is collected in the next run.
+type Pruner struct {
+	db     ethdb.Database
+	config Config
+}
+
+// NewPruner creates a state pruner over the given chain database.
+func NewPruner(db ethdb.Database, config Config) *Pruner {
+	return &Pruner{db: db, config: config}
+}
+
+// Prune marks all the nodes reachable from the retained states in a bloom filter
+// and sweeps everything else out of the database.
+func (p *Pruner) Prune() error {
+	bloom, err := newStateBloom(p.config.BloomSize)
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	states, err := p.markRetained(bloom)
+	if err != nil {
+		return err
+	}
+	log.Info("Marked retained states", "states", states, "elapsed", common.PrettyDuration(time.Since(start)))
+
+	if err := sweep(p.db, bloom); err != nil {
+		return err
+	}
+	// Deleting the garbage doesn't free up the disk until it's compacted away
+	cstart := time.Now()
+	log.Info("Compacting database to release disk space")
+	if err := p.db.Compact(nil, nil); err != nil {
+		return err
+	}
+	log.Info("Pruned state", "elapsed", common.PrettyDuration(time.Since(start)), "compaction", common.PrettyDuration(time.Since(cstart)))
+	return nil
+}
+
+// markRetained marks the states which must survive the pruning in the bloom
+// filter, returning their number. The head state must be complete, the states
+// of older blocks are skipped if they are missing: non-archive nodes only ever
+// persist a few of them.
+func (p *Pruner) markRetained(bloom *stateBloom) (int, error) {
+	seen := make(map[common.Hash]bool)
+
+	retain := func(hash common.Hash, required bool) error {
+		number := rawdb.ReadHeaderNumber(p.db, hash)
+		if number == nil {
+			return fmt.Errorf("unknown block %x", hash)
+		}
+		header := rawdb.ReadHeader(p.db, hash, *number)
+		if header == nil {
+			return fmt.Errorf("missing header #%d [%x]", *number, hash)
+		}
+		for _, root := range []common.Hash{header.Root, rawdb.ReadPrivateStateRoot(p.db, hash)} {
+			if root == (common.Hash{}) || seen[root] {
+				continue
+			}
+			if err := markState(p.db, bloom, root); err != nil {
+				if required {
+					return fmt.Errorf("head state missing: block #%d [%x]: %v", *number, hash, err)
+				}
+				log.Warn("Skipping incomplete state", "number", *number, "hash", hash, "root", root, "err", err)
+				continue
+			}
+			seen[root] = true
+		}
+		return nil
+	}
+	head := rawdb.ReadHeadBlockHash(p.db)
+	if head == (common.Hash{}) {
+		return 0, errMissingHead
+	}
+	if err := retain(head, true); err != nil {
+		return 0, err
+	}
+	number := *rawdb.ReadHeaderNumber(p.db, head)
+	for i := uint64(1); i <= p.config.Retain && i <= number; i++ {
+		if err := retain(rawdb.ReadCanonicalHash(p.db, number-i), false); err != nil {
+			return 0, err
+		}
+	}
+	if err := retain(rawdb.ReadCanonicalHash(p.db, 0), false); err != nil {
+		return 0, err
+	}
+	for _, hash := range p.config.Blocks {
+		if err := retain(hash, false); err != nil {
+			return 0, err
+		}
+	}
+	return len(seen), nil
+}
+
+// markState adds all the trie nodes and contract codes of the state with the
+// given root to the bloom filter.
+func markState(db ethdb.Database, bloom *stateBloom, root common.Hash) error {
+	statedb, err := state.New(root, state.NewDatabase(db))
+	if err != nil {
+		return err
+	}
+	var (
+		nodes  int
+		start  = time.Now()
+		logged = time.Now()
+	)
+	it := state.NewNodeIterator(statedb)
+	for it.Next() {
+		if it.Hash == (common.Hash{}) {
+			continue // Embedded node, stored within its parent
+		}
+		bloom.add(it.Hash)
+		nodes++
+
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Marking retained state", "root", root, "nodes", nodes, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if it.Error != nil {
+		return fmt.Errorf("state %x: %v", root, it.Error)
+	}
+	log.Debug("Marked retained state", "root", root, "nodes", nodes, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// sweep deletes every trie node and contract code missing from the bloom filter.
+func sweep(db ethdb.Database, bloom *stateBloom) error {
+	var (
+		count  int
+		size   common.StorageSize
+		start  = time.Now()
+		logged = time.Now()
+		batch  = db.NewBatch()
+	)
+	it := db.NewIterator()
+	defer it.Release()
+
+	for it.Next() {
+		// Trie nodes and contract codes are keyed by their bare hash, anything
+		// else is prefixed and never pruned
+		key := it.Key()
+		if len(key) != common.HashLength || bloom.contain(key) {
+			continue
+		}
+		if err := batch.Delete(key); err != nil {
+			return err
+		}
+		count++
+		size += common.StorageSize(len(key) + len(it.Value()))
+
+		if batch.ValueSize() > ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Pruning state data", "nodes", count, "size", size, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if err := it.Error(); err != nil {
+		return err
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	log.Info("Pruned state data", "nodes", count, "size", size, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
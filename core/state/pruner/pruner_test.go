@@ -0,0 +1,118 @@
+// Copyright 2020 The go-simplechain Authors
+// This file is part of the go-simplechain library.
+//
+// The go-simplechain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-simplechain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-simplechain library. If not, see <http://www.gnu.org/licenses/>.
+
+package pruner
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/simplechain-org/go-simplechain/common"
+	"github.com/simplechain-org/go-simplechain/consensus/ethash"
+	"github.com/simplechain-org/go-simplechain/core"
+	"github.com/simplechain-org/go-simplechain/core/rawdb"
+	"github.com/simplechain-org/go-simplechain/core/state"
+	"github.com/simplechain-org/go-simplechain/core/types"
+	"github.com/simplechain-org/go-simplechain/core/vm"
+	"github.com/simplechain-org/go-simplechain/crypto"
+	"github.com/simplechain-org/go-simplechain/ethdb"
+	"github.com/simplechain-org/go-simplechain/params"
+)
+
+// completeState reports whether every node and code of the state is on disk.
+func completeState(db ethdb.Database, root common.Hash) bool {
+	statedb, err := state.New(root, state.NewDatabase(db))
+	if err != nil {
+		return false
+	}
+	it := state.NewNodeIterator(statedb)
+	for it.Next() {
+	}
+	return it.Error == nil
+}
+
+// Tests that pruning keeps the head, recent and genesis states as well as all
+// non-state data, and deletes the states of older blocks.
+func TestPruneState(t *testing.T) {
+	var (
+		key, _   = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr     = crypto.PubkeyToAddress(key.PublicKey)
+		contract = common.HexToAddress("0xc0de")
+		code     = common.Hex2Bytes("600160005500")
+		db       = rawdb.NewMemoryDatabase()
+		gspec    = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc: core.GenesisAlloc{
+				addr:     {Balance: big.NewInt(1000000000)},
+				contract: {Balance: new(big.Int), Code: code, Storage: map[common.Hash]common.Hash{{}: {1}}},
+			},
+		}
+		signer = types.NewEIP155Signer(gspec.Config.ChainID)
+	)
+	if err := NewPruner(db, Config{BloomSize: 1}).Prune(); err != errMissingHead {
+		t.Fatalf("pruning empty database error mismatch: have %v, want %v", err, errMissingHead)
+	}
+	genesis := gspec.MustCommit(db)
+
+	// Create an archive chain so every state is persisted
+	cacheConfig := &core.CacheConfig{TrieCleanLimit: 256, TrieDirtyDisabled: true}
+	chain, err := core.NewBlockChain(db, cacheConfig, gspec.Config, ethash.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	blocks, _ := core.GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, 10, func(i int, gen *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(gen.TxNonce(addr), common.Address{byte(i + 1)}, big.NewInt(1), params.TxGas, nil, nil), signer, key)
+		gen.AddTx(tx)
+	})
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	chain.Stop()
+
+	// Consensus engine snapshots share the database, but are never pruned
+	voteKey := append([]byte("istanbul-snapshot"), blocks[4].Hash().Bytes()...)
+	if err := db.Put(voteKey, []byte{0x01}); err != nil {
+		t.Fatalf("failed to write vote snapshot: %v", err)
+	}
+	for i, block := range blocks {
+		if !completeState(db, block.Root()) {
+			t.Fatalf("state of block #%d missing before pruning", i+1)
+		}
+	}
+	// Retain the last two states beneath the head and an older one explicitly
+	config := Config{Retain: 2, BloomSize: 1, Blocks: []common.Hash{blocks[2].Hash()}}
+	if err := NewPruner(db, config).Prune(); err != nil {
+		t.Fatalf("failed to prune state: %v", err)
+	}
+	for i, block := range blocks {
+		retained := i >= 7 || i == 2
+		if have := completeState(db, block.Root()); have != retained {
+			t.Errorf("state of block #%d presence mismatch: have %v, want %v", i+1, have, retained)
+		}
+	}
+	if !completeState(db, genesis.Root()) {
+		t.Errorf("genesis state pruned")
+	}
+	if has, _ := db.Has(crypto.Keccak256(code)); !has {
+		t.Errorf("contract code pruned")
+	}
+	if has, _ := db.Has(voteKey); !has {
+		t.Errorf("vote snapshot pruned")
+	}
+	if rawdb.ReadBlock(db, blocks[0].Hash(), 1) == nil {
+		t.Errorf("block pruned")
+	}
+}
@@ -0,0 +1,218 @@
+// Copyright 2020 The go-simplechain Authors
+// This file is part of the go-simplechain library.
+//
+// The go-simplechain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-simplechain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-simplechain library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/VictoriaMetrics/fastcache"
+	"github.com/simplechain-org/go-simplechain/common"
+	"github.com/simplechain-org/go-simplechain/core/rawdb"
+	"github.com/simplechain-org/go-simplechain/ethdb"
+	"github.com/simplechain-org/go-simplechain/log"
+	"github.com/simplechain-org/go-simplechain/rlp"
+	"github.com/simplechain-org/go-simplechain/trie"
+)
+
+// generatorStats is a collection of statistics gathered by the snapshot generator
+// for logging purposes.
+type generatorStats struct {
+	start    time.Time          // Timestamp when generation started
+	logged   time.Time          // Timestamp when the progress was last reported
+	accounts uint64             // Number of accounts indexed
+	slots    uint64             // Number of storage slots indexed
+	storage  common.StorageSize // Account and storage slot size
+}
+
+// Log creates an contextual log with the given message and the context pulled
+// from the internally maintained statistics.
+func (gs *generatorStats) Log(msg string, root common.Hash, marker []byte) {
+	ctx := []interface{}{
+		"root", root, "accounts", gs.accounts, "slots", gs.slots,
+		"storage", gs.storage, "elapsed", common.PrettyDuration(time.Since(gs.start)),
+	}
+	if len(marker) > 0 {
+		ctx = append(ctx, "at", common.BytesToHash(marker))
+	}
+	log.Info(msg, ctx...)
+}
+
+// generateSnapshot regenerates a brand new snapshot based on an existing state
+// database and head block asynchronously. The snapshot is returned immediately
+// and generation is continued in the background until done.
+func generateSnapshot(diskdb ethdb.KeyValueStore, triedb *trie.Database, cache int, root common.Hash) *diskLayer {
+	// Wipe any previously existing snapshot from the database
+	if err := wipeSnapshot(diskdb); err != nil {
+		log.Crit("Failed to wipe state snapshot", "err", err)
+	}
+	base := &diskLayer{
+		diskdb:    diskdb,
+		triedb:    triedb,
+		root:      root,
+		cache:     fastcache.New(cache * 1024 * 1024),
+		genMarker: []byte{}, // Initialized but empty!
+		genAbort:  make(chan chan struct{}),
+		genStats:  &generatorStats{start: time.Now(), logged: time.Now()},
+	}
+	go base.generate()
+	return base
+}
+
+// wipeSnapshot deletes all the account and storage entries of the snapshot, as
+// well as its root marker, from the database.
+func wipeSnapshot(db ethdb.KeyValueStore) error {
+	batch := db.NewBatch()
+	rawdb.DeleteSnapshotRoot(batch)
+
+	for _, wipe := range []struct {
+		prefix []byte
+		keylen int
+	}{
+		{rawdb.SnapshotAccountPrefix, len(rawdb.SnapshotAccountPrefix) + common.HashLength},
+		{rawdb.SnapshotStoragePrefix, len(rawdb.SnapshotStoragePrefix) + 2*common.HashLength},
+	} {
+		it := db.NewIteratorWithPrefix(wipe.prefix)
+		for it.Next() {
+			// Skip any keys with the correct prefix but wrong length (trie nodes)
+			if len(it.Key()) != wipe.keylen {
+				continue
+			}
+			if err := batch.Delete(it.Key()); err != nil {
+				it.Release()
+				return err
+			}
+			if batch.ValueSize() > ethdb.IdealBatchSize {
+				if err := batch.Write(); err != nil {
+					it.Release()
+					return err
+				}
+				batch.Reset()
+			}
+		}
+		it.Release()
+	}
+	return batch.Write()
+}
+
+// generate is a background thread that iterates over the state and storage tries
+// of the layer, constructing the state snapshot. It surfs the blocks: as soon as
+// the layer is flattened into, generation stops and resumes on the new layer
+// from the last persisted account.
+func (dl *diskLayer) generate() {
+	var (
+		stats  = dl.genStats
+		marker = dl.genMarker
+	)
+	accTrie, err := trie.NewSecure(dl.root, dl.triedb)
+	if err != nil {
+		dl.pause(err)
+		return
+	}
+	var (
+		batch = dl.diskdb.NewBatch()
+		accIt = trie.NewIterator(accTrie.NodeIterator(marker))
+	)
+	for accIt.Next() {
+		// The iterator starts at the marker, which was already generated
+		if bytes.Equal(accIt.Key, marker) {
+			continue
+		}
+		accountHash := common.BytesToHash(accIt.Key)
+
+		var acc fullAccount
+		if err := rlp.DecodeBytes(accIt.Value, &acc); err != nil {
+			log.Crit("Invalid account encountered during snapshot creation", "err", err)
+		}
+		data := SlimAccountRLP(acc.Nonce, acc.Balance, acc.Root, acc.CodeHash)
+		rawdb.WriteAccountSnapshot(batch, accountHash, data)
+		stats.storage += common.StorageSize(1 + common.HashLength + len(data))
+		stats.accounts++
+
+		// If the account has storage, index all the slots too
+		if acc.Root != emptyRoot {
+			storeTrie, err := trie.NewSecure(acc.Root, dl.triedb)
+			if err != nil {
+				dl.pause(err)
+				return
+			}
+			storeIt := trie.NewIterator(storeTrie.NodeIterator(nil))
+			for storeIt.Next() {
+				rawdb.WriteStorageSnapshot(batch, accountHash, common.BytesToHash(storeIt.Key), storeIt.Value)
+				stats.storage += common.StorageSize(1 + 2*common.HashLength + len(storeIt.Value))
+				stats.slots++
+			}
+			if storeIt.Err != nil {
+				dl.pause(storeIt.Err)
+				return
+			}
+		}
+		// The account is complete, persist the progress if the batch grew large
+		// or if somebody is waiting for the generator to stop
+		var abort chan struct{}
+		select {
+		case abort = <-dl.genAbort:
+		default:
+		}
+		if batch.ValueSize() > ethdb.IdealBatchSize || abort != nil {
+			if err := batch.Write(); err != nil {
+				log.Crit("Failed to write state snapshot", "err", err)
+			}
+			batch.Reset()
+
+			dl.lock.Lock()
+			dl.genMarker = accountHash[:]
+			dl.lock.Unlock()
+		}
+		if abort != nil {
+			close(abort)
+			return
+		}
+		if time.Since(stats.logged) > 8*time.Second {
+			stats.Log("Generating state snapshot", dl.root, accountHash[:])
+			stats.logged = time.Now()
+		}
+	}
+	if accIt.Err != nil {
+		dl.pause(accIt.Err)
+		return
+	}
+	// Snapshot fully generated, set the marker to nil
+	dl.lock.Lock()
+	rawdb.WriteSnapshotRoot(batch, dl.root)
+	if err := batch.Write(); err != nil {
+		log.Crit("Failed to write state snapshot", "err", err)
+	}
+	dl.genMarker = nil
+	dl.lock.Unlock()
+
+	stats.Log("Generated state snapshot", dl.root, nil)
+
+	// Someone will be looking for us, wait it out
+	abort := <-dl.genAbort
+	close(abort)
+}
+
+// pause suspends the generation after a failure to read the tries of the layer,
+// e.g. because they were already garbage collected. It is resumed on the state
+// of the next block flattened into the snapshot.
+func (dl *diskLayer) pause(err error) {
+	log.Debug("Snapshot generation paused", "root", dl.root, "err", err)
+
+	abort := <-dl.genAbort
+	close(abort)
+}
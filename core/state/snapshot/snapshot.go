@@ -0,0 +1,408 @@
+// Copyright 2020 The go-simplechain Authors
+// This file is part of the go-simplechain library.
+//
+// The go-simplechain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-simplechain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-simplechain library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package snapshot implements a layered, dynamic state dump.
+//
+// The snapshot keeps the leaves of the account and storage tries of a recent
+// state in a flat key-value layout, so they can be read in O(1) disk accesses
+// instead of walking the tries. The persisted state lags behind the chain head,
+// the state transitions of the most recent blocks are kept in memory as diff
+// layers stacked on top of it.
+package snapshot
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/VictoriaMetrics/fastcache"
+	"github.com/simplechain-org/go-simplechain/common"
+	"github.com/simplechain-org/go-simplechain/core/rawdb"
+	"github.com/simplechain-org/go-simplechain/crypto"
+	"github.com/simplechain-org/go-simplechain/ethdb"
+	"github.com/simplechain-org/go-simplechain/log"
+	"github.com/simplechain-org/go-simplechain/metrics"
+	"github.com/simplechain-org/go-simplechain/trie"
+)
+
+var (
+	snapshotCleanAccountHitMeter  = metrics.NewRegisteredMeter("state/snapshot/clean/account/hit", nil)
+	snapshotCleanAccountMissMeter = metrics.NewRegisteredMeter("state/snapshot/clean/account/miss", nil)
+	snapshotCleanStorageHitMeter  = metrics.NewRegisteredMeter("state/snapshot/clean/storage/hit", nil)
+	snapshotCleanStorageMissMeter = metrics.NewRegisteredMeter("state/snapshot/clean/storage/miss", nil)
+
+	snapshotFlushAccountItemMeter = metrics.NewRegisteredMeter("state/snapshot/flush/account/item", nil)
+	snapshotFlushStorageItemMeter = metrics.NewRegisteredMeter("state/snapshot/flush/storage/item", nil)
+)
+
+var (
+	// emptyRoot is the known root hash of an empty trie.
+	emptyRoot = common.HexToHash("56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421")
+
+	// emptyCode is the known hash of the empty EVM bytecode.
+	emptyCode = crypto.Keccak256Hash(nil)
+)
+
+var (
+	// ErrSnapshotStale is returned from data accessors if the underlying snapshot
+	// layer had been invalidated due to the chain progressing forward far enough
+	// to not maintain the layer's original state.
+	ErrSnapshotStale = errors.New("snapshot stale")
+
+	// ErrNotCoveredYet is returned from data accessors if the underlying snapshot
+	// is being generated currently and the requested data item is not yet in the
+	// range of accounts covered.
+	ErrNotCoveredYet = errors.New("not covered yet")
+
+	// errSnapshotCycle is returned if a snapshot is attempted to be inserted
+	// that forms a cycle in the snapshot tree.
+	errSnapshotCycle = errors.New("snapshot cycle")
+)
+
+// Snapshot represents the functionality supported by a snapshot storage layer.
+type Snapshot interface {
+	// Root returns the root hash for which this snapshot was made.
+	Root() common.Hash
+
+	// Account directly retrieves the account associated with a particular hash in
+	// the snapshot slim data format.
+	Account(hash common.Hash) (*Account, error)
+
+	// AccountRLP directly retrieves the account RLP associated with a particular
+	// hash in the snapshot slim data format.
+	AccountRLP(hash common.Hash) ([]byte, error)
+
+	// Storage directly retrieves the storage data associated with a particular hash,
+	// within a particular account.
+	Storage(accountHash, storageHash common.Hash) ([]byte, error)
+}
+
+// snapshot is the internal version of the snapshot data layer that supports some
+// additional methods compared to the public API.
+type snapshot interface {
+	Snapshot
+
+	// Parent returns the subsequent layer of a snapshot, or nil if the base was
+	// reached.
+	Parent() snapshot
+
+	// Update creates a new layer on top of the existing snapshot diff tree with
+	// the specified data items.
+	Update(blockRoot common.Hash, destructs map[common.Hash]struct{}, accounts map[common.Hash][]byte, storage map[common.Hash]map[common.Hash][]byte) *diffLayer
+
+	// Stale return whether this layer has become stale (was flattened across) or
+	// if it's still live.
+	Stale() bool
+}
+
+// Tree is an Ethereum state snapshot tree. It consists of one persistent base
+// layer backed by a key-value store, on top of which arbitrarily many in-memory
+// diff layers are topped. The memory diffs can form a tree with branching, but
+// the disk layer is singleton and common to all. If a reorg goes deeper than the
+// disk layer, everything needs to be deleted.
+//
+// The goal of a state snapshot is to allow direct access to account and storage
+// data to avoid expensive multi-level trie lookups.
+type Tree struct {
+	diskdb ethdb.KeyValueStore      // Persistent database to store the snapshot
+	triedb *trie.Database           // In-memory cache to access the trie through
+	cache  int                      // Megabytes permitted to use for read caches
+	layers map[common.Hash]snapshot // Collection of all known layers
+	lock   sync.RWMutex
+}
+
+// New attempts to load an already existing snapshot from a persistent key-value
+// store, ensuring that the head of the snapshot matches the expected one.
+//
+// If the snapshot is missing or inconsistent, the entirety is deleted and will
+// be reconstructed from scratch based on the tries in the key-value store, on a
+// background thread.
+func New(diskdb ethdb.KeyValueStore, triedb *trie.Database, cache int, root common.Hash) *Tree {
+	snap := &Tree{
+		diskdb: diskdb,
+		triedb: triedb,
+		cache:  cache,
+		layers: make(map[common.Hash]snapshot),
+	}
+	if rawdb.ReadSnapshotRoot(diskdb) == root {
+		snap.layers[root] = &diskLayer{
+			diskdb: diskdb,
+			triedb: triedb,
+			cache:  fastcache.New(cache * 1024 * 1024),
+			root:   root,
+		}
+		log.Info("Loaded state snapshot", "root", root)
+	} else {
+		snap.layers[root] = generateSnapshot(diskdb, triedb, cache, root)
+	}
+	return snap
+}
+
+// Snapshot retrieves a snapshot belonging to the given block root, or nil if no
+// snapshot is maintained for that block.
+func (t *Tree) Snapshot(blockRoot common.Hash) Snapshot {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	return t.layers[blockRoot]
+}
+
+// Update adds a new snapshot into the tree, if that can be linked to an existing
+// old parent. It is disallowed to insert a disk layer (the origin of all).
+func (t *Tree) Update(blockRoot common.Hash, parentRoot common.Hash, destructs map[common.Hash]struct{}, accounts map[common.Hash][]byte, storage map[common.Hash]map[common.Hash][]byte) error {
+	// Reject noop updates to avoid self-loops in the snapshot tree. This is a
+	// special case that can only happen for Clique networks where empty blocks
+	// don't modify the state (0 block subsidy).
+	//
+	// Although we could silently ignore this internally, it should be the caller's
+	// responsibility to avoid even attempting to insert such a snapshot.
+	if blockRoot == parentRoot {
+		return errSnapshotCycle
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	// The layers are determined by their root, a block processed again (e.g. on
+	// a reorg back to a seen branch) already has its layer.
+	if _, ok := t.layers[blockRoot]; ok {
+		return nil
+	}
+	parent := t.layers[parentRoot]
+	if parent == nil {
+		return fmt.Errorf("parent [%#x] snapshot missing", parentRoot)
+	}
+	t.layers[blockRoot] = parent.Update(blockRoot, destructs, accounts, storage)
+	return nil
+}
+
+// Cap traverses downwards the snapshot tree from a head block hash until the
+// number of allowed layers are crossed. All layers beyond the permitted number
+// are flattened downwards into the persistent disk layer. Layers on branches
+// not descending from the retained ones are dropped.
+//
+// With zero layers permitted, the entire tree up to the given root is persisted
+// and the disk layer becomes the root.
+func (t *Tree) Cap(root common.Hash, layers int) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	snap := t.layers[root]
+	if snap == nil {
+		return fmt.Errorf("snapshot [%#x] missing", root)
+	}
+	diff, ok := snap.(*diffLayer)
+	if !ok {
+		return nil // Already the disk layer, nothing to cap
+	}
+	// Find the topmost layer to persist: the one right below the retained ones
+	var keep *diffLayer
+	for i := 0; i < layers; i++ {
+		parent, ok := diff.Parent().(*diffLayer)
+		if !ok {
+			return nil // Not enough layers to flatten any
+		}
+		keep, diff = diff, parent
+	}
+	// Persist the layers from the bottom up, each one into a new disk layer
+	var chain []*diffLayer
+	for layer := snap; layer != nil; layer = layer.Parent() {
+		if d, ok := layer.(*diffLayer); ok && (len(chain) > 0 || d == diff) {
+			chain = append(chain, d)
+		}
+	}
+	var base *diskLayer
+	for i := len(chain) - 1; i >= 0; i-- {
+		if base != nil {
+			chain[i].lock.Lock()
+			chain[i].parent = base
+			chain[i].lock.Unlock()
+		}
+		base = diffToDisk(chain[i])
+	}
+	if keep != nil {
+		keep.lock.Lock()
+		keep.parent = base
+		keep.lock.Unlock()
+	}
+	// Drop all the layers which don't descend from the new base any more
+	remove := func(layer snapshot) bool {
+		for ; layer != nil; layer = layer.Parent() {
+			if layer == snapshot(base) {
+				return false
+			}
+			if layer.Stale() {
+				return true
+			}
+		}
+		return true
+	}
+	for root, layer := range t.layers {
+		if remove(layer) {
+			delete(t.layers, root)
+		}
+	}
+	t.layers[base.root] = base
+	return nil
+}
+
+// diffToDisk merges a bottom-most diff into the persistent disk layer underneath
+// it. The method will panic if called onto a non-bottom-most diff layer.
+func diffToDisk(bottom *diffLayer) *diskLayer {
+	var (
+		base  = bottom.parent.(*diskLayer)
+		batch = base.diskdb.NewBatch()
+	)
+	// Stop the generator while the disk layer changes below it
+	if base.genAbort != nil {
+		abort := make(chan struct{})
+		base.genAbort <- abort
+		<-abort
+	}
+	// Start by temporarily deleting the current snapshot block marker. This
+	// ensures that in the case of a crash, the entire snapshot is invalidated.
+	rawdb.DeleteSnapshotRoot(batch)
+
+	// Mark the original base as stale as we're going to create a new wrapper
+	base.lock.Lock()
+	if base.stale {
+		panic("parent disk layer is stale") // we've committed into the same base from two children, boo
+	}
+	base.stale = true
+	base.lock.Unlock()
+
+	// Destroy all the destructed accounts from the database
+	for hash := range bottom.destructSet {
+		// Skip any account not covered yet by the snapshot
+		if !base.covered(hash) {
+			continue
+		}
+		rawdb.DeleteAccountSnapshot(batch, hash)
+		base.cache.Set(hash[:], nil)
+
+		it := rawdb.IterateStorageSnapshots(base.diskdb, hash)
+		for it.Next() {
+			if key := it.Key(); len(key) == len(rawdb.SnapshotStoragePrefix)+2*common.HashLength {
+				batch.Delete(key)
+				base.cache.Del(key[len(rawdb.SnapshotStoragePrefix):])
+			}
+		}
+		it.Release()
+		snapshotFlushAccountItemMeter.Mark(1)
+	}
+	// Push all updated accounts into the database
+	for hash, data := range bottom.accountData {
+		if !base.covered(hash) {
+			continue
+		}
+		if len(data) > 0 {
+			rawdb.WriteAccountSnapshot(batch, hash, data)
+		} else {
+			rawdb.DeleteAccountSnapshot(batch, hash)
+		}
+		base.cache.Set(hash[:], data)
+		snapshotFlushAccountItemMeter.Mark(1)
+	}
+	// Push all the storage slots into the database
+	for accountHash, storage := range bottom.storageData {
+		if !base.covered(accountHash) {
+			continue
+		}
+		for storageHash, data := range storage {
+			if len(data) > 0 {
+				rawdb.WriteStorageSnapshot(batch, accountHash, storageHash, data)
+			} else {
+				rawdb.DeleteStorageSnapshot(batch, accountHash, storageHash)
+			}
+			base.cache.Set(append(accountHash[:], storageHash[:]...), data)
+			snapshotFlushStorageItemMeter.Mark(1)
+		}
+	}
+	// Update the snapshot block marker and write any remainder data
+	if base.genMarker == nil {
+		rawdb.WriteSnapshotRoot(batch, bottom.root)
+	}
+	if err := batch.Write(); err != nil {
+		log.Crit("Failed to write leftover snapshot", "err", err)
+	}
+	res := &diskLayer{
+		root:      bottom.root,
+		cache:     base.cache,
+		diskdb:    base.diskdb,
+		triedb:    base.triedb,
+		genMarker: base.genMarker,
+		genStats:  base.genStats,
+	}
+	bottom.lock.Lock()
+	bottom.stale = true
+	bottom.lock.Unlock()
+
+	// If snapshot generation hasn't finished yet, port over all the stats and
+	// continue where the previous round left off.
+	if res.genMarker != nil {
+		res.genAbort = make(chan chan struct{})
+		go res.generate()
+	}
+	return res
+}
+
+// Rebuild wipes all available snapshot data from the persistent database and
+// discard all caches and diff layers. Afterwards, it starts a new snapshot
+// generator with the given root hash.
+func (t *Tree) Rebuild(root common.Hash) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.release()
+
+	// Start generating a new snapshot from scratch on a background thread
+	log.Info("Rebuilding state snapshot")
+	t.layers = map[common.Hash]snapshot{
+		root: generateSnapshot(t.diskdb, t.triedb, t.cache, root),
+	}
+}
+
+// Release stops the background generation of the snapshot, if any. The tree
+// must not be used afterwards.
+func (t *Tree) Release() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.release()
+}
+
+// release marks all the layers stale and stops the generator of the disk layer.
+// The caller must hold the tree lock.
+func (t *Tree) release() {
+	for _, layer := range t.layers {
+		switch layer := layer.(type) {
+		case *diskLayer:
+			layer.lock.Lock()
+			stale := layer.stale
+			layer.stale = true
+			layer.lock.Unlock()
+
+			if !stale && layer.genAbort != nil {
+				abort := make(chan struct{})
+				layer.genAbort <- abort
+				<-abort
+			}
+		case *diffLayer:
+			layer.lock.Lock()
+			layer.stale = true
+			layer.lock.Unlock()
+		}
+	}
+}
@@ -0,0 +1,193 @@
+// Copyright 2020 The go-simplechain Authors
+// This file is part of the go-simplechain library.
+//
+// The go-simplechain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-simplechain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-simplechain library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/simplechain-org/go-simplechain/common"
+	"github.com/simplechain-org/go-simplechain/core/rawdb"
+	"github.com/simplechain-org/go-simplechain/ethdb/memorydb"
+	"github.com/simplechain-org/go-simplechain/rlp"
+	"github.com/simplechain-org/go-simplechain/trie"
+)
+
+// testHash is a deterministic stand-in for hashes used as keys and roots.
+func testHash(b byte) common.Hash {
+	return common.Hash{b}
+}
+
+// newTestTree creates a snapshot tree on top of a complete, empty disk layer.
+func newTestTree(base common.Hash) (*Tree, *memorydb.Database) {
+	db := memorydb.New()
+	rawdb.WriteSnapshotRoot(db, base)
+	return New(db, trie.NewDatabase(db), 16, base), db
+}
+
+// Tests that lookups are resolved through the diff layers, honouring account
+// destructions, and that capping the tree flattens the layers into the disk.
+func TestDiffLayers(t *testing.T) {
+	var (
+		base = testHash(0xb0)
+		acc1 = testHash(0x01)
+		acc2 = testHash(0x02)
+		slot = testHash(0xaa)
+	)
+	tree, db := newTestTree(base)
+	defer tree.Release()
+
+	rawdb.WriteAccountSnapshot(db, acc2, []byte("acc2-old"))
+	rawdb.WriteStorageSnapshot(db, acc2, slot, []byte("slot-old"))
+
+	// Block 1 modifies the first account, block 2 destroys the second one and
+	// block 3 resurrects it without storage
+	update := func(root, parent common.Hash, destructs map[common.Hash]struct{}, accounts map[common.Hash][]byte, storage map[common.Hash]map[common.Hash][]byte) {
+		if err := tree.Update(root, parent, destructs, accounts, storage); err != nil {
+			t.Fatalf("failed to update %x: %v", root, err)
+		}
+	}
+	update(testHash(0xd1), base, nil, map[common.Hash][]byte{acc1: []byte("acc1-new")}, nil)
+	update(testHash(0xd2), testHash(0xd1), map[common.Hash]struct{}{acc2: {}}, nil, nil)
+	update(testHash(0xd3), testHash(0xd2), nil, map[common.Hash][]byte{acc2: []byte("acc2-new")}, nil)
+	update(testHash(0xe2), testHash(0xd1), nil, nil, nil) // Side branch
+
+	if err := tree.Update(testHash(0xd4), testHash(0xff), nil, nil, nil); err == nil {
+		t.Errorf("update on missing parent succeeded")
+	}
+	check := func(root common.Hash, account common.Hash, want []byte, wantSlot []byte) {
+		t.Helper()
+
+		snap := tree.Snapshot(root)
+		if have, err := snap.AccountRLP(account); err != nil || !bytes.Equal(have, want) {
+			t.Errorf("root %x: account %x mismatch: have %q, %v, want %q", root[:1], account[:1], have, err, want)
+		}
+		if have, err := snap.Storage(account, slot); err != nil || !bytes.Equal(have, wantSlot) {
+			t.Errorf("root %x: slot of %x mismatch: have %q, %v, want %q", root[:1], account[:1], have, err, wantSlot)
+		}
+	}
+	check(base, acc1, nil, nil)
+	check(testHash(0xd1), acc1, []byte("acc1-new"), nil)
+	check(testHash(0xd1), acc2, []byte("acc2-old"), []byte("slot-old"))
+	check(testHash(0xd2), acc2, nil, nil)
+	check(testHash(0xd3), acc2, []byte("acc2-new"), nil)
+	check(testHash(0xe2), acc2, []byte("acc2-old"), []byte("slot-old"))
+
+	// Keep a single diff layer, flattening the rest into the disk
+	stale := tree.Snapshot(testHash(0xd1))
+	if err := tree.Cap(testHash(0xd3), 1); err != nil {
+		t.Fatalf("failed to cap tree: %v", err)
+	}
+	if _, err := stale.AccountRLP(acc1); err != ErrSnapshotStale {
+		t.Errorf("flattened layer error mismatch: have %v, want %v", err, ErrSnapshotStale)
+	}
+	if tree.Snapshot(testHash(0xe2)) != nil {
+		t.Errorf("side branch retained after flattening its parent")
+	}
+	if root := rawdb.ReadSnapshotRoot(db); root != testHash(0xd2) {
+		t.Errorf("persisted root mismatch: have %x, want %x", root, testHash(0xd2))
+	}
+	if blob := rawdb.ReadStorageSnapshot(db, acc2, slot); blob != nil {
+		t.Errorf("storage of destructed account persisted: %q", blob)
+	}
+	check(testHash(0xd3), acc1, []byte("acc1-new"), nil)
+	check(testHash(0xd3), acc2, []byte("acc2-new"), nil)
+
+	// Flatten everything
+	if err := tree.Cap(testHash(0xd3), 0); err != nil {
+		t.Fatalf("failed to flatten tree: %v", err)
+	}
+	if root := rawdb.ReadSnapshotRoot(db); root != testHash(0xd3) {
+		t.Errorf("persisted root mismatch: have %x, want %x", root, testHash(0xd3))
+	}
+	if blob := rawdb.ReadAccountSnapshot(db, acc2); !bytes.Equal(blob, []byte("acc2-new")) {
+		t.Errorf("persisted account mismatch: have %q, want %q", blob, "acc2-new")
+	}
+	check(testHash(0xd3), acc1, []byte("acc1-new"), nil)
+}
+
+// makeTestState creates a state trie with a few accounts, one of them having
+// storage, and commits it to the database.
+func makeTestState(t *testing.T, triedb *trie.Database) common.Hash {
+	storage, _ := trie.NewSecure(common.Hash{}, triedb)
+	for i := byte(1); i <= 3; i++ {
+		value, _ := rlp.EncodeToBytes([]byte{i})
+		storage.Update([]byte{i}, value)
+	}
+	storageRoot, err := storage.Commit(nil)
+	if err != nil {
+		t.Fatalf("failed to commit storage: %v", err)
+	}
+	accounts, _ := trie.NewSecure(common.Hash{}, triedb)
+	for i := byte(1); i <= 10; i++ {
+		acc := fullAccount{Nonce: uint64(i), Balance: big.NewInt(int64(i)), Root: emptyRoot, CodeHash: emptyCode[:]}
+		if i == 5 {
+			acc.Root = storageRoot
+		}
+		blob, _ := rlp.EncodeToBytes(acc)
+		accounts.Update(common.LeftPadBytes([]byte{i}, 20), blob)
+	}
+	root, err := accounts.Commit(nil)
+	if err != nil {
+		t.Fatalf("failed to commit accounts: %v", err)
+	}
+	if err := triedb.Commit(root, false); err != nil {
+		t.Fatalf("failed to commit tries: %v", err)
+	}
+	return root
+}
+
+// Tests that a snapshot is generated from the state trie in the background,
+// and that verification detects a snapshot deviating from the trie.
+func TestGenerateAndVerify(t *testing.T) {
+	var (
+		db     = memorydb.New()
+		triedb = trie.NewDatabase(db)
+		root   = makeTestState(t, triedb)
+	)
+	tree := New(db, triedb, 16, root)
+	defer tree.Release()
+
+	for deadline := time.Now().Add(5 * time.Second); rawdb.ReadSnapshotRoot(db) != root; {
+		if time.Now().After(deadline) {
+			t.Fatalf("snapshot generation timed out")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	stats, err := Verify(db, triedb, root)
+	if err != nil {
+		t.Fatalf("failed to verify snapshot: %v", err)
+	}
+	if stats.Accounts != 10 || stats.Slots != 3 {
+		t.Errorf("verified entries mismatch: have %d/%d, want 10/3", stats.Accounts, stats.Slots)
+	}
+	acc, err := tree.Snapshot(root).Account(testHash(0)) // Unknown account
+	if err != nil || acc != nil {
+		t.Errorf("unknown account retrieved: %v, %v", acc, err)
+	}
+	// Tamper with the snapshot and ensure it's detected
+	rawdb.WriteStorageSnapshot(db, testHash(0x42), testHash(0x42), []byte{0x42})
+	if _, err := Verify(db, triedb, root); err == nil {
+		t.Errorf("dangling storage slot not detected")
+	}
+	rawdb.WriteAccountSnapshot(db, testHash(0x42), []byte{0x42})
+	if _, err := Verify(db, triedb, root); err == nil {
+		t.Errorf("dangling account not detected")
+	}
+}
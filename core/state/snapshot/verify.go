@@ -0,0 +1,166 @@
+// Copyright 2020 The go-simplechain Authors
+// This file is part of the go-simplechain library.
+//
+// The go-simplechain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-simplechain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-simplechain library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/simplechain-org/go-simplechain/common"
+	"github.com/simplechain-org/go-simplechain/core/rawdb"
+	"github.com/simplechain-org/go-simplechain/ethdb"
+	"github.com/simplechain-org/go-simplechain/log"
+	"github.com/simplechain-org/go-simplechain/rlp"
+	"github.com/simplechain-org/go-simplechain/trie"
+)
+
+// VerifyStats contains the number of entries checked by Verify.
+type VerifyStats struct {
+	Accounts uint64 // Number of accounts found identical in the trie and snapshot
+	Slots    uint64 // Number of storage slots found identical in the trie and snapshot
+}
+
+// Verify checks the persisted snapshot in the database against the state trie
+// with the given root. Every trie leaf must have an identical snapshot entry and
+// the snapshot mustn't contain any entry missing from the tries.
+func Verify(diskdb ethdb.KeyValueStore, triedb *trie.Database, root common.Hash) (*VerifyStats, error) {
+	if have := rawdb.ReadSnapshotRoot(diskdb); have != root {
+		return nil, fmt.Errorf("snapshot root mismatch: have %#x, want %#x", have, root)
+	}
+	accTrie, err := trie.NewSecure(root, triedb)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		stats  = new(VerifyStats)
+		start  = time.Now()
+		logged = time.Now()
+	)
+	accIt := trie.NewIterator(accTrie.NodeIterator(nil))
+	snapIt := diskdb.NewIteratorWithPrefix(rawdb.SnapshotAccountPrefix)
+	defer snapIt.Release()
+
+	err = compareLeaves(accIt, snapIt, rawdb.SnapshotAccountPrefix, "account", func(hash common.Hash, leaf []byte, entry []byte) error {
+		slim, err := slimAccountRLPFromFull(leaf)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(slim, entry) {
+			return fmt.Errorf("account %#x mismatch: trie %x, snapshot %x", hash, slim, entry)
+		}
+		stats.Accounts++
+
+		var acc fullAccount
+		if err := rlp.DecodeBytes(leaf, &acc); err != nil {
+			return err
+		}
+		// Compare the storage of the account, if any
+		storeTrie, err := trie.NewSecure(acc.Root, triedb)
+		if err != nil {
+			return err
+		}
+		prefix := append(common.CopyBytes(rawdb.SnapshotStoragePrefix), hash[:]...)
+		slotIt := rawdb.IterateStorageSnapshots(diskdb, hash)
+		defer slotIt.Release()
+
+		err = compareLeaves(trie.NewIterator(storeTrie.NodeIterator(nil)), slotIt, prefix, "storage slot", func(slot common.Hash, leaf []byte, entry []byte) error {
+			if !bytes.Equal(leaf, entry) {
+				return fmt.Errorf("storage slot %#x of account %#x mismatch: trie %x, snapshot %x", slot, hash, leaf, entry)
+			}
+			stats.Slots++
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Verifying state snapshot", "accounts", stats.Accounts, "slots", stats.Slots, "at", hash, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+		return nil
+	})
+	if err != nil {
+		return stats, err
+	}
+	// The storage of every existing account was checked, anything else in the
+	// snapshot belongs to missing accounts
+	var slots uint64
+	it := diskdb.NewIteratorWithPrefix(rawdb.SnapshotStoragePrefix)
+	defer it.Release()
+
+	for it.Next() {
+		if len(it.Key()) == len(rawdb.SnapshotStoragePrefix)+2*common.HashLength {
+			slots++
+		}
+	}
+	if err := it.Error(); err != nil {
+		return stats, err
+	}
+	if slots != stats.Slots {
+		return stats, fmt.Errorf("%d dangling storage slots in snapshot", slots-stats.Slots)
+	}
+	return stats, nil
+}
+
+// compareLeaves walks the leaves of a trie and the matching snapshot entries in
+// lockstep, both sorted by hash, and reports leaves or entries missing from the
+// other side. Matching pairs are passed to the callback to compare their values.
+func compareLeaves(trieIt *trie.Iterator, snapIt ethdb.Iterator, prefix []byte, kind string, match func(hash common.Hash, leaf []byte, entry []byte) error) error {
+	// nextEntry advances the snapshot iterator, skipping any unrelated key
+	// which happens to share the prefix
+	nextEntry := func() bool {
+		for snapIt.Next() {
+			if len(snapIt.Key()) == len(prefix)+common.HashLength {
+				return true
+			}
+		}
+		return false
+	}
+	var (
+		haveLeaf  = trieIt.Next()
+		haveEntry = nextEntry()
+	)
+	for haveLeaf || haveEntry {
+		if trieIt.Err != nil {
+			return trieIt.Err
+		}
+		var cmp int
+		switch {
+		case !haveEntry:
+			cmp = -1
+		case !haveLeaf:
+			cmp = 1
+		default:
+			cmp = bytes.Compare(trieIt.Key, snapIt.Key()[len(prefix):])
+		}
+		switch {
+		case cmp < 0:
+			return fmt.Errorf("%s %#x missing from snapshot", kind, trieIt.Key)
+		case cmp > 0:
+			return fmt.Errorf("dangling %s %#x in snapshot", kind, snapIt.Key()[len(prefix):])
+		}
+		if err := match(common.BytesToHash(trieIt.Key), trieIt.Value, snapIt.Value()); err != nil {
+			return err
+		}
+		haveLeaf, haveEntry = trieIt.Next(), nextEntry()
+	}
+	if trieIt.Err != nil {
+		return trieIt.Err
+	}
+	return snapIt.Error()
+}
@@ -28,9 +28,11 @@ import (
 	"sync"
 	"testing"
 	"testing/quick"
+	"time"
 
 	"github.com/simplechain-org/go-simplechain/common"
 	"github.com/simplechain-org/go-simplechain/core/rawdb"
+	"github.com/simplechain-org/go-simplechain/core/state/snapshot"
 	"github.com/simplechain-org/go-simplechain/core/types"
 )
 
@@ -680,3 +682,65 @@ func TestDeleteCreateRevert(t *testing.T) {
 		t.Fatalf("self-destructed contract came alive")
 	}
 }
+
+// Tests that a state backed by a snapshot reads the same data as the trie, and
+// that committing it extends the snapshot with the changes of the state.
+func TestSnapshotBackedState(t *testing.T) {
+	var (
+		diskdb = rawdb.NewMemoryDatabase()
+		db     = NewDatabase(diskdb)
+		alive  = common.HexToAddress("0x01")
+		doomed = common.HexToAddress("0x02")
+		slot   = common.HexToHash("0x03")
+	)
+	state, _ := New(common.Hash{}, db)
+	state.SetBalance(alive, big.NewInt(1))
+	state.SetState(alive, slot, common.HexToHash("0x11"))
+	state.SetBalance(doomed, big.NewInt(2))
+	state.SetState(doomed, slot, common.HexToHash("0x22"))
+	root, _ := state.Commit(false)
+	db.TrieDB().Commit(root, false)
+
+	snaps := snapshot.New(diskdb, db.TrieDB(), 16, root)
+	defer snaps.Release()
+	for deadline := time.Now().Add(5 * time.Second); rawdb.ReadSnapshotRoot(diskdb) != root; {
+		if time.Now().After(deadline) {
+			t.Fatalf("snapshot generation timed out")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	// Modify the state through the snapshot, destructing an account
+	state, _ = NewWithSnapshot(root, db, snaps)
+	if have := state.GetState(doomed, slot); have != common.HexToHash("0x22") {
+		t.Fatalf("snapshot storage mismatch: have %x, want %x", have, common.HexToHash("0x22"))
+	}
+	state.SetState(alive, slot, common.HexToHash("0x33"))
+	state.Suicide(doomed)
+	root, _ = state.Commit(true)
+
+	if snaps.Snapshot(root) == nil {
+		t.Fatalf("snapshot of committed state missing")
+	}
+	// Reads through the snapshot must match the trie
+	snapState, _ := NewWithSnapshot(root, db, snaps)
+	trieState, _ := New(root, db)
+	for _, addr := range []common.Address{alive, doomed} {
+		if have, want := snapState.GetBalance(addr), trieState.GetBalance(addr); have.Cmp(want) != 0 {
+			t.Errorf("%x: balance mismatch: have %v, want %v", addr, have, want)
+		}
+		if have, want := snapState.GetState(addr, slot), trieState.GetState(addr, slot); have != want {
+			t.Errorf("%x: storage mismatch: have %x, want %x", addr, have, want)
+		}
+		if have, want := snapState.Exist(addr), trieState.Exist(addr); have != want {
+			t.Errorf("%x: existence mismatch: have %v, want %v", addr, have, want)
+		}
+	}
+	// Persisting the snapshot must yield an exact copy of the trie
+	db.TrieDB().Commit(root, false)
+	if err := snaps.Cap(root, 0); err != nil {
+		t.Fatalf("failed to flatten snapshot: %v", err)
+	}
+	if _, err := snapshot.Verify(diskdb, db.TrieDB(), root); err != nil {
+		t.Errorf("persisted snapshot invalid: %v", err)
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright 2020 The go-simplechain Authors
+// This file is part of the go-simplechain library.
+//
+// The go-simplechain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-simplechain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-simplechain library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"runtime"
+	"sync"
+
+	"github.com/simplechain-org/go-simplechain/common"
+	"github.com/simplechain-org/go-simplechain/core/state"
+	"github.com/simplechain-org/go-simplechain/core/types"
+	"github.com/simplechain-org/go-simplechain/core/vm"
+	"github.com/simplechain-org/go-simplechain/crypto"
+	"github.com/simplechain-org/go-simplechain/metrics"
+)
+
+var (
+	parallelTxMeter       = metrics.NewRegisteredMeter("chain/parallel/txs", nil)
+	parallelConflictMeter = metrics.NewRegisteredMeter("chain/parallel/conflicts", nil)
+)
+
+// speculation is the outcome of executing a transaction on the state at the
+// beginning of its block.
+type speculation struct {
+	msg    types.Message
+	gas    uint64
+	failed bool
+	access *state.AccessSet
+	err    error
+}
+
+// applyTransactionsParallel applies the transactions of a block optimistically
+// in parallel, with the very same result as applying them one after the other.
+//
+// Every transaction is first executed concurrently on a copy of the state at the
+// beginning of the block, tracking the state it reads and modifies. Going through
+// them in order, the modifications of a transaction are then replayed on the
+// state, unless it read something modified by a preceding transaction, in which
+// case it's executed again.
+func (p *StateProcessor) applyTransactionsParallel(block *types.Block, statedb *state.StateDB, gp *GasPool, usedGas *uint64, cfg vm.Config) (types.Receipts, error) {
+	var (
+		receipts types.Receipts
+		header   = block.Header()
+		specs    = p.speculate(block, statedb, cfg)
+		modified = state.NewAccessSet()
+	)
+	for i, tx := range block.Transactions() {
+		statedb.Prepare(tx.Hash(), block.Hash(), i)
+
+		spec := specs[i]
+		if spec.err == nil && gp.Gas() >= tx.Gas() && !spec.access.Conflicts(modified) {
+			spec.access.Replay(statedb)
+			statedb.Finalise(true)
+
+			gp.SubGas(spec.gas)
+			*usedGas += spec.gas
+
+			receipt := types.NewReceipt(nil, spec.failed, *usedGas)
+			receipt.TxHash = tx.Hash()
+			receipt.GasUsed = spec.gas
+			if spec.msg.To() == nil {
+				receipt.ContractAddress = crypto.CreateAddress(spec.msg.From(), tx.Nonce())
+			}
+			receipt.Logs = statedb.GetLogs(tx.Hash())
+			receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+			receipt.BlockHash = statedb.BlockHash()
+			receipt.BlockNumber = header.Number
+			receipt.TransactionIndex = uint(statedb.TxIndex())
+
+			receipts = append(receipts, receipt)
+			modified.Merge(spec.access)
+			continue
+		}
+		// The speculation was invalidated, execute the transaction again
+		parallelConflictMeter.Mark(1)
+
+		statedb.StartAccessTracking()
+		receipt, err := ApplyTransaction(p.config, p.bc, nil, gp, statedb, header, tx, usedGas, cfg)
+		access := statedb.StopAccessTracking()
+		if err != nil {
+			return nil, err
+		}
+		receipts = append(receipts, receipt)
+		modified.Merge(access)
+	}
+	parallelTxMeter.Mark(int64(len(receipts)))
+	return receipts, nil
+}
+
+// speculate executes every transaction of a block concurrently on the given
+// state, each thread on its own copy of it.
+func (p *StateProcessor) speculate(block *types.Block, statedb *state.StateDB, cfg vm.Config) []*speculation {
+	var (
+		txs     = block.Transactions()
+		header  = block.Header()
+		specs   = make([]*speculation, len(txs))
+		tasks   = make(chan int, len(txs))
+		pending sync.WaitGroup
+	)
+	// Resolve the block author once instead of in every transaction
+	author, _ := p.engine.Author(header) // Ignore error, we're past header validation
+
+	for i := range txs {
+		tasks <- i
+	}
+	close(tasks)
+
+	threads := runtime.NumCPU()
+	if threads > len(txs) {
+		threads = len(txs)
+	}
+	pending.Add(threads)
+	for n := 0; n < threads; n++ {
+		// The state mustn't be accessed concurrently, copy it upfront
+		statedb := statedb.Copy()
+		go func() {
+			defer pending.Done()
+			for i := range tasks {
+				specs[i] = p.speculateTx(statedb, block.Hash(), i, txs[i], header, author, cfg)
+			}
+		}()
+	}
+	pending.Wait()
+	return specs
+}
+
+// speculateTx executes a transaction on the state, tracking the state accessed,
+// and reverts it afterwards.
+func (p *StateProcessor) speculateTx(statedb *state.StateDB, blockHash common.Hash, index int, tx *types.Transaction, header *types.Header, author common.Address, cfg vm.Config) *speculation {
+	msg, err := tx.AsMessage(types.MakeSigner(p.config))
+	if err != nil {
+		return &speculation{err: err}
+	}
+	statedb.Prepare(tx.Hash(), blockHash, index)
+	snap := statedb.Snapshot()
+
+	statedb.StartAccessTracking()
+	vmenv := vm.NewEVM(NewEVMContext(msg, header, p.bc, &author), statedb, p.config, cfg)
+	_, gas, failed, err := ApplyMessage(vmenv, msg, new(GasPool).AddGas(header.GasLimit))
+	access := statedb.StopAccessTracking()
+
+	if err == nil {
+		// Reads failing in the database yield empty values, don't trust them
+		err = statedb.Error()
+	}
+	statedb.RevertToSnapshot(snap)
+	return &speculation{msg: msg, gas: gas, failed: failed, access: access, err: err}
+}
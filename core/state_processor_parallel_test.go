@@ -0,0 +1,124 @@
+// Copyright 2020 The go-simplechain Authors
+// This file is part of the go-simplechain library.
+//
+// The go-simplechain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-simplechain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-simplechain library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/simplechain-org/go-simplechain/common"
+	"github.com/simplechain-org/go-simplechain/consensus/ethash"
+	"github.com/simplechain-org/go-simplechain/core/rawdb"
+	"github.com/simplechain-org/go-simplechain/core/state"
+	"github.com/simplechain-org/go-simplechain/core/types"
+	"github.com/simplechain-org/go-simplechain/core/vm"
+	"github.com/simplechain-org/go-simplechain/params"
+)
+
+// Tests that executing the transactions of blocks in parallel yields the same
+// state and receipts as executing them in order, with both independent and
+// conflicting transactions in the blocks.
+func TestParallelProcessing(t *testing.T) {
+	var (
+		// Increments the storage slot of the caller and emits a log
+		counter = common.Address{0xc1}
+		// Increments a single storage slot shared by all callers
+		shared = common.Address{0xc2}
+		// Writes a storage slot and reverts
+		reverter = common.Address{0xc3}
+		// Self destructs, sending its balance to the caller
+		destructor = common.Address{0xc4}
+
+		senders = ringKeys[:16]
+		alloc   = GenesisAlloc{
+			counter:    {Code: common.Hex2Bytes("3354600101335560006000a000"), Balance: new(big.Int)},
+			shared:     {Code: common.Hex2Bytes("60005460010160005500"), Balance: new(big.Int)},
+			reverter:   {Code: common.Hex2Bytes("600160005560006000fd"), Balance: new(big.Int)},
+			destructor: {Code: common.Hex2Bytes("33ff"), Balance: big.NewInt(1000)},
+		}
+	)
+	for _, addr := range ringAddrs[:len(senders)] {
+		alloc[addr] = GenesisAccount{Balance: benchRootFunds}
+	}
+	gspec := &Genesis{Config: params.TestChainConfig, Alloc: alloc}
+
+	gendb := rawdb.NewMemoryDatabase()
+	genesis := gspec.MustCommit(gendb)
+	blocks, _ := GenerateChain(gspec.Config, genesis, ethash.NewFaker(), gendb, 8, func(i int, gen *BlockGen) {
+		for j, key := range senders {
+			var (
+				from = ringAddrs[j]
+				to   common.Address
+				gas  = uint64(100000)
+			)
+			switch (i + j) % 6 {
+			case 0:
+				to, gas = common.BigToAddress(big.NewInt(int64(i*len(senders)+j+1)<<32)), params.TxGas
+			case 1:
+				to, gas = ringAddrs[(j+1)%len(senders)], params.TxGas
+			case 2:
+				to = counter
+			case 3:
+				to = shared
+			case 4:
+				to = reverter
+			case 5:
+				to = destructor
+			}
+			tx, _ := types.SignTx(types.NewTransaction(gen.TxNonce(from), to, big.NewInt(1), gas, big.NewInt(1), nil), types.HomesteadSigner{}, key)
+			gen.AddTx(tx)
+		}
+	})
+	db := rawdb.NewMemoryDatabase()
+	gspec.MustCommit(db)
+
+	chain, _ := NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{ParallelTxs: true}, nil)
+	defer chain.Stop()
+
+	// The block import verifies the state root, receipt root and bloom
+	if n, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to import block %d: %v", n, err)
+	}
+	// Compare the derived fields of the receipts as well
+	for i, block := range blocks {
+		parent := chain.GetBlockByHash(block.ParentHash())
+
+		serial, _ := state.New(parent.Root(), chain.StateCache())
+		want, wantLogs, wantGas, err := chain.Processor().Process(block, serial, vm.Config{})
+		if err != nil {
+			t.Fatalf("block %d: serial processing failed: %v", i, err)
+		}
+		parallel, _ := state.New(parent.Root(), chain.StateCache())
+		have, haveLogs, haveGas, err := chain.Processor().Process(block, parallel, vm.Config{ParallelTxs: true})
+		if err != nil {
+			t.Fatalf("block %d: parallel processing failed: %v", i, err)
+		}
+		if !reflect.DeepEqual(have, want) {
+			t.Errorf("block %d: receipts mismatch", i)
+		}
+		if !reflect.DeepEqual(haveLogs, wantLogs) {
+			t.Errorf("block %d: logs mismatch: have %v, want %v", i, haveLogs, wantLogs)
+		}
+		if haveGas != wantGas {
+			t.Errorf("block %d: gas mismatch: have %d, want %d", i, haveGas, wantGas)
+		}
+		if have, want := parallel.IntermediateRoot(true), serial.IntermediateRoot(true); have != want {
+			t.Errorf("block %d: state root mismatch: have %x, want %x", i, have, want)
+		}
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"math/big"
 
 	"github.com/simplechain-org/go-simplechain/common"
+	"github.com/simplechain-org/go-simplechain/core/types"
 	"github.com/simplechain-org/go-simplechain/core/vm"
 	"github.com/simplechain-org/go-simplechain/log"
 	"github.com/simplechain-org/go-simplechain/params"
@@ -65,6 +66,9 @@ type Message interface {
 	From() common.Address
 	//FromFrontier() (common.Address, error)
 	To() *common.Address
+	// Payer returns the account buying the gas, the sender unless the fees
+	// were delegated.
+	Payer() common.Address
 
 	GasPrice() *big.Int
 	Gas() uint64
@@ -72,6 +76,9 @@ type Message interface {
 
 	Nonce() uint64
 	CheckNonce() bool
+	// BlockLimited returns whether the message is a block-limit transaction,
+	// which doesn't carry a nonce.
+	BlockLimited() bool
 	Data() []byte
 }
 
@@ -155,7 +162,7 @@ func (st *StateTransition) useGas(amount uint64) error {
 
 func (st *StateTransition) buyGas() error {
 	mgval := new(big.Int).Mul(new(big.Int).SetUint64(st.msg.Gas()), st.gasPrice)
-	if st.state.GetBalance(st.msg.From()).Cmp(mgval) < 0 {
+	if st.state.GetBalance(st.msg.Payer()).Cmp(mgval) < 0 {
 		return errInsufficientBalanceForGas
 	}
 	if err := st.gp.SubGas(st.msg.Gas()); err != nil {
@@ -164,11 +171,16 @@ func (st *StateTransition) buyGas() error {
 	st.gas += st.msg.Gas()
 
 	st.initialGas = st.msg.Gas()
-	st.state.SubBalance(st.msg.From(), mgval)
+	st.state.SubBalance(st.msg.Payer(), mgval)
 	return nil
 }
 
 func (st *StateTransition) preCheck() error {
+	if st.evm.ChainConfig().IsSystemContract(st.evm.BlockNumber) {
+		if err := checkSystemContractValue(st.msg.To(), st.msg.Value()); err != nil {
+			return err
+		}
+	}
 	// Make sure this transaction's nonce is correct.
 	if st.msg.CheckNonce() {
 		nonce := st.state.GetNonce(st.msg.From())
@@ -178,9 +190,34 @@ func (st *StateTransition) preCheck() error {
 			return ErrNonceTooLow
 		}
 	}
+	// Make sure the sender is permitted to send this transaction. Calls made
+	// without nonce checks (eth_call) are never persisted, so they are open to
+	// readonly accounts too.
+	if st.msg.CheckNonce() || st.msg.BlockLimited() {
+		if err := CheckAccountPermission(st.evm.ChainConfig(), st.evm.BlockNumber, st.state, st.msg.From(), st.msg.To()); err != nil {
+			return err
+		}
+	}
 	return st.buyGas()
 }
 
+// incrementNonce bumps the nonce of the sender. Block-limit transactions don't
+// consume a nonce, so they never invalidate the pending nonce-ordered
+// transactions of their sender.
+func (st *StateTransition) incrementNonce() {
+	if !st.msg.BlockLimited() {
+		st.state.SetNonce(st.msg.From(), st.state.GetNonce(st.msg.From())+1)
+	}
+}
+
+// checkSystemContractValue rejects value sent to the DPoS system contract.
+func checkSystemContractValue(to *common.Address, value *big.Int) error {
+	if to != nil && *to == params.DPoSSystemContractAddress && value.Sign() > 0 {
+		return ErrSystemContractValue
+	}
+	return nil
+}
+
 // TransitionDb will transition the state by applying the current message and
 // returning the result including the used gas. It returns an error if failed.
 // An error indicates a consensus issue.
@@ -209,11 +246,15 @@ func (st *StateTransition) TransitionDb() (ret []byte, usedGas uint64, failed bo
 		// error.
 		vmerr error
 	)
-	if contractCreation {
+	if _, private := types.PrivatePayloadHash(st.data); private && evm.ChainConfig().IsPrivacy(evm.BlockNumber) {
+		// The payload only references the encrypted transaction, which the
+		// participants execute on their private state. Publicly it's opaque.
+		st.incrementNonce()
+	} else if contractCreation {
 		ret, _, st.gas, vmerr = evm.Create(sender, st.data, st.gas, st.value)
 	} else {
 		// Increment the nonce for the next transaction
-		st.state.SetNonce(msg.From(), st.state.GetNonce(sender.Address())+1)
+		st.incrementNonce()
 		ret, st.gas, vmerr = evm.Call(sender, st.to(), st.data, st.gas, st.value)
 	}
 	if vmerr != nil {
@@ -241,7 +282,7 @@ func (st *StateTransition) refundGas() {
 
 	// Return ETH for remaining gas, exchanged at the original rate.
 	remaining := new(big.Int).Mul(new(big.Int).SetUint64(st.gas), st.gasPrice)
-	st.state.AddBalance(st.msg.From(), remaining)
+	st.state.AddBalance(st.msg.Payer(), remaining)
 
 	// Also return remaining gas to the block gas counter so it is
 	// available for the next transaction.
@@ -0,0 +1,160 @@
+// Copyright 2020 The go-simplechain Authors
+// This file is part of the go-simplechain library.
+//
+// The go-simplechain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-simplechain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-simplechain library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/simplechain-org/go-simplechain/common"
+	"github.com/simplechain-org/go-simplechain/consensus/ethash"
+	"github.com/simplechain-org/go-simplechain/core/rawdb"
+	"github.com/simplechain-org/go-simplechain/core/state"
+	"github.com/simplechain-org/go-simplechain/core/types"
+	"github.com/simplechain-org/go-simplechain/core/vm"
+	"github.com/simplechain-org/go-simplechain/crypto"
+	"github.com/simplechain-org/go-simplechain/params"
+)
+
+// Tests that the gas of fee-delegated transactions is bought from and refunded
+// to the fee payer, while the sender only pays the value.
+func TestFeeDelegatedTransactions(t *testing.T) {
+	var (
+		key, _      = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		payerKey, _ = crypto.HexToECDSA("8a1f9a8f95be41cd7ccb6168179afb4504aefe388d1e14474d32c45c72ce7b7a")
+		addr        = crypto.PubkeyToAddress(key.PublicKey)
+		payer       = crypto.PubkeyToAddress(payerKey.PublicKey)
+		to          = common.Address{0x01}
+		funds       = big.NewInt(1000000000000000)
+	)
+	config := *params.TestChainConfig
+	config.TypedTxBlock = big.NewInt(0)
+	gspec := &Genesis{Config: &config, Alloc: GenesisAlloc{addr: {Balance: big.NewInt(1)}, payer: {Balance: funds}}}
+	signer := types.NewEIP155Signer(config.ChainID)
+
+	newTx := func(nonce uint64, value int64, sponsor *ecdsa.PrivateKey) *types.Transaction {
+		tx, err := types.SignTx(types.NewFeeDelegatedTransaction(config.ChainID, nonce, &to, big.NewInt(value), 100000, big.NewInt(1), nil, payer), signer, key)
+		if err != nil {
+			t.Fatalf("failed to sign tx: %v", err)
+		}
+		if tx, err = types.SignFeePayer(tx, signer, sponsor); err != nil {
+			t.Fatalf("failed to sign tx as fee payer: %v", err)
+		}
+		return tx
+	}
+	gendb := rawdb.NewMemoryDatabase()
+	genesis := gspec.MustCommit(gendb)
+
+	db := rawdb.NewMemoryDatabase()
+	gspec.MustCommit(db)
+
+	chain, _ := NewBlockChain(db, nil, &config, ethash.NewFaker(), vm.Config{}, nil)
+	defer chain.Stop()
+
+	blocks, _ := GenerateChain(&config, genesis, ethash.NewFaker(), gendb, 1, func(i int, gen *BlockGen) {
+		gen.AddTx(newTx(0, 1, payerKey))
+	})
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	state, _ := chain.State()
+	if balance := state.GetBalance(addr); balance.Sign() != 0 {
+		t.Errorf("sender balance mismatch: have %v, want 0", balance)
+	}
+	if balance := state.GetBalance(to); balance.Cmp(common.Big1) != 0 {
+		t.Errorf("recipient balance mismatch: have %v, want 1", balance)
+	}
+	// Only the gas used is charged, the rest is refunded to the fee payer
+	fee := new(big.Int).SetUint64(params.TxGas)
+	if balance := state.GetBalance(payer); balance.Cmp(new(big.Int).Sub(funds, fee)) != 0 {
+		t.Errorf("fee payer balance mismatch: have %v, want %v", balance, new(big.Int).Sub(funds, fee))
+	}
+	if nonce := state.GetNonce(addr); nonce != 1 {
+		t.Errorf("sender nonce mismatch: have %d, want 1", nonce)
+	}
+	// Transactions signed by anyone but the designated fee payer are invalid
+	gp := new(GasPool).AddGas(chain.CurrentHeader().GasLimit)
+	if _, err := ApplyTransaction(&config, chain, &common.Address{}, gp, state, chain.CurrentHeader(), newTx(1, 0, key), new(uint64), vm.Config{}); err != types.ErrInvalidFeePayer {
+		t.Errorf("foreign fee payer error mismatch: have %v, want %v", err, types.ErrInvalidFeePayer)
+	}
+}
+
+// Tests that value sent to the DPoS system contract is rejected once the system
+// contract fork is active, both by the pool and when applying the transaction.
+func TestSystemContractValue(t *testing.T) {
+	var (
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr   = crypto.PubkeyToAddress(key.PublicKey)
+		signer = types.HomesteadSigner{}
+	)
+	config := *params.TestChainConfig
+	config.DPoS = &params.DPoSConfig{SystemContractBlock: big.NewInt(1)}
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()))
+	statedb.SetBalance(addr, big.NewInt(1000000000))
+
+	newTx := func(nonce uint64, value int64) *types.Transaction {
+		tx, err := types.SignTx(types.NewTransaction(nonce, params.DPoSSystemContractAddress, big.NewInt(value), params.TxGas, big.NewInt(1), nil), signer, key)
+		if err != nil {
+			t.Fatalf("failed to sign tx: %v", err)
+		}
+		return tx
+	}
+	for i, tt := range []struct {
+		number int64
+		value  int64
+		err    error
+	}{
+		// Before the fork the address is a plain account
+		{0, 1, nil},
+		{1, 0, nil},
+		{1, 1, ErrSystemContractValue},
+	} {
+		msg, _ := newTx(statedb.GetNonce(addr), tt.value).AsMessage(signer)
+		context := vm.Context{
+			CanTransfer: CanTransfer,
+			Transfer:    Transfer,
+			GasLimit:    params.TxGas,
+			BlockNumber: big.NewInt(tt.number),
+			Time:        new(big.Int),
+			Difficulty:  new(big.Int),
+			GasPrice:    msg.GasPrice(),
+		}
+		evm := vm.NewEVM(context, statedb, &config, vm.Config{})
+		if _, _, _, err := ApplyMessage(evm, msg, new(GasPool).AddGas(params.TxGas)); err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+	// The pool validates against the next block, which is past the fork
+	db := rawdb.NewMemoryDatabase()
+	(&Genesis{Config: &config, Alloc: GenesisAlloc{addr: {Balance: big.NewInt(1000000000)}}}).MustCommit(db)
+	chain, _ := NewBlockChain(db, nil, &config, ethash.NewFaker(), vm.Config{}, nil)
+	defer chain.Stop()
+
+	poolConfig := DefaultTxPoolConfig
+	poolConfig.Journal = ""
+	pool := NewTxPool(poolConfig, &config, chain)
+	defer pool.Stop()
+
+	if err := pool.AddRemote(newTx(0, 1)); err != ErrSystemContractValue {
+		t.Errorf("pool error mismatch: have %v, want %v", err, ErrSystemContractValue)
+	}
+	if err := pool.AddRemote(newTx(0, 0)); err != nil {
+		t.Errorf("valueless system call rejected: %v", err)
+	}
+}
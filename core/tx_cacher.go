@@ -63,6 +63,9 @@ func (cacher *txSenderCacher) cache() {
 	for task := range cacher.tasks {
 		for i := 0; i < len(task.txs); i += task.inc {
 			types.Sender(task.signer, task.txs[i])
+			if task.txs[i].Type() == types.FeeDelegatedTxType {
+				types.Payer(task.signer, task.txs[i])
+			}
 		}
 	}
 }
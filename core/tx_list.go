@@ -288,7 +288,7 @@ func (l *txList) Add(tx *types.Transaction, priceBump uint64) (bool, *types.Tran
 	}
 	// Otherwise overwrite the old transaction with the current one
 	l.txs.Put(tx)
-	if cost := tx.Cost(); l.costcap.Cmp(cost) < 0 {
+	if cost := tx.SenderCost(); l.costcap.Cmp(cost) < 0 {
 		l.costcap = cost
 	}
 	if gas := tx.Gas(); l.gascap < gas {
@@ -323,7 +323,7 @@ func (l *txList) Filter(costLimit *big.Int, gasLimit uint64) (types.Transactions
 
 	// Filter out all the transactions above the account's funds
 	removed := l.txs.Filter(func(tx *types.Transaction) bool {
-		return tx.Gas() > gasLimit || tx.Cost().Cmp(costLimit) > 0
+		return tx.Gas() > gasLimit || tx.SenderCost().Cmp(costLimit) > 0
 	})
 
 	if len(removed) == 0 {
@@ -401,6 +401,77 @@ func (l *txList) LastElement() *types.Transaction {
 	return l.txs.LastElement()
 }
 
+// txLimitList is the set of block-limit transactions of an account, indexed by
+// their ID. Not being ordered by nonce, any of them is executable until its block
+// limit passes.
+type txLimitList struct {
+	txs map[uint64]*types.Transaction
+}
+
+// newTxLimitList creates a new block-limit transaction set.
+func newTxLimitList() *txLimitList {
+	return &txLimitList{
+		txs: make(map[uint64]*types.Transaction),
+	}
+}
+
+// Get retrieves the transaction with the given ID.
+func (l *txLimitList) Get(id uint64) *types.Transaction {
+	return l.txs[id]
+}
+
+// Add inserts a new transaction into the set, overwriting any with the same ID.
+func (l *txLimitList) Add(tx *types.Transaction) {
+	l.txs[tx.ID()] = tx
+}
+
+// Remove deletes a transaction from the set, returning whether it was found.
+func (l *txLimitList) Remove(tx *types.Transaction) bool {
+	if old := l.txs[tx.ID()]; old == nil || old.Hash() != tx.Hash() {
+		return false
+	}
+	delete(l.txs, tx.ID())
+	return true
+}
+
+// Filter removes all transactions for which the specified function evaluates to
+// true, returning them.
+func (l *txLimitList) Filter(filter func(*types.Transaction) bool) types.Transactions {
+	var removed types.Transactions
+	for id, tx := range l.txs {
+		if filter(tx) {
+			removed = append(removed, tx)
+			delete(l.txs, id)
+		}
+	}
+	return removed
+}
+
+// Flatten returns the transactions of the set, those expiring first in front.
+func (l *txLimitList) Flatten() types.Transactions {
+	txs := make(types.Transactions, 0, len(l.txs))
+	for _, tx := range l.txs {
+		txs = append(txs, tx)
+	}
+	sort.Slice(txs, func(i, j int) bool {
+		if txs[i].BlockLimit() != txs[j].BlockLimit() {
+			return txs[i].BlockLimit() < txs[j].BlockLimit()
+		}
+		return txs[i].ID() < txs[j].ID()
+	})
+	return txs
+}
+
+// Len returns the number of transactions in the set.
+func (l *txLimitList) Len() int {
+	return len(l.txs)
+}
+
+// Empty returns whether the set is empty.
+func (l *txLimitList) Empty() bool {
+	return len(l.txs) == 0
+}
+
 // priceHeap is a heap.Interface implementation over transactions for retrieving
 // price-sorted transactions to discard when the pool fills up.
 type priceHeap []*types.Transaction
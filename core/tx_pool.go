@@ -73,6 +73,14 @@ var (
 	// is higher than the balance of the user's account.
 	ErrInsufficientFunds = errors.New("insufficient funds for gas * price + value")
 
+	// ErrInvalidFeePayer is returned if the fee payer signature of a fee-delegated
+	// transaction is invalid.
+	ErrInvalidFeePayer = errors.New("invalid fee payer")
+
+	// ErrFeePayerInsufficientFunds is returned if the fee payer of a fee-delegated
+	// transaction can't afford the gas.
+	ErrFeePayerInsufficientFunds = errors.New("insufficient funds of fee payer for gas * price")
+
 	// ErrIntrinsicGas is returned if the transaction is specified to use less gas
 	// than required to start the invocation.
 	ErrIntrinsicGas = errors.New("intrinsic gas too low")
@@ -145,6 +153,10 @@ type blockChain interface {
 	SubscribeChainHeadEvent(ch chan<- ChainHeadEvent) event.Subscription
 
 	Engine() consensus.Engine
+
+	// CheckBlockLimit verifies that a block-limit transaction can be included in
+	// the block with the given number on top of the current chain.
+	CheckBlockLimit(tx *types.Transaction, from common.Address, number uint64) error
 }
 
 // TxPoolConfig are the configuration parameters of the transaction pool.
@@ -239,19 +251,24 @@ type TxPool struct {
 	mu          sync.RWMutex
 
 	singularity bool // Fork indicator whether we are in the singularity stage.
+	permission  bool // Fork indicator whether account permissioning is active.
+	typedTx     bool // Fork indicator whether typed transactions are accepted.
+	system      bool // Fork indicator whether the DPoS system contract is active.
 
 	currentState  *state.StateDB // Current state in the blockchain head
 	pendingNonces *txNoncer      // Pending state tracking virtual nonces
 	currentMaxGas uint64         // Current gas limit for transaction caps
+	pendingNumber uint64         // Number of the next block, checked against block limits
 
 	locals  *accountSet // Set of local transaction to exempt from eviction rules
 	journal *txJournal  // Journal of local transaction to back up to disk
 
-	pending         map[common.Address]*txList   // All currently processable transactions
-	queue           map[common.Address]*txList   // Queued but non-processable transactions
-	beats           map[common.Address]time.Time // Last heartbeat from each known account
-	all             *txLookup                    // All transactions to allow lookups
-	priced          *txPricedList                // All transactions sorted by price
+	pending         map[common.Address]*txList      // All currently processable transactions
+	queue           map[common.Address]*txList      // Queued but non-processable transactions
+	limited         map[common.Address]*txLimitList // Processable block-limit transactions
+	beats           map[common.Address]time.Time    // Last heartbeat from each known account
+	all             *txLookup                       // All transactions to allow lookups
+	priced          *txPricedList                   // All transactions sorted by price
 	chainHeadCh     chan ChainHeadEvent
 	chainHeadSub    event.Subscription
 	reqResetCh      chan *txpoolResetRequest
@@ -280,6 +297,7 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 		signer:          types.NewEIP155Signer(chainconfig.ChainID),
 		pending:         make(map[common.Address]*txList),
 		queue:           make(map[common.Address]*txList),
+		limited:         make(map[common.Address]*txLimitList),
 		beats:           make(map[common.Address]time.Time),
 		all:             newTxLookup(), //todo 参数传入
 		chainHeadCh:     make(chan ChainHeadEvent, chainHeadChanSize),
@@ -464,6 +482,9 @@ func (pool *TxPool) stats() (int, int) {
 	for _, list := range pool.pending {
 		pending += list.Len()
 	}
+	for _, list := range pool.limited {
+		pending += list.Len()
+	}
 	queued := 0
 	for _, list := range pool.queue {
 		queued += list.Len()
@@ -473,14 +494,12 @@ func (pool *TxPool) stats() (int, int) {
 
 // Content retrieves the data content of the transaction pool, returning all the
 // pending as well as queued transactions, grouped by account and sorted by nonce.
+// Block-limit transactions follow the nonce-ordered ones of an account.
 func (pool *TxPool) Content() (map[common.Address]types.Transactions, map[common.Address]types.Transactions) {
 	pool.mu.Lock()
 	defer pool.mu.Unlock()
 
-	pending := make(map[common.Address]types.Transactions)
-	for addr, list := range pool.pending {
-		pending[addr] = list.Flatten()
-	}
+	pending := pool.pendingTxs()
 	queued := make(map[common.Address]types.Transactions)
 	for addr, list := range pool.queue {
 		queued[addr] = list.Flatten()
@@ -489,17 +508,27 @@ func (pool *TxPool) Content() (map[common.Address]types.Transactions, map[common
 }
 
 // Pending retrieves all currently processable transactions, grouped by origin
-// account and sorted by nonce. The returned transaction set is a copy and can be
-// freely modified by calling code.
+// account and sorted by nonce, followed by the block-limit transactions of the
+// account. The returned transaction set is a copy and can be freely modified by
+// calling code.
 func (pool *TxPool) Pending() (map[common.Address]types.Transactions, error) {
 	pool.mu.Lock()
 	defer pool.mu.Unlock()
 
+	return pool.pendingTxs(), nil
+}
+
+// pendingTxs retrieves all currently processable transactions, the block-limit
+// ones of an account following those ordered by nonce.
+func (pool *TxPool) pendingTxs() map[common.Address]types.Transactions {
 	pending := make(map[common.Address]types.Transactions)
 	for addr, list := range pool.pending {
 		pending[addr] = list.Flatten()
 	}
-	return pending, nil
+	for addr, list := range pool.limited {
+		pending[addr] = append(pending[addr], list.Flatten()...)
+	}
+	return pending
 }
 
 // Locals retrieves the accounts currently considered local by the pool.
@@ -522,6 +551,9 @@ func (pool *TxPool) local() map[common.Address]types.Transactions {
 		if queued := pool.queue[addr]; queued != nil {
 			txs[addr] = append(txs[addr], queued.Flatten()...)
 		}
+		if limited := pool.limited[addr]; limited != nil {
+			txs[addr] = append(txs[addr], limited.Flatten()...)
+		}
 	}
 	return txs
 }
@@ -552,15 +584,45 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 	if !local && pool.gasPrice.Cmp(tx.GasPrice()) > 0 {
 		return ErrUnderpriced
 	}
-	// Ensure the transaction adheres to nonce ordering
-	if pool.currentState.GetNonce(from) > tx.Nonce() {
+	// Ensure the transaction adheres to nonce ordering, or the block limit and
+	// ID replacing it for typed transactions
+	if tx.Type() != types.LegacyTxType && !pool.typedTx {
+		return types.ErrTxTypeNotSupported
+	}
+	if tx.Type() == types.BlockLimitTxType {
+		if err := pool.chain.CheckBlockLimit(tx, from, pool.pendingNumber); err != nil {
+			return err
+		}
+	} else if pool.currentState.GetNonce(from) > tx.Nonce() {
 		return ErrNonceTooLow
 	}
 	// Transactor should have enough funds to cover the costs
-	// cost == V + GP * GL
-	if pool.currentState.GetBalance(from).Cmp(tx.Cost()) < 0 {
+	// cost == V + GP * GL, GP * GL being paid by the fee payer if delegated
+	if pool.currentState.GetBalance(from).Cmp(tx.SenderCost()) < 0 {
 		return ErrInsufficientFunds
 	}
+	if tx.Type() == types.FeeDelegatedTxType {
+		payer, err := types.Payer(pool.signer, tx)
+		if err != nil {
+			return ErrInvalidFeePayer
+		}
+		// The payer must cover the fees of its other delegated transactions too
+		fee := pool.payerFees(payer, from, tx)
+		if payer == from {
+			fee.Add(fee, tx.Cost())
+		} else {
+			fee.Add(fee, tx.Fee())
+		}
+		if pool.currentState.GetBalance(payer).Cmp(fee) < 0 {
+			return ErrFeePayerInsufficientFunds
+		}
+	}
+	// Value sent to the system contract would be lost
+	if pool.system {
+		if err := checkSystemContractValue(tx.To(), tx.Value()); err != nil {
+			return err
+		}
+	}
 	// Ensure the transaction has more gas than the basic tx fee.
 	intrGas, err := IntrinsicGas(tx.Data(), tx.To() == nil, pool.singularity)
 	if err != nil {
@@ -569,6 +631,10 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 	if tx.Gas() < intrGas {
 		return ErrIntrinsicGas
 	}
+	// Ensure the sender's role allows the transaction
+	if pool.permission {
+		return checkAccountRole(pool.chainconfig.Permission, pool.currentState, from, tx.To())
+	}
 	return nil
 }
 
@@ -611,6 +677,10 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (replaced bool, err e
 			pool.removeTx(tx.Hash(), false)
 		}
 	}
+	// Block-limit transactions are executable right away, they skip the queue
+	if tx.Type() == types.BlockLimitTxType {
+		return false, pool.addLimited(from, tx, local)
+	}
 	// Try to replace an existing transaction in the pending pool
 	//from, _ := types.Sender(pool.signer, tx) // already validated
 	if list := pool.pending[from]; list != nil && list.Overlaps(tx) {
@@ -691,6 +761,37 @@ func (pool *TxPool) enqueueTx(hash common.Hash, tx *types.Transaction) (bool, er
 	return old != nil, nil
 }
 
+// addLimited inserts a block-limit transaction into the executable set of its
+// sender. Unlike nonces, an ID can't be replaced with a higher priced one.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *TxPool) addLimited(from common.Address, tx *types.Transaction, local bool) error {
+	if pool.limited[from] == nil {
+		pool.limited[from] = newTxLimitList()
+	}
+	list := pool.limited[from]
+	if list.Get(tx.ID()) != nil {
+		pendingDiscardMeter.Mark(1)
+		return ErrTxIDUsed
+	}
+	list.Add(tx)
+	pool.all.Add(tx)
+	pool.priced.Put(tx)
+	pendingGauge.Inc(1)
+
+	// Mark local addresses and journal local transactions
+	if local && !pool.locals.contains(from) {
+		log.Info("Setting new local account", "address", from)
+		pool.locals.add(from)
+	}
+	if local || pool.locals.contains(from) {
+		localGauge.Inc(1)
+	}
+	pool.journalTx(from, tx)
+	log.Trace("Pooled new block-limit transaction", "hash", tx.Hash(), "from", from, "to", tx.To())
+	return nil
+}
+
 // journalTx adds the specified transaction to the local disk journal if it is
 // deemed to have been sent from a local account.
 func (pool *TxPool) journalTx(from common.Address, tx *types.Transaction) {
@@ -827,6 +928,16 @@ func (pool *TxPool) addTxs(txs []*types.Transaction, local, sync bool) []error {
 		errs[nilSlot] = err
 		nilSlot++
 	}
+	// Block-limit transactions skip the queue, announce them right away
+	var limited []*types.Transaction
+	for i, tx := range news {
+		if newErrs[i] == nil && tx.Type() == types.BlockLimitTxType {
+			limited = append(limited, tx)
+		}
+	}
+	if len(limited) > 0 {
+		pool.txFeed.Send(NewTxsEvent{limited})
+	}
 	// Reorg the pool internals if needed and return
 	done := pool.requestPromoteExecutables(dirtyAddrs)
 	if sync {
@@ -862,7 +973,11 @@ func (pool *TxPool) Status(hashes []common.Hash) []TxStatus {
 		}
 		from, _ := types.Sender(pool.signer, tx) // already validated
 		pool.mu.RLock()
-		if txList := pool.pending[from]; txList != nil && txList.txs.items[tx.Nonce()] != nil {
+		if tx.Type() == types.BlockLimitTxType {
+			if list := pool.limited[from]; list != nil && list.Get(tx.ID()) == tx {
+				status[i] = TxStatusPending
+			}
+		} else if txList := pool.pending[from]; txList != nil && txList.txs.items[tx.Nonce()] != nil {
 			status[i] = TxStatusPending
 		} else if txList := pool.queue[from]; txList != nil && txList.txs.items[tx.Nonce()] != nil {
 			status[i] = TxStatusQueued
@@ -898,6 +1013,16 @@ func (pool *TxPool) removeTx(hash common.Hash, outofbound bool) {
 	if pool.locals.contains(addr) {
 		localGauge.Dec(1)
 	}
+	// Block-limit transactions don't invalidate any others
+	if tx.Type() == types.BlockLimitTxType {
+		if list := pool.limited[addr]; list != nil && list.Remove(tx) {
+			if list.Empty() {
+				delete(pool.limited, addr)
+			}
+			pendingGauge.Dec(1)
+		}
+		return
+	}
 	// Remove the transaction from the pending lists and reset the account nonce
 	if pending := pool.pending[addr]; pending != nil {
 		if removed, invalids := pending.Remove(tx); removed {
@@ -1069,6 +1194,8 @@ func (pool *TxPool) runReorg(done chan struct{}, reset *txpoolResetRequest, dirt
 	// because of another transaction (e.g. higher gas price).
 	if reset != nil {
 		pool.demoteUnexecutables()
+		pool.dropUnpayableFees()
+		pool.dropStaleLimited()
 	}
 	// Ensure pool.queue and pool.pending sizes stay within the configured limits.
 	pool.truncatePending()
@@ -1183,6 +1310,10 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 	// Update all fork indicator by next pending block number.
 	next := new(big.Int).Add(newHead.Number, big.NewInt(1))
 	pool.singularity = pool.chainconfig.IsSingularity(next)
+	pool.permission = pool.chainconfig.IsPermission(next)
+	pool.typedTx = pool.chainconfig.IsTypedTx(next)
+	pool.system = pool.chainconfig.IsSystemContract(next)
+	pool.pendingNumber = next.Uint64()
 }
 
 // promoteExecutables moves transactions that have become processable from the
@@ -1442,6 +1573,81 @@ func (pool *TxPool) demoteUnexecutables() {
 	}
 }
 
+// payerFees returns the gas fees of the delegated transactions in the pool paid
+// by payer, leaving out the one of from that tx would replace.
+func (pool *TxPool) payerFees(payer, from common.Address, tx *types.Transaction) *big.Int {
+	fees := pool.all.PayerFees(payer)
+	for _, list := range []*txList{pool.pending[from], pool.queue[from]} {
+		if list == nil {
+			continue
+		}
+		if old := list.txs.Get(tx.Nonce()); old != nil && old.FeePayer() != nil && *old.FeePayer() == payer {
+			fees.Sub(fees, old.Fee())
+		}
+	}
+	return fees
+}
+
+// dropUnpayableFees removes the fee-delegated transactions whose fee payers
+// can't cover the gas fees of all of them any more, the cheapest ones first.
+func (pool *TxPool) dropUnpayableFees() {
+	excess := make(map[common.Address]*big.Int)
+	for payer, fees := range pool.all.Fees() {
+		if fees.Sub(fees, pool.currentState.GetBalance(payer)); fees.Sign() > 0 {
+			excess[payer] = fees
+		}
+	}
+	if len(excess) == 0 {
+		return
+	}
+	var drops types.Transactions
+	pool.all.Range(func(hash common.Hash, tx *types.Transaction) bool {
+		if payer := tx.FeePayer(); payer != nil && excess[*payer] != nil {
+			drops = append(drops, tx)
+		}
+		return true
+	})
+	sort.Slice(drops, func(i, j int) bool {
+		if cmp := drops[i].GasPrice().Cmp(drops[j].GasPrice()); cmp != 0 {
+			return cmp < 0
+		}
+		return drops[i].Nonce() > drops[j].Nonce()
+	})
+	for _, tx := range drops {
+		payer := *tx.FeePayer()
+		if excess[payer].Sign() <= 0 {
+			continue
+		}
+		excess[payer].Sub(excess[payer], tx.Fee())
+		log.Trace("Removed fee-delegated transaction of unfunded payer", "hash", tx.Hash(), "payer", payer)
+		pool.removeTx(tx.Hash(), true)
+	}
+}
+
+// dropStaleLimited removes the block-limit transactions which can't be included
+// in the next block any more: they expired or got included, or became too costly.
+func (pool *TxPool) dropStaleLimited() {
+	for addr, list := range pool.limited {
+		balance := pool.currentState.GetBalance(addr)
+		drops := list.Filter(func(tx *types.Transaction) bool {
+			return !pool.typedTx || tx.Cost().Cmp(balance) > 0 || tx.Gas() > pool.currentMaxGas ||
+				pool.chain.CheckBlockLimit(tx, addr, pool.pendingNumber) != nil
+		})
+		for _, tx := range drops {
+			log.Trace("Removed stale block-limit transaction", "hash", tx.Hash())
+			pool.all.Remove(tx.Hash())
+		}
+		pool.priced.Removed(len(drops))
+		pendingGauge.Dec(int64(len(drops)))
+		if pool.locals.contains(addr) {
+			localGauge.Dec(int64(len(drops)))
+		}
+		if list.Empty() {
+			delete(pool.limited, addr)
+		}
+	}
+}
+
 // addressByHeartbeat is an account address tagged with its last activity timestamp.
 type addressByHeartbeat struct {
 	address   common.Address
@@ -1539,6 +1745,7 @@ func (as *accountSet) merge(other *accountSet) {
 // TxPool.mu mutex.
 type txLookup struct {
 	all   map[common.Hash]*types.Transaction
+	fees  map[common.Address]*big.Int // Gas fees of the delegated transactions per fee payer
 	slots int
 	lock  sync.RWMutex
 }
@@ -1546,7 +1753,8 @@ type txLookup struct {
 // newTxLookup returns a new txLookup structure.
 func newTxLookup() *txLookup {
 	return &txLookup{
-		all: make(map[common.Hash]*types.Transaction),
+		all:  make(map[common.Hash]*types.Transaction),
+		fees: make(map[common.Address]*big.Int),
 	}
 }
 
@@ -1594,6 +1802,13 @@ func (t *txLookup) Add(tx *types.Transaction) {
 	t.slots += numSlots(tx)
 	slotsGauge.Update(int64(t.slots))
 	t.all[tx.Hash()] = tx
+
+	if payer := tx.FeePayer(); payer != nil {
+		if t.fees[*payer] == nil {
+			t.fees[*payer] = new(big.Int)
+		}
+		t.fees[*payer].Add(t.fees[*payer], tx.Fee())
+	}
 }
 
 // Remove removes a transaction from the lookup.
@@ -1601,9 +1816,41 @@ func (t *txLookup) Remove(hash common.Hash) {
 	t.lock.Lock()
 	defer t.lock.Unlock()
 
-	t.slots -= numSlots(t.all[hash])
+	tx := t.all[hash]
+	t.slots -= numSlots(tx)
 	slotsGauge.Update(int64(t.slots))
 	delete(t.all, hash)
+
+	if payer := tx.FeePayer(); payer != nil {
+		if t.fees[*payer].Sub(t.fees[*payer], tx.Fee()); t.fees[*payer].Sign() == 0 {
+			delete(t.fees, *payer)
+		}
+	}
+}
+
+// PayerFees returns the gas fees of the delegated transactions in the lookup
+// paid by payer.
+func (t *txLookup) PayerFees(payer common.Address) *big.Int {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if fees := t.fees[payer]; fees != nil {
+		return new(big.Int).Set(fees)
+	}
+	return new(big.Int)
+}
+
+// Fees returns the gas fees of the delegated transactions in the lookup per fee
+// payer.
+func (t *txLookup) Fees() map[common.Address]*big.Int {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	fees := make(map[common.Address]*big.Int, len(t.fees))
+	for payer, fee := range t.fees {
+		fees[payer] = new(big.Int).Set(fee)
+	}
+	return fees
 }
 
 // numSlots calculates the number of slots needed for a single transaction.
@@ -92,6 +92,10 @@ func (bc *testBlockChain) Engine() consensus.Engine {
 	return nil
 }
 
+func (bc *testBlockChain) CheckBlockLimit(tx *types.Transaction, from common.Address, number uint64) error {
+	return checkBlockLimit(tx, number)
+}
+
 func transaction(nonce uint64, gaslimit uint64, key *ecdsa.PrivateKey) *types.Transaction {
 	return pricedTransaction(nonce, gaslimit, big.NewInt(1), key)
 }
@@ -330,7 +334,7 @@ func TestTransactionQueue2(t *testing.T) {
 	pool.enqueueTx(tx2.Hash(), tx2)
 	pool.enqueueTx(tx3.Hash(), tx3)
 
-	pool.promoteExecutables([]common.Address{from})
+	pool.promoteExecutables([]common.Address{from}, false)
 	if len(pool.pending) != 1 {
 		t.Error("expected pending length to be 1, got", len(pool.pending))
 	}
@@ -1824,7 +1828,7 @@ func benchmarkFuturePromotion(b *testing.B, size int) {
 	// Benchmark the speed of pool validation
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		pool.promoteExecutables(nil)
+		pool.promoteExecutables(nil, false)
 	}
 }
 
@@ -1854,3 +1858,71 @@ func benchmarkPoolBatchInsert(b *testing.B, size int) {
 		pool.AddRemotes(batch)
 	}
 }
+
+// Tests that the pool holds fee payers liable for the gas fees of all their
+// delegated transactions, and drops the cheapest ones once a payer can't cover
+// them any more.
+func TestTransactionFeeDelegation(t *testing.T) {
+	t.Parallel()
+
+	config := *params.TestChainConfig
+	config.TypedTxBlock = big.NewInt(0)
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()))
+	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	pool := NewTxPool(testTxPoolConfig, &config, blockchain)
+	defer pool.Stop()
+
+	keyA, _ := crypto.GenerateKey()
+	keyB, _ := crypto.GenerateKey()
+	payerKey, _ := crypto.GenerateKey()
+	payer := crypto.PubkeyToAddress(payerKey.PublicKey)
+
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(keyA.PublicKey), big.NewInt(1000000000))
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(keyB.PublicKey), big.NewInt(1000000000))
+	pool.currentState.AddBalance(payer, big.NewInt(2500000))
+
+	// Every transaction commits the payer to 100000 * price
+	signer := types.NewEIP155Signer(config.ChainID)
+	delegated := func(key *ecdsa.PrivateKey, nonce uint64, value, price int64) *types.Transaction {
+		tx, _ := types.SignTx(types.NewFeeDelegatedTransaction(config.ChainID, nonce, &common.Address{}, big.NewInt(value), 100000, big.NewInt(price), nil, payer), signer, key)
+		tx, _ = types.SignFeePayer(tx, signer, payerKey)
+		return tx
+	}
+	txA, txB := delegated(keyA, 0, 0, 10), delegated(keyB, 0, 0, 10)
+	for i, tt := range []struct {
+		tx  *types.Transaction
+		err error
+	}{
+		{txA, nil},
+		{txB, nil},
+		// The payer can't afford a third fee on top
+		{delegated(keyA, 1, 0, 10), ErrFeePayerInsufficientFunds},
+		// But it can replace one of the two with a pricier one
+		{delegated(keyA, 0, 0, 11), nil},
+		// Paying for itself, the value counts on top of the fees of the others
+		{delegated(payerKey, 0, 300001, 1), ErrFeePayerInsufficientFunds},
+		{delegated(payerKey, 0, 300000, 1), nil},
+	} {
+		if err := pool.AddRemotesSync([]*types.Transaction{tt.tx})[0]; err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+	if pending, _ := pool.Stats(); pending != 3 {
+		t.Fatalf("pending transactions mismatched: have %d, want %d", pending, 3)
+	}
+	// Spend the funds of the payer, leaving it enough for the pricier one only
+	pool.currentState.SetBalance(payer, big.NewInt(2000000))
+	<-pool.requestReset(nil, nil)
+
+	if pool.Get(txB.Hash()) != nil {
+		t.Errorf("unfunded delegated transaction not dropped")
+	}
+	if pending, _ := pool.Stats(); pending != 1 {
+		t.Errorf("pending transactions mismatched: have %d, want %d", pending, 1)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
@@ -22,9 +22,17 @@ func (t txdata) MarshalJSON() ([]byte, error) {
 		Recipient    *common.Address `json:"to"       rlp:"nil"`
 		Amount       *hexutil.Big    `json:"value"    gencodec:"required"`
 		Payload      hexutil.Bytes   `json:"input"    gencodec:"required"`
+		Type         hexutil.Uint64  `json:"type"                 rlp:"-"`
+		ChainID      *hexutil.Big    `json:"chainId,omitempty"    rlp:"-"`
+		ID           hexutil.Uint64  `json:"id,omitempty"         rlp:"-"`
+		BlockLimit   hexutil.Uint64  `json:"blockLimit,omitempty" rlp:"-"`
+		FeePayer     *common.Address `json:"feePayer,omitempty" rlp:"-"`
 		V            *hexutil.Big    `json:"v" gencodec:"required"`
 		R            *hexutil.Big    `json:"r" gencodec:"required"`
 		S            *hexutil.Big    `json:"s" gencodec:"required"`
+		PV           *hexutil.Big    `json:"feePayerV,omitempty" rlp:"-"`
+		PR           *hexutil.Big    `json:"feePayerR,omitempty" rlp:"-"`
+		PS           *hexutil.Big    `json:"feePayerS,omitempty" rlp:"-"`
 		Hash         *common.Hash    `json:"hash" rlp:"-"`
 	}
 	var enc txdata
@@ -34,9 +42,17 @@ func (t txdata) MarshalJSON() ([]byte, error) {
 	enc.Recipient = t.Recipient
 	enc.Amount = (*hexutil.Big)(t.Amount)
 	enc.Payload = t.Payload
+	enc.Type = hexutil.Uint64(t.Type)
+	enc.ChainID = (*hexutil.Big)(t.ChainID)
+	enc.ID = hexutil.Uint64(t.ID)
+	enc.BlockLimit = hexutil.Uint64(t.BlockLimit)
+	enc.FeePayer = t.FeePayer
 	enc.V = (*hexutil.Big)(t.V)
 	enc.R = (*hexutil.Big)(t.R)
 	enc.S = (*hexutil.Big)(t.S)
+	enc.PV = (*hexutil.Big)(t.PV)
+	enc.PR = (*hexutil.Big)(t.PR)
+	enc.PS = (*hexutil.Big)(t.PS)
 	enc.Hash = t.Hash
 	return json.Marshal(&enc)
 }
@@ -50,9 +66,17 @@ func (t *txdata) UnmarshalJSON(input []byte) error {
 		Recipient    *common.Address `json:"to"       rlp:"nil"`
 		Amount       *hexutil.Big    `json:"value"    gencodec:"required"`
 		Payload      *hexutil.Bytes  `json:"input"    gencodec:"required"`
+		Type         *hexutil.Uint64 `json:"type"                 rlp:"-"`
+		ChainID      *hexutil.Big    `json:"chainId,omitempty"    rlp:"-"`
+		ID           *hexutil.Uint64 `json:"id,omitempty"         rlp:"-"`
+		BlockLimit   *hexutil.Uint64 `json:"blockLimit,omitempty" rlp:"-"`
+		FeePayer     *common.Address `json:"feePayer,omitempty" rlp:"-"`
 		V            *hexutil.Big    `json:"v" gencodec:"required"`
 		R            *hexutil.Big    `json:"r" gencodec:"required"`
 		S            *hexutil.Big    `json:"s" gencodec:"required"`
+		PV           *hexutil.Big    `json:"feePayerV,omitempty" rlp:"-"`
+		PR           *hexutil.Big    `json:"feePayerR,omitempty" rlp:"-"`
+		PS           *hexutil.Big    `json:"feePayerS,omitempty" rlp:"-"`
 		Hash         *common.Hash    `json:"hash" rlp:"-"`
 	}
 	var dec txdata
@@ -82,6 +106,21 @@ func (t *txdata) UnmarshalJSON(input []byte) error {
 		return errors.New("missing required field 'input' for txdata")
 	}
 	t.Payload = *dec.Payload
+	if dec.Type != nil {
+		t.Type = uint8(*dec.Type)
+	}
+	if dec.ChainID != nil {
+		t.ChainID = (*big.Int)(dec.ChainID)
+	}
+	if dec.ID != nil {
+		t.ID = uint64(*dec.ID)
+	}
+	if dec.BlockLimit != nil {
+		t.BlockLimit = uint64(*dec.BlockLimit)
+	}
+	if dec.FeePayer != nil {
+		t.FeePayer = dec.FeePayer
+	}
 	if dec.V == nil {
 		return errors.New("missing required field 'v' for txdata")
 	}
@@ -94,6 +133,15 @@ func (t *txdata) UnmarshalJSON(input []byte) error {
 		return errors.New("missing required field 's' for txdata")
 	}
 	t.S = (*big.Int)(dec.S)
+	if dec.PV != nil {
+		t.PV = (*big.Int)(dec.PV)
+	}
+	if dec.PR != nil {
+		t.PR = (*big.Int)(dec.PR)
+	}
+	if dec.PS != nil {
+		t.PS = (*big.Int)(dec.PS)
+	}
 	if dec.Hash != nil {
 		t.Hash = dec.Hash
 	}
@@ -0,0 +1,51 @@
+// Copyright 2020 The go-simplechain Authors
+// This file is part of the go-simplechain library.
+//
+// The go-simplechain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-simplechain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-simplechain library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import "bytes"
+
+// PrivatePayloadHashLength is the length of the hash a private transaction
+// manager references an encrypted payload by.
+const PrivatePayloadHashLength = 64
+
+// privatePayloadPrefix marks the payload of a private transaction, which is
+// followed by the hash of the encrypted payload and nothing else.
+var privatePayloadPrefix = []byte{0x00, 'p', 't', 'x'}
+
+// NewPrivatePayload builds the public payload of a private transaction
+// referencing the encrypted payload with the given hash.
+func NewPrivatePayload(hash []byte) []byte {
+	payload := make([]byte, 0, len(privatePayloadPrefix)+PrivatePayloadHashLength)
+	payload = append(payload, privatePayloadPrefix...)
+	return append(payload, hash...)
+}
+
+// PrivatePayloadHash returns the hash of the encrypted payload referenced by
+// the transaction data, and false if the data isn't a private payload.
+func PrivatePayloadHash(data []byte) ([]byte, bool) {
+	if len(data) != len(privatePayloadPrefix)+PrivatePayloadHashLength || !bytes.HasPrefix(data, privatePayloadPrefix) {
+		return nil, false
+	}
+	return data[len(privatePayloadPrefix):], true
+}
+
+// IsPrivate returns whether the transaction carries a private payload. Its
+// contents are only known to the participants, public nodes don't execute it.
+func (tx *Transaction) IsPrivate() bool {
+	_, ok := PrivatePayloadHash(tx.data.Payload)
+	return ok
+}
@@ -17,6 +17,7 @@
 package types
 
 import (
+	"bytes"
 	"container/heap"
 	"errors"
 	"io"
@@ -33,16 +34,30 @@ import (
 //go:generate gencodec -type txdata -field-override txdataMarshaling -out gen_tx_json.go
 
 var (
-	ErrInvalidSig = errors.New("invalid transaction v, r, s values")
+	ErrInvalidSig         = errors.New("invalid transaction v, r, s values")
+	ErrTxTypeNotSupported = errors.New("transaction type not supported")
+	errEmptyTypedTx       = errors.New("empty typed transaction bytes")
+
+	// ErrInvalidFeePayer is returned if the fee payer signature of a fee-delegated
+	// transaction wasn't made by its fee payer.
+	ErrInvalidFeePayer = errors.New("invalid fee payer signature")
+)
+
+// Transaction types.
+const (
+	LegacyTxType = iota
+	BlockLimitTxType
+	FeeDelegatedTxType
 )
 
 type Transaction struct {
 	data txdata
 	time time.Time
 	// caches
-	hash atomic.Value
-	size atomic.Value
-	from atomic.Value
+	hash  atomic.Value
+	size  atomic.Value
+	from  atomic.Value
+	payer atomic.Value
 }
 
 type txdata struct {
@@ -53,11 +68,24 @@ type txdata struct {
 	Amount       *big.Int        `json:"value"    gencodec:"required"`
 	Payload      []byte          `json:"input"    gencodec:"required"`
 
+	// Typed transaction fields, not part of the legacy encoding
+	Type       uint8    `json:"type"                 rlp:"-"`
+	ChainID    *big.Int `json:"chainId,omitempty"    rlp:"-"`
+	ID         uint64   `json:"id,omitempty"         rlp:"-"`
+	BlockLimit uint64   `json:"blockLimit,omitempty" rlp:"-"`
+
+	FeePayer *common.Address `json:"feePayer,omitempty" rlp:"-"`
+
 	// Signature values
 	V *big.Int `json:"v" gencodec:"required"`
 	R *big.Int `json:"r" gencodec:"required"`
 	S *big.Int `json:"s" gencodec:"required"`
 
+	// Fee payer signature values of fee-delegated transactions
+	PV *big.Int `json:"feePayerV,omitempty" rlp:"-"`
+	PR *big.Int `json:"feePayerR,omitempty" rlp:"-"`
+	PS *big.Int `json:"feePayerS,omitempty" rlp:"-"`
+
 	// This is only used when marshaling to JSON.
 	Hash *common.Hash `json:"hash" rlp:"-"`
 }
@@ -68,9 +96,16 @@ type txdataMarshaling struct {
 	GasLimit     hexutil.Uint64
 	Amount       *hexutil.Big
 	Payload      hexutil.Bytes
+	Type         hexutil.Uint64
+	ChainID      *hexutil.Big
+	ID           hexutil.Uint64
+	BlockLimit   hexutil.Uint64
 	V            *hexutil.Big
 	R            *hexutil.Big
 	S            *hexutil.Big
+	PV           *hexutil.Big
+	PR           *hexutil.Big
+	PS           *hexutil.Big
 }
 
 func NewTransaction(nonce uint64, to common.Address, amount *big.Int, gasLimit uint64, gasPrice *big.Int, data []byte) *Transaction {
@@ -108,14 +143,95 @@ func newTransaction(nonce uint64, to *common.Address, amount *big.Int, gasLimit
 	}
 }
 
+// blockLimitTxdata is the consensus encoding of a block-limit transaction. In
+// place of a nonce, it is identified by a random ID which the chain doesn't let
+// the sender reuse as long as the transaction could be included, that is up to
+// and including block BlockLimit. The sender's nonce is left untouched.
+type blockLimitTxdata struct {
+	ChainID    *big.Int
+	ID         uint64
+	Price      *big.Int
+	GasLimit   uint64
+	BlockLimit uint64
+	Recipient  *common.Address `rlp:"nil"` // nil means contract creation
+	Amount     *big.Int
+	Payload    []byte
+
+	// Signature values, V being the parity of the signature
+	V *big.Int
+	R *big.Int
+	S *big.Int
+}
+
+// NewBlockLimitTransaction creates a block-limit transaction which is valid up
+// to and including block blockLimit. Block-limit transactions can't create
+// contracts, a nil recipient is rejected by the chain.
+func NewBlockLimitTransaction(chainID *big.Int, id uint64, blockLimit uint64, to *common.Address, amount *big.Int, gasLimit uint64, gasPrice *big.Int, data []byte) *Transaction {
+	tx := newTransaction(0, to, amount, gasLimit, gasPrice, data)
+	tx.data.Type = BlockLimitTxType
+	tx.data.ChainID = new(big.Int)
+	if chainID != nil {
+		tx.data.ChainID.Set(chainID)
+	}
+	tx.data.ID = id
+	tx.data.BlockLimit = blockLimit
+	return tx
+}
+
+// feeDelegatedTxdata is the consensus encoding of a fee-delegated transaction.
+// The sender signs the transaction including the address of the fee payer, who
+// in turn signs it including the signature of the sender. The gas is bought
+// from, and refunded to, the fee payer, while the value is sent by the sender.
+type feeDelegatedTxdata struct {
+	ChainID      *big.Int
+	AccountNonce uint64
+	Price        *big.Int
+	GasLimit     uint64
+	Recipient    *common.Address `rlp:"nil"` // nil means contract creation
+	Amount       *big.Int
+	Payload      []byte
+	FeePayer     common.Address
+
+	// Signature values of the sender, V being the parity of the signature
+	V *big.Int
+	R *big.Int
+	S *big.Int
+
+	// Signature values of the fee payer, PV being the parity of the signature
+	PV *big.Int
+	PR *big.Int
+	PS *big.Int
+}
+
+// NewFeeDelegatedTransaction creates a transaction whose fees are paid by the
+// given fee payer. It has to be signed by the sender first, then by the fee
+// payer.
+func NewFeeDelegatedTransaction(chainID *big.Int, nonce uint64, to *common.Address, amount *big.Int, gasLimit uint64, gasPrice *big.Int, data []byte, feePayer common.Address) *Transaction {
+	tx := newTransaction(nonce, to, amount, gasLimit, gasPrice, data)
+	tx.data.Type = FeeDelegatedTxType
+	tx.data.ChainID = new(big.Int)
+	if chainID != nil {
+		tx.data.ChainID.Set(chainID)
+	}
+	tx.data.FeePayer = &feePayer
+	tx.data.PV, tx.data.PR, tx.data.PS = new(big.Int), new(big.Int), new(big.Int)
+	return tx
+}
+
+// Type returns the transaction type.
+func (tx *Transaction) Type() uint8 { return tx.data.Type }
+
 // ChainId returns which chain id this transaction was signed for (if at all)
 func (tx *Transaction) ChainId() *big.Int {
+	if tx.data.Type != LegacyTxType {
+		return new(big.Int).Set(tx.data.ChainID)
+	}
 	return DeriveChainId(tx.data.V)
 }
 
 // Protected returns whether the transaction is protected from replay protection.
 func (tx *Transaction) Protected() bool {
-	return isProtectedV(tx.data.V)
+	return tx.data.Type != LegacyTxType || isProtectedV(tx.data.V)
 }
 
 func isProtectedV(V *big.Int) bool {
@@ -127,21 +243,173 @@ func isProtectedV(V *big.Int) bool {
 	return true
 }
 
-// EncodeRLP implements rlp.Encoder
+// EncodeRLP implements rlp.Encoder. Legacy transactions are encoded as an RLP
+// list, typed ones as an RLP string holding the type byte and the payload.
 func (tx *Transaction) EncodeRLP(w io.Writer) error {
-	return rlp.Encode(w, &tx.data)
+	if tx.data.Type == LegacyTxType {
+		return rlp.Encode(w, &tx.data)
+	}
+	enc, err := tx.encodeTyped()
+	if err != nil {
+		return err
+	}
+	return rlp.Encode(w, enc)
 }
 
 // DecodeRLP implements rlp.Decoder
 func (tx *Transaction) DecodeRLP(s *rlp.Stream) error {
-	_, size, _ := s.Kind()
-	err := s.Decode(&tx.data)
-	if err == nil {
-		tx.size.Store(common.StorageSize(rlp.ListSize(size)))
-		tx.time = time.Now()
+	kind, size, err := s.Kind()
+	switch {
+	case err != nil:
+		return err
+	case kind == rlp.List:
+		var data txdata
+		if err := s.Decode(&data); err != nil {
+			return err
+		}
+		tx.data = data
+	default:
+		enc, err := s.Bytes()
+		if err != nil {
+			return err
+		}
+		if err := tx.decodeTyped(enc); err != nil {
+			return err
+		}
 	}
+	tx.size.Store(common.StorageSize(rlp.ListSize(size)))
+	tx.time = time.Now()
+	return nil
+}
 
-	return err
+// MarshalBinary returns the canonical encoding of the transaction, the RLP list
+// for legacy transactions and the type byte followed by the payload for typed
+// ones. It is the format of raw transactions submitted over RPC.
+func (tx *Transaction) MarshalBinary() ([]byte, error) {
+	if tx.data.Type == LegacyTxType {
+		return rlp.EncodeToBytes(&tx.data)
+	}
+	return tx.encodeTyped()
+}
+
+// UnmarshalBinary decodes the canonical encoding of a transaction.
+func (tx *Transaction) UnmarshalBinary(b []byte) error {
+	if len(b) > 0 && b[0] > 0x7f {
+		var data txdata
+		if err := rlp.DecodeBytes(b, &data); err != nil {
+			return err
+		}
+		*tx = Transaction{data: data, time: time.Now()}
+		tx.size.Store(common.StorageSize(len(b)))
+		return nil
+	}
+	var dec Transaction
+	if err := dec.decodeTyped(b); err != nil {
+		return err
+	}
+	*tx = Transaction{data: dec.data, time: time.Now()}
+	return nil
+}
+
+// typedPayload returns the consensus fields of a typed transaction.
+func (tx *Transaction) typedPayload() interface{} {
+	if tx.data.Type == FeeDelegatedTxType {
+		return &feeDelegatedTxdata{
+			ChainID:      tx.data.ChainID,
+			AccountNonce: tx.data.AccountNonce,
+			Price:        tx.data.Price,
+			GasLimit:     tx.data.GasLimit,
+			Recipient:    tx.data.Recipient,
+			Amount:       tx.data.Amount,
+			Payload:      tx.data.Payload,
+			FeePayer:     *tx.data.FeePayer,
+			V:            tx.data.V,
+			R:            tx.data.R,
+			S:            tx.data.S,
+			PV:           tx.data.PV,
+			PR:           tx.data.PR,
+			PS:           tx.data.PS,
+		}
+	}
+	return &blockLimitTxdata{
+		ChainID:    tx.data.ChainID,
+		ID:         tx.data.ID,
+		Price:      tx.data.Price,
+		GasLimit:   tx.data.GasLimit,
+		BlockLimit: tx.data.BlockLimit,
+		Recipient:  tx.data.Recipient,
+		Amount:     tx.data.Amount,
+		Payload:    tx.data.Payload,
+		V:          tx.data.V,
+		R:          tx.data.R,
+		S:          tx.data.S,
+	}
+}
+
+// encodeTyped returns the type byte of a typed transaction followed by the RLP
+// encoding of its payload.
+func (tx *Transaction) encodeTyped() ([]byte, error) {
+	buf := new(bytes.Buffer)
+	buf.WriteByte(tx.data.Type)
+	if err := rlp.Encode(buf, tx.typedPayload()); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeTyped decodes a typed transaction from the type byte followed by the
+// RLP encoding of its payload.
+func (tx *Transaction) decodeTyped(b []byte) error {
+	if len(b) == 0 {
+		return errEmptyTypedTx
+	}
+	switch b[0] {
+	case BlockLimitTxType:
+		var dec blockLimitTxdata
+		if err := rlp.DecodeBytes(b[1:], &dec); err != nil {
+			return err
+		}
+		tx.data = txdata{
+			Price:      dec.Price,
+			GasLimit:   dec.GasLimit,
+			Recipient:  dec.Recipient,
+			Amount:     dec.Amount,
+			Payload:    dec.Payload,
+			Type:       BlockLimitTxType,
+			ChainID:    dec.ChainID,
+			ID:         dec.ID,
+			BlockLimit: dec.BlockLimit,
+			V:          dec.V,
+			R:          dec.R,
+			S:          dec.S,
+		}
+		return nil
+	case FeeDelegatedTxType:
+		var dec feeDelegatedTxdata
+		if err := rlp.DecodeBytes(b[1:], &dec); err != nil {
+			return err
+		}
+		tx.data = txdata{
+			AccountNonce: dec.AccountNonce,
+			Price:        dec.Price,
+			GasLimit:     dec.GasLimit,
+			Recipient:    dec.Recipient,
+			Amount:       dec.Amount,
+			Payload:      dec.Payload,
+			Type:         FeeDelegatedTxType,
+			ChainID:      dec.ChainID,
+			FeePayer:     &dec.FeePayer,
+			V:            dec.V,
+			R:            dec.R,
+			S:            dec.S,
+			PV:           dec.PV,
+			PR:           dec.PR,
+			PS:           dec.PS,
+		}
+		return nil
+	default:
+		return ErrTxTypeNotSupported
+	}
 }
 
 // MarshalJSON encodes the web3 RPC transaction format.
@@ -159,10 +427,35 @@ func (tx *Transaction) UnmarshalJSON(input []byte) error {
 		return err
 	}
 
+	switch dec.Type {
+	case LegacyTxType:
+	case BlockLimitTxType:
+		if dec.ChainID == nil {
+			return errors.New("missing required field 'chainId' for block-limit transaction")
+		}
+	case FeeDelegatedTxType:
+		if dec.ChainID == nil {
+			return errors.New("missing required field 'chainId' for fee-delegated transaction")
+		}
+		if dec.FeePayer == nil {
+			return errors.New("missing required field 'feePayer' for fee-delegated transaction")
+		}
+		if dec.PV == nil || dec.PR == nil || dec.PS == nil {
+			dec.PV, dec.PR, dec.PS = new(big.Int), new(big.Int), new(big.Int)
+		}
+		withFeePayerSignature := dec.PV.Sign() != 0 || dec.PR.Sign() != 0 || dec.PS.Sign() != 0
+		if withFeePayerSignature && !crypto.ValidateSignatureValues(byte(dec.PV.Uint64()), dec.PR, dec.PS, false) {
+			return ErrInvalidSig
+		}
+	default:
+		return ErrTxTypeNotSupported
+	}
 	withSignature := dec.V.Sign() != 0 || dec.R.Sign() != 0 || dec.S.Sign() != 0
 	if withSignature {
 		var V byte
-		if isProtectedV(dec.V) {
+		if dec.Type != LegacyTxType {
+			V = byte(dec.V.Uint64())
+		} else if isProtectedV(dec.V) {
 			chainID := DeriveChainId(dec.V).Uint64()
 			V = byte(dec.V.Uint64() - 35 - 2*chainID)
 		} else {
@@ -185,7 +478,24 @@ func (tx *Transaction) Gas() uint64        { return tx.data.GasLimit }
 func (tx *Transaction) GasPrice() *big.Int { return new(big.Int).Set(tx.data.Price) }
 func (tx *Transaction) Value() *big.Int    { return new(big.Int).Set(tx.data.Amount) }
 func (tx *Transaction) Nonce() uint64      { return tx.data.AccountNonce }
-func (tx *Transaction) CheckNonce() bool   { return true }
+func (tx *Transaction) CheckNonce() bool   { return tx.data.Type != BlockLimitTxType }
+
+// ID returns the random identifier of a block-limit transaction.
+func (tx *Transaction) ID() uint64 { return tx.data.ID }
+
+// BlockLimit returns the number of the last block a block-limit transaction can
+// be included in.
+func (tx *Transaction) BlockLimit() uint64 { return tx.data.BlockLimit }
+
+// FeePayer returns the address of the fee payer of a fee-delegated transaction.
+// It returns nil for all other transactions, whose sender pays the fees.
+func (tx *Transaction) FeePayer() *common.Address {
+	if tx.data.FeePayer == nil {
+		return nil
+	}
+	payer := *tx.data.FeePayer
+	return &payer
+}
 
 // To returns the recipient address of the transaction.
 // It returns nil if the transaction is a contract creation.
@@ -203,7 +513,12 @@ func (tx *Transaction) Hash() common.Hash {
 	if hash := tx.hash.Load(); hash != nil {
 		return hash.(common.Hash)
 	}
-	v := rlpHash(tx)
+	var v common.Hash
+	if tx.data.Type == LegacyTxType {
+		v = rlpHash(tx)
+	} else {
+		v = prefixedRlpHash(tx.data.Type, tx.typedPayload())
+	}
 	tx.hash.Store(v)
 	return v
 }
@@ -215,7 +530,7 @@ func (tx *Transaction) Size() common.StorageSize {
 		return size.(common.StorageSize)
 	}
 	c := WriteCounter(0)
-	rlp.Encode(&c, &tx.data)
+	rlp.Encode(&c, tx)
 	tx.size.Store(common.StorageSize(c))
 	return common.StorageSize(c)
 }
@@ -233,12 +548,23 @@ func (tx *Transaction) AsMessage(s Signer) (Message, error) {
 		to:         tx.data.Recipient,
 		amount:     tx.data.Amount,
 		data:       tx.data.Payload,
-		checkNonce: true,
+		checkNonce: tx.CheckNonce(),
+		blockLimit: tx.data.Type == BlockLimitTxType,
 	}
 
 	var err error
 	msg.from, err = Sender(s, tx)
-	return msg, err
+	if err != nil {
+		return msg, err
+	}
+	if tx.data.Type == FeeDelegatedTxType {
+		payer, err := Payer(s, tx)
+		if err != nil {
+			return msg, err
+		}
+		msg.payer = &payer
+	}
+	return msg, nil
 }
 
 // WithSignature returns a new transaction with the given signature.
@@ -256,6 +582,25 @@ func (tx *Transaction) WithSignature(signer Signer, sig []byte) (*Transaction, e
 	return cpy, nil
 }
 
+// WithFeePayerSignature returns a new fee-delegated transaction with the given
+// fee payer signature. This signature needs to be in the [R || S || V] format
+// where V is 0 or 1.
+func (tx *Transaction) WithFeePayerSignature(signer Signer, sig []byte) (*Transaction, error) {
+	if tx.data.Type != FeeDelegatedTxType {
+		return nil, ErrTxTypeNotSupported
+	}
+	r, s, v, err := signer.SignatureValues(tx, sig)
+	if err != nil {
+		return nil, err
+	}
+	cpy := &Transaction{
+		data: tx.data,
+		time: tx.time,
+	}
+	cpy.data.PR, cpy.data.PS, cpy.data.PV = r, s, v
+	return cpy, nil
+}
+
 // Cost returns amount + gasprice * gaslimit.
 func (tx *Transaction) Cost() *big.Int {
 	total := new(big.Int).Mul(tx.data.Price, new(big.Int).SetUint64(tx.data.GasLimit))
@@ -263,12 +608,33 @@ func (tx *Transaction) Cost() *big.Int {
 	return total
 }
 
+// Fee returns gasprice * gaslimit, the most the transaction can pay for gas.
+func (tx *Transaction) Fee() *big.Int {
+	return new(big.Int).Mul(tx.data.Price, new(big.Int).SetUint64(tx.data.GasLimit))
+}
+
+// SenderCost returns the most the sender of the transaction can be charged: the
+// value for fee-delegated transactions, amount + gasprice * gaslimit otherwise.
+func (tx *Transaction) SenderCost() *big.Int {
+	if tx.data.Type == FeeDelegatedTxType {
+		return tx.Value()
+	}
+	return tx.Cost()
+}
+
 // RawSignatureValues returns the V, R, S signature values of the transaction.
 // The return values should not be modified by the caller.
 func (tx *Transaction) RawSignatureValues() (v, r, s *big.Int) {
 	return tx.data.V, tx.data.R, tx.data.S
 }
 
+// RawFeePayerSignatureValues returns the V, R, S fee payer signature values of
+// a fee-delegated transaction, nil for all other transactions. The return
+// values should not be modified by the caller.
+func (tx *Transaction) RawFeePayerSignatureValues() (v, r, s *big.Int) {
+	return tx.data.PV, tx.data.PR, tx.data.PS
+}
+
 // Transactions is a Transaction slice type for basic sorting.
 type Transactions []*Transaction
 
@@ -407,12 +773,14 @@ func (t *TransactionsByPriceAndNonce) Pop() {
 type Message struct {
 	to         *common.Address
 	from       common.Address
+	payer      *common.Address
 	nonce      uint64
 	amount     *big.Int
 	gasLimit   uint64
 	gasPrice   *big.Int
 	data       []byte
 	checkNonce bool
+	blockLimit bool
 }
 
 func NewMessage(from common.Address, to *common.Address, nonce uint64, amount *big.Int, gasLimit uint64, gasPrice *big.Int, data []byte, checkNonce bool) Message {
@@ -436,3 +804,22 @@ func (m Message) Gas() uint64          { return m.gasLimit }
 func (m Message) Nonce() uint64        { return m.nonce }
 func (m Message) Data() []byte         { return m.data }
 func (m Message) CheckNonce() bool     { return m.checkNonce }
+
+// BlockLimited reports whether the message is a block-limit transaction, which
+// is protected from replays by its ID instead of the nonce of the sender.
+func (m Message) BlockLimited() bool { return m.blockLimit }
+
+// Payer returns the address paying the gas of the message, the sender unless
+// the fees were delegated.
+func (m Message) Payer() common.Address {
+	if m.payer != nil {
+		return *m.payer
+	}
+	return m.from
+}
+
+// WithPayer returns a copy of the message whose gas is paid by the given account.
+func (m Message) WithPayer(payer common.Address) Message {
+	m.payer = &payer
+	return m
+}
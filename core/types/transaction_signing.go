@@ -54,6 +54,17 @@ func SignTx(tx *Transaction, s Signer, prv *ecdsa.PrivateKey) (*Transaction, err
 	return tx.WithSignature(s, sig)
 }
 
+// SignFeePayer signs a fee-delegated transaction, already signed by its sender,
+// as the fee payer using the given signer and private key.
+func SignFeePayer(tx *Transaction, s Signer, prv *ecdsa.PrivateKey) (*Transaction, error) {
+	h := s.FeePayerHash(tx)
+	sig, err := crypto.Sign(h[:], prv)
+	if err != nil {
+		return nil, err
+	}
+	return tx.WithFeePayerSignature(s, sig)
+}
+
 // Sender returns the address derived from the signature (V, R, S) using secp256k1
 // elliptic curve and an error if it failed deriving or upon an incorrect
 // signature.
@@ -79,6 +90,27 @@ func Sender(signer Signer, tx *Transaction) (common.Address, error) {
 	return addr, nil
 }
 
+// Payer returns the address paying the fees of the transaction: the fee payer
+// derived from the fee payer signature of fee-delegated transactions, the sender
+// of all others. Like Sender, it may cache the address.
+func Payer(signer Signer, tx *Transaction) (common.Address, error) {
+	if tx.Type() != FeeDelegatedTxType {
+		return Sender(signer, tx)
+	}
+	if sc := tx.payer.Load(); sc != nil {
+		sigCache := sc.(sigCache)
+		if sigCache.signer.Equal(signer) {
+			return sigCache.from, nil
+		}
+	}
+	addr, err := signer.FeePayer(tx)
+	if err != nil {
+		return common.Address{}, err
+	}
+	tx.payer.Store(sigCache{signer: signer, from: addr})
+	return addr, nil
+}
+
 // Signer encapsulates transaction signature handling. Note that this interface is not a
 // stable API and may change at any time to accommodate new protocol rules.
 type Signer interface {
@@ -89,6 +121,10 @@ type Signer interface {
 	SignatureValues(tx *Transaction, sig []byte) (r, s, v *big.Int, err error)
 	// Hash returns the hash to be signed.
 	Hash(tx *Transaction) common.Hash
+	// FeePayer returns the fee payer address of a fee-delegated transaction.
+	FeePayer(tx *Transaction) (common.Address, error)
+	// FeePayerHash returns the hash to be signed by the fee payer.
+	FeePayerHash(tx *Transaction) common.Hash
 	// Equal returns true if the given signer is the same as the receiver.
 	Equal(Signer) bool
 }
@@ -113,9 +149,23 @@ func (s EIP155Signer) Equal(s2 Signer) bool {
 	return ok && eip155.chainId.Cmp(s.chainId) == 0
 }
 
-var big8 = big.NewInt(8)
+var (
+	big8  = big.NewInt(8)
+	big27 = big.NewInt(27)
+)
 
 func (s EIP155Signer) Sender(tx *Transaction) (common.Address, error) {
+	if tx.Type() != LegacyTxType {
+		if tx.Type() != BlockLimitTxType && tx.Type() != FeeDelegatedTxType {
+			return common.Address{}, ErrTxTypeNotSupported
+		}
+		if tx.data.ChainID.Cmp(s.chainId) != 0 {
+			return common.Address{}, ErrInvalidChainId
+		}
+		// Typed transactions carry the bare signature parity in V
+		V := new(big.Int).Add(tx.data.V, big27)
+		return RecoverPlain(s.Hash(tx), tx.data.R, tx.data.S, V, true)
+	}
 	if !tx.Protected() {
 		return HomesteadSigner{}.Sender(tx)
 	}
@@ -127,6 +177,26 @@ func (s EIP155Signer) Sender(tx *Transaction) (common.Address, error) {
 	return RecoverPlain(s.Hash(tx), tx.data.R, tx.data.S, V, true)
 }
 
+// FeePayer recovers the fee payer of a fee-delegated transaction, which has to
+// match the fee payer the sender designated.
+func (s EIP155Signer) FeePayer(tx *Transaction) (common.Address, error) {
+	if tx.Type() != FeeDelegatedTxType {
+		return common.Address{}, ErrTxTypeNotSupported
+	}
+	if tx.data.ChainID.Cmp(s.chainId) != 0 {
+		return common.Address{}, ErrInvalidChainId
+	}
+	V := new(big.Int).Add(tx.data.PV, big27)
+	addr, err := RecoverPlain(s.FeePayerHash(tx), tx.data.PR, tx.data.PS, V, true)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if addr != *tx.data.FeePayer {
+		return common.Address{}, ErrInvalidFeePayer
+	}
+	return addr, nil
+}
+
 // SignatureValues returns signature values. This signature
 // needs to be in the [R || S || V] format where V is 0 or 1.
 func (s EIP155Signer) SignatureValues(tx *Transaction, sig []byte) (R, S, V *big.Int, err error) {
@@ -134,6 +204,9 @@ func (s EIP155Signer) SignatureValues(tx *Transaction, sig []byte) (R, S, V *big
 	if err != nil {
 		return nil, nil, nil, err
 	}
+	if tx.Type() != LegacyTxType {
+		return R, S, big.NewInt(int64(sig[64])), nil
+	}
 	if s.chainId.Sign() != 0 {
 		V = big.NewInt(int64(sig[64] + 35))
 		V.Add(V, s.chainIdMul)
@@ -144,6 +217,30 @@ func (s EIP155Signer) SignatureValues(tx *Transaction, sig []byte) (R, S, V *big
 // Hash returns the hash to be signed by the sender.
 // It does not uniquely identify the transaction.
 func (s EIP155Signer) Hash(tx *Transaction) common.Hash {
+	if tx.Type() == BlockLimitTxType {
+		return prefixedRlpHash(tx.Type(), []interface{}{
+			s.chainId,
+			tx.data.ID,
+			tx.data.Price,
+			tx.data.GasLimit,
+			tx.data.BlockLimit,
+			tx.data.Recipient,
+			tx.data.Amount,
+			tx.data.Payload,
+		})
+	}
+	if tx.Type() == FeeDelegatedTxType {
+		return prefixedRlpHash(tx.Type(), []interface{}{
+			s.chainId,
+			tx.data.AccountNonce,
+			tx.data.Price,
+			tx.data.GasLimit,
+			tx.data.Recipient,
+			tx.data.Amount,
+			tx.data.Payload,
+			tx.data.FeePayer,
+		})
+	}
 	return rlpHash([]interface{}{
 		tx.data.AccountNonce,
 		tx.data.Price,
@@ -155,6 +252,24 @@ func (s EIP155Signer) Hash(tx *Transaction) common.Hash {
 	})
 }
 
+// FeePayerHash returns the hash to be signed by the fee payer of a fee-delegated
+// transaction. It covers the signature of the sender as well.
+func (s EIP155Signer) FeePayerHash(tx *Transaction) common.Hash {
+	return prefixedRlpHash(FeeDelegatedTxType, []interface{}{
+		s.chainId,
+		tx.data.AccountNonce,
+		tx.data.Price,
+		tx.data.GasLimit,
+		tx.data.Recipient,
+		tx.data.Amount,
+		tx.data.Payload,
+		tx.data.FeePayer,
+		tx.data.V,
+		tx.data.R,
+		tx.data.S,
+	})
+}
+
 // HomesteadTransaction implements TransactionInterface using the
 // homestead rules.
 type HomesteadSigner struct{ FrontierSigner }
@@ -171,11 +286,25 @@ func (hs HomesteadSigner) SignatureValues(tx *Transaction, sig []byte) (r, s, v
 }
 
 func (hs HomesteadSigner) Sender(tx *Transaction) (common.Address, error) {
+	if tx.Type() != LegacyTxType {
+		return common.Address{}, ErrTxTypeNotSupported
+	}
 	return RecoverPlain(hs.Hash(tx), tx.data.R, tx.data.S, tx.data.V, true)
 }
 
 type FrontierSigner struct{}
 
+// FeePayer is not supported, fee-delegated transactions are typed transactions.
+func (fs FrontierSigner) FeePayer(tx *Transaction) (common.Address, error) {
+	return common.Address{}, ErrTxTypeNotSupported
+}
+
+// FeePayerHash returns the empty hash, fee-delegated transactions are typed
+// transactions.
+func (fs FrontierSigner) FeePayerHash(tx *Transaction) common.Hash {
+	return common.Hash{}
+}
+
 func (s FrontierSigner) Equal(s2 Signer) bool {
 	_, ok := s2.(FrontierSigner)
 	return ok
@@ -207,6 +336,9 @@ func (fs FrontierSigner) Hash(tx *Transaction) common.Hash {
 }
 
 func (fs FrontierSigner) Sender(tx *Transaction) (common.Address, error) {
+	if tx.Type() != LegacyTxType {
+		return common.Address{}, ErrTxTypeNotSupported
+	}
 	return RecoverPlain(fs.Hash(tx), tx.data.R, tx.data.S, tx.data.V, false)
 }
 
@@ -183,11 +183,15 @@ func TestTransactionJSON(t *testing.T) {
 	transactions := make([]*Transaction, 0, 50)
 	for i := uint64(0); i < 25; i++ {
 		var tx *Transaction
-		switch i % 2 {
+		switch i % 4 {
 		case 0:
 			tx = NewTransaction(i, common.Address{1}, common.Big0, 1, common.Big2, []byte("abcdef"))
 		case 1:
 			tx = NewContractCreation(i, common.Big0, 1, common.Big2, []byte("abcdef"))
+		case 2:
+			tx = NewBlockLimitTransaction(common.Big1, i, 100, &common.Address{1}, common.Big0, 1, common.Big2, []byte("abcdef"))
+		case 3:
+			tx = NewFeeDelegatedTransaction(common.Big1, i, &common.Address{1}, common.Big0, 1, common.Big2, []byte("abcdef"), common.Address{2})
 		}
 		transactions = append(transactions, tx)
 
@@ -219,3 +223,134 @@ func TestTransactionJSON(t *testing.T) {
 		}
 	}
 }
+
+// Tests that block-limit transactions are signed and encoded in the typed
+// envelope, both standalone and within a block body.
+func TestBlockLimitTransaction(t *testing.T) {
+	key, addr := defaultTestKey()
+	signer := NewEIP155Signer(big.NewInt(18))
+
+	tx, err := SignTx(NewBlockLimitTransaction(big.NewInt(18), 0xdeadbeef, 100, &common.Address{1}, big.NewInt(10), 21000, big.NewInt(1), nil), signer, key)
+	if err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	if from, err := Sender(signer, tx); err != nil || from != addr {
+		t.Fatalf("sender mismatch: have %x, %v, want %x", from, err, addr)
+	}
+	if _, err := Sender(NewEIP155Signer(big.NewInt(19)), tx); err != ErrInvalidChainId {
+		t.Errorf("foreign chain error mismatch: have %v, want %v", err, ErrInvalidChainId)
+	}
+	if _, err := (HomesteadSigner{}).Sender(tx); err != ErrTxTypeNotSupported {
+		t.Errorf("homestead signer error mismatch: have %v, want %v", err, ErrTxTypeNotSupported)
+	}
+	// The canonical encoding is the type byte followed by the payload
+	enc, err := tx.MarshalBinary()
+	if err != nil {
+		t.Fatalf("failed to encode transaction: %v", err)
+	}
+	if enc[0] != BlockLimitTxType {
+		t.Errorf("type byte mismatch: have %d, want %d", enc[0], BlockLimitTxType)
+	}
+	if hash := crypto.Keccak256Hash(enc); hash != tx.Hash() {
+		t.Errorf("hash mismatch: have %x, want %x", tx.Hash(), hash)
+	}
+	var dec Transaction
+	if err := dec.UnmarshalBinary(enc); err != nil {
+		t.Fatalf("failed to decode transaction: %v", err)
+	}
+	if dec.Hash() != tx.Hash() || dec.ID() != 0xdeadbeef || dec.BlockLimit() != 100 || dec.CheckNonce() {
+		t.Errorf("decoded transaction mismatch: have %v, want %v", &dec, tx)
+	}
+	if err := dec.UnmarshalBinary(append([]byte{0x7f}, enc[1:]...)); err != ErrTxTypeNotSupported {
+		t.Errorf("unknown type error mismatch: have %v, want %v", err, ErrTxTypeNotSupported)
+	}
+	// Within a list the typed transaction is wrapped in an RLP string
+	legacy, _ := SignTx(NewTransaction(0, common.Address{2}, big.NewInt(1), 21000, big.NewInt(1), nil), signer, key)
+	blob, err := rlp.EncodeToBytes(Transactions{legacy, tx})
+	if err != nil {
+		t.Fatalf("failed to encode transactions: %v", err)
+	}
+	var txs Transactions
+	if err := rlp.DecodeBytes(blob, &txs); err != nil {
+		t.Fatalf("failed to decode transactions: %v", err)
+	}
+	if len(txs) != 2 || txs[0].Hash() != legacy.Hash() || txs[1].Hash() != tx.Hash() {
+		t.Fatalf("decoded transactions mismatch")
+	}
+	if txs[1].Size() != tx.Size() {
+		t.Errorf("size mismatch: have %v, want %v", txs[1].Size(), tx.Size())
+	}
+	if from, err := Sender(signer, txs[1]); err != nil || from != addr {
+		t.Errorf("decoded sender mismatch: have %x, %v, want %x", from, err, addr)
+	}
+}
+
+// Tests that fee-delegated transactions are signed by the sender and then the
+// fee payer, and that the fee payer signature commits to the sender's.
+func TestFeeDelegatedTransaction(t *testing.T) {
+	key, addr := defaultTestKey()
+	payerKey, _ := crypto.GenerateKey()
+	payer := crypto.PubkeyToAddress(payerKey.PublicKey)
+	signer := NewEIP155Signer(big.NewInt(18))
+
+	tx, err := SignTx(NewFeeDelegatedTransaction(big.NewInt(18), 5, &common.Address{1}, big.NewInt(10), 21000, big.NewInt(1), nil, payer), signer, key)
+	if err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	if _, err := Payer(signer, tx); err == nil {
+		t.Fatalf("fee payer recovered without fee payer signature")
+	}
+	signed, err := SignFeePayer(tx, signer, payerKey)
+	if err != nil {
+		t.Fatalf("failed to sign as fee payer: %v", err)
+	}
+	if from, err := Sender(signer, signed); err != nil || from != addr {
+		t.Fatalf("sender mismatch: have %x, %v, want %x", from, err, addr)
+	}
+	if have, err := Payer(signer, signed); err != nil || have != payer {
+		t.Fatalf("fee payer mismatch: have %x, %v, want %x", have, err, payer)
+	}
+	if cost := signed.SenderCost(); cost.Cmp(big.NewInt(10)) != 0 {
+		t.Errorf("sender cost mismatch: have %v, want 10", cost)
+	}
+	// Only the designated fee payer may sign
+	forged, err := SignFeePayer(tx, signer, key)
+	if err != nil {
+		t.Fatalf("failed to sign as foreign fee payer: %v", err)
+	}
+	if _, err := Payer(signer, forged); err != ErrInvalidFeePayer {
+		t.Errorf("foreign fee payer error mismatch: have %v, want %v", err, ErrInvalidFeePayer)
+	}
+	// The encoding round trip preserves both signatures
+	enc, err := signed.MarshalBinary()
+	if err != nil {
+		t.Fatalf("failed to encode transaction: %v", err)
+	}
+	if enc[0] != FeeDelegatedTxType {
+		t.Errorf("type byte mismatch: have %d, want %d", enc[0], FeeDelegatedTxType)
+	}
+	var dec Transaction
+	if err := dec.UnmarshalBinary(enc); err != nil {
+		t.Fatalf("failed to decode transaction: %v", err)
+	}
+	if dec.Hash() != signed.Hash() || dec.Nonce() != 5 || !dec.CheckNonce() {
+		t.Errorf("decoded transaction mismatch: have %v, want %v", &dec, signed)
+	}
+	if have, err := Payer(signer, &dec); err != nil || have != payer {
+		t.Errorf("decoded fee payer mismatch: have %x, %v, want %x", have, err, payer)
+	}
+	// Re-signing by the sender invalidates the fee payer signature
+	resigned, _ := SignTx(NewFeeDelegatedTransaction(big.NewInt(18), 6, &common.Address{1}, big.NewInt(10), 21000, big.NewInt(1), nil, payer), signer, key)
+	pv, pr, ps := signed.RawFeePayerSignatureValues()
+	resigned.data.PV, resigned.data.PR, resigned.data.PS = pv, pr, ps
+	if _, err := Payer(signer, resigned); err == nil {
+		t.Errorf("fee payer signature accepted for a different sender signature")
+	}
+	msg, err := signed.AsMessage(signer)
+	if err != nil {
+		t.Fatalf("failed to convert to message: %v", err)
+	}
+	if msg.From() != addr || msg.Payer() != payer {
+		t.Errorf("message mismatch: have from %x payer %x, want %x, %x", msg.From(), msg.Payer(), addr, payer)
+	}
+}
@@ -39,6 +39,8 @@ type Config struct {
 	EVMInterpreter   string // External EVM interpreter options
 
 	ExtraEips []int // Additional EIPS that are to be enabled
+
+	ParallelTxs bool // Enables the optimistic parallel execution of the transactions of blocks
 }
 
 // Interpreter is used to run Ethereum based contracts and will utilise the
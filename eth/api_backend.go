@@ -35,6 +35,7 @@ import (
 	"github.com/simplechain-org/go-simplechain/ethdb"
 	"github.com/simplechain-org/go-simplechain/event"
 	"github.com/simplechain-org/go-simplechain/params"
+	"github.com/simplechain-org/go-simplechain/private"
 	"github.com/simplechain-org/go-simplechain/rpc"
 )
 
@@ -50,6 +51,12 @@ func (b *EthAPIBackend) ChainConfig() *params.ChainConfig {
 	return b.eth.blockchain.Config()
 }
 
+// PrivateTransactionManager returns the private transaction manager of the
+// chain, nil if the node doesn't participate in private transactions.
+func (b *EthAPIBackend) PrivateTransactionManager() private.PrivateTransactionManager {
+	return b.eth.blockchain.PrivateTransactionManager()
+}
+
 func (b *EthAPIBackend) CurrentBlock() *types.Block {
 	return b.eth.blockchain.CurrentBlock()
 }
@@ -37,6 +37,7 @@ import (
 	"github.com/simplechain-org/go-simplechain/consensus/ethash"
 	"github.com/simplechain-org/go-simplechain/consensus/istanbul"
 	istanbulBackend "github.com/simplechain-org/go-simplechain/consensus/istanbul/backend"
+	"github.com/simplechain-org/go-simplechain/consensus/multi"
 	"github.com/simplechain-org/go-simplechain/consensus/raft"
 	"github.com/simplechain-org/go-simplechain/consensus/scrypt"
 	"github.com/simplechain-org/go-simplechain/core"
@@ -155,14 +156,15 @@ func New(ctx *node.ServiceContext, config *Config) (*Ethereum, error) {
 	}
 	log.Info("Initialised chain configuration", "config", chainConfig)
 
-	switch {
-	case chainConfig.Istanbul != nil:
-		return nil, errors.New("Istanbul consensus is not support in MainChain role")
-	case chainConfig.DPoS != nil:
-		return nil, errors.New("DPoS consensus is not support in MainChain role")
-	case chainConfig.Raft:
-		return nil, errors.New("Raft consensus is not support in MainChain role")
-
+	for _, consensusConfig := range chainConfig.ConsensusConfigs() {
+		switch {
+		case consensusConfig.Istanbul != nil:
+			return nil, errors.New("Istanbul consensus is not support in MainChain role")
+		case consensusConfig.DPoS != nil:
+			return nil, errors.New("DPoS consensus is not support in MainChain role")
+		case consensusConfig.Raft:
+			return nil, errors.New("Raft consensus is not support in MainChain role")
+		}
 	}
 
 	eth := &Ethereum{
@@ -201,6 +203,7 @@ func New(ctx *node.ServiceContext, config *Config) (*Ethereum, error) {
 			EnablePreimageRecording: config.EnablePreimageRecording,
 			EWASMInterpreter:        config.EWASMInterpreter,
 			EVMInterpreter:          config.EVMInterpreter,
+			ParallelTxs:             config.ParallelTxs,
 		}
 		cacheConfig = &core.CacheConfig{
 			TrieCleanLimit:      config.TrieCleanCache,
@@ -208,6 +211,7 @@ func New(ctx *node.ServiceContext, config *Config) (*Ethereum, error) {
 			TrieDirtyLimit:      config.TrieDirtyCache,
 			TrieDirtyDisabled:   config.NoPruning,
 			TrieTimeLimit:       config.TrieTimeout,
+			SnapshotLimit:       config.SnapshotCache,
 		}
 	)
 	eth.blockchain, err = core.NewBlockChain(chainDb, cacheConfig, chainConfig, eth.engine, vmConfig, eth.shouldPreserve)
@@ -215,6 +219,9 @@ func New(ctx *node.ServiceContext, config *Config) (*Ethereum, error) {
 		return nil, err
 
 	}
+	if config.PrivateTxManager != nil {
+		eth.blockchain.SetPrivateTransactionManager(config.PrivateTxManager)
+	}
 	// Rewind the chain in case of an incompatible config upgrade.
 	if compat, ok := genesisErr.(*params.ConfigCompatError); ok {
 		log.Warn("Rewinding chain to upgrade configuration", "err", compat)
@@ -222,6 +229,11 @@ func New(ctx *node.ServiceContext, config *Config) (*Ethereum, error) {
 		rawdb.WriteChainConfig(chainDb, genesisHash, chainConfig)
 	}
 	eth.bloomIndexer.Start(eth.blockchain)
+	for _, engine := range multi.Engines(eth.engine) {
+		if dpos, ok := engine.(*dpos.DPoS); ok {
+			dpos.IndexRewards(eth.blockchain)
+		}
+	}
 
 	if config.TxPool.Journal != "" {
 		config.TxPool.Journal = ctx.ResolvePath(config.TxPool.Journal)
@@ -278,7 +290,26 @@ func makeExtraData(extra []byte) []byte {
 }
 
 // CreateConsensusEngine creates the required type of consensus engine instance for an Ethereum service
+//
+// If the chain config schedules consensus forks, the engine switches to the
+// engine of each fork at its block.
 func CreateConsensusEngine(ctx *node.ServiceContext, chainConfig *params.ChainConfig, config *Config, notify []string, noverify bool, db ethdb.Database) consensus.Engine {
+	if len(chainConfig.ConsensusForks) == 0 {
+		return createConsensusEngine(ctx, chainConfig, 0, config, notify, noverify, db)
+	}
+	engines := []consensus.Engine{createConsensusEngine(ctx, chainConfig, 0, config, notify, noverify, db)}
+	starts := []uint64{0}
+	for _, fork := range chainConfig.ConsensusForks {
+		start := fork.Block.Uint64()
+		engines = append(engines, createConsensusEngine(ctx, fork.Apply(chainConfig), start, config, notify, noverify, db))
+		starts = append(starts, start)
+	}
+	return multi.New(engines, starts)
+}
+
+// createConsensusEngine creates the consensus engine configured in the chain
+// config, sealing blocks from number start on.
+func createConsensusEngine(ctx *node.ServiceContext, chainConfig *params.ChainConfig, start uint64, config *Config, notify []string, noverify bool, db ethdb.Database) consensus.Engine {
 	// If proof-of-authority is requested, set it up
 	if chainConfig.Clique != nil {
 		return clique.New(chainConfig.Clique, db)
@@ -294,7 +325,10 @@ func CreateConsensusEngine(ctx *node.ServiceContext, chainConfig *params.ChainCo
 			log.Warn("Scrypt used in test mode")
 			return scrypt.NewTester(notify, noverify)
 		default:
-			engine := scrypt.NewScrypt(scrypt.Config{PowMode: scrypt.ModeNormal}, notify, noverify)
+			engine := scrypt.NewScrypt(scrypt.Config{
+				PowMode:     scrypt.ModeNormal,
+				WorkHistory: config.Scrypt.WorkHistory,
+			}, notify, noverify)
 			engine.SetThreads(-1) // Disable CPU mining
 			return engine
 		}
@@ -310,6 +344,8 @@ func CreateConsensusEngine(ctx *node.ServiceContext, chainConfig *params.ChainCo
 			config.Istanbul.Epoch = chainConfig.Istanbul.Epoch
 		}
 		config.Istanbul.ProposerPolicy = istanbul.ProposerPolicy(chainConfig.Istanbul.ProposerPolicy)
+		config.Istanbul.TransitionBlock = start
+		config.Istanbul.TransitionValidators = chainConfig.Istanbul.Validators
 		return istanbulBackend.New(&config.Istanbul, ctx.NodeKey(), db)
 	}
 
@@ -538,21 +574,23 @@ func (s *Ethereum) StartMining(threads int) error {
 			log.Error("Cannot start mining without etherbase", "err", err)
 			return fmt.Errorf("etherbase missing: %v", err)
 		}
-		if clique, ok := s.engine.(*clique.Clique); ok {
-			wallet, err := s.accountManager.Find(accounts.Account{Address: eb})
-			if wallet == nil || err != nil {
-				log.Error("Etherbase account unavailable locally", "err", err)
-				return fmt.Errorf("signer missing: %v", err)
+		for _, engine := range multi.Engines(s.engine) {
+			if clique, ok := engine.(*clique.Clique); ok {
+				wallet, err := s.accountManager.Find(accounts.Account{Address: eb})
+				if wallet == nil || err != nil {
+					log.Error("Etherbase account unavailable locally", "err", err)
+					return fmt.Errorf("signer missing: %v", err)
+				}
+				clique.Authorize(eb, wallet.SignData)
 			}
-			clique.Authorize(eb, wallet.SignData)
-		}
-		if dpos, ok := s.engine.(*dpos.DPoS); ok {
-			wallet, err := s.accountManager.Find(accounts.Account{Address: eb})
-			if wallet == nil || err != nil {
-				log.Error("Etherbase account unavailable locally", "err", err)
-				return fmt.Errorf("signer missing: %v", err)
+			if dpos, ok := engine.(*dpos.DPoS); ok {
+				wallet, err := s.accountManager.Find(accounts.Account{Address: eb})
+				if wallet == nil || err != nil {
+					log.Error("Etherbase account unavailable locally", "err", err)
+					return fmt.Errorf("signer missing: %v", err)
+				}
+				dpos.Authorize(eb, wallet.SignData)
 			}
-			dpos.Authorize(eb, wallet.SignData)
 		}
 		// If mining is started, we can disable the transaction rejection mechanism
 		// introduced to speed sync times.
@@ -27,12 +27,14 @@ import (
 	"github.com/simplechain-org/go-simplechain/common"
 	"github.com/simplechain-org/go-simplechain/consensus/ethash"
 	"github.com/simplechain-org/go-simplechain/consensus/istanbul"
+	"github.com/simplechain-org/go-simplechain/consensus/scrypt"
 	"github.com/simplechain-org/go-simplechain/core"
 	"github.com/simplechain-org/go-simplechain/cross"
 	"github.com/simplechain-org/go-simplechain/eth/downloader"
 	"github.com/simplechain-org/go-simplechain/eth/gasprice"
 	"github.com/simplechain-org/go-simplechain/miner"
 	"github.com/simplechain-org/go-simplechain/params"
+	"github.com/simplechain-org/go-simplechain/private"
 )
 
 // DefaultConfig contains default settings for use on the Ethereum main net.
@@ -45,6 +47,9 @@ var DefaultConfig = Config{
 		DatasetsInMem:  1,
 		DatasetsOnDisk: 2,
 	},
+	Scrypt: scrypt.Config{
+		WorkHistory: scrypt.DefaultWorkHistory,
+	},
 	NetworkId:          1,
 	LightPeers:         100,
 	UltraLightFraction: 75,
@@ -125,6 +130,7 @@ type Config struct {
 	TrieCleanCache int
 	TrieDirtyCache int
 	TrieTimeout    time.Duration
+	SnapshotCache  int // Megabytes of memory for the state snapshot read cache, 0 disables the snapshot
 
 	// Mining options
 	Miner miner.Config
@@ -132,6 +138,9 @@ type Config struct {
 	// Ethash options
 	Ethash ethash.Config
 
+	// Scrypt options
+	Scrypt scrypt.Config
+
 	// Istanbul options
 	Istanbul istanbul.Config
 
@@ -144,6 +153,9 @@ type Config struct {
 	// Enables tracking of SHA3 preimages in the VM
 	EnablePreimageRecording bool
 
+	// Executes the transactions of imported blocks in parallel
+	ParallelTxs bool
+
 	// Miscellaneous options
 	DocRoot string `toml:"-"`
 
@@ -153,6 +165,9 @@ type Config struct {
 	// Type of the EVM interpreter ("" for default)
 	EVMInterpreter string
 
+	// Run the built-in tracers in the JavaScript interpreter instead of natively
+	JSTracers bool
+
 	// RPCGasCap is the global gas cap for eth-call variants.
 	RPCGasCap *big.Int `toml:",omitempty"`
 
@@ -165,6 +180,14 @@ type Config struct {
 	// Singularity block override (TODO: remove after the fork)
 	OverrideSingularity *big.Int
 
+	// PrivateTxManager supplies the payloads of private transactions, nil if
+	// the node doesn't take part in any.
+	PrivateTxManager private.PrivateTransactionManager `toml:"-"`
+
+	// PrivateTxManagerURL is the http(s) URL or the IPC socket path of the
+	// transaction manager the command line tools connect PrivateTxManager to.
+	PrivateTxManagerURL string `toml:",omitempty"`
+
 	Role common.ChainRole
 
 	CrossConfig cross.Config
@@ -0,0 +1,555 @@
+// Copyright 2020 The go-simplechain Authors
+// This file is part of the go-simplechain library.
+//
+// The go-simplechain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-simplechain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-simplechain library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/simplechain-org/go-simplechain/common"
+	"github.com/simplechain-org/go-simplechain/common/hexutil"
+	"github.com/simplechain-org/go-simplechain/core/vm"
+	"github.com/simplechain-org/go-simplechain/crypto"
+)
+
+// errNoExecution is returned by the prestate tracer if the traced message ran no
+// code at all, so the state was never available to it.
+var errNoExecution = errors.New("no code executed")
+
+// natives contains the constructors of the built-in tracers implemented in Go,
+// by the name of the JavaScript tracer they replace.
+var natives = map[string]func() ResultTracer{
+	"callTracer":     func() ResultTracer { return newCallTracer() },
+	"prestateTracer": func() ResultTracer { return newPrestateTracer() },
+	"4byteTracer":    func() ResultTracer { return newFourByteTracer() },
+}
+
+// interrupter implements the interruption of the native tracers, matching the
+// JavaScript tracer: once stopped, no more steps are traced and the reason is
+// reported alongside the result.
+type interrupter struct {
+	interrupt uint32 // Atomic flag to signal execution interruption
+	reason    error  // Textual reason for the interruption
+	err       error  // Error, if one has occurred
+}
+
+// Stop terminates execution of the tracer at the first opportune moment.
+func (i *interrupter) Stop(err error) {
+	i.reason = err
+	atomic.StoreUint32(&i.interrupt, 1)
+}
+
+// halted reports whether the tracer was interrupted, recording the reason.
+func (i *interrupter) halted() bool {
+	if i.err == nil && atomic.LoadUint32(&i.interrupt) > 0 {
+		i.err = i.reason
+	}
+	return i.err != nil
+}
+
+// peek returns the nth-from-the-top element of the stack, or zero if the stack
+// is too shallow.
+func peek(stack *vm.Stack, n int) *big.Int {
+	data := stack.Data()
+	if len(data) <= n {
+		return new(big.Int)
+	}
+	return data[len(data)-n-1]
+}
+
+// memorySlice returns a copy of the requested range of memory, or nil if it is
+// out of bounds. Like in the JavaScript tracers, memory is not expanded.
+func memorySlice(memory *vm.Memory, offset, size *big.Int) []byte {
+	if !offset.IsUint64() || !size.IsUint64() {
+		return nil
+	}
+	start, end := offset.Uint64(), offset.Uint64()+size.Uint64()
+	if end < start || uint64(memory.Len()) < end {
+		return nil
+	}
+	return memory.GetCopy(int64(start), int64(end-start))
+}
+
+// isPrecompiled reports whether the address is a precompiled contract, which is
+// not traced as a call, just like any other opcode.
+func isPrecompiled(addr common.Address) bool {
+	_, ok := vm.PrecompiledContractsIstanbul[addr]
+	return ok
+}
+
+// bigHex formats a number the way the JavaScript tracers do, lowercase hex with
+// a 0x prefix, keeping the sign behind the prefix.
+func bigHex(n *big.Int) string {
+	return "0x" + n.Text(16)
+}
+
+// addrHex formats an address the way the JavaScript tracers do.
+func addrHex(addr common.Address) string {
+	return hexutil.Encode(addr[:])
+}
+
+// callFrame is a single call in the result of the call tracer. The fields are in
+// the order of the JavaScript tracer output, unset ones are omitted.
+type callFrame struct {
+	Type    string       `json:"type"`
+	From    string       `json:"from,omitempty"`
+	To      string       `json:"to,omitempty"`
+	Value   string       `json:"value,omitempty"`
+	Gas     string       `json:"gas,omitempty"`
+	GasUsed string       `json:"gasUsed,omitempty"`
+	Input   string       `json:"input,omitempty"`
+	Output  string       `json:"output,omitempty"`
+	Error   string       `json:"error,omitempty"`
+	Time    string       `json:"time,omitempty"`
+	Calls   []*callFrame `json:"calls,omitempty"`
+
+	gas     uint64   // Gas available within the call, if known
+	hasGas  bool     // Whether the gas within the call is known
+	gasIn   uint64   // Gas available to the caller before the call
+	gasCost uint64   // Cost of the call opcode
+	outOff  *big.Int // Memory offset of the call output
+	outLen  *big.Int // Length of the call output
+}
+
+// callTracer is the native implementation of call_tracer.js, extracting all the
+// internal calls made by a transaction.
+type callTracer struct {
+	interrupter
+
+	callstack []*callFrame // Current recursive call stack of the EVM execution
+	descended bool         // Whether we've just descended into an inner call
+
+	typ     string
+	from    common.Address
+	to      common.Address
+	input   []byte
+	gas     uint64
+	value   *big.Int
+	output  []byte
+	gasUsed uint64
+	time    string
+	failure string
+}
+
+// newCallTracer creates a native call tracer.
+func newCallTracer() *callTracer {
+	return &callTracer{callstack: []*callFrame{{}}}
+}
+
+// CaptureStart implements the Tracer interface to initialize the tracing operation.
+func (t *callTracer) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	t.typ = "CALL"
+	if create {
+		t.typ = "CREATE"
+	}
+	t.from, t.to, t.input, t.gas, t.value = from, to, common.CopyBytes(input), gas, value
+	return nil
+}
+
+// CaptureState implements the Tracer interface to trace a single step of VM execution.
+func (t *callTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	if t.halted() {
+		return nil
+	}
+	if err != nil {
+		t.fault(err)
+		return nil
+	}
+	switch op {
+	case vm.CREATE, vm.CREATE2:
+		// A new contract is being created, add to the call stack
+		t.callstack = append(t.callstack, &callFrame{
+			Type:    op.String(),
+			From:    addrHex(contract.Address()),
+			Input:   hexutil.Encode(memorySlice(memory, peek(stack, 1), peek(stack, 2))),
+			Value:   bigHex(peek(stack, 0)),
+			gasIn:   gas,
+			gasCost: cost,
+		})
+		t.descended = true
+		return nil
+
+	case vm.SELFDESTRUCT:
+		// A contract is being self destructed, gather that as a subcall too
+		parent := t.callstack[len(t.callstack)-1]
+		parent.Calls = append(parent.Calls, &callFrame{Type: op.String()})
+		return nil
+
+	case vm.CALL, vm.CALLCODE, vm.DELEGATECALL, vm.STATICCALL:
+		// Skip any pre-compile invocations, those are just fancy opcodes
+		to := common.BigToAddress(peek(stack, 1))
+		if isPrecompiled(to) {
+			return nil
+		}
+		off := 1
+		if op == vm.DELEGATECALL || op == vm.STATICCALL {
+			off = 0
+		}
+		call := &callFrame{
+			Type:    op.String(),
+			From:    addrHex(contract.Address()),
+			To:      addrHex(to),
+			Input:   hexutil.Encode(memorySlice(memory, peek(stack, 2+off), peek(stack, 3+off))),
+			gasIn:   gas,
+			gasCost: cost,
+			outOff:  new(big.Int).Set(peek(stack, 4+off)),
+			outLen:  new(big.Int).Set(peek(stack, 5+off)),
+		}
+		if off == 1 {
+			call.Value = bigHex(peek(stack, 2))
+		}
+		t.callstack = append(t.callstack, call)
+		t.descended = true
+		return nil
+	}
+	// If we've just descended into an inner call, retrieve its true allowance. It
+	// is only known if the call has code to run, calls to plain accounts are left
+	// without gas.
+	if t.descended {
+		if depth >= len(t.callstack) {
+			call := t.callstack[len(t.callstack)-1]
+			call.gas, call.hasGas = gas, true
+		}
+		t.descended = false
+	}
+	if op == vm.REVERT {
+		t.callstack[len(t.callstack)-1].Error = "execution reverted"
+		return nil
+	}
+	if depth == len(t.callstack)-1 {
+		// An inner call returned, pop it off and get the execution results
+		call := t.callstack[len(t.callstack)-1]
+		t.callstack = t.callstack[:len(t.callstack)-1]
+
+		ret := peek(stack, 0)
+		if call.Type == vm.CREATE.String() || call.Type == vm.CREATE2.String() {
+			call.GasUsed = hexutil.EncodeUint64(call.gasIn - call.gasCost - gas)
+			if ret.Sign() != 0 {
+				addr := common.BigToAddress(ret)
+				call.To = addrHex(addr)
+				call.Output = hexutil.Encode(env.StateDB.GetCode(addr))
+			} else if call.Error == "" {
+				call.Error = "internal failure"
+			}
+		} else if call.hasGas {
+			call.GasUsed = hexutil.EncodeUint64(call.gasIn - call.gasCost + call.gas - gas)
+			if ret.Sign() != 0 {
+				call.Output = hexutil.Encode(memorySlice(memory, call.outOff, call.outLen))
+			} else if call.Error == "" {
+				call.Error = "internal failure"
+			}
+		}
+		if call.hasGas {
+			call.Gas = hexutil.EncodeUint64(call.gas)
+		}
+		parent := t.callstack[len(t.callstack)-1]
+		parent.Calls = append(parent.Calls, call)
+	}
+	return nil
+}
+
+// CaptureFault implements the Tracer interface to trace an execution fault
+// while running an opcode.
+func (t *callTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	if t.err == nil {
+		t.fault(err)
+	}
+	return nil
+}
+
+// fault pops the call failed with the given error off the call stack.
+func (t *callTracer) fault(err error) {
+	// If the topmost call already reverted, don't handle the additional fault again
+	if t.callstack[len(t.callstack)-1].Error != "" {
+		return
+	}
+	call := t.callstack[len(t.callstack)-1]
+	t.callstack = t.callstack[:len(t.callstack)-1]
+	call.Error = err.Error()
+
+	// Consume all available gas
+	if call.hasGas {
+		call.Gas = hexutil.EncodeUint64(call.gas)
+		call.GasUsed = call.Gas
+	}
+	// Flatten the failed call into its parent, unless it's the last one
+	if len(t.callstack) > 0 {
+		parent := t.callstack[len(t.callstack)-1]
+		parent.Calls = append(parent.Calls, call)
+		return
+	}
+	t.callstack = append(t.callstack, call)
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t *callTracer) CaptureEnd(output []byte, gasUsed uint64, d time.Duration, err error) error {
+	t.output, t.gasUsed, t.time = common.CopyBytes(output), gasUsed, d.String()
+	if err != nil {
+		t.failure = err.Error()
+	}
+	return nil
+}
+
+// GetResult returns the outermost call with all the inner calls nested within,
+// or the error which interrupted the tracing.
+func (t *callTracer) GetResult() (json.RawMessage, error) {
+	result := &callFrame{
+		Type:    t.typ,
+		From:    addrHex(t.from),
+		To:      addrHex(t.to),
+		Value:   bigHex(t.value),
+		Gas:     hexutil.EncodeUint64(t.gas),
+		GasUsed: hexutil.EncodeUint64(t.gasUsed),
+		Input:   hexutil.Encode(t.input),
+		Output:  hexutil.Encode(t.output),
+		Time:    t.time,
+		Calls:   t.callstack[0].Calls,
+	}
+	if result.Error = t.callstack[0].Error; result.Error == "" {
+		result.Error = t.failure
+	}
+	if result.Error != "" {
+		result.Output = ""
+	}
+	blob, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	return blob, t.err
+}
+
+// prestateAccount is the state of an account before the traced execution.
+type prestateAccount struct {
+	balance *big.Int
+	nonce   int64
+	code    []byte
+	storage map[common.Hash]common.Hash
+}
+
+// MarshalJSON encodes the account the way the JavaScript tracer does.
+func (acc *prestateAccount) MarshalJSON() ([]byte, error) {
+	storage := make(map[string]string, len(acc.storage))
+	for key, val := range acc.storage {
+		storage[hexutil.Encode(key[:])] = hexutil.Encode(val[:])
+	}
+	return json.Marshal(struct {
+		Balance string            `json:"balance"`
+		Nonce   int64             `json:"nonce"`
+		Code    string            `json:"code"`
+		Storage map[string]string `json:"storage"`
+	}{bigHex(acc.balance), acc.nonce, hexutil.Encode(acc.code), storage})
+}
+
+// prestateTracer is the native implementation of prestate_tracer.js, collecting
+// the accounts and storage slots touched by a transaction as they were before
+// its execution.
+type prestateTracer struct {
+	interrupter
+
+	db       vm.StateDB
+	prestate map[common.Address]*prestateAccount
+
+	create bool
+	from   common.Address
+	to     common.Address
+	value  *big.Int
+}
+
+// newPrestateTracer creates a native prestate tracer.
+func newPrestateTracer() *prestateTracer {
+	return &prestateTracer{prestate: make(map[common.Address]*prestateAccount)}
+}
+
+// lookupAccount injects the account into the prestate if not yet present.
+func (t *prestateTracer) lookupAccount(addr common.Address) {
+	if _, ok := t.prestate[addr]; ok {
+		return
+	}
+	t.prestate[addr] = &prestateAccount{
+		balance: new(big.Int).Set(t.db.GetBalance(addr)),
+		nonce:   int64(t.db.GetNonce(addr)),
+		code:    common.CopyBytes(t.db.GetCode(addr)),
+		storage: make(map[common.Hash]common.Hash),
+	}
+}
+
+// lookupStorage injects the storage slot of the account into the prestate if
+// not yet present.
+func (t *prestateTracer) lookupStorage(addr common.Address, key common.Hash) {
+	t.lookupAccount(addr)
+	if _, ok := t.prestate[addr].storage[key]; ok {
+		return
+	}
+	t.prestate[addr].storage[key] = t.db.GetState(addr, key)
+}
+
+// CaptureStart implements the Tracer interface to initialize the tracing operation.
+func (t *prestateTracer) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	t.create, t.from, t.to, t.value = create, from, to, value
+	return nil
+}
+
+// CaptureState implements the Tracer interface to trace a single step of VM execution.
+func (t *prestateTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	if t.halted() {
+		return nil
+	}
+	// Add the current account if we just started tracing. Its balance includes
+	// the value sent along with the message, fixed up in the result.
+	if t.db == nil {
+		t.db = env.StateDB
+		t.lookupAccount(contract.Address())
+	}
+	// Whenever new state is accessed, add it to the prestate
+	switch op {
+	case vm.EXTCODECOPY, vm.EXTCODESIZE, vm.BALANCE:
+		t.lookupAccount(common.BigToAddress(peek(stack, 0)))
+	case vm.CREATE:
+		from := contract.Address()
+		t.lookupAccount(crypto.CreateAddress(from, t.db.GetNonce(from)))
+	case vm.CREATE2:
+		code := memorySlice(memory, peek(stack, 1), peek(stack, 2))
+		t.lookupAccount(crypto.CreateAddress2(contract.Address(), common.BigToHash(peek(stack, 3)), crypto.Keccak256(code)))
+	case vm.CALL, vm.CALLCODE, vm.DELEGATECALL, vm.STATICCALL:
+		t.lookupAccount(common.BigToAddress(peek(stack, 1)))
+	case vm.SSTORE, vm.SLOAD:
+		t.lookupStorage(contract.Address(), common.BigToHash(peek(stack, 0)))
+	}
+	return nil
+}
+
+// CaptureFault implements the Tracer interface to trace an execution fault
+// while running an opcode.
+func (t *prestateTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	return nil
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t *prestateTracer) CaptureEnd(output []byte, gasUsed uint64, d time.Duration, err error) error {
+	return nil
+}
+
+// GetResult returns the assembled prestate, or the error which interrupted the
+// tracing.
+func (t *prestateTracer) GetResult() (json.RawMessage, error) {
+	if t.db == nil {
+		return nil, errNoExecution
+	}
+	// Move the value of the outer transaction back to the origin and decrement
+	// its nonce
+	t.lookupAccount(t.from)
+	t.lookupAccount(t.to)
+
+	t.prestate[t.to].balance = new(big.Int).Sub(t.prestate[t.to].balance, t.value)
+	t.prestate[t.from].balance = new(big.Int).Add(t.prestate[t.from].balance, t.value)
+	t.prestate[t.from].nonce--
+
+	// Any existing state at a creation target would have made the transaction
+	// invalid, drop it
+	if t.create {
+		delete(t.prestate, t.to)
+	}
+	prestate := make(map[string]*prestateAccount, len(t.prestate))
+	for addr, acc := range t.prestate {
+		prestate[addrHex(addr)] = acc
+	}
+	blob, err := json.Marshal(prestate)
+	if err != nil {
+		return nil, err
+	}
+	return blob, t.err
+}
+
+// fourByteTracer is the native implementation of 4byte_tracer.js, collecting the
+// 4 byte method identifiers of all the calls along with the size of their data.
+type fourByteTracer struct {
+	interrupter
+
+	ids   map[string]int // Number of calls by identifier and data size
+	input []byte
+}
+
+// newFourByteTracer creates a native 4byte tracer.
+func newFourByteTracer() *fourByteTracer {
+	return &fourByteTracer{ids: make(map[string]int)}
+}
+
+// store saves the given identifier and data size.
+func (t *fourByteTracer) store(id []byte, size string) {
+	t.ids[hexutil.Encode(id)+"-"+size]++
+}
+
+// CaptureStart implements the Tracer interface to initialize the tracing operation.
+func (t *fourByteTracer) CaptureStart(from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) error {
+	t.input = common.CopyBytes(input)
+	return nil
+}
+
+// CaptureState implements the Tracer interface to trace a single step of VM execution.
+func (t *fourByteTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	if t.halted() {
+		return nil
+	}
+	// Skip any opcodes that are not internal calls, the stack points to the call
+	// input after the optional value
+	var in int
+	switch op {
+	case vm.CALL, vm.CALLCODE:
+		in = 3
+	case vm.DELEGATECALL, vm.STATICCALL:
+		in = 2
+	default:
+		return nil
+	}
+	// Skip any pre-compile invocations, those are just fancy opcodes
+	if isPrecompiled(common.BigToAddress(peek(stack, 1))) {
+		return nil
+	}
+	if size := peek(stack, in+1); size.Cmp(big.NewInt(4)) >= 0 {
+		t.store(memorySlice(memory, peek(stack, in), big.NewInt(4)), new(big.Int).Sub(size, big.NewInt(4)).String())
+	}
+	return nil
+}
+
+// CaptureFault implements the Tracer interface to trace an execution fault
+// while running an opcode.
+func (t *fourByteTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, memory *vm.Memory, stack *vm.Stack, contract *vm.Contract, depth int, err error) error {
+	return nil
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t *fourByteTracer) CaptureEnd(output []byte, gasUsed uint64, d time.Duration, err error) error {
+	return nil
+}
+
+// GetResult returns the collected identifiers, or the error which interrupted
+// the tracing.
+func (t *fourByteTracer) GetResult() (json.RawMessage, error) {
+	// Save the outer calldata also
+	if len(t.input) >= 4 {
+		t.store(t.input[:4], strconv.Itoa(len(t.input)-4))
+	}
+	blob, err := json.Marshal(t.ids)
+	if err != nil {
+		return nil, err
+	}
+	return blob, t.err
+}
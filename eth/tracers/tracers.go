@@ -14,16 +14,30 @@
 // You should have received a copy of the GNU Lesser General Public License
 // along with the go-simplechain library. If not, see <http://www.gnu.org/licenses/>.
 
-// Package tracers is a collection of JavaScript transaction tracers.
+// Package tracers is a collection of JavaScript and native transaction tracers.
 package tracers
 
 import (
+	"encoding/json"
 	"strings"
 	"unicode"
 
+	"github.com/simplechain-org/go-simplechain/core/vm"
 	"github.com/simplechain-org/go-simplechain/eth/tracers/internal/tracers"
 )
 
+// ResultTracer is a transaction tracer assembling a JSON result, implemented by
+// both the JavaScript and the native tracers.
+type ResultTracer interface {
+	vm.Tracer
+
+	// GetResult returns the result of the trace, or any accumulated error.
+	GetResult() (json.RawMessage, error)
+
+	// Stop terminates execution of the tracer at the first opportune moment.
+	Stop(err error)
+}
+
 // all contains all the built in JavaScript tracers by name.
 var all = make(map[string]string)
 
@@ -51,3 +65,13 @@ func tracer(name string) (string, bool) {
 	}
 	return "", false
 }
+
+// NewTracer creates a tracer from a JavaScript snippet or the name of a built-in
+// tracer. Built-in tracers with a native implementation run natively, unless the
+// JavaScript version is explicitly requested.
+func NewTracer(code string, forceJS bool) (ResultTracer, error) {
+	if native, ok := natives[code]; ok && !forceJS {
+		return native(), nil
+	}
+	return New(code)
+}
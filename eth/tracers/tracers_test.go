@@ -168,34 +168,36 @@ func TestPrestateTracerCreate2(t *testing.T) {
 		Code:    []byte{},
 		Balance: big.NewInt(500000000000000),
 	}
-	statedb := tests.MakePreState(rawdb.NewMemoryDatabase(), alloc)
+	for _, forceJS := range []bool{false, true} {
+		statedb := tests.MakePreState(rawdb.NewMemoryDatabase(), alloc)
 
-	// Create the tracer, the EVM environment and run it
-	tracer, err := New("prestateTracer")
-	if err != nil {
-		t.Fatalf("failed to create call tracer: %v", err)
-	}
-	evm := vm.NewEVM(context, statedb, params.MainnetChainConfig, vm.Config{Debug: true, Tracer: tracer})
+		// Create the tracer, the EVM environment and run it
+		tracer, err := NewTracer("prestateTracer", forceJS)
+		if err != nil {
+			t.Fatalf("failed to create call tracer: %v", err)
+		}
+		evm := vm.NewEVM(context, statedb, params.MainnetChainConfig, vm.Config{Debug: true, Tracer: tracer})
 
-	msg, err := tx.AsMessage(signer)
-	if err != nil {
-		t.Fatalf("failed to prepare transaction for tracing: %v", err)
-	}
-	st := core.NewStateTransition(evm, msg, new(core.GasPool).AddGas(tx.Gas()))
-	if _, _, _, err = st.TransitionDb(); err != nil {
-		t.Fatalf("failed to execute transaction: %v", err)
-	}
-	// Retrieve the trace result and compare against the etalon
-	res, err := tracer.GetResult()
-	if err != nil {
-		t.Fatalf("failed to retrieve trace result: %v", err)
-	}
-	ret := make(map[string]interface{})
-	if err := json.Unmarshal(res, &ret); err != nil {
-		t.Fatalf("failed to unmarshal trace result: %v", err)
-	}
-	if _, has := ret["0x60f3f640a8508fc6a86d45df051962668e1e8ac7"]; !has {
-		t.Fatalf("Expected 0x60f3f640a8508fc6a86d45df051962668e1e8ac7 in result")
+		msg, err := tx.AsMessage(signer)
+		if err != nil {
+			t.Fatalf("failed to prepare transaction for tracing: %v", err)
+		}
+		st := core.NewStateTransition(evm, msg, new(core.GasPool).AddGas(tx.Gas()))
+		if _, _, _, err = st.TransitionDb(); err != nil {
+			t.Fatalf("failed to execute transaction: %v", err)
+		}
+		// Retrieve the trace result and compare against the etalon
+		res, err := tracer.GetResult()
+		if err != nil {
+			t.Fatalf("failed to retrieve trace result: %v", err)
+		}
+		ret := make(map[string]interface{})
+		if err := json.Unmarshal(res, &ret); err != nil {
+			t.Fatalf("failed to unmarshal trace result: %v", err)
+		}
+		if _, has := ret["0x60f3f640a8508fc6a86d45df051962668e1e8ac7"]; !has {
+			t.Fatalf("Expected 0x60f3f640a8508fc6a86d45df051962668e1e8ac7 in result (forceJS %v)", forceJS)
+		}
 	}
 }
 
@@ -275,3 +277,106 @@ func TestCallTracer(t *testing.T) {
 		})
 	}
 }
+
+// runTracerTest executes the transaction of a tracer test with the given tracer
+// and returns the result of the trace.
+func runTracerTest(t *testing.T, test *callTracerTest, tracer ResultTracer) json.RawMessage {
+	tx := new(types.Transaction)
+	if err := rlp.DecodeBytes(common.FromHex(test.Input), tx); err != nil {
+		t.Fatalf("failed to parse testcase input: %v", err)
+	}
+	signer := types.MakeSigner(test.Genesis.Config)
+	origin, _ := signer.Sender(tx)
+
+	context := vm.Context{
+		CanTransfer: core.CanTransfer,
+		Transfer:    core.Transfer,
+		Origin:      origin,
+		Coinbase:    test.Context.Miner,
+		BlockNumber: new(big.Int).SetUint64(uint64(test.Context.Number)),
+		Time:        new(big.Int).SetUint64(uint64(test.Context.Time)),
+		Difficulty:  (*big.Int)(test.Context.Difficulty),
+		GasLimit:    uint64(test.Context.GasLimit),
+		GasPrice:    tx.GasPrice(),
+	}
+	statedb := tests.MakePreState(rawdb.NewMemoryDatabase(), test.Genesis.Alloc)
+	evm := vm.NewEVM(context, statedb, test.Genesis.Config, vm.Config{Debug: true, Tracer: tracer})
+
+	msg, err := tx.AsMessage(signer)
+	if err != nil {
+		t.Fatalf("failed to prepare transaction for tracing: %v", err)
+	}
+	st := core.NewStateTransition(evm, msg, new(core.GasPool).AddGas(tx.Gas()))
+	if _, _, _, err = st.TransitionDb(); err != nil {
+		t.Fatalf("failed to execute transaction: %v", err)
+	}
+	res, err := tracer.GetResult()
+	if err != nil {
+		t.Fatalf("failed to retrieve trace result: %v", err)
+	}
+	return res
+}
+
+// Iterates over all the input-output datasets in the tracer test harness and
+// checks that the native tracers produce the same results as the JavaScript ones.
+func TestNativeTracers(t *testing.T) {
+	files, err := ioutil.ReadDir("testdata")
+	if err != nil {
+		t.Fatalf("failed to retrieve tracer test suite: %v", err)
+	}
+	for _, file := range files {
+		if !strings.HasPrefix(file.Name(), "call_tracer_") {
+			continue
+		}
+		file := file // capture range variable
+		t.Run(camel(strings.TrimSuffix(strings.TrimPrefix(file.Name(), "call_tracer_"), ".json")), func(t *testing.T) {
+			t.Parallel()
+
+			blob, err := ioutil.ReadFile(filepath.Join("testdata", file.Name()))
+			if err != nil {
+				t.Fatalf("failed to read testcase: %v", err)
+			}
+			test := new(callTracerTest)
+			if err := json.Unmarshal(blob, test); err != nil {
+				t.Fatalf("failed to parse testcase: %v", err)
+			}
+			for name := range natives {
+				native, err := NewTracer(name, false)
+				if err != nil {
+					t.Fatalf("failed to create native %s: %v", name, err)
+				}
+				js, err := NewTracer(name, true)
+				if err != nil {
+					t.Fatalf("failed to create JavaScript %s: %v", name, err)
+				}
+				if _, ok := js.(*Tracer); !ok {
+					t.Fatalf("forced JavaScript %s is native", name)
+				}
+				// The results must match, apart from the measured execution time
+				var have, want map[string]interface{}
+				if err := json.Unmarshal(runTracerTest(t, test, native), &have); err != nil {
+					t.Fatalf("failed to unmarshal native %s result: %v", name, err)
+				}
+				if err := json.Unmarshal(runTracerTest(t, test, js), &want); err != nil {
+					t.Fatalf("failed to unmarshal JavaScript %s result: %v", name, err)
+				}
+				if name == "callTracer" {
+					delete(have, "time")
+					delete(want, "time")
+				}
+				if !reflect.DeepEqual(have, want) {
+					t.Errorf("%s mismatch: \nhave %+v\nwant %+v", name, have, want)
+				}
+			}
+			// Ensure the native call tracer matches the etalon too
+			tracer, _ := NewTracer("callTracer", false)
+			ret := new(callTrace)
+			if err := json.Unmarshal(runTracerTest(t, test, tracer), ret); err != nil {
+				t.Fatalf("failed to unmarshal trace result: %v", err)
+			}
+			if !reflect.DeepEqual(ret, test.Result) {
+				t.Fatalf("trace mismatch: \nhave %+v\nwant %+v", ret, test.Result)
+			}
+		})
+	}
+}
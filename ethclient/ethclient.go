@@ -512,13 +512,34 @@ func (ec *Client) EstimateGas(ctx context.Context, msg simplechain.CallMsg) (uin
 // If the transaction was a contract creation use the TransactionReceipt method to get the
 // contract address after the transaction has been mined.
 func (ec *Client) SendTransaction(ctx context.Context, tx *types.Transaction) error {
-	data, err := rlp.EncodeToBytes(tx)
+	data, err := tx.MarshalBinary()
 	if err != nil {
 		return err
 	}
 	return ec.c.CallContext(ctx, nil, "eth_sendRawTransaction", hexutil.Encode(data))
 }
 
+// SignTransactionAsFeePayer has the node sign a fee-delegated transaction, already
+// signed by its sender, as the given fee payer. The fee payer's account has to be
+// unlocked on the node. The returned transaction is ready to be sent.
+func (ec *Client) SignTransactionAsFeePayer(ctx context.Context, feePayer common.Address, tx *types.Transaction) (*types.Transaction, error) {
+	data, err := tx.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		Raw hexutil.Bytes `json:"raw"`
+	}
+	if err := ec.c.CallContext(ctx, &result, "eth_signTransactionAsFeePayer", feePayer, hexutil.Bytes(data)); err != nil {
+		return nil, err
+	}
+	signed := new(types.Transaction)
+	if err := signed.UnmarshalBinary(result.Raw); err != nil {
+		return nil, err
+	}
+	return signed, nil
+}
+
 func toCallArg(msg simplechain.CallMsg) interface{} {
 	arg := map[string]interface{}{
 		"from": msg.From,
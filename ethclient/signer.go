@@ -57,3 +57,9 @@ func (s *senderFromServer) Hash(tx *types.Transaction) common.Hash {
 func (s *senderFromServer) SignatureValues(tx *types.Transaction, sig []byte) (R, S, V *big.Int, err error) {
 	panic("can't sign with senderFromServer")
 }
+func (s *senderFromServer) FeePayer(tx *types.Transaction) (common.Address, error) {
+	return common.Address{}, errNotCached
+}
+func (s *senderFromServer) FeePayerHash(tx *types.Transaction) common.Hash {
+	panic("can't sign with senderFromServer")
+}
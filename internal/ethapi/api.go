@@ -19,6 +19,8 @@ package ethapi
 import (
 	"bytes"
 	"context"
+	crand "crypto/rand"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"math/big"
@@ -35,6 +37,7 @@ import (
 	"github.com/simplechain-org/go-simplechain/consensus/ethash"
 	"github.com/simplechain-org/go-simplechain/core"
 	"github.com/simplechain-org/go-simplechain/core/rawdb"
+	"github.com/simplechain-org/go-simplechain/core/state"
 	"github.com/simplechain-org/go-simplechain/core/types"
 	"github.com/simplechain-org/go-simplechain/core/vm"
 	"github.com/simplechain-org/go-simplechain/crypto"
@@ -52,6 +55,14 @@ const (
 	defaultGasPrice = params.GWei
 )
 
+// ErrExecutionAborted is returned if the execution of a call was cancelled before
+// it could finish.
+var ErrExecutionAborted = errors.New("execution aborted")
+
+// errBundleOverdraft is reported for a call of a simulated bundle spending more
+// than the balance of its sender.
+var errBundleOverdraft = errors.New("insufficient balance of the sender")
+
 // PublicEthereumAPI provides an API to access Ethereum related information.
 // It offers only methods that operate on public data that is freely available to anyone.
 type PublicEthereumAPI struct {
@@ -363,7 +374,7 @@ func (s *PrivateAccountAPI) signTransaction(ctx context.Context, args *SendTxArg
 // tries to sign it with the key associated with args.To. If the given passwd isn't
 // able to decrypt the key it fails.
 func (s *PrivateAccountAPI) SendTransaction(ctx context.Context, args SendTxArgs, passwd string) (common.Hash, error) {
-	if args.Nonce == nil {
+	if args.Nonce == nil && args.BlockLimit == nil {
 		// Hold the addresse's mutex around signing to prevent concurrent assignment of
 		// the same nonce to multiple accounts.
 		s.nonceLock.LockAddr(args.From)
@@ -390,7 +401,7 @@ func (s *PrivateAccountAPI) SignTransaction(ctx context.Context, args SendTxArgs
 	if args.GasPrice == nil {
 		return nil, fmt.Errorf("gasPrice not specified")
 	}
-	if args.Nonce == nil {
+	if args.Nonce == nil && args.BlockLimit == nil {
 		return nil, fmt.Errorf("nonce not specified")
 	}
 	signed, err := s.signTransaction(ctx, &args, passwd)
@@ -398,7 +409,7 @@ func (s *PrivateAccountAPI) SignTransaction(ctx context.Context, args SendTxArgs
 		log.Warn("Failed transaction sign attempt", "from", args.From, "to", args.To, "value", args.Value.ToInt(), "err", err)
 		return nil, err
 	}
-	data, err := rlp.EncodeToBytes(signed)
+	data, err := signed.MarshalBinary()
 	if err != nil {
 		return nil, err
 	}
@@ -743,15 +754,16 @@ type CallArgs struct {
 	Value    *hexutil.Big    `json:"value"`
 	Data     *hexutil.Bytes  `json:"data"`
 	Nonce    *hexutil.Uint64 `json:"nonce"`
+	FeePayer *common.Address `json:"feePayer"`
 }
 
-// account indicates the overriding fields of account during the execution of
-// a message call.
+// OverrideAccount indicates the overriding fields of account during the execution
+// of a message call.
 // Note, state and stateDiff can't be specified at the same time. If state is
 // set, message execution will only use the data in the given state. Otherwise
 // if statDiff is set, all diff will be applied first and then execute the call
 // message.
-type account struct {
+type OverrideAccount struct {
 	Nonce     *hexutil.Uint64              `json:"nonce"`
 	Code      *hexutil.Bytes               `json:"code"`
 	Balance   **hexutil.Big                `json:"balance"`
@@ -759,26 +771,15 @@ type account struct {
 	StateDiff *map[common.Hash]common.Hash `json:"stateDiff"`
 }
 
-func DoCall(ctx context.Context, b Backend, args CallArgs, blockNrOrHash rpc.BlockNumberOrHash, overrides map[common.Address]account, vmCfg vm.Config, timeout time.Duration, globalGasCap *big.Int) ([]byte, uint64, bool, error) {
-	defer func(start time.Time) { log.Debug("Executing EVM call finished", "runtime", time.Since(start)) }(time.Now())
+// StateOverride is the collection of overridden accounts.
+type StateOverride map[common.Address]OverrideAccount
 
-	state, header, err := b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
-	if state == nil || err != nil {
-		return nil, 0, false, err
-	}
-	// Set sender address or use a default if none specified
-	var addr common.Address
-	if args.From == nil {
-		if wallets := b.AccountManager().Wallets(); len(wallets) > 0 {
-			if accounts := wallets[0].Accounts(); len(accounts) > 0 {
-				addr = accounts[0].Address
-			}
-		}
-	} else {
-		addr = *args.From
+// Apply overrides the fields of specified accounts into the given state.
+func (diff *StateOverride) Apply(state *state.StateDB) error {
+	if diff == nil {
+		return nil
 	}
-	// Override the fields of specified contracts before execution.
-	for addr, account := range overrides {
+	for addr, account := range *diff {
 		// Override account nonce.
 		if account.Nonce != nil {
 			state.SetNonce(addr, uint64(*account.Nonce))
@@ -792,7 +793,7 @@ func DoCall(ctx context.Context, b Backend, args CallArgs, blockNrOrHash rpc.Blo
 			state.SetBalance(addr, (*big.Int)(*account.Balance))
 		}
 		if account.State != nil && account.StateDiff != nil {
-			return nil, 0, false, fmt.Errorf("account %s has both 'state' and 'stateDiff'", addr.Hex())
+			return fmt.Errorf("account %s has both 'state' and 'stateDiff'", addr.Hex())
 		}
 		// Replace entire state if caller requires.
 		if account.State != nil {
@@ -805,6 +806,23 @@ func DoCall(ctx context.Context, b Backend, args CallArgs, blockNrOrHash rpc.Blo
 			}
 		}
 	}
+	return nil
+}
+
+// toMessage converts the call arguments into a message, filling in the defaults
+// of the unset fields. The gas is capped at the global gas cap, if any.
+func (args *CallArgs) toMessage(b Backend, globalGasCap *big.Int) types.Message {
+	// Set sender address or use a default if none specified
+	var addr common.Address
+	if args.From == nil {
+		if wallets := b.AccountManager().Wallets(); len(wallets) > 0 {
+			if accounts := wallets[0].Accounts(); len(accounts) > 0 {
+				addr = accounts[0].Address
+			}
+		}
+	} else {
+		addr = *args.From
+	}
 	// Set default gas & gas price if none were set
 	gas := uint64(math.MaxUint64 / 2)
 	if args.Gas != nil {
@@ -832,9 +850,50 @@ func DoCall(ctx context.Context, b Backend, args CallArgs, blockNrOrHash rpc.Blo
 	if args.Nonce != nil {
 		nonce = uint64(*args.Nonce)
 	}
+	msg := types.NewMessage(addr, args.To, nonce, value, gas, gasPrice, data, false)
+	if args.FeePayer != nil {
+		msg = msg.WithPayer(*args.FeePayer)
+	}
+	return msg
+}
+
+// ExecuteCall applies the message in the given EVM with an unlimited gas pool,
+// cancelling the execution once the context is done. It returns
+// ErrExecutionAborted if the execution was cancelled.
+func ExecuteCall(ctx context.Context, evm *vm.EVM, vmError func() error, msg core.Message) ([]byte, uint64, bool, error) {
+	// Wait for the context to be done and cancel the evm. Even if the
+	// EVM has finished, cancelling may be done (repeatedly)
+	go func() {
+		<-ctx.Done()
+		evm.Cancel()
+	}()
 
+	// Setup the gas pool (also for unmetered requests)
+	// and apply the message.
+	gp := new(core.GasPool).AddGas(math.MaxUint64)
+	res, gas, failed, err := core.ApplyMessage(evm, msg, gp)
+	if err := vmError(); err != nil {
+		return nil, 0, false, err
+	}
+	if evm.Cancelled() {
+		return nil, 0, false, ErrExecutionAborted
+	}
+	return res, gas, failed, err
+}
+
+func DoCall(ctx context.Context, b Backend, args CallArgs, blockNrOrHash rpc.BlockNumberOrHash, overrides *StateOverride, vmCfg vm.Config, timeout time.Duration, globalGasCap *big.Int) ([]byte, uint64, bool, error) {
+	defer func(start time.Time) { log.Debug("Executing EVM call finished", "runtime", time.Since(start)) }(time.Now())
+
+	state, header, err := b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
+	if state == nil || err != nil {
+		return nil, 0, false, err
+	}
+	// Override the fields of specified contracts before execution.
+	if err := overrides.Apply(state); err != nil {
+		return nil, 0, false, err
+	}
 	// Create new call message
-	msg := types.NewMessage(addr, args.To, nonce, value, gas, gasPrice, data, false)
+	msg := args.toMessage(b, globalGasCap)
 
 	// Setup context so it may be cancelled the call has completed
 	// or, in case of unmetered gas, setup a context with a timeout.
@@ -853,22 +912,9 @@ func DoCall(ctx context.Context, b Backend, args CallArgs, blockNrOrHash rpc.Blo
 	if err != nil {
 		return nil, 0, false, err
 	}
-	// Wait for the context to be done and cancel the evm. Even if the
-	// EVM has finished, cancelling may be done (repeatedly)
-	go func() {
-		<-ctx.Done()
-		evm.Cancel()
-	}()
-
-	// Setup the gas pool (also for unmetered requests)
-	// and apply the message.
-	gp := new(core.GasPool).AddGas(math.MaxUint64)
-	res, gas, failed, err := core.ApplyMessage(evm, msg, gp)
-	if err := vmError(); err != nil {
-		return nil, 0, false, err
-	}
+	res, gas, failed, err := ExecuteCall(ctx, evm, vmError, msg)
 	// If the timer caused an abort, return an appropriate error message
-	if evm.Cancelled() {
+	if err == ErrExecutionAborted {
 		return nil, 0, false, fmt.Errorf("execution aborted (timeout = %v)", timeout)
 	}
 	return res, gas, failed, err
@@ -880,15 +926,86 @@ func DoCall(ctx context.Context, b Backend, args CallArgs, blockNrOrHash rpc.Blo
 //
 // Note, this function doesn't make and changes in the state/blockchain and is
 // useful to execute and retrieve values.
-func (s *PublicBlockChainAPI) Call(ctx context.Context, args CallArgs, blockNrOrHash rpc.BlockNumberOrHash, overrides *map[common.Address]account) (hexutil.Bytes, error) {
-	var accounts map[common.Address]account
-	if overrides != nil {
-		accounts = *overrides
-	}
-	result, _, _, err := DoCall(ctx, s.b, args, blockNrOrHash, accounts, vm.Config{}, 5*time.Second, s.b.RPCGasCap())
+func (s *PublicBlockChainAPI) Call(ctx context.Context, args CallArgs, blockNrOrHash rpc.BlockNumberOrHash, overrides *StateOverride) (hexutil.Bytes, error) {
+	result, _, _, err := DoCall(ctx, s.b, args, blockNrOrHash, overrides, vm.Config{}, 5*time.Second, s.b.RPCGasCap())
 	return (hexutil.Bytes)(result), err
 }
 
+// BundleCallResult is the outcome of a single call of a simulated bundle.
+type BundleCallResult struct {
+	ReturnValue hexutil.Bytes  `json:"returnValue"`
+	Logs        []*types.Log   `json:"logs"`
+	GasUsed     hexutil.Uint64 `json:"gasUsed"`
+	Failed      bool           `json:"failed"`
+	Error       string         `json:"error,omitempty"`
+}
+
+// SimulateBundle executes the given calls in order on top of the state for the
+// given block number, each one seeing the state changes of the previous ones,
+// and returns the outcome of every call. Calls which can't be applied at all,
+// including those spending more than the balance of their sender, are reported
+// with an error and don't change the state.
+//
+// Additionally, the caller can specify a batch of contract for fields overriding,
+// applied before the first call.
+//
+// Note, this function doesn't make and changes in the state/blockchain and is
+// useful to preview the effects of a batch of transactions.
+func (s *PublicBlockChainAPI) SimulateBundle(ctx context.Context, calls []CallArgs, blockNrOrHash rpc.BlockNumberOrHash, overrides *StateOverride) ([]*BundleCallResult, error) {
+	defer func(start time.Time) { log.Debug("Simulating call bundle finished", "runtime", time.Since(start)) }(time.Now())
+
+	state, header, err := s.b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	if err := overrides.Apply(state); err != nil {
+		return nil, err
+	}
+	timeout := 5 * time.Second
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	results := make([]*BundleCallResult, 0, len(calls))
+	for i, args := range calls {
+		msg := args.toMessage(s.b, s.b.RPCGasCap())
+
+		// The EVM funds the sender for the call, carry its real balance forward
+		balance := new(big.Int).Set(state.GetBalance(msg.From()))
+		snapshot := state.Snapshot()
+
+		evm, vmError, err := s.b.GetEVM(ctx, msg, state, header)
+		if err != nil {
+			return nil, err
+		}
+		state.Prepare(common.Hash{}, header.Hash(), i)
+		logs := len(state.GetLogs(common.Hash{}))
+
+		res, gas, failed, err := ExecuteCall(ctx, evm, vmError, msg)
+		if err == ErrExecutionAborted {
+			return nil, fmt.Errorf("execution aborted (timeout = %v)", timeout)
+		}
+		result := &BundleCallResult{ReturnValue: res, Logs: []*types.Log{}, GasUsed: hexutil.Uint64(gas), Failed: failed}
+
+		// A call spending more than the real balance of the sender couldn't
+		// be applied, drop its state changes
+		balance.Add(balance, new(big.Int).Sub(state.GetBalance(msg.From()), math.MaxBig256))
+		switch {
+		case err != nil:
+			result.Error = err.Error()
+			state.RevertToSnapshot(snapshot)
+		case balance.Sign() < 0:
+			result = &BundleCallResult{ReturnValue: hexutil.Bytes{}, Logs: []*types.Log{}, Failed: true, Error: errBundleOverdraft.Error()}
+			state.RevertToSnapshot(snapshot)
+		default:
+			result.Logs = append(result.Logs, state.GetLogs(common.Hash{})[logs:]...)
+			state.SetBalance(msg.From(), balance)
+		}
+		results = append(results, result)
+		state.Finalise(true)
+	}
+	return results, nil
+}
+
 func DoEstimateGas(ctx context.Context, b Backend, args CallArgs, blockNrOrHash rpc.BlockNumberOrHash, gasCap *big.Int) (hexutil.Uint64, error) {
 	// Binary search the gas requirement, as it may be higher than the amount used
 	var (
@@ -1114,6 +1231,16 @@ type RPCTransaction struct {
 	V                *hexutil.Big    `json:"v"`
 	R                *hexutil.Big    `json:"r"`
 	S                *hexutil.Big    `json:"s"`
+
+	// Fields of typed transactions
+	Type       hexutil.Uint64  `json:"type"`
+	ChainID    *hexutil.Big    `json:"chainId,omitempty"`
+	ID         *hexutil.Uint64 `json:"id,omitempty"`
+	BlockLimit *hexutil.Uint64 `json:"blockLimit,omitempty"`
+	FeePayer   *common.Address `json:"feePayer,omitempty"`
+	FeePayerV  *hexutil.Big    `json:"feePayerV,omitempty"`
+	FeePayerR  *hexutil.Big    `json:"feePayerR,omitempty"`
+	FeePayerS  *hexutil.Big    `json:"feePayerS,omitempty"`
 }
 
 // newRPCTransaction returns a transaction that will serialize to the RPC
@@ -1138,6 +1265,19 @@ func newRPCTransaction(tx *types.Transaction, blockHash common.Hash, blockNumber
 		V:        (*hexutil.Big)(v),
 		R:        (*hexutil.Big)(r),
 		S:        (*hexutil.Big)(s),
+		Type:     hexutil.Uint64(tx.Type()),
+	}
+	if tx.Type() == types.BlockLimitTxType {
+		id, limit := hexutil.Uint64(tx.ID()), hexutil.Uint64(tx.BlockLimit())
+		result.ChainID = (*hexutil.Big)(tx.ChainId())
+		result.ID = &id
+		result.BlockLimit = &limit
+	}
+	if tx.Type() == types.FeeDelegatedTxType {
+		pv, pr, ps := tx.RawFeePayerSignatureValues()
+		result.ChainID = (*hexutil.Big)(tx.ChainId())
+		result.FeePayer = tx.FeePayer()
+		result.FeePayerV, result.FeePayerR, result.FeePayerS = (*hexutil.Big)(pv), (*hexutil.Big)(pr), (*hexutil.Big)(ps)
 	}
 	if blockHash != (common.Hash{}) {
 		result.BlockHash = &blockHash
@@ -1167,7 +1307,7 @@ func newRPCRawTransactionFromBlockIndex(b *types.Block, index uint64) hexutil.By
 	if index >= uint64(len(txs)) {
 		return nil
 	}
-	blob, _ := rlp.EncodeToBytes(txs[index])
+	blob, _ := txs[index].MarshalBinary()
 	return blob
 }
 
@@ -1293,8 +1433,8 @@ func (s *PublicTransactionPoolAPI) GetRawTransactionByHash(ctx context.Context,
 			return nil, nil
 		}
 	}
-	// Serialize to RLP and return
-	return rlp.EncodeToBytes(tx)
+	// Serialize to the raw format and return
+	return tx.MarshalBinary()
 }
 
 // GetTransactionReceipt returns the transaction receipt for the given transaction hash.
@@ -1373,6 +1513,21 @@ type SendTxArgs struct {
 	// newer name and should be preferred by clients.
 	Data  *hexutil.Bytes `json:"data"`
 	Input *hexutil.Bytes `json:"input"`
+
+	// Private transactions send the payload through the private transaction
+	// manager to the listed participants and only carry its hash publicly.
+	PrivateFrom string   `json:"privateFrom"`
+	PrivateFor  []string `json:"privateFor"`
+
+	// Block-limit transactions replace the nonce by a random ID, only valid up
+	// to and including the given block.
+	BlockLimit *hexutil.Uint64 `json:"blockLimit"`
+	ID         *hexutil.Uint64 `json:"id"`
+	ChainID    *hexutil.Big    `json:"chainId"`
+
+	// Fee-delegated transactions have their gas paid by the fee payer, who
+	// signs them after the sender.
+	FeePayer *common.Address `json:"feePayer"`
 }
 
 // setDefaults is a helper function that fills in default values for unspecified tx fields.
@@ -1387,6 +1542,30 @@ func (args *SendTxArgs) setDefaults(ctx context.Context, b Backend) error {
 	if args.Value == nil {
 		args.Value = new(hexutil.Big)
 	}
+	if args.FeePayer != nil {
+		if args.BlockLimit != nil {
+			return errors.New("block-limit transactions can't delegate fees")
+		}
+		if args.ChainID == nil {
+			args.ChainID = (*hexutil.Big)(b.ChainConfig().ChainID)
+		}
+	}
+	if args.BlockLimit != nil {
+		if args.ID == nil {
+			var blob [8]byte
+			if _, err := crand.Read(blob[:]); err != nil {
+				return err
+			}
+			id := binary.BigEndian.Uint64(blob[:])
+			args.ID = (*hexutil.Uint64)(&id)
+		}
+		if args.ChainID == nil {
+			args.ChainID = (*hexutil.Big)(b.ChainConfig().ChainID)
+		}
+		if args.Nonce == nil {
+			args.Nonce = new(hexutil.Uint64) // Block-limit transactions carry no nonce
+		}
+	}
 	if args.Nonce == nil {
 		nonce, err := b.GetPoolNonce(ctx, args.From)
 		if err != nil {
@@ -1423,6 +1602,7 @@ func (args *SendTxArgs) setDefaults(ctx context.Context, b Backend) error {
 			GasPrice: args.GasPrice,
 			Value:    args.Value,
 			Data:     input,
+			FeePayer: args.FeePayer,
 		}
 		pendingBlockNr := rpc.BlockNumberOrHashWithNumber(rpc.PendingBlockNumber)
 		estimated, err := DoEstimateGas(ctx, b, callArgs, pendingBlockNr, b.RPCGasCap())
@@ -1435,6 +1615,44 @@ func (args *SendTxArgs) setDefaults(ctx context.Context, b Backend) error {
 	return nil
 }
 
+// setPrivatePayload hands the payload of a private transaction to the private
+// transaction manager and replaces it by the reference to the stored copy.
+func (args *SendTxArgs) setPrivatePayload(b Backend) error {
+	ptm := b.PrivateTransactionManager()
+	if ptm == nil {
+		return errors.New("private transactions not supported by this node")
+	}
+	next := new(big.Int).Add(b.CurrentBlock().Number(), common.Big1)
+	if !b.ChainConfig().IsPrivacy(next) {
+		return errors.New("private transactions not activated")
+	}
+	var input []byte
+	if args.Input != nil {
+		input = *args.Input
+	} else if args.Data != nil {
+		input = *args.Data
+	}
+	if len(input) == 0 {
+		return errors.New("private transaction without any data provided")
+	}
+	hash, err := ptm.Send(input, args.PrivateFrom, args.PrivateFor)
+	if err != nil {
+		return err
+	}
+	payload := hexutil.Bytes(types.NewPrivatePayload(hash[:]))
+	args.Input, args.Data = &payload, nil
+
+	// The public transaction only pays for its own payload
+	intrinsic, err := core.IntrinsicGas(payload, args.To == nil, b.ChainConfig().IsSingularity(next))
+	if err != nil {
+		return err
+	}
+	if uint64(*args.Gas) < intrinsic {
+		args.Gas = (*hexutil.Uint64)(&intrinsic)
+	}
+	return nil
+}
+
 func (args *SendTxArgs) toTransaction() *types.Transaction {
 	var input []byte
 	if args.Input != nil {
@@ -1442,6 +1660,12 @@ func (args *SendTxArgs) toTransaction() *types.Transaction {
 	} else if args.Data != nil {
 		input = *args.Data
 	}
+	if args.BlockLimit != nil {
+		return types.NewBlockLimitTransaction((*big.Int)(args.ChainID), uint64(*args.ID), uint64(*args.BlockLimit), args.To, (*big.Int)(args.Value), uint64(*args.Gas), (*big.Int)(args.GasPrice), input)
+	}
+	if args.FeePayer != nil {
+		return types.NewFeeDelegatedTransaction((*big.Int)(args.ChainID), uint64(*args.Nonce), args.To, (*big.Int)(args.Value), uint64(*args.Gas), (*big.Int)(args.GasPrice), input, *args.FeePayer)
+	}
 	if args.To == nil {
 		return types.NewContractCreation(uint64(*args.Nonce), (*big.Int)(args.Value), uint64(*args.Gas), (*big.Int)(args.GasPrice), input)
 	}
@@ -1453,7 +1677,7 @@ func SubmitTransaction(ctx context.Context, b Backend, tx *types.Transaction) (c
 	if err := b.SendTx(ctx, tx); err != nil {
 		return common.Hash{}, err
 	}
-	if tx.To() == nil {
+	if tx.To() == nil && tx.CheckNonce() {
 		signer := types.MakeSigner(b.ChainConfig())
 		from, err := types.Sender(signer, tx)
 		if err != nil {
@@ -1478,7 +1702,7 @@ func (s *PublicTransactionPoolAPI) SendTransaction(ctx context.Context, args Sen
 		return common.Hash{}, err
 	}
 
-	if args.Nonce == nil {
+	if args.Nonce == nil && args.BlockLimit == nil {
 		// Hold the addresse's mutex around signing to prevent concurrent assignment of
 		// the same nonce to multiple accounts.
 		s.nonceLock.LockAddr(args.From)
@@ -1489,6 +1713,11 @@ func (s *PublicTransactionPoolAPI) SendTransaction(ctx context.Context, args Sen
 	if err := args.setDefaults(ctx, s.b); err != nil {
 		return common.Hash{}, err
 	}
+	if args.PrivateFor != nil {
+		if err := args.setPrivatePayload(s.b); err != nil {
+			return common.Hash{}, err
+		}
+	}
 	// Assemble the transaction and sign with the wallet
 	tx := args.toTransaction()
 
@@ -1496,6 +1725,13 @@ func (s *PublicTransactionPoolAPI) SendTransaction(ctx context.Context, args Sen
 	if err != nil {
 		return common.Hash{}, err
 	}
+	// Fee-delegated transactions can only be sent at once if the fee payer's
+	// account is managed by this node too
+	if args.FeePayer != nil {
+		if signed, err = s.signAsFeePayer(*args.FeePayer, signed); err != nil {
+			return common.Hash{}, err
+		}
+	}
 	return SubmitTransaction(ctx, s.b, signed)
 }
 
@@ -1506,9 +1742,9 @@ func (s *PublicTransactionPoolAPI) FillTransaction(ctx context.Context, args Sen
 	if err := args.setDefaults(ctx, s.b); err != nil {
 		return nil, err
 	}
-	// Assemble the transaction and obtain rlp
+	// Assemble the transaction and obtain its raw encoding
 	tx := args.toTransaction()
-	data, err := rlp.EncodeToBytes(tx)
+	data, err := tx.MarshalBinary()
 	if err != nil {
 		return nil, err
 	}
@@ -1519,7 +1755,7 @@ func (s *PublicTransactionPoolAPI) FillTransaction(ctx context.Context, args Sen
 // The sender is responsible for signing the transaction and using the correct nonce.
 func (s *PublicTransactionPoolAPI) SendRawTransaction(ctx context.Context, encodedTx hexutil.Bytes) (common.Hash, error) {
 	tx := new(types.Transaction)
-	if err := rlp.DecodeBytes(encodedTx, tx); err != nil {
+	if err := tx.UnmarshalBinary(encodedTx); err != nil {
 		return common.Hash{}, err
 	}
 	return SubmitTransaction(ctx, s.b, tx)
@@ -1566,7 +1802,7 @@ func (s *PublicTransactionPoolAPI) SignTransaction(ctx context.Context, args Sen
 	if args.GasPrice == nil {
 		return nil, fmt.Errorf("gasPrice not specified")
 	}
-	if args.Nonce == nil {
+	if args.Nonce == nil && args.BlockLimit == nil {
 		return nil, fmt.Errorf("nonce not specified")
 	}
 	if err := args.setDefaults(ctx, s.b); err != nil {
@@ -1576,13 +1812,60 @@ func (s *PublicTransactionPoolAPI) SignTransaction(ctx context.Context, args Sen
 	if err != nil {
 		return nil, err
 	}
-	data, err := rlp.EncodeToBytes(tx)
+	data, err := tx.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return &SignTransactionResult{data, tx}, nil
+}
+
+// SignTransactionAsFeePayer signs the given fee-delegated transaction, already
+// signed by its sender, as its fee payer. The node needs to have the private key
+// of the fee payer account and it needs to be unlocked. The result can be sent
+// with eth_sendRawTransaction.
+func (s *PublicTransactionPoolAPI) SignTransactionAsFeePayer(ctx context.Context, addr common.Address, encodedTx hexutil.Bytes) (*SignTransactionResult, error) {
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(encodedTx); err != nil {
+		return nil, err
+	}
+	tx, err := s.signAsFeePayer(addr, tx)
+	if err != nil {
+		return nil, err
+	}
+	data, err := tx.MarshalBinary()
 	if err != nil {
 		return nil, err
 	}
 	return &SignTransactionResult{data, tx}, nil
 }
 
+// signAsFeePayer adds the fee payer signature of the given account to a
+// fee-delegated transaction signed by its sender.
+func (s *PublicTransactionPoolAPI) signAsFeePayer(addr common.Address, tx *types.Transaction) (*types.Transaction, error) {
+	if tx.Type() != types.FeeDelegatedTxType {
+		return nil, errors.New("not a fee-delegated transaction")
+	}
+	if payer := tx.FeePayer(); *payer != addr {
+		return nil, fmt.Errorf("fee payer mismatch: transaction designates %s", payer.Hex())
+	}
+	signer := types.MakeSigner(s.b.ChainConfig())
+	if _, err := types.Sender(signer, tx); err != nil {
+		return nil, fmt.Errorf("invalid sender signature: %v", err)
+	}
+	// Look up the wallet containing the fee payer and sign with it
+	account := accounts.Account{Address: addr}
+
+	wallet, err := s.b.AccountManager().Find(account)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := wallet.SignHash(account, signer.FeePayerHash(tx).Bytes())
+	if err != nil {
+		return nil, err
+	}
+	return tx.WithFeePayerSignature(signer, sig)
+}
+
 // PendingTransactions returns the transactions that are in the transaction pool
 // and have a from address that is one of the accounts this node manages.
 func (s *PublicTransactionPoolAPI) PendingTransactions() ([]*RPCTransaction, error) {
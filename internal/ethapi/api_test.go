@@ -0,0 +1,198 @@
+// Copyright 2020 The go-simplechain Authors
+// This file is part of the go-simplechain library.
+//
+// The go-simplechain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-simplechain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-simplechain library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/simplechain-org/go-simplechain/common"
+	"github.com/simplechain-org/go-simplechain/common/hexutil"
+	"github.com/simplechain-org/go-simplechain/common/math"
+	"github.com/simplechain-org/go-simplechain/core"
+	"github.com/simplechain-org/go-simplechain/core/rawdb"
+	"github.com/simplechain-org/go-simplechain/core/state"
+	"github.com/simplechain-org/go-simplechain/core/types"
+	"github.com/simplechain-org/go-simplechain/core/vm"
+	"github.com/simplechain-org/go-simplechain/crypto"
+	"github.com/simplechain-org/go-simplechain/params"
+	"github.com/simplechain-org/go-simplechain/rpc"
+)
+
+var (
+	// loadCode returns the value of storage slot 0.
+	loadCode = hexutil.Bytes(common.FromHex("60005460005260206000f3"))
+	// incrementCode increments storage slot 0 and returns the new value.
+	incrementCode = hexutil.Bytes(common.FromHex("6000546001018060005560005260206000f3"))
+	// deployCode deploys a contract whose code is the single byte 0x01.
+	deployCode = hexutil.Bytes(common.FromHex("600160005360016000f3"))
+)
+
+// bundleBackend serves the calls of a bundle on a single in-memory state.
+type bundleBackend struct {
+	Backend
+	state  *state.StateDB
+	header *types.Header
+}
+
+func newBundleBackend(t *testing.T) *bundleBackend {
+	statedb, err := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()))
+	if err != nil {
+		t.Fatalf("failed to create state: %v", err)
+	}
+	header := &types.Header{Number: big.NewInt(1), GasLimit: 8000000, Difficulty: big.NewInt(1)}
+	return &bundleBackend{state: statedb, header: header}
+}
+
+func (b *bundleBackend) RPCGasCap() *big.Int { return nil }
+
+func (b *bundleBackend) StateAndHeaderByNumberOrHash(context.Context, rpc.BlockNumberOrHash) (*state.StateDB, *types.Header, error) {
+	return b.state, b.header, nil
+}
+
+func (b *bundleBackend) GetEVM(ctx context.Context, msg core.Message, state *state.StateDB, header *types.Header) (*vm.EVM, func() error, error) {
+	state.SetBalance(msg.From(), math.MaxBig256)
+	context := core.NewEVMContext(msg, header, nil, &header.Coinbase)
+	return vm.NewEVM(context, state, params.AllScryptProtocolChanges, vm.Config{}), func() error { return nil }, nil
+}
+
+func bundleCall(from, to common.Address, value int64) CallArgs {
+	gas := hexutil.Uint64(100000)
+	return CallArgs{
+		From:     &from,
+		To:       &to,
+		Gas:      &gas,
+		GasPrice: (*hexutil.Big)(new(big.Int)),
+		Value:    (*hexutil.Big)(big.NewInt(value)),
+	}
+}
+
+// Tests that the overrides are applied to the state the bundle runs on.
+func TestSimulateBundleOverrides(t *testing.T) {
+	var (
+		backend  = newBundleBackend(t)
+		api      = NewPublicBlockChainAPI(backend)
+		sender   = common.Address{1}
+		contract = common.Address{2}
+		balance  = (*hexutil.Big)(big.NewInt(1000))
+		storage  = map[common.Hash]common.Hash{{}: common.BigToHash(big.NewInt(42))}
+	)
+	overrides := &StateOverride{
+		sender:   OverrideAccount{Balance: &balance},
+		contract: OverrideAccount{Code: &loadCode, State: &storage},
+	}
+	results, err := api.SimulateBundle(context.Background(), []CallArgs{bundleCall(sender, contract, 0)}, rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber), overrides)
+	if err != nil {
+		t.Fatalf("failed to simulate bundle: %v", err)
+	}
+	if have := new(big.Int).SetBytes(results[0].ReturnValue); have.Int64() != 42 {
+		t.Errorf("overridden storage not read: have %v, want 42", have)
+	}
+	if have := backend.state.GetBalance(sender); have.Int64() != 1000 {
+		t.Errorf("overridden balance mismatch: have %v, want 1000", have)
+	}
+	conflicting := &StateOverride{contract: OverrideAccount{State: &storage, StateDiff: &storage}}
+	if _, err := api.SimulateBundle(context.Background(), nil, rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber), conflicting); err == nil {
+		t.Errorf("override with both state and stateDiff accepted")
+	}
+}
+
+// Tests that every call of a bundle sees the state changes of the previous
+// ones, and that a call overdrawing its sender fails without changing it.
+func TestSimulateBundleState(t *testing.T) {
+	var (
+		backend   = newBundleBackend(t)
+		api       = NewPublicBlockChainAPI(backend)
+		sender    = common.Address{1}
+		counter   = common.Address{2}
+		recipient = common.Address{3}
+		balance   = (*hexutil.Big)(big.NewInt(1000))
+	)
+	overrides := &StateOverride{
+		sender:  OverrideAccount{Balance: &balance},
+		counter: OverrideAccount{Code: &incrementCode},
+	}
+	calls := []CallArgs{
+		bundleCall(sender, counter, 0),
+		bundleCall(sender, recipient, 600),
+		bundleCall(sender, recipient, 600), // overdraws the sender
+		bundleCall(sender, counter, 0),
+		bundleCall(sender, recipient, 400),
+	}
+	results, err := api.SimulateBundle(context.Background(), calls, rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber), overrides)
+	if err != nil {
+		t.Fatalf("failed to simulate bundle: %v", err)
+	}
+	if len(results) != len(calls) {
+		t.Fatalf("result count mismatch: have %d, want %d", len(results), len(calls))
+	}
+	for i, want := range map[int]int64{0: 1, 3: 2} {
+		if have := new(big.Int).SetBytes(results[i].ReturnValue); have.Int64() != want {
+			t.Errorf("call %d: counter mismatch: have %v, want %d", i, have, want)
+		}
+	}
+	for i, result := range results {
+		if overdraft := i == 2; (result.Error == errBundleOverdraft.Error()) != overdraft || result.Failed != overdraft {
+			t.Errorf("call %d: result mismatch: failed %v, error %q", i, result.Failed, result.Error)
+		}
+	}
+	if have := backend.state.GetBalance(recipient); have.Int64() != 1000 {
+		t.Errorf("recipient balance mismatch: have %v, want 1000", have)
+	}
+	if have := backend.state.GetBalance(sender); have.Sign() != 0 {
+		t.Errorf("sender balance mismatch: have %v, want 0", have)
+	}
+	// Every call but the failed one advances the nonce of the sender
+	if have := backend.state.GetNonce(sender); have != 4 {
+		t.Errorf("sender nonce mismatch: have %d, want 4", have)
+	}
+}
+
+// Tests that contracts created within a bundle get their addresses from the
+// nonce of the sender as advanced by the previous calls.
+func TestSimulateBundleCreate(t *testing.T) {
+	var (
+		backend = newBundleBackend(t)
+		api     = NewPublicBlockChainAPI(backend)
+		sender  = common.Address{1}
+		balance = (*hexutil.Big)(big.NewInt(1000))
+	)
+	create := bundleCall(sender, common.Address{}, 0)
+	create.To, create.Data = nil, &deployCode
+
+	overrides := &StateOverride{sender: OverrideAccount{Balance: &balance}}
+	calls := []CallArgs{create, create, bundleCall(sender, common.Address{2}, 0), create}
+	results, err := api.SimulateBundle(context.Background(), calls, rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber), overrides)
+	if err != nil {
+		t.Fatalf("failed to simulate bundle: %v", err)
+	}
+	for i, result := range results {
+		if result.Failed {
+			t.Fatalf("create %d failed: %s", i, result.Error)
+		}
+	}
+	// The plain call in between consumes nonce 2
+	for _, nonce := range []uint64{0, 1, 3} {
+		if code := backend.state.GetCode(crypto.CreateAddress(sender, nonce)); len(code) != 1 || code[0] != 0x01 {
+			t.Errorf("contract of nonce %d mismatch: have code %x", nonce, code)
+		}
+	}
+	if have := backend.state.GetNonce(sender); have != 4 {
+		t.Errorf("sender nonce mismatch: have %d, want 4", have)
+	}
+}
@@ -32,6 +32,7 @@ import (
 	"github.com/simplechain-org/go-simplechain/ethdb"
 	"github.com/simplechain-org/go-simplechain/event"
 	"github.com/simplechain-org/go-simplechain/params"
+	"github.com/simplechain-org/go-simplechain/private"
 	"github.com/simplechain-org/go-simplechain/rpc"
 )
 
@@ -85,6 +86,9 @@ type Backend interface {
 	ChainConfig() *params.ChainConfig
 	CurrentBlock() *types.Block
 
+	// Private transaction API
+	PrivateTransactionManager() private.PrivateTransactionManager
+
 	//CtxStats() (pending int)
 	//CtxStatus() (pending, queue int)
 	//
@@ -26,6 +26,7 @@ var Modules = map[string]string{
 	"ethash":     EthashJs,
 	"dpos":       DPoS_JS,
 	"raft":       Raft_JS,
+	"stratum":    Stratum_JS,
 	"istanbul":   Istanbul_JS,
 	"debug":      DebugJs,
 	"eth":        EthJs,
@@ -270,6 +271,47 @@ web3._extend({
 			call: 'dpos_getSnapshotByHeaderTime',
 			params: 2
 		}),
+		new web3._extend.Method({
+			name: 'getRewards',
+			call: 'dpos_getRewards',
+			params: 3,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputBlockNumberFormatter, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getVoters',
+			call: 'dpos_getVoters',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null]
+		}),
+	]
+});
+`
+
+const Stratum_JS = `
+web3._extend({
+	property: 'stratum',
+	methods: [
+		new web3._extend.Method({
+			name: 'getWorker',
+			call: 'stratum_getWorker',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'getBlocks',
+			call: 'stratum_getBlocks',
+			params: 1,
+			inputFormatter: [null]
+		}),
+	],
+	properties: [
+		new web3._extend.Property({
+			name: 'workers',
+			getter: 'stratum_getWorkers'
+		}),
+		new web3._extend.Property({
+			name: 'payouts',
+			getter: 'stratum_getPayouts'
+		}),
 	]
 });
 `
@@ -442,8 +484,31 @@ web3._extend({
 			name: 'stopWS',
 			call: 'admin_stopWS'
 		}),
+		new web3._extend.Method({
+			name: 'permissionsAllowed',
+			call: 'admin_permissionsAllowed',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'permissionsAdd',
+			call: 'admin_permissionsAdd',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'permissionsRemove',
+			call: 'admin_permissionsRemove',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'permissionsReload',
+			call: 'admin_permissionsReload'
+		}),
 	],
 	properties: [
+		new web3._extend.Property({
+			name: 'permissionsNodes',
+			getter: 'admin_permissionsNodes'
+		}),
 		new web3._extend.Property({
 			name: 'nodeInfo',
 			getter: 'admin_nodeInfo'
@@ -729,6 +794,12 @@ web3._extend({
 			params: 1,
 			inputFormatter: [web3._extend.formatters.inputTransactionFormatter]
 		}),
+		new web3._extend.Method({
+			name: 'signTransactionAsFeePayer',
+			call: 'eth_signTransactionAsFeePayer',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null]
+		}),
 		new web3._extend.Method({
 			name: 'submitTransaction',
 			call: 'eth_submitTransaction',
@@ -741,6 +812,12 @@ web3._extend({
 			params: 1,
 			inputFormatter: [web3._extend.formatters.inputTransactionFormatter]
 		}),
+		new web3._extend.Method({
+			name: 'simulateBundle',
+			call: 'eth_simulateBundle',
+			params: 3,
+			inputFormatter: [null, web3._extend.formatters.inputBlockNumberFormatter, null]
+		}),
 		new web3._extend.Method({
 			name: 'getHeaderByNumber',
 			call: 'eth_getHeaderByNumber',
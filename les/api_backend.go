@@ -36,6 +36,7 @@ import (
 	"github.com/simplechain-org/go-simplechain/event"
 	"github.com/simplechain-org/go-simplechain/light"
 	"github.com/simplechain-org/go-simplechain/params"
+	"github.com/simplechain-org/go-simplechain/private"
 	"github.com/simplechain-org/go-simplechain/rpc"
 )
 
@@ -49,6 +50,11 @@ func (b *LesApiBackend) ChainConfig() *params.ChainConfig {
 	return b.eth.chainConfig
 }
 
+// PrivateTransactionManager returns nil, light clients don't keep private state.
+func (b *LesApiBackend) PrivateTransactionManager() private.PrivateTransactionManager {
+	return nil
+}
+
 func (b *LesApiBackend) CurrentBlock() *types.Block {
 	return types.NewBlockWithHeader(b.eth.BlockChain().CurrentHeader())
 }
@@ -296,6 +296,21 @@ func (h *clientHandler) handleMsg(p *peer) error {
 			ReqID:   resp.ReqID,
 			Obj:     resp.Status,
 		}
+	case EpochHeadersMsg:
+		p.Log().Trace("Received epoch headers response")
+		var resp struct {
+			ReqID, BV uint64
+			Headers   []*types.Header
+		}
+		if err := msg.Decode(&resp); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		p.fcServer.ReceivedReply(resp.ReqID, resp.BV)
+		deliverMsg = &Msg{
+			MsgType: MsgEpochHeaders,
+			ReqID:   resp.ReqID,
+			Obj:     resp.Headers,
+		}
 	case StopMsg:
 		p.freezeServer(true)
 		h.backend.retriever.frozen(p)
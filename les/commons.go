@@ -18,10 +18,13 @@ package les
 
 import (
 	"fmt"
+	"math"
 	"math/big"
 	"sync"
 
 	"github.com/simplechain-org/go-simplechain/common"
+	"github.com/simplechain-org/go-simplechain/consensus/dpos"
+	"github.com/simplechain-org/go-simplechain/consensus/istanbul"
 	"github.com/simplechain-org/go-simplechain/core"
 	"github.com/simplechain-org/go-simplechain/core/rawdb"
 	"github.com/simplechain-org/go-simplechain/core/types"
@@ -130,6 +133,76 @@ func (c *lesCommons) latestLocalCheckpoint() params.TrustedCheckpoint {
 	return c.localCheckpoint(sections - 1)
 }
 
+// epochSpan is a run of blocks of the chain sealed by an engine handing off its
+// signers at epoch boundaries, either from the genesis on or between consensus
+// forks. DPoS hands off its signer queue at the start of every loop, so its
+// epochs are the loops.
+type epochSpan struct {
+	epoch       uint64           // Number of blocks in an epoch
+	first, last uint64           // First and last block sealed by the engine
+	validators  []common.Address // Validators of the first block when switching from another engine
+	dpos        bool             // Whether DPoS seals the span instead of istanbul
+}
+
+// covers reports whether any block of the epoch with the given index is sealed
+// within the span.
+func (s *epochSpan) covers(index uint64) bool {
+	return index*s.epoch >= s.first && (index-1)*s.epoch < s.last
+}
+
+// epochSpans returns the runs of blocks sealed by istanbul or DPoS in the order
+// of the consensus schedule, none if the chain never runs either. DPoS only runs
+// from the genesis on, its first loop starting with the self voted signers.
+func (c *lesCommons) epochSpans() []*epochSpan {
+	var (
+		spans   []*epochSpan
+		configs = c.chainConfig.ConsensusConfigs()
+	)
+	for i, config := range configs {
+		var span *epochSpan
+		switch {
+		case config.Istanbul != nil:
+			span = &epochSpan{
+				epoch:      config.Istanbul.Epoch,
+				validators: config.Istanbul.Validators,
+			}
+			if span.epoch == 0 {
+				span.epoch = istanbul.DefaultConfig.Epoch
+			}
+		case config.DPoS != nil && i == 0:
+			span = &epochSpan{
+				epoch: config.DPoS.MaxSignerCount,
+				dpos:  true,
+			}
+			if span.epoch == 0 {
+				span.epoch = dpos.DefaultMaxSignerCount
+			}
+		default:
+			continue
+		}
+		span.last = math.MaxUint64
+		if i > 0 {
+			span.first = c.chainConfig.ConsensusForks[i-1].Block.Uint64()
+		}
+		if i+1 < len(configs) {
+			span.last = c.chainConfig.ConsensusForks[i].Block.Uint64() - 1
+		}
+		spans = append(spans, span)
+	}
+	return spans
+}
+
+// epochSpan returns the run of blocks sealed by istanbul or DPoS the given block
+// is part of, or nil if another engine seals it.
+func (c *lesCommons) epochSpan(number uint64) *epochSpan {
+	for _, span := range c.epochSpans() {
+		if span.first <= number && number <= span.last {
+			return span
+		}
+	}
+	return nil
+}
+
 // localCheckpoint returns a set of post-processed trie roots (CHT and BloomTrie)
 // associated with the appropriate head hash by specific section index.
 //
@@ -44,6 +44,7 @@ var (
 		GetHelperTrieProofsMsg: {0, 1000000},
 		SendTxV2Msg:            {0, 450000},
 		GetTxStatusMsg:         {0, 250000},
+		GetEpochHeadersMsg:     {0, 3000000},
 	}
 	// maximum incoming message size estimates
 	reqMaxInSize = requestCostTable{
@@ -55,6 +56,7 @@ var (
 		GetHelperTrieProofsMsg: {0, 20},
 		SendTxV2Msg:            {0, 16500},
 		GetTxStatusMsg:         {0, 50},
+		GetEpochHeadersMsg:     {40, 0},
 	}
 	// maximum outgoing message size estimates
 	reqMaxOutSize = requestCostTable{
@@ -66,6 +68,7 @@ var (
 		GetHelperTrieProofsMsg: {0, 4000},
 		SendTxV2Msg:            {0, 100},
 		GetTxStatusMsg:         {0, 100},
+		GetEpochHeadersMsg:     {0, 5560},
 	}
 	// request amounts that have to fit into the minimum buffer size minBufferMultiplier times
 	minBufferReqAmount = map[uint64]uint64{
@@ -77,6 +80,7 @@ var (
 		GetHelperTrieProofsMsg: 16,
 		SendTxV2Msg:            8,
 		GetTxStatusMsg:         64,
+		GetEpochHeadersMsg:     1,
 	}
 	minBufferMultiplier = 3
 )
@@ -294,6 +298,8 @@ func (ct *costTracker) gfLoop() {
 						relativeCostSendTxHistogram.Update(relCost)
 					case GetTxStatusMsg:
 						relativeCostTxStatusHistogram.Update(relCost)
+					case GetEpochHeadersMsg:
+						relativeCostEpochHistogram.Update(relCost)
 					}
 				}
 				// SendTxV2 and GetTxStatus requests are two special cases.
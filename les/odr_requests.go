@@ -43,6 +43,7 @@ var (
 	errCHTHashMismatch     = errors.New("cht hash mismatch")
 	errCHTNumberMismatch   = errors.New("cht number mismatch")
 	errUselessNodes        = errors.New("useless nodes in merkle proof nodeset")
+	errEpochHeaderMismatch = errors.New("epoch header mismatch")
 )
 
 type LesOdrRequest interface {
@@ -68,6 +69,8 @@ func LesRequest(req light.OdrRequest) LesOdrRequest {
 		return (*BloomRequest)(r)
 	case *light.TxStatusRequest:
 		return (*TxStatusRequest)(r)
+	case *light.EpochHeadersRequest:
+		return (*EpochHeadersRequest)(r)
 	default:
 		return nil
 	}
@@ -522,6 +525,60 @@ func (r *TxStatusRequest) Validate(db ethdb.Database, msg *Msg) error {
 	return nil
 }
 
+// EpochHeadersRequest is the ODR request type for the validator handoff headers
+// of istanbul epochs or DPoS loops
+type EpochHeadersRequest light.EpochHeadersRequest
+
+// GetCost returns the cost of the given ODR request according to the serving
+// peer's cost table (implementation of LesOdrRequest)
+func (r *EpochHeadersRequest) GetCost(peer *peer) uint64 {
+	return peer.GetRequestCost(GetEpochHeadersMsg, int(r.Amount))
+}
+
+// CanSend tells if a certain peer is suitable for serving the given request
+func (r *EpochHeadersRequest) CanSend(peer *peer) bool {
+	peer.lock.RLock()
+	defer peer.lock.RUnlock()
+
+	return peer.version >= lpv4 && peer.id == r.PeerId && peer.headInfo.Number >= r.From*r.Epoch
+}
+
+// Request sends an ODR request to the LES network (implementation of LesOdrRequest)
+func (r *EpochHeadersRequest) Request(reqID uint64, peer *peer) error {
+	peer.Log().Debug("Requesting epoch headers", "from", r.From, "count", r.Amount)
+	return peer.RequestEpochHeaders(reqID, r.GetCost(peer), r.From, r.Amount)
+}
+
+// Valid processes an ODR request reply message from the LES network
+// returns true and stores results in memory if the message was a valid reply
+// to the request (implementation of LesOdrRequest)
+func (r *EpochHeadersRequest) Validate(db ethdb.Database, msg *Msg) error {
+	log.Debug("Validating epoch headers", "from", r.From, "count", r.Amount)
+
+	// Ensure we have a correct message with ascending headers in the epochs
+	if msg.MsgType != MsgEpochHeaders {
+		return errInvalidMessageType
+	}
+	headers := msg.Obj.([]*types.Header)
+	var (
+		first = (r.From - 1) * r.Epoch
+		last  = (r.From + r.Amount - 1) * r.Epoch
+	)
+	for _, header := range headers {
+		number := header.Number.Uint64()
+		if number <= first || number > last {
+			return errEpochHeaderMismatch
+		}
+		first = number
+	}
+	// A partial reply has to end with an epoch header all the same
+	if len(headers) > 0 && first%r.Epoch != 0 {
+		return errEpochHeaderMismatch
+	}
+	r.Headers = headers
+	return nil
+}
+
 // readTraceDB stores the keys of database reads. We use this to check that received node
 // sets contain only the trie nodes necessary to make proofs pass.
 type readTraceDB struct {
@@ -0,0 +1,57 @@
+// Copyright 2020 The go-simplechain Authors
+// This file is part of the go-simplechain library.
+//
+// The go-simplechain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-simplechain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-simplechain library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/simplechain-org/go-simplechain/core/types"
+)
+
+func TestEpochHeadersValidate(t *testing.T) {
+	headers := func(numbers ...int64) []*types.Header {
+		var headers []*types.Header
+		for _, number := range numbers {
+			headers = append(headers, &types.Header{Number: big.NewInt(number)})
+		}
+		return headers
+	}
+	tests := []struct {
+		headers []*types.Header
+		err     error
+	}{
+		{nil, nil},                   // No complete epoch yet
+		{headers(10), nil},           // Epoch header only
+		{headers(3, 7, 10, 20), nil}, // Handoffs and epoch headers
+		{headers(3, 10, 14), errEpochHeaderMismatch}, // Not ending with an epoch header
+		{headers(10, 7), errEpochHeaderMismatch},     // Not ascending
+		{headers(0, 10), errEpochHeaderMismatch},     // Before the first epoch
+		{headers(10, 40), errEpochHeaderMismatch},    // After the last epoch
+	}
+	for i, tt := range tests {
+		r := &EpochHeadersRequest{Epoch: 10, From: 1, Amount: 3}
+		err := r.Validate(nil, &Msg{MsgType: MsgEpochHeaders, Obj: tt.headers})
+		if err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+	r := &EpochHeadersRequest{Epoch: 10, From: 1, Amount: 3}
+	if err := r.Validate(nil, &Msg{MsgType: MsgTxStatus}); err != errInvalidMessageType {
+		t.Errorf("error mismatch: have %v, want %v", err, errInvalidMessageType)
+	}
+}
@@ -443,6 +443,13 @@ func (p *peer) ReplyTxStatus(reqID uint64, stats []light.TxStatus) *reply {
 	return &reply{p.rw, TxStatusMsg, reqID, data}
 }
 
+// ReplyEpochHeaders creates a reply with the validator handoff headers of a
+// range of epochs.
+func (p *peer) ReplyEpochHeaders(reqID uint64, headers []*types.Header) *reply {
+	data, _ := rlp.EncodeToBytes(headers)
+	return &reply{p.rw, EpochHeadersMsg, reqID, data}
+}
+
 // RequestHeadersByHash fetches a batch of blocks' headers corresponding to the
 // specified header query, based on the hash of an origin block.
 func (p *peer) RequestHeadersByHash(reqID, cost uint64, origin common.Hash, amount int, skip int, reverse bool) error {
@@ -495,6 +502,13 @@ func (p *peer) RequestTxStatus(reqID, cost uint64, txHashes []common.Hash) error
 	return sendRequest(p.rw, GetTxStatusMsg, reqID, cost, txHashes)
 }
 
+// RequestEpochHeaders fetches the validator handoff headers of a range of
+// epochs from a remote node.
+func (p *peer) RequestEpochHeaders(reqID, cost, from, amount uint64) error {
+	p.Log().Debug("Fetching epoch headers", "from", from, "count", amount)
+	return sendRequest(p.rw, GetEpochHeadersMsg, reqID, cost, &getEpochHeadersData{From: from, Amount: amount})
+}
+
 // SendTxStatus creates a reply with a batch of transactions to be added to the remote transaction pool.
 func (p *peer) SendTxs(reqID, cost uint64, txs rlp.RawValue) error {
 	p.Log().Debug("Sending batch of transactions", "size", len(txs))
@@ -721,7 +735,7 @@ func (p *peer) Handshake(td *big.Int, head common.Hash, headNum uint64, genesis
 
 		if !p.onlyAnnounce {
 			for msgCode := range reqAvgTimeCost {
-				if p.fcCosts[msgCode] == nil {
+				if msgCode < ProtocolLengths[uint(p.version)] && p.fcCosts[msgCode] == nil {
 					return errResp(ErrUselessPeer, "peer does not support message %d", msgCode)
 				}
 			}
@@ -52,6 +52,9 @@ const (
 	MaxHelperTrieProofsFetch = 64  // Amount of helper tries to be fetched per retrieval request
 	MaxTxSend                = 64  // Amount of transactions to be send per request
 	MaxTxStatus              = 256 // Amount of transactions to queried per request
+	MaxEpochHeadersFetch     = 16  // Amount of epochs to fetch the validator handoffs of per request
+
+	epochHeaderStride = 1024 // Blocks between the headers sampled for validator changes
 )
 
 var (
@@ -818,6 +821,64 @@ func (h *serverHandler) handleMsg(p *peer, wg *sync.WaitGroup) error {
 			}()
 		}
 
+	case GetEpochHeadersMsg:
+		p.Log().Trace("Received epoch headers request")
+		if metrics.EnabledExpensive {
+			miscInEpochPacketsMeter.Mark(1)
+			miscInEpochTrafficMeter.Mark(int64(msg.Size))
+			defer func(start time.Time) { miscServingTimeEpochTimer.UpdateSince(start) }(time.Now())
+		}
+		var req struct {
+			ReqID uint64
+			Query getEpochHeadersData
+		}
+		if err := msg.Decode(&req); err != nil {
+			clientErrorMeter.Mark(1)
+			return errResp(ErrDecode, "%v: %v", msg, err)
+		}
+		query := req.Query
+		if accept(req.ReqID, query.Amount, MaxEpochHeadersFetch) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				var (
+					span    *epochSpan
+					bytes   common.StorageSize
+					headers []*types.Header
+				)
+				if query.From != 0 {
+					for _, s := range h.server.epochSpans() {
+						if s.covers(query.From) {
+							span = s
+							break
+						}
+					}
+				}
+				if span == nil {
+					atomic.AddUint32(&p.invalidCount, 1)
+				} else {
+					for i := uint64(0); i < query.Amount && bytes < softResponseLimit; i++ {
+						if i != 0 && !task.waitOrStop() {
+							sendResponse(req.ReqID, 0, nil, task.servingTime)
+							return
+						}
+						handoffs := h.epochHeaders(span, query.From+i)
+						if handoffs == nil {
+							break
+						}
+						headers = append(headers, handoffs...)
+						bytes += common.StorageSize(len(handoffs) * estHeaderRlpSize)
+					}
+				}
+				reply := p.ReplyEpochHeaders(req.ReqID, headers)
+				sendResponse(req.ReqID, query.Amount, reply, task.done())
+				if metrics.EnabledExpensive {
+					miscOutEpochPacketsMeter.Mark(1)
+					miscOutEpochTrafficMeter.Mark(int64(reply.size()))
+				}
+			}()
+		}
+
 	default:
 		p.Log().Trace("Received invalid message", "code", msg.Code)
 		clientErrorMeter.Mark(1)
@@ -872,6 +933,87 @@ func (h *serverHandler) getAuxiliaryHeaders(req HelperTrieReq) []byte {
 	return nil
 }
 
+// epochHeaders returns the headers of the validator handoffs in an epoch of an
+// istanbul chain, followed by the epoch header. Headers sampled along the epoch
+// are compared, bisecting any range the validators changed in to find where the
+// validators of the previous handoff are replaced, so validator changes undone
+// within a range may be skipped. DPoS only hands off its signer queue at the
+// loop header, returned alone. Nil is returned if the epoch isn't complete or
+// the engine of the span doesn't seal it up to the epoch header.
+func (h *serverHandler) epochHeaders(span *epochSpan, index uint64) []*types.Header {
+	var (
+		epoch   = span.epoch
+		end     = index * epoch
+		current = h.blockchain.CurrentHeader().Number.Uint64()
+	)
+	if end > current || end > span.last {
+		return nil
+	}
+	if span.dpos {
+		return []*types.Header{h.blockchain.GetHeaderByNumber(end)}
+	}
+	validators := func(number uint64) []common.Address {
+		header := h.blockchain.GetHeaderByNumber(number)
+		if header == nil {
+			return nil
+		}
+		extra, err := types.ExtractIstanbulExtra(header)
+		if err != nil {
+			return nil
+		}
+		return extra.Validators
+	}
+	var (
+		headers []*types.Header
+		prev    = end - epoch
+		prevSet []common.Address
+	)
+	// The epoch of a switch to istanbul starts with the configured validators
+	if prev < span.first {
+		prev, prevSet = span.first-1, span.validators
+	} else {
+		prevSet = validators(prev)
+	}
+	for prev < end {
+		sample := prev + epochHeaderStride
+		if sample > end {
+			sample = end
+		}
+		set := validators(sample)
+		for !sameValidators(prevSet, set) {
+			// Bisect for the first header not run by the previous validators
+			lo, hi := prev, sample
+			for hi-lo > 1 {
+				mid := lo + (hi-lo)/2
+				if sameValidators(prevSet, validators(mid)) {
+					lo = mid
+				} else {
+					hi = mid
+				}
+			}
+			if hi != end {
+				headers = append(headers, h.blockchain.GetHeaderByNumber(hi))
+			}
+			prev, prevSet = hi, validators(hi)
+		}
+		prev, prevSet = sample, set
+	}
+	return append(headers, h.blockchain.GetHeaderByNumber(end))
+}
+
+// sameValidators reports whether two validator lists are the same.
+func sameValidators(a, b []common.Address) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
 // txStatus returns the status of a specified transaction.
 func (h *serverHandler) txStatus(hash common.Hash) light.TxStatus {
 	var stat light.TxStatus
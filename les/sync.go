@@ -20,16 +20,46 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math/big"
 	"time"
 
 	"github.com/simplechain-org/go-simplechain/common"
+	"github.com/simplechain-org/go-simplechain/consensus/multi"
 	"github.com/simplechain-org/go-simplechain/core/rawdb"
+	"github.com/simplechain-org/go-simplechain/core/types"
 	"github.com/simplechain-org/go-simplechain/eth/downloader"
 	"github.com/simplechain-org/go-simplechain/light"
 	"github.com/simplechain-org/go-simplechain/log"
 )
 
-var errInvalidCheckpoint = errors.New("invalid advertised checkpoint")
+var (
+	errInvalidCheckpoint = errors.New("invalid advertised checkpoint")
+	errUnknownHeadTd     = errors.New("unknown total difficulty of the head")
+)
+
+// epochEngine is a consensus engine able to verify the validator handoffs of
+// the epochs, without the whole header chain, like istanbul.
+type epochEngine interface {
+	// VerifyEpochHeader checks a validator handoff header against the trusted
+	// validators, returning the validators to trust from the header on.
+	VerifyEpochHeader(validators []common.Address, header *types.Header) ([]common.Address, error)
+
+	// TrustEpochHeader makes the engine verify the headers after the verified
+	// epoch header without the history before it.
+	TrustEpochHeader(header *types.Header) error
+}
+
+// loopEngine is a consensus engine able to verify the signer queue handoffs at
+// the loop headers, without the whole header chain, like DPoS.
+type loopEngine interface {
+	// VerifyLoopHeader checks the header opening a loop against a trusted header
+	// of the loop before it.
+	VerifyLoopHeader(trusted, header *types.Header) error
+
+	// TrustLoopHeader makes the engine verify the headers after the verified
+	// loop header without the history before it.
+	TrustLoopHeader(header *types.Header) error
+}
 
 const (
 	// lightSync starts syncing from the current highest block.
@@ -87,6 +117,112 @@ func (h *clientHandler) validateCheckpoint(peer *peer) error {
 	return nil
 }
 
+// syncEpochs fast forwards the local chain of an istanbul or DPoS network to the
+// latest epoch header of a remote peer, verifying the signer handoffs only. The
+// peer serves the handoffs of the epochs after the local head. Istanbul ones are
+// each to be signed by more than a third of the validators trusted before it. On
+// chains switching consensus engines the fast forward is confined to the blocks
+// istanbul seals, starting from the configured validators if the local head
+// precedes the switch.
+//
+// DPoS hands off its signer queue at every loop header, each one to be sealed by
+// the signer in turn in the queue trusted before it. The queues are tallied from
+// the votes of the skipped blocks, so they are trusted as sealed, a signer sealing
+// conflicting ones being jailed by the double signing evidences.
+func (h *clientHandler) syncEpochs(peer *peer) error {
+	if peer.version < lpv4 {
+		return nil
+	}
+	head := h.backend.blockchain.CurrentHeader()
+	next := head.Number.Uint64() + 1
+	span := h.backend.epochSpan(next)
+	if span == nil {
+		return nil
+	}
+	epoch := span.epoch
+	from := head.Number.Uint64()/epoch + 1
+	if peer.headBlockInfo().Number < from*epoch || from*epoch > span.last {
+		return nil
+	}
+	var (
+		verify func(header *types.Header) error
+		trust  func(header *types.Header) error
+	)
+	if span.dpos {
+		engine, ok := multi.EngineAt(h.backend.engine, next).(loopEngine)
+		if !ok {
+			return nil
+		}
+		trusted := head
+		verify = func(header *types.Header) error {
+			if err := engine.VerifyLoopHeader(trusted, header); err != nil {
+				return err
+			}
+			trusted = header
+			return nil
+		}
+		trust = engine.TrustLoopHeader
+	} else {
+		engine, ok := multi.EngineAt(h.backend.engine, next).(epochEngine)
+		if !ok {
+			return nil
+		}
+		var validators []common.Address
+		if next == span.first && span.first > 0 {
+			validators = span.validators
+		} else {
+			extra, err := types.ExtractIstanbulExtra(head)
+			if err != nil {
+				return err
+			}
+			validators = extra.Validators
+		}
+		verify = func(header *types.Header) (err error) {
+			validators, err = engine.VerifyEpochHeader(validators, header)
+			return err
+		}
+		trust = engine.TrustEpochHeader
+	}
+	var latest *types.Header
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+		headers, err := light.GetUntrustedEpochHeaders(ctx, h.backend.odr, epoch, from, MaxEpochHeadersFetch, peer.id)
+		cancel()
+		if err != nil {
+			return err
+		}
+		if len(headers) == 0 {
+			break
+		}
+		for _, header := range headers {
+			if err := verify(header); err != nil {
+				return err
+			}
+		}
+		latest = headers[len(headers)-1]
+		from = latest.Number.Uint64()/epoch + 1
+		if from*epoch > span.last {
+			break
+		}
+	}
+	if latest == nil {
+		return nil
+	}
+	if err := trust(latest); err != nil {
+		return err
+	}
+	// Every istanbul and DPoS block has the same difficulty
+	td := h.backend.blockchain.GetTd(head.Hash(), head.Number.Uint64())
+	if td == nil {
+		return errUnknownHeadTd
+	}
+	blocks := new(big.Int).SetUint64(latest.Number.Uint64() - head.Number.Uint64())
+	td = new(big.Int).Add(td, blocks.Mul(blocks, latest.Difficulty))
+
+	h.backend.blockchain.SyncEpochHeader(latest, td)
+	return nil
+}
+
 // synchronise tries to sync up our local chain with a remote peer.
 func (h *clientHandler) synchronise(peer *peer) {
 	// Short circuit if the peer is nil.
@@ -178,6 +314,12 @@ func (h *clientHandler) synchronise(peer *peer) {
 			return
 		}
 	}
+	// Jump over the verified validator handoffs of istanbul networks.
+	if err := h.syncEpochs(peer); err != nil {
+		log.Debug("Epoch syncing failed", "reason", err)
+		h.removePeer(peer.id)
+		return
+	}
 	// Fetch the remaining block headers based on the current chain header.
 	if err := h.downloader.Synchronise(peer.id, peer.Head(), peer.Td(), downloader.LightSync); err != nil {
 		fmt.Println("Synchronise failed", "reason", err)
@@ -23,6 +23,8 @@ import (
 	"time"
 
 	"github.com/simplechain-org/go-simplechain/accounts/abi/bind"
+	"github.com/simplechain-org/go-simplechain/common"
+	"github.com/simplechain-org/go-simplechain/consensus/istanbul"
 	"github.com/simplechain-org/go-simplechain/core"
 	"github.com/simplechain-org/go-simplechain/crypto"
 	"github.com/simplechain-org/go-simplechain/light"
@@ -227,3 +229,73 @@ func testMissOracleBackend(t *testing.T, hasCheckpoint bool) {
 		t.Error("checkpoint syncing timeout")
 	}
 }
+
+// Tests that the runs of istanbul and DPoS sealed blocks are derived from the
+// consensus schedule, and that epochs are matched with the runs sealing any of
+// them.
+func TestEpochSpans(t *testing.T) {
+	validators := []common.Address{{0x01}}
+	config := &params.ChainConfig{
+		Ethash: new(params.EthashConfig),
+		ConsensusForks: []*params.ConsensusFork{
+			{Block: big.NewInt(25), Istanbul: &params.IstanbulConfig{Epoch: 10, Validators: validators}},
+			{Block: big.NewInt(100), Clique: &params.CliqueConfig{Period: 1, Epoch: 10}},
+		},
+	}
+	c := &lesCommons{chainConfig: config}
+
+	spans := c.epochSpans()
+	if len(spans) != 1 {
+		t.Fatalf("span count mismatch: have %d, want 1", len(spans))
+	}
+	if span := spans[0]; span.epoch != 10 || span.first != 25 || span.last != 99 || len(span.validators) != 1 || span.dpos {
+		t.Errorf("span mismatch: have %+v", span)
+	}
+	for _, tt := range []struct {
+		number uint64
+		sealed bool
+	}{{24, false}, {25, true}, {99, true}, {100, false}} {
+		if sealed := c.epochSpan(tt.number) != nil; sealed != tt.sealed {
+			t.Errorf("block %d: istanbul sealed mismatch: have %v, want %v", tt.number, sealed, tt.sealed)
+		}
+	}
+	for _, tt := range []struct {
+		index   uint64
+		covered bool
+	}{{2, false}, {3, true}, {10, true}, {11, false}} {
+		if covered := spans[0].covers(tt.index); covered != tt.covered {
+			t.Errorf("epoch %d: covered mismatch: have %v, want %v", tt.index, covered, tt.covered)
+		}
+	}
+	// Istanbul from the genesis on runs open-ended with the default epoch
+	c.chainConfig = &params.ChainConfig{Istanbul: new(params.IstanbulConfig)}
+	if span := c.epochSpan(1 << 40); span == nil || span.first != 0 || span.epoch != istanbul.DefaultConfig.Epoch || span.dpos {
+		t.Errorf("genesis span mismatch: have %+v", span)
+	}
+	// DPoS from the genesis on runs its loops as epochs up to the next fork
+	c.chainConfig = &params.ChainConfig{
+		DPoS: &params.DPoSConfig{MaxSignerCount: 3},
+		ConsensusForks: []*params.ConsensusFork{
+			{Block: big.NewInt(50), Istanbul: &params.IstanbulConfig{Epoch: 10, Validators: validators}},
+		},
+	}
+	if spans := c.epochSpans(); len(spans) != 2 {
+		t.Fatalf("dpos span count mismatch: have %d, want 2", len(spans))
+	}
+	if span := c.epochSpan(49); span == nil || !span.dpos || span.epoch != 3 || span.first != 0 || span.last != 49 {
+		t.Errorf("dpos span mismatch: have %+v", span)
+	}
+	if span := c.epochSpan(50); span == nil || span.dpos || span.first != 50 {
+		t.Errorf("istanbul span after dpos mismatch: have %+v", span)
+	}
+	// DPoS can't be switched to, its first loop running the genesis signers
+	c.chainConfig = &params.ChainConfig{
+		Ethash: new(params.EthashConfig),
+		ConsensusForks: []*params.ConsensusFork{
+			{Block: big.NewInt(50), DPoS: new(params.DPoSConfig)},
+		},
+	}
+	if span := c.epochSpan(50); span != nil {
+		t.Errorf("dpos fork span mismatch: have %+v, want nil", span)
+	}
+}
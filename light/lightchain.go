@@ -500,6 +500,25 @@ func (lc *LightChain) SyncCheckpoint(ctx context.Context, checkpoint *params.Tru
 	return false
 }
 
+// SyncEpochHeader fast forwards the chain to an epoch header the consensus
+// engine verified by the validator handoffs, without the headers leading to it.
+func (lc *LightChain) SyncEpochHeader(header *types.Header, td *big.Int) {
+	lc.chainmu.Lock()
+	defer lc.chainmu.Unlock()
+
+	// Ensure the chain didn't move past the epoch header while verifying it
+	if lc.hc.CurrentHeader().Number.Uint64() >= header.Number.Uint64() {
+		return
+	}
+	hash, number := header.Hash(), header.Number.Uint64()
+	rawdb.WriteHeader(lc.chainDb, header)
+	rawdb.WriteTd(lc.chainDb, hash, number, td)
+	rawdb.WriteCanonicalHash(lc.chainDb, hash, number)
+
+	log.Info("Updated latest header based on epoch headers", "number", number, "hash", hash, "age", common.PrettyAge(time.Unix(int64(header.Time), 0)))
+	lc.hc.SetCurrentHeader(header)
+}
+
 // LockChain locks the chain mutex for reading so that multiple canonical hashes can be
 // retrieved while it is guaranteed that they belong to the same version of the chain
 func (lc *LightChain) LockChain() {
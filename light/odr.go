@@ -191,6 +191,20 @@ type TxStatus struct {
 	Error  string
 }
 
+// EpochHeadersRequest is the ODR request type for retrieving the validator
+// handoff headers of a range of istanbul epochs or DPoS loops from a specified peer
+type EpochHeadersRequest struct {
+	OdrRequest
+	PeerId       string // The specified peer id from which to retrieve data.
+	Epoch        uint64 // Number of blocks in an epoch
+	From, Amount uint64 // Range of epochs to retrieve the headers of
+	Headers      []*types.Header
+}
+
+// StoreResult stores the retrieved data in local database. The headers are not
+// stored until the consensus engine verified them.
+func (req *EpochHeadersRequest) StoreResult(db ethdb.Database) {}
+
 // TxStatusRequest is the ODR request type for retrieving transaction status
 type TxStatusRequest struct {
 	OdrRequest
@@ -79,6 +79,18 @@ func GetUntrustedHeaderByNumber(ctx context.Context, odr OdrBackend, number uint
 	return r.Header, nil
 }
 
+// GetUntrustedEpochHeaders fetches the validator handoff headers of a range of
+// istanbul epochs or DPoS loops without correctness checking, each epoch ending
+// with its epoch header. Note this function should only be used in light client
+// epoch syncing.
+func GetUntrustedEpochHeaders(ctx context.Context, odr OdrBackend, epoch, from, amount uint64, peerId string) ([]*types.Header, error) {
+	r := &EpochHeadersRequest{PeerId: peerId, Epoch: epoch, From: from, Amount: amount}
+	if err := odr.Retrieve(ctx, r); err != nil {
+		return nil, err
+	}
+	return r.Headers, nil
+}
+
 func GetCanonicalHash(ctx context.Context, odr OdrBackend, number uint64) (common.Hash, error) {
 	hash := rawdb.ReadCanonicalHash(odr.Database(), number)
 	if (hash != common.Hash{}) {
@@ -354,9 +354,10 @@ func (pool *TxPool) validateTx(ctx context.Context, tx *types.Transaction) error
 	if from, err = types.Sender(pool.signer, tx); err != nil {
 		return core.ErrInvalidSender
 	}
-	// Last but not least check for nonce errors
+	// Last but not least check for nonce errors, block-limit transactions have
+	// their IDs checked by the server
 	currentState := pool.currentState(ctx)
-	if n := currentState.GetNonce(from); n > tx.Nonce() {
+	if n := currentState.GetNonce(from); tx.CheckNonce() && n > tx.Nonce() {
 		return core.ErrNonceTooLow
 	}
 
@@ -375,10 +376,23 @@ func (pool *TxPool) validateTx(ctx context.Context, tx *types.Transaction) error
 	}
 
 	// Transactor should have enough funds to cover the costs
-	// cost == V + GP * GL
-	if b := currentState.GetBalance(from); b.Cmp(tx.Cost()) < 0 {
+	// cost == V + GP * GL, GP * GL being paid by the fee payer if delegated
+	if b := currentState.GetBalance(from); b.Cmp(tx.SenderCost()) < 0 {
 		return core.ErrInsufficientFunds
 	}
+	if tx.Type() == types.FeeDelegatedTxType {
+		payer, err := types.Payer(pool.signer, tx)
+		if err != nil {
+			return core.ErrInvalidFeePayer
+		}
+		fee := tx.Fee()
+		if payer == from {
+			fee = tx.Cost()
+		}
+		if b := currentState.GetBalance(payer); b.Cmp(fee) < 0 {
+			return core.ErrFeePayerInsufficientFunds
+		}
+	}
 
 	// Should supply enough intrinsic gas
 	gas, err := core.IntrinsicGas(tx.Data(), tx.To() == nil, pool.singularity)
@@ -407,11 +421,13 @@ func (pool *TxPool) add(ctx context.Context, tx *types.Transaction) error {
 	if _, ok := pool.pending[hash]; !ok {
 		pool.pending[hash] = tx
 
-		nonce := tx.Nonce() + 1
+		if tx.CheckNonce() {
+			nonce := tx.Nonce() + 1
 
-		addr, _ := types.Sender(pool.signer, tx)
-		if nonce > pool.nonce[addr] {
-			pool.nonce[addr] = nonce
+			addr, _ := types.Sender(pool.signer, tx)
+			if nonce > pool.nonce[addr] {
+				pool.nonce[addr] = nonce
+			}
 		}
 
 		// Notify the subscribers. This event is posted in a goroutine
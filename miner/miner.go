@@ -53,6 +53,8 @@ type Config struct {
 	GasPrice  *big.Int       // Minimum gas price for mining a transaction
 	Recommit  time.Duration  // The time interval for miner to re-create mining work.
 	Noverify  bool           // Disable remote mining solution verification(only useful in ethash).
+
+	Raft params.RaftConfig `toml:",omitempty"` // Minting strategy of a raft minter(only useful in raft).
 }
 
 // Miner creates blocks and searches for proof-of-work values.
@@ -13,7 +13,9 @@ import (
 	"github.com/simplechain-org/go-simplechain/common"
 	"github.com/simplechain-org/go-simplechain/consensus"
 	"github.com/simplechain-org/go-simplechain/consensus/scrypt"
+	"github.com/simplechain-org/go-simplechain/core"
 	"github.com/simplechain-org/go-simplechain/core/types"
+	"github.com/simplechain-org/go-simplechain/event"
 	"github.com/simplechain-org/go-simplechain/log"
 	"github.com/simplechain-org/go-simplechain/stratum"
 )
@@ -22,8 +24,17 @@ var (
 	maxUint256 = new(big.Int).Exp(big.NewInt(2), big.NewInt(256), big.NewInt(0))
 )
 
+// stratumChain is the chain the stratum agent seals blocks on, and watches to
+// credit the rewards of the found blocks once they are canonical.
+type stratumChain interface {
+	consensus.ChainReader
+
+	// SubscribeChainHeadEvent registers a subscription of ChainHeadEvent.
+	SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription
+}
+
 type StratumAgent struct {
-	chain      consensus.ChainReader
+	chain      stratumChain
 	engine     consensus.Engine
 	workCh     chan *types.Block
 	resultCh   chan<- *types.Block
@@ -35,7 +46,7 @@ type StratumAgent struct {
 	rand       *rand.Rand
 }
 
-func NewStratumAgent(chain consensus.ChainReader, engine consensus.Engine) *StratumAgent {
+func NewStratumAgent(chain stratumChain, engine consensus.Engine) *StratumAgent {
 	miner := &StratumAgent{
 		chain:  chain,
 		engine: engine,
@@ -112,12 +123,21 @@ func (self *StratumAgent) resultLoop(ctx context.Context) {
 	}
 	result := make(chan uint64, 1)
 	self.server.ReadResult(result)
+
+	heads := make(chan core.ChainHeadEvent, chainHeadChanSize)
+	headSub := self.chain.SubscribeChainHeadEvent(heads)
+	defer headSub.Unsubscribe()
+
 	var currentBlock *types.Block
 	for {
 		select {
 		case <-ctx.Done():
 			log.Debug("[StratumAgent]update done")
 			return
+		case head := <-heads:
+			self.server.ChainHead(head.Block.NumberU64(), self.chain)
+		case <-headSub.Err():
+			return
 		case work := <-self.workCh:
 			//get work from work chan,and dispatch work to server
 			log.Info("[StratumAgent]Received work", "difficulty", work.Difficulty())
@@ -134,6 +154,7 @@ func (self *StratumAgent) resultLoop(ctx context.Context) {
 				header.MixDigest = common.BytesToHash(digest)
 				block := currentBlock.WithSeal(header)
 				log.Info("[StratumAgent] got expected nonce", "number", block.Number(), "hash", block.Hash(), "nonce", nonce)
+				self.server.BlockFound(block.NumberU64(), block.Hash(), scrypt.MinerReward(block.Number()))
 				self.resultCh <- block
 			} else {
 				log.Info("[StratumAgent] sealed new block failed", "number", currentBlock.Number(), "hash", currentBlock.Hash())
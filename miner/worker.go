@@ -28,6 +28,7 @@ import (
 	"github.com/simplechain-org/go-simplechain/common"
 	"github.com/simplechain-org/go-simplechain/consensus"
 	"github.com/simplechain-org/go-simplechain/consensus/dpos"
+	"github.com/simplechain-org/go-simplechain/consensus/multi"
 	"github.com/simplechain-org/go-simplechain/consensus/raft"
 	"github.com/simplechain-org/go-simplechain/consensus/scrypt"
 	"github.com/simplechain-org/go-simplechain/core"
@@ -231,6 +232,7 @@ func newWorker(config *Config, chainConfig *params.ChainConfig, engine consensus
 			shouldMine:              channels.NewRingChannel(1),
 			speculativeChain:        raft.NewSpeculativeChain(),
 			invalidRaftOrderingChan: make(chan raft.InvalidRaftOrdering, 1),
+			strategy:                newRaftMintingStrategy(worker.config.Raft),
 		}
 		worker.raftCtx.speculativeChain.Clear(worker.chain.CurrentBlock())
 
@@ -600,7 +602,7 @@ func (w *worker) taskLoop() {
 			w.pendingMu.Lock()
 			w.pendingTasks[w.engine.SealHash(task.block.Header())] = task
 			w.pendingMu.Unlock()
-			_, ok := w.engine.(*scrypt.PowScrypt)
+			_, ok := multi.EngineAt(w.engine, task.block.NumberU64()).(*scrypt.PowScrypt)
 			if ok {
 				w.seal(task.block)
 			} else {
@@ -764,6 +766,13 @@ func (w *worker) updateSnapshot() {
 }
 
 func (w *worker) commitTransaction(tx *types.Transaction, coinbase common.Address) ([]*types.Log, error) {
+	// Block-limit transactions may have expired or got included since pooled
+	if tx.Type() == types.BlockLimitTxType {
+		from, _ := types.Sender(w.current.signer, tx)
+		if err := w.chain.CheckBlockLimit(tx, from, w.current.header.Number.Uint64()); err != nil {
+			return nil, err
+		}
+	}
 	snap := w.current.state.Snapshot()
 
 	receipt, err := core.ApplyTransaction(w.chainConfig, w.chain, &coinbase, w.current.gasPool, w.current.state, w.current.header, tx, &w.current.header.GasUsed, *w.chain.GetVMConfig())
@@ -1003,7 +1012,7 @@ func (w *worker) commit(uncles []*types.Header, interval func(), update bool, st
 		*receipts[i] = *l
 	}
 	s := w.current.state.Copy()
-	block, err := w.engine.FinalizeAndAssemble(w.chain, w.current.header, s, w.current.txs, uncles, w.current.receipts)
+	block, err := w.engine.FinalizeAndAssemble(w.chain, w.current.header, s, w.current.txs, uncles, receipts)
 	if err != nil {
 		log.Warn("Fail to Finalize block", "err", err)
 		return
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"math/big"
 
+	"github.com/simplechain-org/go-simplechain/common"
+	"github.com/simplechain-org/go-simplechain/consensus/dpos"
 	"github.com/simplechain-org/go-simplechain/core/types"
 )
 
@@ -22,7 +24,17 @@ func (w *worker) sendConfirmTx(blockNumber uint64) error {
 					// coinbase account found
 					// send custom tx
 					nonce := w.snapshotState.GetNonce(account.Address)
-					tmpTx := types.NewTransaction(nonce, account.Address, big.NewInt(0), uint64(100000), big.NewInt(10000), []byte(fmt.Sprintf("dpos:1:event:confirm:%d", blockNumber)))
+					to, data := account.Address, []byte(fmt.Sprintf("dpos:1:event:confirm:%d", blockNumber))
+					// the confirmation lands in a following block, switch format by the next one
+					next := new(big.Int).Add(w.chain.CurrentBlock().Number(), common.Big1)
+					if w.chainConfig.DPoS.IsSystemContract(next) {
+						packed, err := dpos.PackSystemCall("confirm", new(big.Int).SetUint64(blockNumber))
+						if err != nil {
+							return err
+						}
+						to, data = dpos.SystemContractAddress, packed
+					}
+					tmpTx := types.NewTransaction(nonce, to, big.NewInt(0), uint64(100000), big.NewInt(10000), data)
 					signedTx, err := wallet.SignTx(account, tmpTx, w.eth.BlockChain().Config().ChainID)
 					if err != nil {
 						return err
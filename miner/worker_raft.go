@@ -12,12 +12,93 @@ import (
 	"github.com/simplechain-org/go-simplechain/core"
 	"github.com/simplechain-org/go-simplechain/core/types"
 	"github.com/simplechain-org/go-simplechain/log"
+	"github.com/simplechain-org/go-simplechain/params"
 )
 
 type raftContext struct {
 	invalidRaftOrderingChan chan raft.InvalidRaftOrdering
 	speculativeChain        *raft.SpeculativeChain
 	shouldMine              *channels.RingChannel
+	strategy                *raftMintingStrategy // Protected by the worker's mu
+}
+
+// raftMintingStrategy decides when the pending transactions of a raft minter
+// are worth a block, according to the configured params.RaftConfig.
+type raftMintingStrategy struct {
+	config       params.RaftConfig
+	waitingSince time.Time // When the pending transactions started waiting for a block; zero if none
+}
+
+func newRaftMintingStrategy(config params.RaftConfig) *raftMintingStrategy {
+	if config.MinBatchTxs > 1 && config.GasTarget == 0 && config.MaxLatency == 0 {
+		log.Warn("Raft batch without deadline, transactions may wait forever", "minBatchTxs", config.MinBatchTxs)
+	}
+	return &raftMintingStrategy{config: config}
+}
+
+// ready reports whether a block should be minted now, given the number and the
+// total gas limit of the pending transactions and the time of the last block.
+// An empty heartbeat block is due if there are no pending transactions but the
+// chain has been idle for the heartbeat interval.
+func (s *raftMintingStrategy) ready(txs int, gas uint64, lastBlock, now time.Time) (mint bool, heartbeat bool) {
+	if txs == 0 {
+		s.waitingSince = time.Time{}
+
+		interval := time.Duration(s.config.HeartbeatInterval) * time.Millisecond
+		if interval > 0 && now.Sub(lastBlock) >= interval {
+			return true, true
+		}
+		return false, false
+	}
+	if s.waitingSince.IsZero() {
+		s.waitingSince = now
+	}
+	switch {
+	case uint64(txs) >= s.config.MinBatchTxs:
+		return true, false
+	case s.config.GasTarget > 0 && gas >= s.config.GasTarget:
+		return true, false
+	case s.config.MaxLatency > 0 && now.Sub(s.waitingSince) >= time.Duration(s.config.MaxLatency)*time.Millisecond:
+		return true, false
+	}
+	return false, false
+}
+
+// minted resets the batch deadline once the pending transactions made it into
+// a block. Leftovers of a capped batch start waiting anew.
+func (s *raftMintingStrategy) minted() {
+	s.waitingSince = time.Time{}
+}
+
+// batch caps the pending transactions to the maximum batch size, keeping the
+// price and nonce order the block would be filled in.
+func (s *raftMintingStrategy) batch(signer types.Signer, txs map[common.Address]types.Transactions) map[common.Address]types.Transactions {
+	if s.config.MaxBatchTxs == 0 {
+		return txs
+	}
+	var (
+		ordered = types.NewTransactionsByPriceAndNonce(signer, txs)
+		batch   = make(map[common.Address]types.Transactions)
+	)
+	for n := uint64(0); n < s.config.MaxBatchTxs; n++ {
+		tx := ordered.Peek()
+		if tx == nil {
+			break
+		}
+		from, _ := types.Sender(signer, tx)
+		batch[from] = append(batch[from], tx)
+		ordered.Shift()
+	}
+	return batch
+}
+
+// wakeupInterval returns how often the minting decision has to be revisited
+// without new transactions or blocks, zero if never.
+func (s *raftMintingStrategy) wakeupInterval(recommit time.Duration) time.Duration {
+	if s.config.MaxLatency == 0 && s.config.HeartbeatInterval == 0 {
+		return 0
+	}
+	return recommit
 }
 
 func (miner *Miner) InvalidRaftOrdering() chan<- raft.InvalidRaftOrdering {
@@ -79,8 +160,12 @@ func (w *worker) raftLoop() {
 // (via requestMinting()). This is throttled by `RaftMinter.blockTime`:
 //
 //   1. A block is guaranteed to be minted within `blockTime` of being
-//      requested.
+//      requested, if the minting strategy finds the pending transactions
+//      worth a block.
 //   2. We never mint a block more frequently than `blockTime`.
+//
+// Batch deadlines and heartbeats are revisited every `blockTime` even if no
+// minting is requested.
 func (w *worker) mintingLoop(recommit time.Duration) {
 	throttledMintNewBlock := throttle(recommit, func() {
 		if w.isRunning() {
@@ -88,8 +173,23 @@ func (w *worker) mintingLoop(recommit time.Duration) {
 		}
 	})
 
-	for range w.raftCtx.shouldMine.Out() {
-		throttledMintNewBlock()
+	var wakeup <-chan time.Time
+	if interval := w.raftCtx.strategy.wakeupInterval(recommit); interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		wakeup = ticker.C
+	}
+	for {
+		select {
+		case <-w.raftCtx.shouldMine.Out():
+			throttledMintNewBlock()
+		case <-wakeup:
+			if w.isRunning() {
+				throttledMintNewBlock()
+			}
+		case <-w.exitCh:
+			return
+		}
 	}
 }
 
@@ -124,6 +224,29 @@ func (w *worker) commitRaftWork() {
 
 	parent := w.raftCtx.speculativeChain.Head()
 
+	allTxs, err := w.eth.TxPool().Pending()
+	if err != nil {
+		log.Error("Failed to fetch pending transactions", "err", err)
+		return
+	}
+	txs := w.raftCtx.speculativeChain.WithoutProposedTxes(allTxs)
+
+	var (
+		count int
+		gas   uint64
+	)
+	for _, accTxs := range txs {
+		for _, tx := range accTxs {
+			count, gas = count+1, gas+tx.Gas()
+		}
+	}
+	mint, heartbeat := w.raftCtx.strategy.ready(count, gas, time.Unix(0, int64(parent.Time())), time.Now())
+	if !mint {
+		// Logged at debug level, as deadlines and heartbeats are polled every block time
+		log.Debug("Not minting a new block since the pending batch is not ready", "txs", count, "gas", gas)
+		return
+	}
+
 	tstamp := time.Now().UnixNano()
 	if parentTime := int64(parent.Time()); parentTime >= tstamp {
 		// Each successive block needs to be after its predecessor.
@@ -145,21 +268,15 @@ func (w *worker) commitRaftWork() {
 		return
 	}
 
-	allTxs, err := w.eth.TxPool().Pending()
-	if err != nil {
-		log.Error("Failed to fetch pending transactions", "err", err)
-		return
-	}
-
-	txs := w.raftCtx.speculativeChain.WithoutProposedTxes(allTxs)
-	transactions := types.NewTransactionsByPriceAndNonce(w.current.signer, txs)
+	batch := w.raftCtx.strategy.batch(w.current.signer, txs)
+	transactions := types.NewTransactionsByPriceAndNonce(w.current.signer, batch)
 
 	if w.commitTransactions(transactions, w.coinbase, nil) {
 		return
 	}
 
-	if w.current.tcount == 0 {
-		log.Info("Not minting a new block since there are no pending transactions")
+	if w.current.tcount == 0 && !heartbeat {
+		log.Info("Not minting a new block since no pending transaction could be applied")
 		return
 	}
 
@@ -169,7 +286,9 @@ func (w *worker) commitRaftWork() {
 		return
 	}
 
-	log.Info("Generated next block", "num", block.Number(), "txs", w.current.tcount)
+	log.Info("Generated next block", "num", block.Number(), "txs", w.current.tcount, "heartbeat", heartbeat)
+
+	w.raftCtx.strategy.minted()
 
 	w.raftCtx.speculativeChain.Extend(block)
 
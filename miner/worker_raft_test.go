@@ -0,0 +1,112 @@
+package miner
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/simplechain-org/go-simplechain/common"
+	"github.com/simplechain-org/go-simplechain/core/types"
+	"github.com/simplechain-org/go-simplechain/crypto"
+	"github.com/simplechain-org/go-simplechain/params"
+)
+
+func TestRaftMintingStrategy(t *testing.T) {
+	var (
+		start = time.Unix(1000, 0)
+		ms    = func(n int) time.Time { return start.Add(time.Duration(n) * time.Millisecond) }
+	)
+	type step struct {
+		txs       int
+		gas       uint64
+		lastBlock time.Time
+		now       time.Time
+		mint      bool
+		heartbeat bool
+	}
+	tests := []struct {
+		config params.RaftConfig
+		steps  []step
+	}{
+		// Default strategy mints for every transaction and never mints empty blocks
+		{
+			config: params.DefaultRaftConfig,
+			steps: []step{
+				{txs: 0, lastBlock: start, now: ms(60000)},
+				{txs: 1, gas: params.TxGas, lastBlock: start, now: ms(60000), mint: true},
+			},
+		},
+		// Batches wait for the minimum size until their deadline
+		{
+			config: params.RaftConfig{BlockTime: 50, MinBatchTxs: 10, MaxLatency: 500},
+			steps: []step{
+				{txs: 3, lastBlock: start, now: ms(0)},
+				{txs: 5, lastBlock: start, now: ms(499)},
+				{txs: 5, lastBlock: start, now: ms(500), mint: true},
+				{txs: 10, lastBlock: start, now: ms(501), mint: true},
+			},
+		},
+		// Gas target mints below the minimum batch size
+		{
+			config: params.RaftConfig{BlockTime: 50, MinBatchTxs: 100, GasTarget: 3 * params.TxGas},
+			steps: []step{
+				{txs: 2, gas: 2 * params.TxGas, lastBlock: start, now: ms(0)},
+				{txs: 3, gas: 3 * params.TxGas, lastBlock: start, now: ms(10), mint: true},
+			},
+		},
+		// Heartbeats prove liveness of an idle chain
+		{
+			config: params.RaftConfig{BlockTime: 50, MinBatchTxs: 1, HeartbeatInterval: 1000},
+			steps: []step{
+				{txs: 0, lastBlock: start, now: ms(999)},
+				{txs: 0, lastBlock: start, now: ms(1000), mint: true, heartbeat: true},
+				{txs: 0, lastBlock: ms(1000), now: ms(1001)},
+			},
+		},
+	}
+	for i, tt := range tests {
+		strategy := newRaftMintingStrategy(tt.config)
+		for j, s := range tt.steps {
+			mint, heartbeat := strategy.ready(s.txs, s.gas, s.lastBlock, s.now)
+			if mint != s.mint || heartbeat != s.heartbeat {
+				t.Errorf("test %d, step %d: mint/heartbeat mismatch: have %v/%v, want %v/%v", i, j, mint, heartbeat, s.mint, s.heartbeat)
+			}
+			if mint {
+				strategy.minted()
+			}
+		}
+	}
+}
+
+func TestRaftMintingStrategyBatch(t *testing.T) {
+	signer := types.NewEIP155Signer(big.NewInt(1))
+	keys := make([]common.Address, 2)
+	txs := make(map[common.Address]types.Transactions)
+	for i := range keys {
+		key, _ := crypto.GenerateKey()
+		keys[i] = crypto.PubkeyToAddress(key.PublicKey)
+		for nonce := uint64(0); nonce < 3; nonce++ {
+			tx, _ := types.SignTx(types.NewTransaction(nonce, testUserAddress, big.NewInt(1), params.TxGas, big.NewInt(int64(i+1)), nil), signer, key)
+			txs[keys[i]] = append(txs[keys[i]], tx)
+		}
+	}
+	strategy := newRaftMintingStrategy(params.RaftConfig{MaxBatchTxs: 4})
+	batch := strategy.batch(signer, txs)
+
+	count := 0
+	for addr, accTxs := range batch {
+		for i, tx := range accTxs {
+			if tx.Nonce() != uint64(i) {
+				t.Errorf("account %x: nonce gap at %d: have %d", addr, i, tx.Nonce())
+			}
+			count++
+		}
+	}
+	if count != 4 {
+		t.Errorf("batch size mismatch: have %d, want %d", count, 4)
+	}
+	// The better paying account gets all its transactions in first
+	if len(batch[keys[1]]) != 3 {
+		t.Errorf("best paying account batch mismatch: have %d, want %d", len(batch[keys[1]]), 3)
+	}
+}
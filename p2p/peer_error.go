@@ -32,6 +32,8 @@ const (
 const (
 	// Unauthorized node joining existing raft cluster
 	errNotInRaftCluster = iota + 100
+	// Node not present in the permissioned node list
+	errNotPermissioned
 )
 
 var errorToString = map[int]string{
@@ -39,6 +41,7 @@ var errorToString = map[int]string{
 	errInvalidMsg:     "invalid message",
 	// Quorum
 	errNotInRaftCluster: "not in raft cluster",
+	errNotPermissioned:  "node not permissioned",
 }
 
 type peerError struct {
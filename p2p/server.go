@@ -203,6 +203,9 @@ type Server struct {
 
 	// raft peers info
 	checkPeerInRaft func(*enode.Node) bool
+
+	// node permissioning, nil if every node may connect
+	checkPeerPermission func(*enode.Node) bool
 }
 
 type peerOpFunc func(map[enode.ID]*Peer)
@@ -954,6 +957,7 @@ func (srv *Server) setupConn(c *conn, flags connFlag, dialDest *enode.Node) erro
 	// Prevent leftover pending conns from entering the handshake.
 	srv.lock.Lock()
 	running := srv.running
+	checkPermission := srv.checkPeerPermission
 	srv.lock.Unlock()
 	if !running {
 		return errServerStopped
@@ -993,7 +997,12 @@ func (srv *Server) setupConn(c *conn, flags connFlag, dialDest *enode.Node) erro
 		return newPeerError(errNotInRaftCluster, "id=%s…%s", node[:4], node[len(node)-4:])
 	}
 
-	//TODO: support QUORUM Permissioning
+	// If permissioning is enabled, reject nodes missing from the allowlist
+	if checkPermission != nil && !checkPermission(c.node) {
+		node := c.node.ID().String()
+		clog.Trace("Connection peer is not permissioned")
+		return newPeerError(errNotPermissioned, "id=%s…%s", node[:4], node[len(node)-4:])
+	}
 
 	err = srv.checkpoint(c, srv.checkpointPostHandshake)
 	if err != nil {
@@ -1152,3 +1161,11 @@ func (srv *Server) PeersInfo() []*PeerInfo {
 func (srv *Server) SetCheckPeerInRaft(f func(*enode.Node) bool) {
 	srv.checkPeerInRaft = f
 }
+
+// SetCheckPeerPermission installs the permissioning check run on every
+// connection after the encryption handshake. Passing nil disables it.
+func (srv *Server) SetCheckPeerPermission(f func(*enode.Node) bool) {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+	srv.checkPeerPermission = f
+}
@@ -611,6 +611,36 @@ func TestServerSetupConn_whenNotInRaftCluster(t *testing.T) {
 	assert.Equal(t, errNotInRaftCluster, perr.code)
 }
 
+func TestServerSetupConn_whenNotPermissioned(t *testing.T) {
+	var (
+		clientkey, srvkey = newkey(), newkey()
+		clientpub         = &clientkey.PublicKey
+	)
+
+	clientNode := enode.NewV4(clientpub, nil, 0, 0)
+	srv := &Server{
+		Config: Config{
+			PrivateKey:  srvkey,
+			NoDiscovery: true,
+		},
+		newTransport: func(fd net.Conn) transport { return newTestTransport(clientpub, fd) },
+		log:          log.New(),
+	}
+	srv.SetCheckPeerPermission(func(node *enode.Node) bool {
+		return node.ID() != clientNode.ID()
+	})
+	if err := srv.Start(); err != nil {
+		t.Fatalf("couldn't start server: %v", err)
+	}
+	defer srv.Stop()
+	p1, _ := net.Pipe()
+	err := srv.SetupConn(p1, inboundConn, clientNode)
+
+	assert.IsType(t, &peerError{}, err)
+	perr := err.(*peerError)
+	assert.Equal(t, errNotPermissioned, perr.code)
+}
+
 type setupTransport struct {
 	pubkey            *ecdsa.PublicKey
 	encHandshakeErr   error
@@ -109,9 +109,9 @@ var (
 	// This configuration is intentionally not using keyed fields to force anyone
 	// adding flags to the config to also have to set these fields.
 
-	AllCliqueProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, nil, &CliqueConfig{Period: 0, Epoch: 30000}, nil, nil, false, nil}
+	AllCliqueProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, nil, nil, nil, nil, nil, &CliqueConfig{Period: 0, Epoch: 30000}, nil, nil, false, nil, nil}
 
-	AllDPoSProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, nil, nil, nil, &DPoSConfig{Period: 3, Epoch: 30000, MaxSignerCount: 21, MinVoterBalance: new(big.Int).Mul(big.NewInt(10000), big.NewInt(1000000000000000000))}, false, nil}
+	AllDPoSProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, nil, nil, nil, nil, nil, nil, nil, &DPoSConfig{Period: 3, Epoch: 30000, MaxSignerCount: 21, MinVoterBalance: new(big.Int).Mul(big.NewInt(10000), big.NewInt(1000000000000000000))}, false, nil, nil}
 
 	// AllScryptProtocolChanges contains every protocol change (EIPs) introduced
 	// and accepted by the Ethereum core developers into the Scrypt consensus.
@@ -119,13 +119,13 @@ var (
 	// This configuration is intentionally not using keyed fields to force anyone
 	// adding flags to the config to also have to set these fields.
 
-	AllScryptProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, nil, nil, new(ScryptConfig), nil, false, nil}
+	AllScryptProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, nil, nil, nil, nil, nil, nil, new(ScryptConfig), nil, false, nil, nil}
 
-	TestChainConfig = &ChainConfig{big.NewInt(1), big.NewInt(0), nil, new(EthashConfig), nil, nil, nil, false, nil}
+	TestChainConfig = &ChainConfig{big.NewInt(1), big.NewInt(0), nil, nil, nil, nil, nil, new(EthashConfig), nil, nil, nil, false, nil, nil}
 
 	TestRules = TestChainConfig.Rules(new(big.Int))
 
-	RaftChainConfig = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, nil, nil, nil, nil, true, nil}
+	RaftChainConfig = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, nil, nil, nil, nil, nil, nil, nil, nil, true, nil, nil}
 )
 
 // TrustedCheckpoint represents a set of post-processed trie roots (CHT and
@@ -180,6 +180,11 @@ type ChainConfig struct {
 
 	SingularityBlock *big.Int `json:"singularityBlock,omitempty"` // Singularity switch block (nil = no fork, 0 = already on singularity)
 	EWASMBlock       *big.Int `json:"ewasmBlock,omitempty"`       // EWASM switch block (nil = no fork, 0 = already activated)
+	PermissionBlock  *big.Int `json:"permissionBlock,omitempty"`  // Account permissioning switch block (nil = no fork, 0 = already activated)
+	PrivacyBlock     *big.Int `json:"privacyBlock,omitempty"`     // Private transactions switch block (nil = no fork, 0 = already activated)
+	TypedTxBlock     *big.Int `json:"typedTxBlock,omitempty"`     // Typed transaction envelope switch block (nil = no fork, 0 = already activated)
+
+	Permission *PermissionConfig `json:"permission,omitempty"` // Account roles enforced from PermissionBlock on
 
 	// Various consensus engines
 	Ethash   *EthashConfig   `json:"ethash,omitempty"`
@@ -188,6 +193,29 @@ type ChainConfig struct {
 	DPoS     *DPoSConfig     `json:"dpos,omitempty"`
 	Raft     bool            `json:"raft,omitempty"`
 	Istanbul *IstanbulConfig `json:"istanbul,omitempty"`
+
+	ConsensusForks []*ConsensusFork `json:"consensusForks,omitempty"` // Switches to other consensus engines, in ascending block order
+}
+
+// ConsensusFork switches the chain from the consensus engine configured before
+// to another one at a block. Exactly one engine has to be configured.
+type ConsensusFork struct {
+	Block *big.Int `json:"block"` // First block sealed by the engine
+
+	Ethash   *EthashConfig   `json:"ethash,omitempty"`
+	Clique   *CliqueConfig   `json:"clique,omitempty"`
+	Scrypt   *ScryptConfig   `json:"scrypt,omitempty"`
+	DPoS     *DPoSConfig     `json:"dpos,omitempty"`
+	Raft     bool            `json:"raft,omitempty"`
+	Istanbul *IstanbulConfig `json:"istanbul,omitempty"`
+}
+
+// Apply returns a copy of the chain config running the consensus engine of the
+// fork instead of the genesis one.
+func (f *ConsensusFork) Apply(c *ChainConfig) *ChainConfig {
+	cpy := *c
+	cpy.Ethash, cpy.Clique, cpy.Scrypt, cpy.DPoS, cpy.Raft, cpy.Istanbul = f.Ethash, f.Clique, f.Scrypt, f.DPoS, f.Raft, f.Istanbul
+	return &cpy
 }
 
 // EthashConfig is the consensus engine configs for proof-of-work based sealing.
@@ -213,10 +241,32 @@ func (c *CliqueConfig) String() string {
 type IstanbulConfig struct {
 	Epoch          uint64 `json:"epoch"`  // Epoch length to reset votes and checkpoint
 	ProposerPolicy uint64 `json:"policy"` // The policy for proposer selection
+
+	Validators []common.Address `json:"validators,omitempty"` // Validators of the first block when switching from another engine
 }
 
+// RaftConfig is the minting strategy of a raft minter. A block is minted once
+// any of the batch conditions is met, but never more often than BlockTime.
 type RaftConfig struct {
-	BlockTime uint64 `json:"blockTime"`
+	BlockTime         uint64 `json:"blockTime"`         // Minimum time between two minted blocks in milliseconds
+	MinBatchTxs       uint64 `json:"minBatchTxs"`       // Number of pending transactions worth minting a block for
+	MaxBatchTxs       uint64 `json:"maxBatchTxs"`       // Maximum number of transactions in a block (0 = unlimited)
+	MaxLatency        uint64 `json:"maxLatency"`        // Milliseconds a transaction may wait for its batch to fill up (0 = no deadline)
+	GasTarget         uint64 `json:"gasTarget"`         // Gas limit of pending transactions worth minting a block for (0 = disabled)
+	HeartbeatInterval uint64 `json:"heartbeatInterval"` // Milliseconds without blocks before an empty liveness block is minted (0 = never)
+}
+
+// DefaultRaftConfig mints a block for every pending transaction as fast as
+// the block time allows, and never mints empty blocks.
+var DefaultRaftConfig = RaftConfig{
+	BlockTime:   50,
+	MinBatchTxs: 1,
+}
+
+// String implements the stringer interface, returning the minting strategy details.
+func (c *RaftConfig) String() string {
+	return fmt.Sprintf("{BlockTime: %dms MinBatchTxs: %d MaxBatchTxs: %d MaxLatency: %dms GasTarget: %d Heartbeat: %dms}",
+		c.BlockTime, c.MinBatchTxs, c.MaxBatchTxs, c.MaxLatency, c.GasTarget, c.HeartbeatInterval)
 }
 
 // String implements the stringer interface, returning the consensus engine details.
@@ -233,6 +283,10 @@ type DPoSLightConfig struct {
 	Alloc map[common.UnprefixedAddress]GenesisAccount `json:"alloc"`
 }
 
+// DPoSSystemContractAddress is the address DPoS governance transactions are
+// sent to once the system contract fork is active.
+var DPoSSystemContractAddress = common.HexToAddress("0x000000000000000000000000000000000000d905")
+
 // DPoSConfig is the consensus engine configs for delegated-proof-of-stake based sealing.
 type DPoSConfig struct {
 	Period           uint64                     `json:"period"`           // Number of seconds between blocks to enforce
@@ -244,6 +298,8 @@ type DPoSConfig struct {
 	PBFTEnable       bool                       `json:"pbft"`             //
 	VoterReward      bool                       `json:"voterReward"`
 	LightConfig      *DPoSLightConfig           `json:"lightConfig,omitempty"`
+
+	SystemContractBlock *big.Int `json:"systemContractBlock,omitempty"` // Governance moves from string tx data to the system contract ABI (nil = no fork)
 }
 
 // String implements the stringer interface, returning the consensus engine details.
@@ -251,6 +307,40 @@ func (a *DPoSConfig) String() string {
 	return "dpos"
 }
 
+// IsSystemContract returns whether num is either equal to the system contract
+// fork block or greater.
+func (a *DPoSConfig) IsSystemContract(num *big.Int) bool {
+	return isForked(a.SystemContractBlock, num)
+}
+
+// AccountRole is the set of transactions an account may send once account
+// permissioning is active.
+type AccountRole string
+
+const (
+	RoleReadOnly AccountRole = "readonly" // May not send transactions, calls only
+	RoleTransact AccountRole = "transact" // May send transactions, but not create contracts
+	RoleDeploy   AccountRole = "deploy"   // May send transactions and create contracts
+)
+
+// valid returns whether the role is one of the known ones.
+func (r AccountRole) valid() bool {
+	return r == RoleReadOnly || r == RoleTransact || r == RoleDeploy
+}
+
+// PermissionConfig configures account permissioning. Roles assigned by the
+// governance contract take precedence over the genesis ones.
+//
+// The governance contract is read straight from storage: it has to declare
+// its role table as the first state variable, mapping(address => uint8),
+// with 1 meaning readonly, 2 transact and 3 deploy. A zero entry falls back
+// to the genesis configuration.
+type PermissionConfig struct {
+	Contract    *common.Address                `json:"contract,omitempty"`    // Governance contract holding the role table (nil = genesis roles only)
+	Accounts    map[common.Address]AccountRole `json:"accounts,omitempty"`    // Roles assigned in the genesis
+	DefaultRole AccountRole                    `json:"defaultRole,omitempty"` // Role of unlisted accounts (empty = readonly)
+}
+
 // ScryptConfig is the consensus engine configs for proof-of-work based sealing.
 type ScryptConfig struct{}
 
@@ -290,6 +380,52 @@ func (c *ChainConfig) IsSingularity(num *big.Int) bool {
 	return isForked(c.SingularityBlock, num)
 }
 
+// IsPermission returns whether num is either equal to the account permissioning
+// fork block or greater.
+func (c *ChainConfig) IsPermission(num *big.Int) bool {
+	return c.Permission != nil && isForked(c.PermissionBlock, num)
+}
+
+// IsPrivacy returns whether num is either equal to the private transactions
+// fork block or greater.
+func (c *ChainConfig) IsPrivacy(num *big.Int) bool {
+	return isForked(c.PrivacyBlock, num)
+}
+
+// IsTypedTx returns whether num is either equal to the typed transaction fork
+// block or greater.
+func (c *ChainConfig) IsTypedTx(num *big.Int) bool {
+	return isForked(c.TypedTxBlock, num)
+}
+
+// IsSystemContract returns whether block num is sealed by DPoS with its system
+// contract fork active.
+func (c *ChainConfig) IsSystemContract(num *big.Int) bool {
+	dpos := c.ConsensusAt(num).DPoS
+	return dpos != nil && dpos.IsSystemContract(num)
+}
+
+// ConsensusAt returns the chain config with the consensus engine sealing the
+// block num, which is the genesis one unless a consensus fork switched it.
+func (c *ChainConfig) ConsensusAt(num *big.Int) *ChainConfig {
+	for i := len(c.ConsensusForks) - 1; i >= 0; i-- {
+		if isForked(c.ConsensusForks[i].Block, num) {
+			return c.ConsensusForks[i].Apply(c)
+		}
+	}
+	return c
+}
+
+// ConsensusConfigs returns the chain config with every consensus engine the
+// chain runs, the genesis one first and then those of the consensus forks.
+func (c *ChainConfig) ConsensusConfigs() []*ChainConfig {
+	configs := []*ChainConfig{c}
+	for _, fork := range c.ConsensusForks {
+		configs = append(configs, fork.Apply(c))
+	}
+	return configs
+}
+
 // IsEWASM returns whether num represents a block number after the EWASM fork
 func (c *ChainConfig) IsEWASM(num *big.Int) bool {
 	return isForked(c.EWASMBlock, num)
@@ -339,6 +475,37 @@ func (c *ChainConfig) CheckConfigForkOrder() error {
 		}
 		lastFork = cur
 	}
+	// A mistyped role would silently make its accounts read only
+	if c.Permission != nil {
+		if c.Permission.DefaultRole != "" && !c.Permission.DefaultRole.valid() {
+			return fmt.Errorf("invalid account permissioning default role %q", c.Permission.DefaultRole)
+		}
+		for addr, role := range c.Permission.Accounts {
+			if !role.valid() {
+				return fmt.Errorf("invalid account permissioning role %q of %s", role, addr.Hex())
+			}
+		}
+	}
+	// Consensus forks have to switch to exactly one engine each, in order
+	var last *big.Int
+	for i, fork := range c.ConsensusForks {
+		if fork.Block == nil || fork.Block.Sign() <= 0 {
+			return fmt.Errorf("invalid consensus fork %d: block %v", i, fork.Block)
+		}
+		if last != nil && last.Cmp(fork.Block) >= 0 {
+			return fmt.Errorf("unsupported consensus fork ordering: fork %d at %v, previous at %v", i, fork.Block, last)
+		}
+		engines := 0
+		for _, set := range []bool{fork.Ethash != nil, fork.Clique != nil, fork.Scrypt != nil, fork.DPoS != nil, fork.Raft, fork.Istanbul != nil} {
+			if set {
+				engines++
+			}
+		}
+		if engines != 1 {
+			return fmt.Errorf("invalid consensus fork %d: %d engines configured", i, engines)
+		}
+		last = fork.Block
+	}
 	return nil
 }
 
@@ -349,6 +516,30 @@ func (c *ChainConfig) checkCompatible(newcfg *ChainConfig, head *big.Int) *Confi
 	if isForkIncompatible(c.EWASMBlock, newcfg.EWASMBlock, head) {
 		return newCompatError("ewasm fork block", c.EWASMBlock, newcfg.EWASMBlock)
 	}
+	if isForkIncompatible(c.PermissionBlock, newcfg.PermissionBlock, head) {
+		return newCompatError("account permissioning fork block", c.PermissionBlock, newcfg.PermissionBlock)
+	}
+	if isForkIncompatible(c.PrivacyBlock, newcfg.PrivacyBlock, head) {
+		return newCompatError("private transactions fork block", c.PrivacyBlock, newcfg.PrivacyBlock)
+	}
+	if isForkIncompatible(c.TypedTxBlock, newcfg.TypedTxBlock, head) {
+		return newCompatError("typed transaction fork block", c.TypedTxBlock, newcfg.TypedTxBlock)
+	}
+	for i := 0; i < len(c.ConsensusForks) || i < len(newcfg.ConsensusForks); i++ {
+		var stored, updated *big.Int
+		if i < len(c.ConsensusForks) {
+			stored = c.ConsensusForks[i].Block
+		}
+		if i < len(newcfg.ConsensusForks) {
+			updated = newcfg.ConsensusForks[i].Block
+		}
+		if isForkIncompatible(stored, updated, head) {
+			return newCompatError("consensus engine fork block", stored, updated)
+		}
+	}
+	if c.DPoS != nil && newcfg.DPoS != nil && isForkIncompatible(c.DPoS.SystemContractBlock, newcfg.DPoS.SystemContractBlock, head) {
+		return newCompatError("DPoS system contract fork block", c.DPoS.SystemContractBlock, newcfg.DPoS.SystemContractBlock)
+	}
 	return nil
 }
 
@@ -20,6 +20,8 @@ import (
 	"math/big"
 	"reflect"
 	"testing"
+
+	"github.com/simplechain-org/go-simplechain/common"
 )
 
 func TestCheckCompatible(t *testing.T) {
@@ -59,6 +61,23 @@ func TestCheckCompatible(t *testing.T) {
 				RewindTo:     0,
 			},
 		},
+		{
+			stored:  &ChainConfig{ConsensusForks: []*ConsensusFork{{Block: big.NewInt(10)}}},
+			new:     &ChainConfig{ConsensusForks: []*ConsensusFork{{Block: big.NewInt(20)}}},
+			head:    9,
+			wantErr: nil,
+		},
+		{
+			stored: &ChainConfig{ConsensusForks: []*ConsensusFork{{Block: big.NewInt(10)}}},
+			new:    &ChainConfig{ConsensusForks: []*ConsensusFork{{Block: big.NewInt(20)}}},
+			head:   15,
+			wantErr: &ConfigCompatError{
+				What:         "consensus engine fork block",
+				StoredConfig: big.NewInt(10),
+				NewConfig:    big.NewInt(20),
+				RewindTo:     9,
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -68,3 +87,65 @@ func TestCheckCompatible(t *testing.T) {
 		}
 	}
 }
+
+func TestConsensusForks(t *testing.T) {
+	config := &ChainConfig{
+		Scrypt: new(ScryptConfig),
+		ConsensusForks: []*ConsensusFork{
+			{Block: big.NewInt(10), Istanbul: &IstanbulConfig{Epoch: 30000}},
+		},
+	}
+	if err := config.CheckConfigForkOrder(); err != nil {
+		t.Fatalf("valid consensus forks rejected: %v", err)
+	}
+	if cfg := config.ConsensusAt(big.NewInt(9)); cfg.Scrypt == nil || cfg.Istanbul != nil {
+		t.Errorf("block 9: have scrypt %v istanbul %v, want scrypt", cfg.Scrypt, cfg.Istanbul)
+	}
+	if cfg := config.ConsensusAt(big.NewInt(10)); cfg.Scrypt != nil || cfg.Istanbul == nil {
+		t.Errorf("block 10: have scrypt %v istanbul %v, want istanbul", cfg.Scrypt, cfg.Istanbul)
+	}
+	if configs := config.ConsensusConfigs(); len(configs) != 2 {
+		t.Errorf("consensus configs mismatch: have %d, want 2", len(configs))
+	}
+
+	invalid := []*ConsensusFork{
+		{Block: big.NewInt(0), Istanbul: new(IstanbulConfig)},
+		{Block: big.NewInt(10)},
+		{Block: big.NewInt(10), Istanbul: new(IstanbulConfig), Clique: new(CliqueConfig)},
+	}
+	for i, fork := range invalid {
+		config := &ChainConfig{Scrypt: new(ScryptConfig), ConsensusForks: []*ConsensusFork{fork}}
+		if err := config.CheckConfigForkOrder(); err == nil {
+			t.Errorf("fork %d: invalid consensus fork accepted", i)
+		}
+	}
+	unordered := &ChainConfig{ConsensusForks: []*ConsensusFork{
+		{Block: big.NewInt(20), Clique: new(CliqueConfig)},
+		{Block: big.NewInt(10), Istanbul: new(IstanbulConfig)},
+	}}
+	if err := unordered.CheckConfigForkOrder(); err == nil {
+		t.Errorf("unordered consensus forks accepted")
+	}
+}
+
+func TestPermissionRoles(t *testing.T) {
+	config := &ChainConfig{
+		PermissionBlock: big.NewInt(0),
+		Permission: &PermissionConfig{
+			Accounts:    map[common.Address]AccountRole{{1}: RoleDeploy, {2}: RoleTransact, {3}: RoleReadOnly},
+			DefaultRole: RoleTransact,
+		},
+	}
+	if err := config.CheckConfigForkOrder(); err != nil {
+		t.Fatalf("valid roles rejected: %v", err)
+	}
+	config.Permission.Accounts[common.Address{4}] = "deployer"
+	if err := config.CheckConfigForkOrder(); err == nil {
+		t.Errorf("unknown account role accepted")
+	}
+	delete(config.Permission.Accounts, common.Address{4})
+	config.Permission.DefaultRole = "Transact"
+	if err := config.CheckConfigForkOrder(); err == nil {
+		t.Errorf("unknown default role accepted")
+	}
+}
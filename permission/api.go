@@ -0,0 +1,88 @@
+// Copyright 2020 The go-simplechain Authors
+// This file is part of the go-simplechain library.
+//
+// The go-simplechain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-simplechain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-simplechain library. If not, see <http://www.gnu.org/licenses/>.
+
+package permission
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/simplechain-org/go-simplechain/p2p/enode"
+)
+
+// PrivateAdminAPI is the collection of node permissioning methods exposed
+// over the private admin endpoint.
+type PrivateAdminAPI struct {
+	permission *NodePermission
+}
+
+// NewPrivateAdminAPI creates a new API definition for node permissioning.
+func NewPrivateAdminAPI(permission *NodePermission) *PrivateAdminAPI {
+	return &PrivateAdminAPI{permission: permission}
+}
+
+// PermissionsNodes returns the enode URLs of the permissioned nodes.
+func (api *PrivateAdminAPI) PermissionsNodes() []string {
+	nodes := api.permission.Nodes()
+	urls := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		urls = append(urls, node.URLv4())
+	}
+	sort.Strings(urls)
+	return urls
+}
+
+// PermissionsAllowed reports whether the given node may connect.
+func (api *PrivateAdminAPI) PermissionsAllowed(url string) (bool, error) {
+	node, err := enode.Parse(enode.ValidSchemes, url)
+	if err != nil {
+		return false, fmt.Errorf("invalid enode: %v", err)
+	}
+	return api.permission.IsAllowed(node), nil
+}
+
+// PermissionsAdd adds a node to the allowlist.
+func (api *PrivateAdminAPI) PermissionsAdd(url string) (bool, error) {
+	node, err := enode.Parse(enode.ValidSchemes, url)
+	if err != nil {
+		return false, fmt.Errorf("invalid enode: %v", err)
+	}
+	if err := api.permission.Add(node); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// PermissionsRemove removes a node from the allowlist, disconnecting it if it
+// is a connected peer.
+func (api *PrivateAdminAPI) PermissionsRemove(url string) (bool, error) {
+	node, err := enode.Parse(enode.ValidSchemes, url)
+	if err != nil {
+		return false, fmt.Errorf("invalid enode: %v", err)
+	}
+	if err := api.permission.Remove(node); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// PermissionsReload reloads the allowlist from its source.
+func (api *PrivateAdminAPI) PermissionsReload() (bool, error) {
+	if err := api.permission.Reload(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
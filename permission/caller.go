@@ -0,0 +1,81 @@
+// Copyright 2020 The go-simplechain Authors
+// This file is part of the go-simplechain library.
+//
+// The go-simplechain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-simplechain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-simplechain library. If not, see <http://www.gnu.org/licenses/>.
+
+package permission
+
+import (
+	"context"
+	"errors"
+	"math/big"
+
+	"github.com/simplechain-org/go-simplechain"
+	"github.com/simplechain-org/go-simplechain/accounts/abi/bind"
+	"github.com/simplechain-org/go-simplechain/common"
+	"github.com/simplechain-org/go-simplechain/common/hexutil"
+	"github.com/simplechain-org/go-simplechain/core/vm"
+	"github.com/simplechain-org/go-simplechain/internal/ethapi"
+	"github.com/simplechain-org/go-simplechain/rpc"
+)
+
+// errExecutionReverted is returned if a permission contract call reverts.
+var errExecutionReverted = errors.New("execution reverted")
+
+// backendCaller runs read only contract calls against the local chain of an
+// in-process node, without going through the RPC layer.
+type backendCaller struct {
+	b ethapi.Backend
+}
+
+// NewBackendCaller creates a contract caller executing calls on the latest
+// state of the given backend.
+func NewBackendCaller(b ethapi.Backend) bind.ContractCaller {
+	return &backendCaller{b: b}
+}
+
+// CodeAt implements bind.ContractCaller.
+func (c *backendCaller) CodeAt(ctx context.Context, contract common.Address, blockNumber *big.Int) ([]byte, error) {
+	state, _, err := c.b.StateAndHeaderByNumber(ctx, toBlockNumber(blockNumber))
+	if state == nil || err != nil {
+		return nil, err
+	}
+	return state.GetCode(contract), state.Error()
+}
+
+// CallContract implements bind.ContractCaller.
+func (c *backendCaller) CallContract(ctx context.Context, call simplechain.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	data := hexutil.Bytes(call.Data)
+	args := ethapi.CallArgs{To: call.To, Data: &data}
+	if call.From != (common.Address{}) {
+		args.From = &call.From
+	}
+	number := rpc.BlockNumberOrHashWithNumber(toBlockNumber(blockNumber))
+	result, _, failed, err := ethapi.DoCall(ctx, c.b, args, number, nil, vm.Config{}, contractCallTimeout, c.b.RPCGasCap())
+	if err != nil {
+		return nil, err
+	}
+	if failed {
+		return nil, errExecutionReverted
+	}
+	return result, nil
+}
+
+// toBlockNumber converts a bind block number, nil meaning the head.
+func toBlockNumber(number *big.Int) rpc.BlockNumber {
+	if number == nil {
+		return rpc.LatestBlockNumber
+	}
+	return rpc.BlockNumber(number.Int64())
+}
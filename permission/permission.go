@@ -0,0 +1,204 @@
+// Copyright 2020 The go-simplechain Authors
+// This file is part of the go-simplechain library.
+//
+// The go-simplechain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-simplechain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-simplechain library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package permission implements node permissioning for consortium networks.
+//
+// Only nodes listed by the configured Source may complete the p2p handshake.
+// The list is refreshed periodically and on admin API changes, and connected
+// peers that lost their permission are dropped right away.
+package permission
+
+import (
+	"sync"
+	"time"
+
+	"github.com/simplechain-org/go-simplechain/log"
+	"github.com/simplechain-org/go-simplechain/p2p"
+	"github.com/simplechain-org/go-simplechain/p2p/enode"
+	"github.com/simplechain-org/go-simplechain/rpc"
+)
+
+// DefaultRefreshPeriod is how often the permissioned node list is reloaded
+// from its source if no other period is configured.
+const DefaultRefreshPeriod = 15 * time.Second
+
+// NodePermission is a node service enforcing a node allowlist on the p2p
+// server of the stack it is registered on.
+type NodePermission struct {
+	source Source
+	period time.Duration
+
+	lock    sync.RWMutex
+	server  *p2p.Server
+	allowed map[enode.ID]*enode.Node
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// New creates a node permissioning service loading the allowlist from source
+// every period.
+func New(source Source, period time.Duration) *NodePermission {
+	if period <= 0 {
+		period = DefaultRefreshPeriod
+	}
+	return &NodePermission{
+		source:  source,
+		period:  period,
+		allowed: make(map[enode.ID]*enode.Node),
+	}
+}
+
+// Protocols implements node.Service.
+func (p *NodePermission) Protocols() []p2p.Protocol { return nil }
+
+// APIs implements node.Service, returning the admin permissioning API.
+func (p *NodePermission) APIs() []rpc.API {
+	return []rpc.API{{
+		Namespace: "admin",
+		Version:   "1.0",
+		Service:   NewPrivateAdminAPI(p),
+		Public:    false,
+	}}
+}
+
+// Start implements node.Service, loading the allowlist and installing the
+// handshake check on the p2p server.
+func (p *NodePermission) Start(server *p2p.Server) error {
+	p.lock.Lock()
+	p.server = server
+	p.lock.Unlock()
+
+	if err := p.Reload(); err != nil {
+		return err
+	}
+	server.SetCheckPeerPermission(p.IsAllowed)
+
+	p.quit = make(chan struct{})
+	p.wg.Add(1)
+	go p.loop()
+
+	log.Info("Node permissioning enabled", "nodes", len(p.Nodes()))
+	return nil
+}
+
+// Stop implements node.Service.
+func (p *NodePermission) Stop() error {
+	p.lock.RLock()
+	server := p.server
+	p.lock.RUnlock()
+
+	if server != nil {
+		server.SetCheckPeerPermission(nil)
+	}
+	if p.quit != nil {
+		close(p.quit)
+		p.wg.Wait()
+	}
+	return nil
+}
+
+// loop reloads the allowlist periodically, picking up changes made directly
+// to the source.
+func (p *NodePermission) loop() {
+	defer p.wg.Done()
+
+	refresh := time.NewTicker(p.period)
+	defer refresh.Stop()
+
+	for {
+		select {
+		case <-refresh.C:
+			if err := p.Reload(); err != nil {
+				log.Warn("Failed to reload permissioned nodes", "err", err)
+			}
+		case <-p.quit:
+			return
+		}
+	}
+}
+
+// IsAllowed reports whether the node may connect.
+func (p *NodePermission) IsAllowed(node *enode.Node) bool {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	_, ok := p.allowed[node.ID()]
+	return ok
+}
+
+// Nodes returns the currently permissioned nodes.
+func (p *NodePermission) Nodes() []*enode.Node {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	nodes := make([]*enode.Node, 0, len(p.allowed))
+	for _, node := range p.allowed {
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
+// Reload refreshes the allowlist from the source and disconnects the peers
+// that are no longer permissioned. On failure the previous list is kept.
+func (p *NodePermission) Reload() error {
+	nodes, err := p.source.Nodes()
+	if err != nil {
+		return err
+	}
+	allowed := make(map[enode.ID]*enode.Node, len(nodes))
+	for _, node := range nodes {
+		allowed[node.ID()] = node
+	}
+	p.lock.Lock()
+	p.allowed = allowed
+	server := p.server
+	p.lock.Unlock()
+
+	if server != nil {
+		for _, peer := range server.Peers() {
+			if _, ok := allowed[peer.ID()]; !ok {
+				log.Info("Dropping peer no longer permissioned", "id", peer.ID(), "addr", peer.RemoteAddr())
+				peer.Disconnect(p2p.DiscRequested)
+			}
+		}
+	}
+	return nil
+}
+
+// Add permissions the node and reloads the allowlist.
+func (p *NodePermission) Add(node *enode.Node) error {
+	source, ok := p.source.(WritableSource)
+	if !ok {
+		return errReadOnlySource
+	}
+	if err := source.Add(node); err != nil {
+		return err
+	}
+	return p.Reload()
+}
+
+// Remove revokes the permission of the node, disconnecting it if connected.
+func (p *NodePermission) Remove(node *enode.Node) error {
+	source, ok := p.source.(WritableSource)
+	if !ok {
+		return errReadOnlySource
+	}
+	if err := source.Remove(node); err != nil {
+		return err
+	}
+	return p.Reload()
+}
@@ -0,0 +1,155 @@
+// Copyright 2020 The go-simplechain Authors
+// This file is part of the go-simplechain library.
+//
+// The go-simplechain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-simplechain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-simplechain library. If not, see <http://www.gnu.org/licenses/>.
+
+package permission
+
+import (
+	"context"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/simplechain-org/go-simplechain"
+	"github.com/simplechain-org/go-simplechain/common"
+	"github.com/simplechain-org/go-simplechain/crypto"
+	"github.com/simplechain-org/go-simplechain/p2p/enode"
+)
+
+func newNode(t *testing.T) *enode.Node {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	return enode.NewV4(&key.PublicKey, net.IP{127, 0, 0, 1}, 30303, 30303)
+}
+
+// Tests that the file source round-trips additions and removals and that the
+// permission service follows them.
+func TestFilePermission(t *testing.T) {
+	dir, err := ioutil.TempDir("", "permission-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var (
+		path  = filepath.Join(dir, "permissioned-nodes.json")
+		alice = newNode(t)
+		bob   = newNode(t)
+	)
+	if err := ioutil.WriteFile(path, []byte(`["`+alice.URLv4()+`"]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	p := New(NewFileSource(path), 0)
+	if err := p.Reload(); err != nil {
+		t.Fatalf("failed to load permissions: %v", err)
+	}
+	if !p.IsAllowed(alice) || p.IsAllowed(bob) {
+		t.Fatalf("initial permissions mismatch: alice %v, bob %v", p.IsAllowed(alice), p.IsAllowed(bob))
+	}
+	if err := p.Add(bob); err != nil {
+		t.Fatalf("failed to add node: %v", err)
+	}
+	if err := p.Remove(alice); err != nil {
+		t.Fatalf("failed to remove node: %v", err)
+	}
+	if p.IsAllowed(alice) || !p.IsAllowed(bob) {
+		t.Fatalf("updated permissions mismatch: alice %v, bob %v", p.IsAllowed(alice), p.IsAllowed(bob))
+	}
+	if err := p.Remove(alice); err != errUnknownNode {
+		t.Fatalf("repeated removal error mismatch: have %v, want %v", err, errUnknownNode)
+	}
+	// The change must have been persisted
+	reloaded := New(NewFileSource(path), 0)
+	if err := reloaded.Reload(); err != nil {
+		t.Fatalf("failed to reload permissions: %v", err)
+	}
+	if nodes := reloaded.Nodes(); len(nodes) != 1 || nodes[0].ID() != bob.ID() {
+		t.Fatalf("persisted permissions mismatch: %v", nodes)
+	}
+}
+
+// Tests that a broken source keeps the previous allowlist in place.
+func TestReloadFailure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "permission-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "permissioned-nodes.json")
+	alice := newNode(t)
+	if err := ioutil.WriteFile(path, []byte(`["`+alice.URLv4()+`"]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	p := New(NewFileSource(path), 0)
+	if err := p.Reload(); err != nil {
+		t.Fatalf("failed to load permissions: %v", err)
+	}
+	if err := ioutil.WriteFile(path, []byte(`["enode://garbage"]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Reload(); err == nil {
+		t.Fatalf("invalid node list accepted")
+	}
+	if !p.IsAllowed(alice) {
+		t.Fatalf("previous allowlist dropped on failed reload")
+	}
+}
+
+// contractCaller is a bind.ContractCaller answering getPermittedNodes calls
+// with a fixed node list.
+type contractCaller struct {
+	urls []string
+}
+
+func (c *contractCaller) CodeAt(ctx context.Context, contract common.Address, blockNumber *big.Int) ([]byte, error) {
+	return []byte{0x00}, nil
+}
+
+func (c *contractCaller) CallContract(ctx context.Context, call simplechain.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	return permissionABI.Methods["getPermittedNodes"].Outputs.Pack(c.urls)
+}
+
+// Tests that the contract source decodes the permissioned nodes and rejects
+// modifications through the admin API.
+func TestContractPermission(t *testing.T) {
+	var (
+		alice  = newNode(t)
+		bob    = newNode(t)
+		caller = &contractCaller{urls: []string{alice.URLv4()}}
+	)
+	p := New(NewContractSource(common.HexToAddress("0x0a"), caller), 0)
+	if err := p.Reload(); err != nil {
+		t.Fatalf("failed to load permissions: %v", err)
+	}
+	if !p.IsAllowed(alice) || p.IsAllowed(bob) {
+		t.Fatalf("initial permissions mismatch: alice %v, bob %v", p.IsAllowed(alice), p.IsAllowed(bob))
+	}
+	caller.urls = []string{bob.URLv4()}
+	if err := p.Reload(); err != nil {
+		t.Fatalf("failed to reload permissions: %v", err)
+	}
+	if p.IsAllowed(alice) || !p.IsAllowed(bob) {
+		t.Fatalf("updated permissions mismatch: alice %v, bob %v", p.IsAllowed(alice), p.IsAllowed(bob))
+	}
+	if err := p.Add(alice); err != errReadOnlySource {
+		t.Fatalf("contract source modification error mismatch: have %v, want %v", err, errReadOnlySource)
+	}
+}
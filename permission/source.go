@@ -0,0 +1,208 @@
+// Copyright 2020 The go-simplechain Authors
+// This file is part of the go-simplechain library.
+//
+// The go-simplechain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-simplechain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-simplechain library. If not, see <http://www.gnu.org/licenses/>.
+
+package permission
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/simplechain-org/go-simplechain/accounts/abi"
+	"github.com/simplechain-org/go-simplechain/accounts/abi/bind"
+	"github.com/simplechain-org/go-simplechain/common"
+	"github.com/simplechain-org/go-simplechain/p2p/enode"
+)
+
+// NodePermissionABI is the JSON ABI a node permission contract has to expose.
+// getPermittedNodes returns the enode URLs allowed to join the network.
+const NodePermissionABI = `[
+	{"type":"function","name":"getPermittedNodes","constant":true,"stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"string[]"}]}
+]`
+
+// permissionABI is the parsed NodePermissionABI.
+var permissionABI abi.ABI
+
+func init() {
+	var err error
+	if permissionABI, err = abi.JSON(strings.NewReader(NodePermissionABI)); err != nil {
+		panic(err)
+	}
+}
+
+// contractCallTimeout bounds a single query of the permission contract.
+const contractCallTimeout = 5 * time.Second
+
+var (
+	// errReadOnlySource is returned when modifying a source that can only be
+	// changed by other means, such as a governance contract.
+	errReadOnlySource = errors.New("permission source is read only")
+
+	// errUnknownNode is returned when removing a node that isn't permissioned.
+	errUnknownNode = errors.New("node not permissioned")
+)
+
+// Source provides the list of nodes allowed to connect.
+type Source interface {
+	// Nodes returns the currently permissioned nodes.
+	Nodes() ([]*enode.Node, error)
+}
+
+// WritableSource is a Source that can be modified through the admin API.
+type WritableSource interface {
+	Source
+
+	// Add permissions the given node.
+	Add(node *enode.Node) error
+
+	// Remove revokes the permission of the given node.
+	Remove(node *enode.Node) error
+}
+
+// FileSource reads the permissioned nodes from a JSON file holding an array of
+// enode URLs, the format of static-nodes.json.
+type FileSource struct {
+	path string
+	lock sync.Mutex // Serializes read-modify-write cycles of the file
+}
+
+// NewFileSource creates a source backed by the JSON file at path.
+func NewFileSource(path string) *FileSource {
+	return &FileSource{path: path}
+}
+
+// Nodes implements Source, parsing the node list file.
+func (s *FileSource) Nodes() ([]*enode.Node, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	urls, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	return parseNodes(urls)
+}
+
+// Add implements WritableSource, appending the node to the list file.
+func (s *FileSource) Add(node *enode.Node) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	urls, err := s.load()
+	if err != nil {
+		return err
+	}
+	nodes, err := parseNodes(urls)
+	if err != nil {
+		return err
+	}
+	for _, n := range nodes {
+		if n.ID() == node.ID() {
+			return nil
+		}
+	}
+	return s.store(append(urls, node.URLv4()))
+}
+
+// Remove implements WritableSource, dropping the node from the list file.
+func (s *FileSource) Remove(node *enode.Node) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	urls, err := s.load()
+	if err != nil {
+		return err
+	}
+	kept := make([]string, 0, len(urls))
+	for _, url := range urls {
+		if n, err := enode.Parse(enode.ValidSchemes, url); err == nil && n.ID() == node.ID() {
+			continue
+		}
+		kept = append(kept, url)
+	}
+	if len(kept) == len(urls) {
+		return errUnknownNode
+	}
+	return s.store(kept)
+}
+
+// load reads the raw URL list from the file.
+func (s *FileSource) load() ([]string, error) {
+	var urls []string
+	if err := common.LoadJSON(s.path, &urls); err != nil {
+		return nil, err
+	}
+	return urls, nil
+}
+
+// store writes the raw URL list back to the file.
+func (s *FileSource) store(urls []string) error {
+	blob, err := json.MarshalIndent(urls, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(s.path, blob, 0644)
+}
+
+// ContractSource reads the permissioned nodes from a contract implementing
+// NodePermissionABI. Changes are made by transacting with the contract itself,
+// so the source is read only.
+type ContractSource struct {
+	address  common.Address
+	contract *bind.BoundContract
+}
+
+// NewContractSource creates a source querying the permission contract deployed
+// at address through caller.
+func NewContractSource(address common.Address, caller bind.ContractCaller) *ContractSource {
+	return &ContractSource{
+		address:  address,
+		contract: bind.NewBoundContract(address, permissionABI, caller, nil, nil),
+	}
+}
+
+// Nodes implements Source, calling getPermittedNodes on the latest state.
+func (s *ContractSource) Nodes() ([]*enode.Node, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), contractCallTimeout)
+	defer cancel()
+
+	var urls []string
+	if err := s.contract.Call(&bind.CallOpts{Context: ctx}, &urls, "getPermittedNodes"); err != nil {
+		return nil, fmt.Errorf("permission contract %s: %v", s.address.Hex(), err)
+	}
+	return parseNodes(urls)
+}
+
+// parseNodes interprets a list of enode URLs, skipping empty entries.
+func parseNodes(urls []string) ([]*enode.Node, error) {
+	nodes := make([]*enode.Node, 0, len(urls))
+	for _, url := range urls {
+		if url == "" {
+			continue
+		}
+		node, err := enode.Parse(enode.ValidSchemes, url)
+		if err != nil {
+			return nil, fmt.Errorf("node URL %s: %v", url, err)
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, nil
+}
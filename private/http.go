@@ -0,0 +1,157 @@
+// Copyright 2020 The go-simplechain Authors
+// This file is part of the go-simplechain library.
+//
+// The go-simplechain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-simplechain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-simplechain library. If not, see <http://www.gnu.org/licenses/>.
+
+package private
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// managerTimeout is the timeout of a request to the transaction manager.
+const managerTimeout = 10 * time.Second
+
+var errInvalidPayloadHash = errors.New("invalid payload hash from the transaction manager")
+
+// HTTPManager is a PrivateTransactionManager backed by an external enclave
+// speaking the Tessera style REST API:
+//
+//	POST /send                {"payload", "from", "to"} -> {"key"}
+//	GET  /transaction/{key}   -> {"payload"}, 404 for non participants
+//
+// with payloads and keys base64 encoded. The enclave is reached over HTTP(S) or
+// over the HTTP server of its IPC socket.
+type HTTPManager struct {
+	client  *http.Client
+	baseURL string
+}
+
+// NewHTTPManager connects to the transaction manager at endpoint, either an
+// http(s) URL or the path of an IPC socket.
+func NewHTTPManager(endpoint string) (*HTTPManager, error) {
+	if strings.HasPrefix(endpoint, "http://") || strings.HasPrefix(endpoint, "https://") {
+		if _, err := url.Parse(endpoint); err != nil {
+			return nil, err
+		}
+		return &HTTPManager{
+			client:  &http.Client{Timeout: managerTimeout},
+			baseURL: strings.TrimSuffix(endpoint, "/"),
+		}, nil
+	}
+	if endpoint == "" {
+		return nil, errors.New("empty transaction manager endpoint")
+	}
+	dialer := new(net.Dialer)
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "unix", endpoint)
+		},
+	}
+	return &HTTPManager{
+		client:  &http.Client{Timeout: managerTimeout, Transport: transport},
+		baseURL: "http://ipc",
+	}, nil
+}
+
+type sendRequest struct {
+	Payload string   `json:"payload"`
+	From    string   `json:"from,omitempty"`
+	To      []string `json:"to"`
+}
+
+type sendResponse struct {
+	Key string `json:"key"`
+}
+
+type receiveResponse struct {
+	Payload string `json:"payload"`
+}
+
+// Send implements PrivateTransactionManager.
+func (m *HTTPManager) Send(payload []byte, from string, to []string) (PayloadHash, error) {
+	if len(to) == 0 {
+		return PayloadHash{}, ErrNoRecipients
+	}
+	body, err := json.Marshal(&sendRequest{
+		Payload: base64.StdEncoding.EncodeToString(payload),
+		From:    from,
+		To:      to,
+	})
+	if err != nil {
+		return PayloadHash{}, err
+	}
+	res, err := m.client.Post(m.baseURL+"/send", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return PayloadHash{}, err
+	}
+	defer res.Body.Close()
+
+	if err := checkStatus(res); err != nil {
+		return PayloadHash{}, err
+	}
+	var result sendResponse
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return PayloadHash{}, err
+	}
+	key, err := base64.StdEncoding.DecodeString(result.Key)
+	if err != nil || len(key) != len(PayloadHash{}) {
+		return PayloadHash{}, errInvalidPayloadHash
+	}
+	return BytesToPayloadHash(key), nil
+}
+
+// Receive implements PrivateTransactionManager.
+func (m *HTTPManager) Receive(hash PayloadHash) ([]byte, error) {
+	key := url.PathEscape(base64.StdEncoding.EncodeToString(hash[:]))
+	res, err := m.client.Get(m.baseURL + "/transaction/" + key)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	// The local node doesn't participate in the transaction
+	if res.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if err := checkStatus(res); err != nil {
+		return nil, err
+	}
+	var result receiveResponse
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(result.Payload)
+}
+
+// checkStatus turns an unsuccessful response into an error.
+func checkStatus(res *http.Response) error {
+	if res.StatusCode >= 200 && res.StatusCode < 300 {
+		return nil
+	}
+	msg, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1024))
+	return fmt.Errorf("transaction manager: %s: %s", res.Status, strings.TrimSpace(string(msg)))
+}
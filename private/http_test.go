@@ -0,0 +1,114 @@
+// Copyright 2020 The go-simplechain Authors
+// This file is part of the go-simplechain library.
+//
+// The go-simplechain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-simplechain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-simplechain library. If not, see <http://www.gnu.org/licenses/>.
+
+package private
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testEnclave serves the REST API of a transaction manager on top of a
+// MemoryManager.
+func testEnclave(m *MemoryManager) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/send", func(w http.ResponseWriter, r *http.Request) {
+		var req sendRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		payload, _ := base64.StdEncoding.DecodeString(req.Payload)
+		hash, err := m.Send(payload, req.From, req.To)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(&sendResponse{Key: base64.StdEncoding.EncodeToString(hash[:])})
+	})
+	mux.HandleFunc("/transaction/", func(w http.ResponseWriter, r *http.Request) {
+		key, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(r.URL.Path, "/transaction/"))
+		payload, _ := m.Receive(BytesToPayloadHash(key))
+		if payload == nil {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(&receiveResponse{Payload: base64.StdEncoding.EncodeToString(payload)})
+	})
+	return mux
+}
+
+// Tests that payloads go through the enclaves over HTTP and IPC, and are only
+// delivered to the participants of a transaction.
+func TestHTTPManager(t *testing.T) {
+	network := NewMemoryNetwork()
+	network.NewManager("outsider")
+
+	sender := httptest.NewServer(testEnclave(network.NewManager("sender")))
+	defer sender.Close()
+
+	dir, err := ioutil.TempDir("", "private")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "tm.ipc")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("failed to listen on IPC socket: %v", err)
+	}
+	defer listener.Close()
+	go http.Serve(listener, testEnclave(network.NewManager("recipient")))
+
+	outsider := httptest.NewServer(testEnclave(network.managers["outsider"]))
+	defer outsider.Close()
+
+	managers := make(map[string]*HTTPManager)
+	for name, endpoint := range map[string]string{"sender": sender.URL, "recipient": socket, "outsider": outsider.URL} {
+		if managers[name], err = NewHTTPManager(endpoint); err != nil {
+			t.Fatalf("%s: failed to connect: %v", name, err)
+		}
+	}
+	payload := []byte("private payload")
+	hash, err := managers["sender"].Send(payload, "", []string{"recipient"})
+	if err != nil {
+		t.Fatalf("failed to send payload: %v", err)
+	}
+	for _, name := range []string{"sender", "recipient"} {
+		have, err := managers[name].Receive(hash)
+		if err != nil {
+			t.Fatalf("%s: failed to receive payload: %v", name, err)
+		}
+		if !bytes.Equal(have, payload) {
+			t.Errorf("%s: payload mismatch: have %x, want %x", name, have, payload)
+		}
+	}
+	if have, err := managers["outsider"].Receive(hash); err != nil || have != nil {
+		t.Errorf("outsider received payload: %x, %v", have, err)
+	}
+	if _, err := managers["sender"].Send(payload, "", []string{"unknown"}); err == nil {
+		t.Errorf("payload sent to an unknown recipient")
+	}
+}
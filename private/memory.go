@@ -0,0 +1,114 @@
+// Copyright 2020 The go-simplechain Authors
+// This file is part of the go-simplechain library.
+//
+// The go-simplechain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-simplechain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-simplechain library. If not, see <http://www.gnu.org/licenses/>.
+
+package private
+
+import (
+	"crypto/rand"
+	"sync"
+
+	"github.com/simplechain-org/go-simplechain/common"
+	"github.com/simplechain-org/go-simplechain/crypto"
+)
+
+// MemoryNetwork connects in-process transaction managers. It stands in for a
+// real deployment of enclaves in tests and single host setups: payloads are
+// copied between the managers of the participants, not encrypted.
+type MemoryNetwork struct {
+	lock     sync.RWMutex
+	managers map[string]*MemoryManager
+}
+
+// NewMemoryNetwork creates an empty in-process transaction manager network.
+func NewMemoryNetwork() *MemoryNetwork {
+	return &MemoryNetwork{managers: make(map[string]*MemoryManager)}
+}
+
+// NewManager creates a transaction manager with the given public key and joins
+// it to the network.
+func (n *MemoryNetwork) NewManager(key string) *MemoryManager {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	m := &MemoryManager{
+		network:  n,
+		key:      key,
+		payloads: make(map[PayloadHash][]byte),
+	}
+	n.managers[key] = m
+	return m
+}
+
+// MemoryManager is a PrivateTransactionManager living in a MemoryNetwork.
+type MemoryManager struct {
+	network *MemoryNetwork
+	key     string
+
+	lock     sync.RWMutex
+	payloads map[PayloadHash][]byte
+}
+
+// PublicKey returns the identity of the manager in its network.
+func (m *MemoryManager) PublicKey() string {
+	return m.key
+}
+
+// Send implements PrivateTransactionManager. The hash is salted, so the same
+// payload sent twice yields distinct transactions.
+func (m *MemoryManager) Send(payload []byte, from string, to []string) (PayloadHash, error) {
+	if len(to) == 0 {
+		return PayloadHash{}, ErrNoRecipients
+	}
+	if from == "" {
+		from = m.key
+	}
+	m.network.lock.RLock()
+	defer m.network.lock.RUnlock()
+
+	participants := []*MemoryManager{m}
+	for _, key := range append([]string{from}, to...) {
+		recipient, ok := m.network.managers[key]
+		if !ok {
+			return PayloadHash{}, ErrUnknownRecipient
+		}
+		participants = append(participants, recipient)
+	}
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return PayloadHash{}, err
+	}
+	hash := BytesToPayloadHash(crypto.Keccak512(salt, payload))
+	for _, p := range participants {
+		p.store(hash, payload)
+	}
+	return hash, nil
+}
+
+// Receive implements PrivateTransactionManager.
+func (m *MemoryManager) Receive(hash PayloadHash) ([]byte, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	return common.CopyBytes(m.payloads[hash]), nil
+}
+
+// store keeps a copy of the payload.
+func (m *MemoryManager) store(hash PayloadHash, payload []byte) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.payloads[hash] = common.CopyBytes(payload)
+}
@@ -0,0 +1,59 @@
+// Copyright 2020 The go-simplechain Authors
+// This file is part of the go-simplechain library.
+//
+// The go-simplechain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-simplechain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-simplechain library. If not, see <http://www.gnu.org/licenses/>.
+
+package private
+
+import (
+	"bytes"
+	"testing"
+)
+
+// Tests that payloads are only delivered to the participants of a transaction.
+func TestMemoryManager(t *testing.T) {
+	var (
+		network   = NewMemoryNetwork()
+		sender    = network.NewManager("sender")
+		recipient = network.NewManager("recipient")
+		outsider  = network.NewManager("outsider")
+		payload   = []byte("private payload")
+	)
+	hash, err := sender.Send(payload, "", []string{"recipient"})
+	if err != nil {
+		t.Fatalf("failed to send payload: %v", err)
+	}
+	for _, m := range []*MemoryManager{sender, recipient} {
+		have, err := m.Receive(hash)
+		if err != nil {
+			t.Fatalf("%s: failed to receive payload: %v", m.PublicKey(), err)
+		}
+		if !bytes.Equal(have, payload) {
+			t.Errorf("%s: payload mismatch: have %x, want %x", m.PublicKey(), have, payload)
+		}
+	}
+	if have, err := outsider.Receive(hash); err != nil || have != nil {
+		t.Errorf("outsider received payload: %x, %v", have, err)
+	}
+	// Identical payloads are referenced by distinct hashes
+	if other, _ := sender.Send(payload, "", []string{"recipient"}); other == hash {
+		t.Errorf("identical payloads share hash %v", hash)
+	}
+	if _, err := sender.Send(payload, "", nil); err != ErrNoRecipients {
+		t.Errorf("error mismatch: have %v, want %v", err, ErrNoRecipients)
+	}
+	if _, err := sender.Send(payload, "", []string{"unknown"}); err != ErrUnknownRecipient {
+		t.Errorf("error mismatch: have %v, want %v", err, ErrUnknownRecipient)
+	}
+}
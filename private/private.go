@@ -0,0 +1,73 @@
+// Copyright 2020 The go-simplechain Authors
+// This file is part of the go-simplechain library.
+//
+// The go-simplechain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-simplechain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-simplechain library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package private defines the transaction manager keeping the payloads of
+// private transactions.
+//
+// A private transaction carries only the hash of its encrypted payload on the
+// public chain. The manager of every participant stores the payload, so their
+// nodes can execute it on their private state while everyone else treats the
+// transaction as opaque.
+package private
+
+import (
+	"encoding/hex"
+	"errors"
+
+	"github.com/simplechain-org/go-simplechain/core/types"
+)
+
+var (
+	// ErrNoRecipients is returned when sending a private payload without any
+	// participant besides the sender.
+	ErrNoRecipients = errors.New("private transaction without recipients")
+
+	// ErrUnknownRecipient is returned when a participant isn't known to the
+	// transaction manager.
+	ErrUnknownRecipient = errors.New("unknown private transaction recipient")
+)
+
+// PayloadHash references an encrypted payload stored by the managers.
+type PayloadHash [types.PrivatePayloadHashLength]byte
+
+// BytesToPayloadHash converts b to a payload hash, cropping from the left if
+// it is too long.
+func BytesToPayloadHash(b []byte) PayloadHash {
+	var h PayloadHash
+	if len(b) > len(h) {
+		b = b[len(b)-len(h):]
+	}
+	copy(h[len(h)-len(b):], b)
+	return h
+}
+
+// String implements fmt.Stringer.
+func (h PayloadHash) String() string {
+	return "0x" + hex.EncodeToString(h[:])
+}
+
+// PrivateTransactionManager stores and distributes the payloads of private
+// transactions between their participants, identified by public keys.
+type PrivateTransactionManager interface {
+	// Send stores the payload, distributes it to the participants in to and
+	// returns the hash referencing it. An empty from selects the default
+	// identity of the local manager.
+	Send(payload []byte, from string, to []string) (PayloadHash, error)
+
+	// Receive returns the payload referenced by hash, or nil if the local
+	// node doesn't participate in the transaction.
+	Receive(hash PayloadHash) ([]byte, error)
+}
@@ -0,0 +1,98 @@
+package stratum
+
+import (
+	"bufio"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/simplechain-org/go-simplechain/log"
+)
+
+// AllowlistAuth authenticates the miners listed in a file. Each line holds a
+// username and an optional password separated by whitespace; a username
+// without a worker suffix admits all the workers of that account, and lines
+// starting with # are comments:
+//
+//	# account, any worker, no password
+//	0x8a0ea6c9d1e2cb2ea5f3f1c7e0f15ab7bc3b2c7d
+//	# single worker with a password
+//	0x3b1fa4e2cd2e8fd7aaf06d6e5e9a0b3c9af3c1d2.rig1 secret
+//
+// The file is reloaded when its modification time changes, so miners can be
+// added or removed without restarting the node.
+type AllowlistAuth struct {
+	path    string
+	modTime time.Time
+	entries map[string]string // username -> password
+	mutex   sync.Mutex
+}
+
+func NewAllowlistAuth(path string) (*AllowlistAuth, error) {
+	auth := &AllowlistAuth{path: path}
+	if err := auth.Reload(); err != nil {
+		return nil, err
+	}
+	return auth, nil
+}
+
+// Reload reads the allowlist file again.
+func (this *AllowlistAuth) Reload() error {
+	info, err := os.Stat(this.path)
+	if err != nil {
+		return err
+	}
+	file, err := os.Open(this.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	entries := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		var passwd string
+		if len(fields) > 1 {
+			passwd = fields[1]
+		}
+		entries[fields[0]] = passwd
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	this.mutex.Lock()
+	this.entries, this.modTime = entries, info.ModTime()
+	this.mutex.Unlock()
+
+	log.Info("[AllowlistAuth] loaded", "path", this.path, "entries", len(entries))
+	return nil
+}
+
+func (this *AllowlistAuth) Auth(username string, passwd string, challenge string) bool {
+	if info, err := os.Stat(this.path); err == nil {
+		this.mutex.Lock()
+		changed := !info.ModTime().Equal(this.modTime)
+		this.mutex.Unlock()
+		if changed {
+			if err := this.Reload(); err != nil {
+				log.Warn("[AllowlistAuth] reload failed, keeping the old list", "path", this.path, "error", err)
+			}
+		}
+	}
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	expected, ok := this.entries[username]
+	if !ok {
+		if i := strings.IndexByte(username, '.'); i >= 0 {
+			expected, ok = this.entries[username[:i]]
+		}
+	}
+	return ok && (expected == "" || expected == passwd)
+}
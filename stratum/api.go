@@ -0,0 +1,87 @@
+package stratum
+
+import (
+	"errors"
+
+	"github.com/simplechain-org/go-simplechain/common/hexutil"
+)
+
+const defaultBlocksCount = 20
+
+var errUnknownWorker = errors.New("unknown worker")
+
+// API exposes the share ledger of the stratum server over RPC.
+type API struct {
+	ledger *ShareLedger
+}
+
+// GetWorkers returns the share accounting of every worker.
+func (api *API) GetWorkers() []map[string]interface{} {
+	workers := api.ledger.Workers()
+	result := make([]map[string]interface{}, len(workers))
+	for i, stats := range workers {
+		result[i] = rpcMarshalWorker(stats)
+	}
+	return result
+}
+
+// GetWorker returns the share accounting of a worker.
+func (api *API) GetWorker(worker string) (map[string]interface{}, error) {
+	stats := api.ledger.Worker(worker)
+	if stats == nil {
+		return nil, errUnknownWorker
+	}
+	return rpcMarshalWorker(stats), nil
+}
+
+// GetBlocks returns the last found blocks with their payouts, most recent
+// first, and whether these are credited. The count defaults to 20.
+func (api *API) GetBlocks(count *hexutil.Uint64) []map[string]interface{} {
+	limit := uint64(defaultBlocksCount)
+	if count != nil {
+		limit = uint64(*count)
+	}
+	blocks := api.ledger.Blocks(limit)
+	result := make([]map[string]interface{}, len(blocks))
+	for i, block := range blocks {
+		payouts := make(map[string]*hexutil.Big, len(block.Payouts))
+		for worker, payout := range block.Payouts {
+			payouts[worker] = (*hexutil.Big)(payout)
+		}
+		result[i] = map[string]interface{}{
+			"number":   hexutil.Uint64(block.Number),
+			"hash":     block.Hash,
+			"finder":   block.Finder,
+			"reward":   (*hexutil.Big)(block.Reward),
+			"time":     hexutil.Uint64(block.Time),
+			"scheme":   block.Scheme,
+			"shares":   hexutil.Uint64(block.Shares),
+			"payouts":  payouts,
+			"credited": block.Credited,
+		}
+	}
+	return result
+}
+
+// GetPayouts returns the sum of the payouts credited to each worker.
+func (api *API) GetPayouts() map[string]*hexutil.Big {
+	payouts := make(map[string]*hexutil.Big)
+	for _, stats := range api.ledger.Workers() {
+		payouts[stats.Worker] = (*hexutil.Big)(stats.Paid)
+	}
+	return payouts
+}
+
+func rpcMarshalWorker(stats *WorkerStats) map[string]interface{} {
+	return map[string]interface{}{
+		"worker":         stats.Worker,
+		"accepted":       hexutil.Uint64(stats.Accepted),
+		"stale":          hexutil.Uint64(stats.Stale),
+		"invalid":        hexutil.Uint64(stats.Invalid),
+		"acceptedWeight": hexutil.Uint64(stats.AcceptedWeight),
+		"staleWeight":    hexutil.Uint64(stats.StaleWeight),
+		"invalidWeight":  hexutil.Uint64(stats.InvalidWeight),
+		"lastShare":      hexutil.Uint64(stats.LastShare),
+		"paid":           (*hexutil.Big)(stats.Paid),
+	}
+}
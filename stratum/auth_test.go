@@ -0,0 +1,89 @@
+package stratum
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/simplechain-org/go-simplechain/accounts"
+	"github.com/simplechain-org/go-simplechain/common/hexutil"
+	"github.com/simplechain-org/go-simplechain/crypto"
+)
+
+func TestSignatureAuth(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	address := crypto.PubkeyToAddress(key.PublicKey).Hex()
+	challenge := "0123456789abcdef"
+
+	sig, err := crypto.Sign(accounts.TextHash([]byte(challenge)), key)
+	if err != nil {
+		t.Fatalf("failed to sign challenge: %v", err)
+	}
+	legacy := append([]byte{}, sig...)
+	legacy[crypto.RecoveryIDOffset] += 27
+
+	auth := NewSignatureAuth()
+	tests := []struct {
+		username, passwd, challenge string
+		ok                          bool
+	}{
+		{address, hexutil.Encode(sig), challenge, true},
+		{address + ".rig1", hexutil.Encode(legacy), challenge, true},
+		{address, hexutil.Encode(sig), "other challenge", false},
+		{address, hexutil.Encode(sig), "", false},
+		{"0x0000000000000000000000000000000000000001", hexutil.Encode(sig), challenge, false},
+		{"rig1", hexutil.Encode(sig), challenge, false},
+		{address, "secret", challenge, false},
+	}
+	for i, tt := range tests {
+		if ok := auth.Auth(tt.username, tt.passwd, tt.challenge); ok != tt.ok {
+			t.Errorf("test %d: auth mismatch: have %v, want %v", i, ok, tt.ok)
+		}
+	}
+}
+
+func TestAllowlistAuth(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stratum-allowlist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "allowlist")
+	if err := ioutil.WriteFile(path, []byte("# miners\n0xaa\n0xbb.rig1 secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	auth, err := NewAllowlistAuth(path)
+	if err != nil {
+		t.Fatalf("failed to load allowlist: %v", err)
+	}
+	tests := []struct {
+		username, passwd string
+		ok               bool
+	}{
+		{"0xaa", "", true},
+		{"0xaa.rig7", "anything", true},
+		{"0xbb.rig1", "secret", true},
+		{"0xbb.rig1", "wrong", false},
+		{"0xbb.rig2", "secret", false},
+		{"0xcc", "", false},
+	}
+	for i, tt := range tests {
+		if ok := auth.Auth(tt.username, tt.passwd, ""); ok != tt.ok {
+			t.Errorf("test %d: auth mismatch: have %v, want %v", i, ok, tt.ok)
+		}
+	}
+	// Changes to the file are picked up without a restart
+	if err := ioutil.WriteFile(path, []byte("0xcc\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if !auth.Auth("0xcc", "", "") || auth.Auth("0xaa", "", "") {
+		t.Errorf("allowlist not reloaded")
+	}
+}
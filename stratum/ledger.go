@@ -0,0 +1,464 @@
+package stratum
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/simplechain-org/go-simplechain/common"
+	"github.com/simplechain-org/go-simplechain/core/types"
+	"github.com/simplechain-org/go-simplechain/ethdb"
+	"github.com/simplechain-org/go-simplechain/log"
+)
+
+// Payout schemes of the share ledger
+const (
+	PayoutPPLNS = "pplns" // reward shared over the last N accepted shares
+	PayoutPROP  = "prop"  // reward shared over the accepted shares of the round
+)
+
+// Share states
+const (
+	ShareAccepted = iota
+	ShareStale
+	ShareInvalid
+)
+
+var (
+	DefaultPPLNSWindow uint64 = 10000
+
+	errUnknownPayout = errors.New("unknown payout scheme")
+
+	sharePrefix   = []byte("stratum-share-")  // sharePrefix + seq (uint64 big endian) -> share
+	workerPrefix  = []byte("stratum-worker-") // workerPrefix + worker name -> worker stats
+	blockPrefix   = []byte("stratum-block-")  // blockPrefix + index (uint64 big endian) -> found block
+	ledgerHeadKey = []byte("stratum-ledger")  // ledger head: sequences of the last share and block
+)
+
+const (
+	sharesRetention = 1024 // shares pruned from the database in one batch
+	confirmDepth    = 64   // blocks after which a found block can no longer be reorged out
+)
+
+// CanonicalChain is used by the ledger to verify whether a found block is part
+// of the canonical chain or not.
+type CanonicalChain interface {
+	// GetHeaderByNumber retrieves the canonical header associated with a block number.
+	GetHeaderByNumber(number uint64) *types.Header
+}
+
+// share is an accepted share kept for the payout calculation.
+type share struct {
+	Seq        uint64 `json:"seq"`
+	Worker     string `json:"worker"`
+	Difficulty uint64 `json:"difficulty"`
+}
+
+// WorkerStats is the share accounting of a worker. Every counter has a
+// difficulty weighted twin, the sum of the session difficulties the shares
+// were submitted at.
+type WorkerStats struct {
+	Worker         string   `json:"worker"`
+	Accepted       uint64   `json:"accepted"`
+	Stale          uint64   `json:"stale"`
+	Invalid        uint64   `json:"invalid"`
+	AcceptedWeight uint64   `json:"acceptedWeight"`
+	StaleWeight    uint64   `json:"staleWeight"`
+	InvalidWeight  uint64   `json:"invalidWeight"`
+	LastShare      int64    `json:"lastShare"` // unix time of the last share
+	Paid           *big.Int `json:"paid"`      // sum of the payouts of the found blocks
+}
+
+// FoundBlock is a block sealed by the pool, with the reward of each worker.
+// The payouts are only credited while the block is canonical.
+type FoundBlock struct {
+	Number   uint64              `json:"number"`
+	Hash     common.Hash         `json:"hash"`
+	Finder   string              `json:"finder"`
+	Reward   *big.Int            `json:"reward"`
+	Time     int64               `json:"time"`
+	Scheme   string              `json:"scheme"`
+	Shares   uint64              `json:"shares"` // number of shares the reward was shared over
+	Payouts  map[string]*big.Int `json:"payouts"`
+	Credited bool                `json:"credited"` // whether the payouts are credited to the workers
+}
+
+// unconfirmedBlock is a found block which may still be reorged in or out of
+// the canonical chain.
+type unconfirmedBlock struct {
+	index uint64 // index of the block in the database
+	block *FoundBlock
+}
+
+type ledgerHead struct {
+	ShareSeq  uint64 `json:"shareSeq"`  // sequence of the last accepted share
+	ShareTail uint64 `json:"shareTail"` // sequence of the first share still in the database
+	Blocks    uint64 `json:"blocks"`    // number of found blocks
+}
+
+// ShareLedger records the shares of the stratum workers and shares the reward
+// of the found blocks between them.
+type ShareLedger struct {
+	db     ethdb.Database
+	scheme string
+	window uint64
+
+	head        ledgerHead
+	shares      []share // accepted shares the next payout is calculated over
+	workers     map[string]*WorkerStats
+	unconfirmed []*unconfirmedBlock // found blocks not yet confirmDepth deep, oldest first
+	mutex       sync.RWMutex
+}
+
+// NewShareLedger opens the ledger stored in db. The window is the number of
+// shares a PPLNS payout is shared over, and is ignored by PROP.
+func NewShareLedger(db ethdb.Database, scheme string, window uint64) (*ShareLedger, error) {
+	if scheme != PayoutPPLNS && scheme != PayoutPROP {
+		return nil, errUnknownPayout
+	}
+	if window == 0 {
+		window = DefaultPPLNSWindow
+	}
+	ledger := &ShareLedger{
+		db:      db,
+		scheme:  scheme,
+		window:  window,
+		workers: make(map[string]*WorkerStats),
+	}
+	if blob, err := db.Get(ledgerHeadKey); err == nil {
+		if err := json.Unmarshal(blob, &ledger.head); err != nil {
+			return nil, err
+		}
+	}
+	it := db.NewIteratorWithPrefix(workerPrefix)
+	for it.Next() {
+		stats := new(WorkerStats)
+		if err := json.Unmarshal(it.Value(), stats); err != nil {
+			it.Release()
+			return nil, err
+		}
+		ledger.workers[stats.Worker] = stats
+	}
+	it.Release()
+
+	it = db.NewIteratorWithStart(shareKey(ledger.head.ShareTail))
+	for it.Next() && len(it.Key()) == len(sharePrefix)+8 && string(it.Key()[:len(sharePrefix)]) == string(sharePrefix) {
+		var s share
+		if err := json.Unmarshal(it.Value(), &s); err != nil {
+			it.Release()
+			return nil, err
+		}
+		ledger.shares = append(ledger.shares, s)
+	}
+	it.Release()
+	ledger.trim()
+
+	for index := ledger.head.Blocks; index > 0 && len(ledger.unconfirmed) < confirmDepth; index-- {
+		blob, err := db.Get(blockKey(index - 1))
+		if err != nil {
+			break
+		}
+		block := new(FoundBlock)
+		if err := json.Unmarshal(blob, block); err != nil {
+			return nil, err
+		}
+		ledger.unconfirmed = append([]*unconfirmedBlock{{index: index - 1, block: block}}, ledger.unconfirmed...)
+	}
+
+	log.Info("[ShareLedger] loaded", "scheme", scheme, "window", window, "workers", len(ledger.workers), "shares", len(ledger.shares), "blocks", ledger.head.Blocks)
+	return ledger, nil
+}
+
+func shareKey(seq uint64) []byte {
+	key := make([]byte, len(sharePrefix)+8)
+	copy(key, sharePrefix)
+	binary.BigEndian.PutUint64(key[len(sharePrefix):], seq)
+	return key
+}
+
+func workerKey(worker string) []byte {
+	return append(append([]byte{}, workerPrefix...), worker...)
+}
+
+func blockKey(index uint64) []byte {
+	key := make([]byte, len(blockPrefix)+8)
+	copy(key, blockPrefix)
+	binary.BigEndian.PutUint64(key[len(blockPrefix):], index)
+	return key
+}
+
+// Record accounts a share submitted by worker at the given session difficulty.
+func (l *ShareLedger) Record(worker string, difficulty uint64, state int) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	stats, ok := l.workers[worker]
+	if !ok {
+		stats = &WorkerStats{Worker: worker, Paid: new(big.Int)}
+		l.workers[worker] = stats
+	}
+	stats.LastShare = time.Now().Unix()
+	switch state {
+	case ShareAccepted:
+		stats.Accepted++
+		stats.AcceptedWeight += difficulty
+	case ShareStale:
+		stats.Stale++
+		stats.StaleWeight += difficulty
+	default:
+		stats.Invalid++
+		stats.InvalidWeight += difficulty
+	}
+	batch := l.db.NewBatch()
+	if state == ShareAccepted {
+		l.head.ShareSeq++
+		s := share{Seq: l.head.ShareSeq, Worker: worker, Difficulty: difficulty}
+		l.shares = append(l.shares, s)
+		if blob, err := json.Marshal(s); err == nil {
+			batch.Put(shareKey(s.Seq), blob)
+		}
+		l.trim()
+		l.prune(batch)
+	}
+	if blob, err := json.Marshal(stats); err == nil {
+		batch.Put(workerKey(worker), blob)
+	}
+	l.writeHead(batch)
+	if err := batch.Write(); err != nil {
+		log.Error("[ShareLedger] failed to store share", "worker", worker, "error", err)
+	}
+}
+
+// trim drops the shares that fell out of the PPLNS window.
+func (l *ShareLedger) trim() {
+	if l.scheme == PayoutPPLNS && uint64(len(l.shares)) > l.window {
+		l.shares = append(l.shares[:0], l.shares[uint64(len(l.shares))-l.window:]...)
+	}
+}
+
+// prune deletes the shares no longer needed by the payout calculation, once
+// enough of them accumulated.
+func (l *ShareLedger) prune(batch ethdb.Batch) {
+	tail := l.head.ShareSeq + 1
+	if len(l.shares) > 0 {
+		tail = l.shares[0].Seq
+	}
+	if tail-l.head.ShareTail < sharesRetention && len(l.shares) > 0 {
+		return
+	}
+	for seq := l.head.ShareTail; seq < tail; seq++ {
+		batch.Delete(shareKey(seq))
+	}
+	l.head.ShareTail = tail
+}
+
+func (l *ShareLedger) writeHead(batch ethdb.Batch) {
+	if blob, err := json.Marshal(l.head); err == nil {
+		batch.Put(ledgerHeadKey, blob)
+	}
+}
+
+// BlockFound shares the reward of a block sealed by the pool between the
+// workers. Any remainder of the integer division goes to the finder. The
+// payouts are credited by ChainHead once the block is canonical.
+func (l *ShareLedger) BlockFound(number uint64, hash common.Hash, finder string, reward *big.Int) *FoundBlock {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	block := &FoundBlock{
+		Number:  number,
+		Hash:    hash,
+		Finder:  finder,
+		Reward:  new(big.Int).Set(reward),
+		Time:    time.Now().Unix(),
+		Scheme:  l.scheme,
+		Shares:  uint64(len(l.shares)),
+		Payouts: calcPayouts(l.shares, reward, finder),
+	}
+	batch := l.db.NewBatch()
+	// A PROP round ends with its block
+	if l.scheme == PayoutPROP {
+		l.shares = l.shares[:0]
+		l.prune(batch)
+	}
+	l.unconfirmed = append(l.unconfirmed, &unconfirmedBlock{index: l.head.Blocks, block: block})
+	l.writeBlock(batch, l.head.Blocks, block)
+	l.head.Blocks++
+	l.writeHead(batch)
+	if err := batch.Write(); err != nil {
+		log.Error("[ShareLedger] failed to store found block", "number", number, "error", err)
+	}
+	log.Info("[ShareLedger] block found", "number", number, "hash", hash, "finder", finder, "shares", block.Shares, "workers", len(block.Payouts))
+	return block
+}
+
+// ChainHead credits the payouts of the found blocks which became canonical
+// and reverts those of the blocks reorged out of the chain. Blocks confirmDepth
+// below the head are final and no longer tracked.
+func (l *ShareLedger) ChainHead(head uint64, chain CanonicalChain) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	for len(l.unconfirmed) > 0 && l.unconfirmed[0].block.Number+confirmDepth <= head {
+		l.unconfirmed = l.unconfirmed[1:]
+	}
+	batch := l.db.NewBatch()
+	for _, item := range l.unconfirmed {
+		block := item.block
+		if block.Number > head {
+			// Above the head, the block cannot be canonical
+			if block.Credited {
+				l.credit(batch, item, false)
+			}
+			continue
+		}
+		header := chain.GetHeaderByNumber(block.Number)
+		canonical := header != nil && header.Hash() == block.Hash
+		if canonical != block.Credited {
+			l.credit(batch, item, canonical)
+		}
+	}
+	if batch.ValueSize() > 0 {
+		if err := batch.Write(); err != nil {
+			log.Error("[ShareLedger] failed to store payouts", "head", head, "error", err)
+		}
+	}
+}
+
+// credit adds the payouts of a found block to its workers, or takes them back
+// from them if the block was reorged out.
+func (l *ShareLedger) credit(batch ethdb.Batch, item *unconfirmedBlock, credit bool) {
+	block := item.block
+	for worker, payout := range block.Payouts {
+		stats, ok := l.workers[worker]
+		if !ok {
+			stats = &WorkerStats{Worker: worker, Paid: new(big.Int)}
+			l.workers[worker] = stats
+		}
+		if credit {
+			stats.Paid.Add(stats.Paid, payout)
+		} else {
+			stats.Paid.Sub(stats.Paid, payout)
+		}
+		if blob, err := json.Marshal(stats); err == nil {
+			batch.Put(workerKey(worker), blob)
+		}
+	}
+	block.Credited = credit
+	l.writeBlock(batch, item.index, block)
+
+	if credit {
+		log.Info("[ShareLedger] block payouts credited", "number", block.Number, "hash", block.Hash, "workers", len(block.Payouts))
+	} else {
+		log.Warn("[ShareLedger] block reorged out, payouts reverted", "number", block.Number, "hash", block.Hash, "workers", len(block.Payouts))
+	}
+}
+
+func (l *ShareLedger) writeBlock(batch ethdb.Batch, index uint64, block *FoundBlock) {
+	if blob, err := json.Marshal(block); err == nil {
+		batch.Put(blockKey(index), blob)
+	}
+}
+
+// calcPayouts shares reward between the workers pro rata to the difficulty
+// of their shares.
+func calcPayouts(shares []share, reward *big.Int, finder string) map[string]*big.Int {
+	weights := make(map[string]*big.Int)
+	total := new(big.Int)
+	for _, s := range shares {
+		weight, ok := weights[s.Worker]
+		if !ok {
+			weight = new(big.Int)
+			weights[s.Worker] = weight
+		}
+		difficulty := new(big.Int).SetUint64(s.Difficulty)
+		weight.Add(weight, difficulty)
+		total.Add(total, difficulty)
+	}
+	payouts := make(map[string]*big.Int)
+	if total.Sign() == 0 {
+		if finder != "" {
+			payouts[finder] = new(big.Int).Set(reward)
+		}
+		return payouts
+	}
+	remainder := new(big.Int).Set(reward)
+	for worker, weight := range weights {
+		payout := new(big.Int).Mul(reward, weight)
+		payout.Div(payout, total)
+		payouts[worker] = payout
+		remainder.Sub(remainder, payout)
+	}
+	if remainder.Sign() > 0 && finder != "" {
+		if _, ok := payouts[finder]; !ok {
+			payouts[finder] = new(big.Int)
+		}
+		payouts[finder].Add(payouts[finder], remainder)
+	}
+	return payouts
+}
+
+// Workers returns the share accounting of all workers, sorted by name.
+func (l *ShareLedger) Workers() []*WorkerStats {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	workers := make([]*WorkerStats, 0, len(l.workers))
+	for _, stats := range l.workers {
+		workers = append(workers, stats.copy())
+	}
+	sort.Slice(workers, func(i, j int) bool { return workers[i].Worker < workers[j].Worker })
+	return workers
+}
+
+// Worker returns the share accounting of a worker, or nil if it never
+// submitted a share.
+func (l *ShareLedger) Worker(worker string) *WorkerStats {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	if stats, ok := l.workers[worker]; ok {
+		return stats.copy()
+	}
+	return nil
+}
+
+// Blocks returns the last count found blocks, most recent first.
+func (l *ShareLedger) Blocks(count uint64) []*FoundBlock {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	var blocks []*FoundBlock
+	for index := l.head.Blocks; index > 0 && uint64(len(blocks)) < count; index-- {
+		blob, err := l.db.Get(blockKey(index - 1))
+		if err != nil {
+			break
+		}
+		block := new(FoundBlock)
+		if err := json.Unmarshal(blob, block); err != nil {
+			log.Error("[ShareLedger] corrupt found block", "index", index-1, "error", err)
+			break
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks
+}
+
+// Pending returns what each worker would be paid if the pool found a block
+// with the given reward now.
+func (l *ShareLedger) Pending(reward *big.Int) map[string]*big.Int {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+
+	return calcPayouts(l.shares, reward, "")
+}
+
+func (s *WorkerStats) copy() *WorkerStats {
+	cpy := *s
+	cpy.Paid = new(big.Int).Set(s.Paid)
+	return &cpy
+}
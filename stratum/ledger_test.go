@@ -0,0 +1,133 @@
+package stratum
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/simplechain-org/go-simplechain/common"
+	"github.com/simplechain-org/go-simplechain/core/rawdb"
+	"github.com/simplechain-org/go-simplechain/core/types"
+)
+
+// testChain is a canonical chain made of the headers of its numbers.
+type testChain map[uint64]*types.Header
+
+func (c testChain) GetHeaderByNumber(number uint64) *types.Header {
+	return c[number]
+}
+
+func (c testChain) set(number uint64, extra byte) common.Hash {
+	c[number] = &types.Header{Number: new(big.Int).SetUint64(number), Extra: []byte{extra}}
+	return c[number].Hash()
+}
+
+func TestLedgerPPLNS(t *testing.T) {
+	db := rawdb.NewMemoryDatabase()
+	ledger, err := NewShareLedger(db, PayoutPPLNS, 3)
+	if err != nil {
+		t.Fatalf("failed to create ledger: %v", err)
+	}
+	// Only the last three accepted shares are paid
+	ledger.Record("a", 100, ShareAccepted)
+	ledger.Record("b", 100, ShareAccepted)
+	ledger.Record("b", 100, ShareStale)
+	ledger.Record("a", 100, ShareInvalid)
+	ledger.Record("b", 200, ShareAccepted)
+	ledger.Record("c", 100, ShareAccepted)
+
+	chain := make(testChain)
+	block := ledger.BlockFound(1, chain.set(1, 0), "c", big.NewInt(1001))
+	if stats := ledger.Worker("b"); stats.Paid.Sign() != 0 {
+		t.Errorf("payout credited before the block is canonical: %v", stats.Paid)
+	}
+	ledger.ChainHead(1, chain)
+	if have := block.Payouts["b"].Int64(); have != 750 {
+		t.Errorf("payout of b mismatch: have %d, want %d", have, 750)
+	}
+	if have := block.Payouts["c"].Int64(); have != 251 {
+		t.Errorf("payout of finder c mismatch: have %d, want %d", have, 251)
+	}
+	if _, ok := block.Payouts["a"]; ok {
+		t.Errorf("share outside of the window paid")
+	}
+	stats := ledger.Worker("b")
+	if stats.Accepted != 2 || stats.Stale != 1 || stats.AcceptedWeight != 300 || stats.Paid.Int64() != 750 {
+		t.Errorf("stats of b mismatch: %+v", stats)
+	}
+	// The ledger survives a restart
+	reopened, err := NewShareLedger(db, PayoutPPLNS, 3)
+	if err != nil {
+		t.Fatalf("failed to reopen ledger: %v", err)
+	}
+	if stats := reopened.Worker("a"); stats == nil || stats.Invalid != 1 || stats.InvalidWeight != 100 {
+		t.Errorf("stats of a not restored: %+v", stats)
+	}
+	if blocks := reopened.Blocks(10); len(blocks) != 1 || blocks[0].Payouts["b"].Int64() != 750 || !blocks[0].Credited {
+		t.Errorf("found blocks not restored: %v", blocks)
+	}
+	if pending := reopened.Pending(big.NewInt(400)); len(pending) != 2 || pending["b"].Int64() != 300 {
+		t.Errorf("shares window not restored: %v", pending)
+	}
+}
+
+func TestLedgerPROP(t *testing.T) {
+	ledger, err := NewShareLedger(rawdb.NewMemoryDatabase(), PayoutPROP, 0)
+	if err != nil {
+		t.Fatalf("failed to create ledger: %v", err)
+	}
+	ledger.Record("a", 100, ShareAccepted)
+	ledger.Record("b", 300, ShareAccepted)
+	block := ledger.BlockFound(1, common.Hash{1}, "a", big.NewInt(1000))
+	if block.Payouts["a"].Int64() != 250 || block.Payouts["b"].Int64() != 750 {
+		t.Errorf("first round payouts mismatch: %v", block.Payouts)
+	}
+	// A new round starts with the block
+	ledger.Record("b", 100, ShareAccepted)
+	block = ledger.BlockFound(2, common.Hash{2}, "b", big.NewInt(1000))
+	if len(block.Payouts) != 1 || block.Payouts["b"].Int64() != 1000 {
+		t.Errorf("second round payouts mismatch: %v", block.Payouts)
+	}
+	if _, err := NewShareLedger(rawdb.NewMemoryDatabase(), "solo", 0); err != errUnknownPayout {
+		t.Errorf("unknown scheme error mismatch: have %v, want %v", err, errUnknownPayout)
+	}
+}
+
+func TestLedgerReorg(t *testing.T) {
+	db := rawdb.NewMemoryDatabase()
+	ledger, err := NewShareLedger(db, PayoutPROP, 0)
+	if err != nil {
+		t.Fatalf("failed to create ledger: %v", err)
+	}
+	chain := make(testChain)
+	ledger.Record("a", 100, ShareAccepted)
+	ledger.BlockFound(1, chain.set(1, 0), "a", big.NewInt(1000))
+	ledger.ChainHead(1, chain)
+	if paid := ledger.Worker("a").Paid.Int64(); paid != 1000 {
+		t.Fatalf("canonical block payout mismatch: have %d, want %d", paid, 1000)
+	}
+	// A competing block replaces the found one, the payout is reverted
+	chain.set(1, 1)
+	ledger.ChainHead(1, chain)
+	if paid := ledger.Worker("a").Paid.Int64(); paid != 0 {
+		t.Errorf("reorged out block still credited: %d", paid)
+	}
+	if blocks := ledger.Blocks(1); blocks[0].Credited {
+		t.Errorf("reorged out block marked credited")
+	}
+	// The found block is reorged back in after a restart of the ledger
+	reopened, err := NewShareLedger(db, PayoutPROP, 0)
+	if err != nil {
+		t.Fatalf("failed to reopen ledger: %v", err)
+	}
+	chain.set(1, 0)
+	reopened.ChainHead(1, chain)
+	if paid := reopened.Worker("a").Paid.Int64(); paid != 1000 {
+		t.Errorf("reorged in block payout mismatch: have %d, want %d", paid, 1000)
+	}
+	// Once deep enough the block is final
+	chain.set(1, 1)
+	reopened.ChainHead(1+confirmDepth, chain)
+	if paid := reopened.Worker("a").Paid.Int64(); paid != 1000 {
+		t.Errorf("confirmed block payout reverted: %d", paid)
+	}
+}
@@ -1,8 +1,10 @@
 package stratum
 
 import (
+	"crypto/tls"
 	"math/big"
 	"net"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -33,25 +35,49 @@ type MineTask struct {
 	NonceEnd   uint64
 }
 
+type submittedNonce struct {
+	worker string
+	nonce  uint64
+}
+
+type stratumListener struct {
+	address  string
+	mode     ProtocolMode
+	listener net.Listener
+}
+
 type Server struct {
 	fanOut             bool // if true, send same task for every session
 	maxConn            uint
-	address            string
+	maxConnPerIP       uint // 0 means no limit
+	tlsConfig          *tls.Config
 	calcHashRate       bool
-	listener           net.Listener
+	listeners          []*stratumListener
+	listenerLock       sync.Mutex
+	extranonce         uint16            // last extranonce1 handed out to a standard session
+	extranonces        map[uint16]string // session id by extranonce1 in use
+	sessionExtranonces map[string]uint16 // extranonce1 by standard session id
+	extranonceLock     sync.Mutex
 	rateLimiter        chan struct{}
 	resultChan         chan uint64
 	stop               chan struct{}
 	closed             int64
 	running            int32
 	auth               Auth
+	ledger             *ShareLedger
+	sessionStore       *SessionStore
+	vardiff            VardiffConfig
+	finder             atomic.Value // worker that submitted the last block nonce
 	newMineTask        chan *MineTask
-	newNonce           chan uint64
+	newNonce           chan submittedNonce
 	newUnauthorized    chan *Session
 	newSession         chan string
 	sessionClose       chan string
 	requestHashRate    chan chan uint64
 	requestStratumTask chan chan *StratumTask
+	ipConns            map[string]uint   // open connections by remote IP
+	sessionIPs         map[string]string // remote IP by session id
+	ipLock             sync.Mutex
 	//todo
 	acceptQuantity uint64
 	hashRateMeter  []uint64
@@ -60,19 +86,24 @@ type Server struct {
 
 func NewServer(address string, maxConn uint, auth Auth, calcHashRate bool, fanOut bool) (*Server, error) {
 	server := &Server{
-		address:            address,
+		listeners:          []*stratumListener{{address: address, mode: ProtocolLegacy}},
 		maxConn:            maxConn,
 		fanOut:             fanOut,
 		calcHashRate:       calcHashRate,
+		vardiff:            DefaultVardiffConfig,
 		running:            0,
 		auth:               auth,
 		newMineTask:        make(chan *MineTask, 10),
-		newNonce:           make(chan uint64, 10),
+		newNonce:           make(chan submittedNonce, 10),
 		newUnauthorized:    make(chan *Session, 10),
 		newSession:         make(chan string, 10),
 		sessionClose:       make(chan string, 10),
 		requestHashRate:    make(chan chan uint64, 10),
 		requestStratumTask: make(chan chan *StratumTask, 10),
+		ipConns:            make(map[string]uint),
+		sessionIPs:         make(map[string]string),
+		extranonces:        make(map[uint16]string),
+		sessionExtranonces: make(map[string]uint16),
 	}
 	_, _, err := net.SplitHostPort(address)
 	if err != nil {
@@ -82,6 +113,23 @@ func NewServer(address string, maxConn uint, auth Auth, calcHashRate bool, fanOu
 	return server, nil
 }
 
+// AddListener makes the server accept connections on another address, in the
+// given protocol mode. It must be called before the server is started.
+func (this *Server) AddListener(address string, mode ProtocolMode) error {
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		log.Error("[Server] wrong address format", "error", err)
+		return err
+	}
+	this.listeners = append(this.listeners, &stratumListener{address: address, mode: mode})
+	return nil
+}
+
+// SetProtocolMode sets the protocol mode of the listener created with the
+// server.
+func (this *Server) SetProtocolMode(mode ProtocolMode) {
+	this.listeners[0].mode = mode
+}
+
 func (this *Server) Start() {
 	log.Info("[Server] starting")
 	if atomic.LoadInt32(&this.running) == 1 {
@@ -96,39 +144,55 @@ func (this *Server) Start() {
 	for ; i < this.maxConn; i++ {
 		this.rateLimiter <- struct{}{}
 	}
-	go this.listen()
+	for _, l := range this.listeners {
+		go this.listen(l)
+	}
 	go this.mineTaskLoop()
 }
 func (this *Server) Stop() {
 	if atomic.CompareAndSwapInt64(&this.closed, 0, 1) {
 		close(this.stop)
-		if this.listener != nil {
-			this.listener.Close()
-			this.listener = nil
+		this.listenerLock.Lock()
+		for _, l := range this.listeners {
+			if l.listener != nil {
+				l.listener.Close()
+				l.listener = nil
+			}
 		}
+		this.listenerLock.Unlock()
 		atomic.StoreInt32(&this.running, 0)
 		log.Info("[Server] Stopped")
 	}
 }
-func (this *Server) listen() {
+func (this *Server) listen(l *stratumListener) {
 	defer func() {
-		log.Warn("[Server] listen exited")
+		log.Warn("[Server] listen exited", "address", l.address)
 	}()
-	listener, err := net.Listen("tcp", this.address)
+	var listener net.Listener
+	var err error
+	if this.tlsConfig != nil {
+		listener, err = tls.Listen("tcp", l.address, this.tlsConfig)
+	} else {
+		listener, err = net.Listen("tcp", l.address)
+	}
 	if err != nil {
 		panic(err)
 	}
-	this.listener = listener
-	log.Info("[Server] listen for accepting")
+	this.listenerLock.Lock()
+	l.listener = listener
+	this.listenerLock.Unlock()
+	log.Info("[Server] listen for accepting", "address", l.address, "mode", l.mode)
 	defer func() {
-		if this.listener != nil {
-			err := this.listener.Close()
+		this.listenerLock.Lock()
+		if l.listener != nil {
+			err := l.listener.Close()
 			if err != nil {
 				log.Error("[Server] listener close", "error", err)
 			}
-			this.listener = nil
+			l.listener = nil
 		}
-		log.Info("[Server] Listen stopped")
+		this.listenerLock.Unlock()
+		log.Info("[Server] Listen stopped", "address", l.address)
 	}()
 	for {
 		select {
@@ -137,26 +201,62 @@ func (this *Server) listen() {
 			return
 		default:
 			this.acquire()
-			conn, err := this.listener.Accept()
+			conn, err := listener.Accept()
 			if err != nil {
 				log.Error("[Server] Accept", "error", err)
 				this.putBack()
 				return
 			}
-			this.handleConn(conn)
+			this.handleConn(conn, l.mode)
 		}
 	}
 }
 
+// SetTLS makes the server accept TLS connections only, with the certificate
+// and key loaded from the given PEM files.
+func (this *Server) SetTLS(certFile, keyFile string) error {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return err
+	}
+	this.tlsConfig = &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	return nil
+}
+
+// SetMaxConnPerIP limits the number of connections from a single IP, in
+// addition to the limit of all connections.
+func (this *Server) SetMaxConnPerIP(max uint) {
+	this.maxConnPerIP = max
+}
+
 //new connection
-func (this *Server) handleConn(conn net.Conn) {
+func (this *Server) handleConn(conn net.Conn, mode ProtocolMode) {
 	sessionId := this.newSessionId()
+	if !this.acquireIP(sessionId, conn) {
+		conn.Close()
+		this.putBack()
+		return
+	}
 	log.Warn("[Server] New session", "id", sessionId)
-	newSession := NewSession(this.auth, sessionId, conn, uint64(InitDifficulty), MinDifficulty, this, this.calcHashRate, HashRateLen)
+	newSession := NewSession(this.auth, sessionId, conn, mode, this.vardiff, this, this.calcHashRate, HashRateLen)
+	if mode == ProtocolStandard {
+		extranonce1, err := this.acquireExtranonce(sessionId)
+		if err != nil {
+			log.Warn("[Server] rejected standard session", "id", sessionId, "error", err)
+			conn.Close()
+			this.releaseIP(sessionId)
+			this.putBack()
+			return
+		}
+		newSession.extranonce1 = extranonce1
+	}
 	newSession.RegisterAuthorizeFunc(this.onSessionAuthorize)
 	newSession.RegisterCloseFunc(this.onSessionClose)
 	newSession.RegisterSubmitFunc(this.onSessionSubmit)
-	newSession.Start(this.calcHashRate)
+	newSession.Start()
 	this.addUnauthorizedSession(newSession)
 }
 
@@ -234,13 +334,15 @@ func (this *Server) mineTaskLoop() {
 			session.dispatchTask(notifyTask)
 		case session := <-this.newUnauthorized:
 			unauthorized[session.sessionId] = session
-		case nonce := <-this.newNonce:
+		case submitted := <-this.newNonce:
+			nonce := submitted.nonce
 			serverTarget := new(big.Int).Div(maxUint256, task.Difficulty)
 			_, result := scrypt.ScryptHash(task.Hash.Bytes(), nonce)
 			intResult := new(big.Int).SetBytes(result)
 			if intResult.Cmp(serverTarget) <= 0 {
 				if !task.IsSubmit {
 					log.Trace("[Server] mineTaskLoop submit", "nonce", nonce, "taskId", task.Id)
+					this.finder.Store(submitted.worker)
 					this.submitNonce(nonce)
 					task.IsSubmit = true
 				} else {
@@ -323,14 +425,60 @@ func (this *Server) Dispatch(hash common.Hash, difficulty *big.Int, nonceBegin,
 }
 
 //session submit
-func (this *Server) onSessionSubmit(nonce uint64) {
+func (this *Server) onSessionSubmit(worker string, nonce uint64) {
 	select {
-	case this.newNonce <- nonce:
+	case this.newNonce <- submittedNonce{worker: worker, nonce: nonce}:
 	default:
 		log.Warn("[Server] onSessionSubmit newNonce block")
 	}
 }
 
+// SetLedger makes the server account the shares of its sessions in ledger.
+func (this *Server) SetLedger(ledger *ShareLedger) {
+	this.ledger = ledger
+}
+
+// SetSessionStore makes sessions resume the difficulty their worker had
+// before reconnecting.
+func (this *Server) SetSessionStore(store *SessionStore) {
+	this.sessionStore = store
+}
+
+// SetVardiff configures the variable difficulty of the sessions.
+func (this *Server) SetVardiff(config VardiffConfig) {
+	this.vardiff = config.sanitize()
+}
+
+func (this *Server) Ledger() *ShareLedger {
+	return this.ledger
+}
+
+//session share
+func (this *Server) recordShare(worker string, difficulty uint64, state int) {
+	if this.ledger != nil {
+		this.ledger.Record(worker, difficulty, state)
+	}
+}
+
+// BlockFound is called by the agent once it sealed a block with the last
+// nonce submitted by the server, to share its reward between the workers.
+func (this *Server) BlockFound(number uint64, hash common.Hash, reward *big.Int) {
+	if this.ledger == nil {
+		return
+	}
+	finder, _ := this.finder.Load().(string)
+	this.ledger.BlockFound(number, hash, finder, reward)
+}
+
+// ChainHead is called by the agent on every new chain head, to credit the
+// rewards of the found blocks which are canonical and revert the others.
+func (this *Server) ChainHead(head uint64, chain CanonicalChain) {
+	if this.ledger == nil {
+		return
+	}
+	this.ledger.ChainHead(head, chain)
+}
+
 //submit to node
 func (this *Server) submitNonce(nonce uint64) {
 	select {
@@ -391,6 +539,66 @@ func (this *Server) acquire() {
 	<-this.rateLimiter
 }
 
+// acquireIP accounts a connection to its remote IP, refusing it if the IP
+// reached its connection limit.
+func (this *Server) acquireIP(sessionId string, conn net.Conn) bool {
+	ip, _, err := net.SplitHostPort(conn.RemoteAddr().String())
+	if err != nil {
+		ip = conn.RemoteAddr().String()
+	}
+	this.ipLock.Lock()
+	defer this.ipLock.Unlock()
+
+	if this.maxConnPerIP > 0 && this.ipConns[ip] >= this.maxConnPerIP {
+		log.Warn("[Server] too many connections from IP", "ip", ip, "max", this.maxConnPerIP)
+		return false
+	}
+	this.ipConns[ip]++
+	this.sessionIPs[sessionId] = ip
+	return true
+}
+
+func (this *Server) releaseIP(sessionId string) {
+	this.ipLock.Lock()
+	defer this.ipLock.Unlock()
+
+	ip, ok := this.sessionIPs[sessionId]
+	if !ok {
+		return
+	}
+	delete(this.sessionIPs, sessionId)
+	if this.ipConns[ip]--; this.ipConns[ip] == 0 {
+		delete(this.ipConns, ip)
+	}
+}
+
+// acquireExtranonce hands out the next extranonce1 not used by another
+// standard session, so that no two sessions search the same nonces.
+func (this *Server) acquireExtranonce(sessionId string) (uint16, error) {
+	this.extranonceLock.Lock()
+	defer this.extranonceLock.Unlock()
+
+	for i := 0; i < 1<<(8*extranonce1Size); i++ {
+		this.extranonce++
+		if _, used := this.extranonces[this.extranonce]; !used {
+			this.extranonces[this.extranonce] = sessionId
+			this.sessionExtranonces[sessionId] = this.extranonce
+			return this.extranonce, nil
+		}
+	}
+	return 0, errNoExtranonce
+}
+
+func (this *Server) releaseExtranonce(sessionId string) {
+	this.extranonceLock.Lock()
+	defer this.extranonceLock.Unlock()
+
+	if extranonce1, ok := this.sessionExtranonces[sessionId]; ok {
+		delete(this.sessionExtranonces, sessionId)
+		delete(this.extranonces, extranonce1)
+	}
+}
+
 func (this *Server) putBack() {
 	select {
 	case this.rateLimiter <- struct{}{}:
@@ -414,6 +622,8 @@ func (this *Server) ReadResult(ch chan uint64) {
 }
 func (this *Server) onSessionClose(sessionId string) {
 	this.putBack()
+	this.releaseIP(sessionId)
+	this.releaseExtranonce(sessionId)
 	select {
 	case this.sessionClose <- sessionId:
 	default:
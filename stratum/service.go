@@ -0,0 +1,55 @@
+package stratum
+
+import (
+	"sync/atomic"
+
+	"github.com/simplechain-org/go-simplechain/ethdb"
+	"github.com/simplechain-org/go-simplechain/p2p"
+	"github.com/simplechain-org/go-simplechain/rpc"
+)
+
+// Service registers the stratum server with the node, so that its share
+// ledger is served over RPC. The server itself is started and stopped by the
+// stratum agent of the miner.
+type Service struct {
+	server *Server
+	ledger *ShareLedger
+	db     ethdb.Database
+}
+
+// NewService attaches a share ledger and a session store kept in db to the
+// server.
+func NewService(server *Server, db ethdb.Database, scheme string, window uint64) (*Service, error) {
+	ledger, err := NewShareLedger(db, scheme, window)
+	if err != nil {
+		return nil, err
+	}
+	server.SetLedger(ledger)
+	server.SetSessionStore(NewSessionStore(db))
+	return &Service{server: server, ledger: ledger, db: db}, nil
+}
+
+func (s *Service) Server() *Server {
+	return s.server
+}
+
+func (s *Service) Protocols() []p2p.Protocol { return nil }
+
+func (s *Service) APIs() []rpc.API {
+	return []rpc.API{{
+		Namespace: "stratum",
+		Version:   "1.0",
+		Service:   &API{ledger: s.ledger},
+		Public:    false,
+	}}
+}
+
+func (s *Service) Start(server *p2p.Server) error { return nil }
+
+func (s *Service) Stop() error {
+	if atomic.LoadInt32(&s.server.running) == 1 {
+		s.server.Stop()
+	}
+	s.db.Close()
+	return nil
+}
@@ -4,6 +4,8 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	crand "crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -26,8 +28,13 @@ import (
 
 var (
 	paramNumbersWrong = errors.New("[stratum]Params number incorrect")
-	PeriodMax         = float64(5) // s
-	PeriodMin         = float64(1.5)
+
+	// Deprecated: the share interval of the sessions is set by
+	// VardiffConfig.TargetTime and VardiffConfig.Variance.
+	PeriodMax = float64(5) // s
+	// Deprecated: the share interval of the sessions is set by
+	// VardiffConfig.TargetTime and VardiffConfig.Variance.
+	PeriodMin = float64(1.5)
 
 	HashRateLen = 90
 
@@ -40,6 +47,10 @@ type Session struct {
 	sessionId string
 	minerName string
 	minerIp   string
+	challenge string // subscription id, signed by SignatureAuth miners
+	mode      ProtocolMode
+	// extranonce1 prefixes the nonces of the session in standard mode
+	extranonce1 uint16
 	conn      net.Conn
 
 	closed int64
@@ -50,7 +61,7 @@ type Session struct {
 
 	onClose     func(sessionId string)
 	onAuthorize func(sessionId string)
-	onSubmit    func(nonce uint64)
+	onSubmit    func(worker string, nonce uint64)
 
 	auth Auth
 
@@ -65,46 +76,46 @@ type Session struct {
 	calcHashRate bool
 
 	adjustDifficultyChan chan AdjustResult
-	minDifficulty        uint64
-	initDifficulty       uint64
+	vardiff              VardiffConfig
 	hashRateMeterLen     int
 	hashRateChan         chan chan uint64
 	difficultyChan       chan chan uint64
 	lastSubmitTimeChan   chan chan int64
 	nonceMeterChan       chan chan map[uint64]NonceMeter
 	nonceDifficulty      chan uint64
+	resume               chan string
 }
 
-func NewSession(auth Auth, sessionId string, conn net.Conn, initDifficulty uint64, minDifficulty uint64, server *Server, calcHashRate bool, hashRateMeterLen int) *Session {
+func NewSession(auth Auth, sessionId string, conn net.Conn, mode ProtocolMode, vardiff VardiffConfig, server *Server, calcHashRate bool, hashRateMeterLen int) *Session {
 	session := &Session{
-		sessionId:          sessionId,
-		conn:               conn,
-		response:           make(chan interface{}, 100),
-		initDifficulty:     initDifficulty,
-		minDifficulty:      minDifficulty,
-		auth:               auth,
-		stop:               make(chan struct{}),
-		newTask:            make(chan *StratumTask, 10),
-		newNonce:           make(chan NonceResult, 10),
-		server:             server,
-		calcHashRate:       calcHashRate,
-		hashRateMeterLen:   hashRateMeterLen,
-		hashRateChan:       make(chan chan uint64, 10),
-		difficultyChan:     make(chan chan uint64, 10),
-		lastSubmitTimeChan: make(chan chan int64, 10),
-		nonceMeterChan:     make(chan chan map[uint64]NonceMeter, 10),
-		nonceDifficulty:    make(chan uint64, 10),
+		mode:                 mode,
+		sessionId:            sessionId,
+		conn:                 conn,
+		response:             make(chan interface{}, 100),
+		vardiff:              vardiff.sanitize(),
+		auth:                 auth,
+		stop:                 make(chan struct{}),
+		newTask:              make(chan *StratumTask, 10),
+		newNonce:             make(chan NonceResult, 10),
+		server:               server,
+		calcHashRate:         calcHashRate,
+		hashRateMeterLen:     hashRateMeterLen,
+		hashRateChan:         make(chan chan uint64, 10),
+		difficultyChan:       make(chan chan uint64, 10),
+		lastSubmitTimeChan:   make(chan chan int64, 10),
+		nonceMeterChan:       make(chan chan map[uint64]NonceMeter, 10),
+		nonceDifficulty:      make(chan uint64, 10),
+		adjustDifficultyChan: make(chan AdjustResult, 10),
+		resume:               make(chan string, 1),
 	}
 	return session
 }
 
-func (this *Session) Start(calcHashRate bool) {
+func (this *Session) Start() {
 	this.minerIp, _, _ = net.SplitHostPort(this.conn.RemoteAddr().String())
 	go this.handleRequest()
 	go this.handleResponse()
-	if calcHashRate {
-		go this.loop()
-	}
+	go this.loop()
 }
 
 func (this *Session) Close() {
@@ -127,7 +138,7 @@ func (this *Session) RegisterCloseFunc(onClose func(sessionId string)) {
 func (this *Session) RegisterAuthorizeFunc(onAuthorize func(sessionId string)) {
 	this.onAuthorize = onAuthorize
 }
-func (this *Session) RegisterSubmitFunc(onSubmit func(nonce uint64)) {
+func (this *Session) RegisterSubmitFunc(onSubmit func(worker string, nonce uint64)) {
 	this.onSubmit = onSubmit
 }
 func (this *Session) sendResponse(result interface{}) {
@@ -247,6 +258,7 @@ func (this *Session) dispatchAndVerify() {
 		log.Info("[Session] dispatchAndVerify exited", "sessionId", this.sessionId)
 	}()
 	difficulty := big.NewInt(10000)
+	sentDifficulty := new(big.Int).SetUint64(this.vardiff.InitDifficulty)
 	var taskId uint64 = 0
 	powHash := make([]byte, 32)
 	rand.Seed(time.Now().UnixNano())
@@ -255,15 +267,25 @@ func (this *Session) dispatchAndVerify() {
 		case <-this.stop:
 			return
 		case task := <-this.newTask:
-			if this.calcHashRate {
-				this.AdjustDifficulty(task.Difficulty.Uint64())
-				difficulty.SetUint64(this.GetDifficulty())
-			} else {
-				difficulty.SetBytes(task.Difficulty.Bytes())
-			}
+			this.AdjustDifficulty(task.Difficulty.Uint64())
+			difficulty.SetUint64(this.GetDifficulty())
 			taskId++
 			task.Id = taskId
 			copy(powHash, task.PowHash.Bytes())
+			if this.mode == ProtocolStandard {
+				if difficulty.Cmp(sentDifficulty) != 0 {
+					this.sendResponse(&Notify{
+						Method: "mining.set_difficulty",
+						Params: []interface{}{difficulty.Uint64()},
+					})
+					sentDifficulty.Set(difficulty)
+				}
+				this.sendResponse(&Notify{
+					Method: "mining.notify",
+					Params: task.toStandardJson(),
+				})
+				continue
+			}
 			notify := &Notify{
 				Id:     rand.Uint64(),
 				Method: "mining.notify",
@@ -287,13 +309,15 @@ func (this *Session) dispatchAndVerify() {
 					Method: nonceResult.Method,
 				}
 				this.sendResponse(response)
+				this.server.recordShare(this.minerName, difficulty.Uint64(), ShareStale)
+				continue
 			}
 			//check nonce
 			target := new(big.Int).Div(maxUint256, difficulty)
 			_, result := scrypt.ScryptHash(powHash, nonceResult.Nonce)
 			if new(big.Int).SetBytes(result).Cmp(target) <= 0 {
 				//expected nonce
-				this.onSubmit(nonceResult.Nonce)
+				this.onSubmit(this.minerName, nonceResult.Nonce)
 				//echo result:true
 				response := &Response{
 					Error:  nil,
@@ -302,6 +326,7 @@ func (this *Session) dispatchAndVerify() {
 					Method: nonceResult.Method,
 				}
 				this.sendResponse(response)
+				this.server.recordShare(this.minerName, difficulty.Uint64(), ShareAccepted)
 				this.NonceMeter(difficulty.Uint64())
 				this.checkNeedNewTask(difficulty.Uint64())
 			} else {
@@ -320,6 +345,7 @@ func (this *Session) dispatchAndVerify() {
 				}
 				//echo result:false
 				this.sendResponse(response)
+				this.server.recordShare(this.minerName, difficulty.Uint64(), ShareInvalid)
 				log.Warn("check nonce", "err:", msg)
 			}
 		}
@@ -342,7 +368,7 @@ func (this *Session) handleAuthorize(req *Request) error {
 	if !ok {
 		log.Warn("handleAuthorize password not ok")
 	}
-	if !this.auth.Auth(username, password) {
+	if !this.auth.Auth(username, password, this.challenge) {
 		log.Error("[Session]Auth Failed!", "passwd", req.Params[1], "IP", this.minerIp)
 		result := &Response{Id: req.Id, Error: &Error{Code: 11, Message: "auth failed"}, Result: false}
 		this.sendResponse(result)
@@ -356,6 +382,7 @@ func (this *Session) handleAuthorize(req *Request) error {
 	}
 	this.sendResponse(result)
 	if !this.authorize {
+		this.resume <- username
 		this.onAuthorize(this.sessionId)
 		this.authorize = true
 	}
@@ -364,8 +391,18 @@ func (this *Session) handleAuthorize(req *Request) error {
 
 //server -> client
 func (this *Session) handleSubscribe(req *Request) error {
-	subscriptionID := strconv.FormatInt(time.Now().Unix(), 16) + strconv.FormatInt(time.Now().Unix(), 16) + strconv.FormatInt(time.Now().Unix(), 16) + strconv.FormatInt(time.Now().Unix(), 16)
-	difficulty := strconv.FormatUint(this.initDifficulty, 16)
+	challenge := make([]byte, 16)
+	if _, err := crand.Read(challenge); err != nil {
+		return err
+	}
+	subscriptionID := hex.EncodeToString(challenge)
+	this.challenge = subscriptionID
+	if this.mode == ProtocolStandard {
+		this.handleStandardSubscribe(req, subscriptionID)
+		go this.dispatchAndVerify()
+		return nil
+	}
+	difficulty := strconv.FormatUint(this.vardiff.InitDifficulty, 16)
 	result := &SubscribeResult{
 		Error: nil,
 		Id:    req.Id,
@@ -388,6 +425,9 @@ func (this *Session) handleSubmit(req *Request) error {
 	if !this.authorize {
 		return errors.New("unauthorized")
 	}
+	if this.mode == ProtocolStandard {
+		return this.handleStandardSubmit(req)
+	}
 	// validate difficulty, submit share or reject
 	if len(req.Params) != 5 {
 		return paramNumbersWrong
@@ -437,9 +477,6 @@ func (this *Session) handleSubmit(req *Request) error {
 	return nil
 }
 func (this *Session) checkNeedNewTask(difficulty uint64) {
-	if !this.calcHashRate {
-		return
-	}
 	task := this.server.GetCurrentMineTask()
 	if task != nil {
 		if this.AdjustDifficulty(task.Difficulty.Uint64()) {
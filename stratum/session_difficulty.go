@@ -2,7 +2,6 @@ package stratum
 
 import (
 	"container/ring"
-	"math"
 	"time"
 
 	"github.com/simplechain-org/go-simplechain/log"
@@ -36,15 +35,31 @@ type NonceMeter struct {
 func (this *Session) loop() {
 	interval := 3
 	ticker := time.NewTicker(3 * time.Second)
+	defer ticker.Stop()
 	var lastAcceptQuantity uint64
 	nonceMeter := make(map[uint64]*NonceMeter)
 	hashRateMeter := ring.New(this.hashRateMeterLen)
-	var difficulty uint64 = this.initDifficulty
+	var difficulty uint64 = this.vardiff.InitDifficulty
 	var hashRate uint64
-	var lastSubmitTime int64 = time.Now().Unix()
-	var factorDiff uint64
+	var worker string
+	lastSubmitTime := time.Now()
+	lastRetarget := time.Now()
 	for {
 		select {
+		case <-this.stop:
+			if worker != "" && this.server.sessionStore != nil {
+				this.server.sessionStore.Save(worker, difficulty)
+			}
+			return
+		case worker = <-this.resume:
+			//resume the difficulty of the worker before its reconnection
+			if this.server.sessionStore != nil {
+				if saved, ok := this.server.sessionStore.Load(worker); ok {
+					difficulty = this.vardiff.clamp(saved)
+					lastRetarget = time.Now()
+					log.Info("[Session] resumed", "worker", worker, "difficulty", difficulty)
+				}
+			}
 		case difficultyResult := <-this.difficultyChan:
 			log.Trace("session get current ", "difficulty", difficulty)
 			difficultyResult <- difficulty
@@ -62,7 +77,7 @@ func (this *Session) loop() {
 			log.Trace("get current NonceMeter:", "NonceMeter", result)
 			need <- result
 		case nonceDifficulty := <-this.nonceDifficulty:
-			lastSubmitTime = time.Now().UnixNano()
+			lastSubmitTime = time.Now()
 			if meter, ok := nonceMeter[nonceDifficulty]; ok {
 				meter.times++
 			} else {
@@ -95,46 +110,17 @@ func (this *Session) loop() {
 		//难度调整
 		case adjustRequest := <-this.adjustDifficultyChan:
 			prev := difficulty
-			if hashRate == 0 && difficulty > this.minDifficulty {
-				//说明没有提交
-				//修改为按时间估算来衰减 3秒钟
-				now := time.Now().UnixNano()
-				if now-lastSubmitTime > 3e9 {
-					if difficulty/4 < this.minDifficulty {
-						factorDiff = this.minDifficulty
-					} else {
-						//间隔多少秒
-						timeTemp := float64(now-lastSubmitTime) / 1e9
-						n := int(math.Log2(timeTemp / PeriodMin))
-						factorDiff = difficulty / (2 << uint(n))
-					}
-				}
-			} else {
-				//单位 秒
-				timeTemp := float64(difficulty) / float64(hashRate)
-				if timeTemp > PeriodMax {
-					if difficulty > this.minDifficulty {
-						factorDiff = difficulty / 2
-					}
-				} else if timeTemp < PeriodMin {
-					pow10 := int(math.Log10(PeriodMin / timeTemp))
-					n := math.Log2(PeriodMin / timeTemp / math.Pow10(pow10))
-					factorDiff = difficulty * (2 << uint(n)) * uint64(math.Pow10(pow10))
-				}
-			}
-			//调整后的难度
-			if factorDiff > 0 {
-				difficulty = factorDiff
-				//如果调整后的难度比最小难度还要小
-				if factorDiff < this.minDifficulty {
-					//要至少保证最小难度
-					difficulty = this.minDifficulty
-				}
+			if time.Since(lastRetarget) >= this.vardiff.RetargetPeriod {
+				difficulty = this.vardiff.retarget(difficulty, hashRate, time.Since(lastSubmitTime), adjustRequest.difficulty)
+				lastRetarget = time.Now()
 			}
 			//如果难度比sipe下发的难度还要大，那么就修正为sipe下发的难度
 			if difficulty > adjustRequest.difficulty {
 				difficulty = adjustRequest.difficulty
 			}
+			if prev != difficulty && worker != "" && this.server.sessionStore != nil {
+				this.server.sessionStore.Save(worker, difficulty)
+			}
 			adjustRequest.adjust <- prev != difficulty
 		}
 	}
@@ -0,0 +1,64 @@
+package stratum
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/simplechain-org/go-simplechain/ethdb"
+	"github.com/simplechain-org/go-simplechain/log"
+)
+
+var (
+	sessionPrefix = []byte("stratum-session-") // sessionPrefix + worker name -> saved session
+
+	// SessionExpiry is how long the state of a disconnected worker is kept
+	// for it to resume.
+	SessionExpiry = 24 * time.Hour
+)
+
+type savedSession struct {
+	Difficulty uint64 `json:"difficulty"`
+	Updated    int64  `json:"updated"` // unix time of the last save
+}
+
+// SessionStore keeps the difficulty of the workers across reconnections and
+// restarts of the node.
+type SessionStore struct {
+	db ethdb.Database
+}
+
+func NewSessionStore(db ethdb.Database) *SessionStore {
+	return &SessionStore{db: db}
+}
+
+func sessionKey(worker string) []byte {
+	return append(append([]byte{}, sessionPrefix...), worker...)
+}
+
+// Load returns the saved difficulty of a worker, if it has not expired.
+func (s *SessionStore) Load(worker string) (uint64, bool) {
+	blob, err := s.db.Get(sessionKey(worker))
+	if err != nil {
+		return 0, false
+	}
+	var saved savedSession
+	if err := json.Unmarshal(blob, &saved); err != nil {
+		log.Warn("[SessionStore] corrupt session", "worker", worker, "error", err)
+		return 0, false
+	}
+	if time.Since(time.Unix(saved.Updated, 0)) > SessionExpiry {
+		return 0, false
+	}
+	return saved.Difficulty, true
+}
+
+// Save stores the current difficulty of a worker.
+func (s *SessionStore) Save(worker string, difficulty uint64) {
+	blob, err := json.Marshal(savedSession{Difficulty: difficulty, Updated: time.Now().Unix()})
+	if err != nil {
+		return
+	}
+	if err := s.db.Put(sessionKey(worker), blob); err != nil {
+		log.Error("[SessionStore] failed to save session", "worker", worker, "error", err)
+	}
+}
@@ -0,0 +1,60 @@
+package stratum
+
+import (
+	"strings"
+
+	"github.com/simplechain-org/go-simplechain/accounts"
+	"github.com/simplechain-org/go-simplechain/common"
+	"github.com/simplechain-org/go-simplechain/common/hexutil"
+	"github.com/simplechain-org/go-simplechain/crypto"
+	"github.com/simplechain-org/go-simplechain/log"
+)
+
+// SignatureAuth authenticates miners owning the address they mine for. The
+// username is the address, optionally followed by a dot and the worker name,
+// and the password the signature of the session challenge by the address, as
+// made by personal_sign or a keystore:
+//
+//	username = 0x<address>[.<worker>]
+//	password = sign(keccak256("\x19Ethereum Signed Message:\n" + len(challenge) + challenge))
+type SignatureAuth struct{}
+
+func NewSignatureAuth() *SignatureAuth {
+	return &SignatureAuth{}
+}
+
+func (this *SignatureAuth) Auth(username string, passwd string, challenge string) bool {
+	if challenge == "" {
+		log.Warn("[SignatureAuth] authorize before subscribe", "username", username)
+		return false
+	}
+	address, ok := minerAddress(username)
+	if !ok {
+		return false
+	}
+	sig, err := hexutil.Decode(passwd)
+	if err != nil || len(sig) != crypto.SignatureLength {
+		return false
+	}
+	// Signatures made by personal_sign carry a legacy recovery id
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+	pubkey, err := crypto.SigToPub(accounts.TextHash([]byte(challenge)), sig)
+	if err != nil {
+		return false
+	}
+	return crypto.PubkeyToAddress(*pubkey) == address
+}
+
+// minerAddress parses the address part of a 0x<address>[.<worker>] username.
+func minerAddress(username string) (common.Address, bool) {
+	account := username
+	if i := strings.IndexByte(username, '.'); i >= 0 {
+		account = username[:i]
+	}
+	if !common.IsHexAddress(account) {
+		return common.Address{}, false
+	}
+	return common.HexToAddress(account), true
+}
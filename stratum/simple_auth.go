@@ -10,7 +10,7 @@ func NewSimpleAuth(passwd string) *SimpleAuth {
 	}
 }
 
-func (this *SimpleAuth) Auth(username string, passwd string) bool {
+func (this *SimpleAuth) Auth(username string, passwd string, challenge string) bool {
 	if this.passwd == "" || this.passwd == passwd {
 		return true
 	} else {
@@ -18,6 +18,9 @@ func (this *SimpleAuth) Auth(username string, passwd string) bool {
 	}
 }
 
+// Auth authenticates the mining.authorize request of a session. The challenge
+// is the random subscription id the session was sent with mining.subscribe,
+// or empty if the miner authorizes before subscribing.
 type Auth interface {
-	Auth(username string, passwd string) bool
+	Auth(username string, passwd string, challenge string) bool
 }
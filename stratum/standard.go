@@ -0,0 +1,206 @@
+package stratum
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/simplechain-org/go-simplechain/log"
+)
+
+// ProtocolMode is the stratum dialect spoken on a listener.
+type ProtocolMode int
+
+const (
+	// ProtocolLegacy is the SimpleChain dialect: mining.notify carries the nonce
+	// range and the difficulty of the session, split by the server.
+	ProtocolLegacy ProtocolMode = iota
+
+	// ProtocolStandard is the mining.set_difficulty/mining.notify/mining.submit
+	// flow of the common stratum miners. The 64-bit nonce is made of the
+	// extranonce1 of the session, the extranonce2 rolled by the miner and the
+	// 32-bit nonce of the header:
+	//
+	//	nonce = extranonce1 (2 bytes) | extranonce2 (2 bytes) | nonce (4 bytes)
+	//
+	// SimpleChain blocks are not sealed over a Bitcoin header: the miner hashes
+	// powHash | powHash | 8 zero bytes | nonce (big endian) with the scrypt of
+	// the chain, so it needs the SimpleChain kernel. The powHash is sent in the
+	// prevhash field of mining.notify, in the word order of the stratum
+	// prevhash, and nbits holds the compact target of the block.
+	ProtocolStandard
+)
+
+const (
+	extranonce1Size = 2
+	extranonce2Size = 2
+
+	standardJobVersion = 1 // version field of the standard jobs
+)
+
+var (
+	errStandardSubmit  = errors.New("malformed mining.submit")
+	errNoExtranonce    = errors.New("all extranonce1 in use")
+	errCompactOverflow = errors.New("target overflows the compact encoding")
+)
+
+func (m ProtocolMode) String() string {
+	switch m {
+	case ProtocolLegacy:
+		return "legacy"
+	case ProtocolStandard:
+		return "standard"
+	default:
+		return fmt.Sprintf("ProtocolMode(%d)", int(m))
+	}
+}
+
+// ParseProtocolMode parses the name of a protocol mode.
+func ParseProtocolMode(name string) (ProtocolMode, error) {
+	switch name {
+	case "legacy":
+		return ProtocolLegacy, nil
+	case "standard":
+		return ProtocolStandard, nil
+	default:
+		return 0, fmt.Errorf("unknown stratum protocol mode %q", name)
+	}
+}
+
+// toStandardJson returns the mining.notify parameters of the standard flow:
+// job_id, prevhash, coinb1, coinb2, merkle_branch, version, nbits, ntime and
+// clean_jobs. SimpleChain blocks have no coinbase to assemble, so coinb1,
+// coinb2 and the merkle branch are empty.
+func (task *StratumTask) toStandardJson() []interface{} {
+	return []interface{}{
+		strconv.FormatUint(task.Id, 16),
+		hex.EncodeToString(swapWords(task.PowHash.Bytes())),
+		"",
+		"",
+		[]string{},
+		fmt.Sprintf("%08x", standardJobVersion),
+		fmt.Sprintf("%08x", compactTarget(task.Difficulty)),
+		fmt.Sprintf("%08x", uint32(task.Timestamp/1e9)),
+		task.IfClearTask,
+	}
+}
+
+// swapWords reverses the byte order of every 32-bit word, the encoding of the
+// prevhash of mining.notify. It is its own inverse.
+func swapWords(hash []byte) []byte {
+	swapped := make([]byte, len(hash))
+	for i := 0; i+4 <= len(hash); i += 4 {
+		swapped[i], swapped[i+1], swapped[i+2], swapped[i+3] = hash[i+3], hash[i+2], hash[i+1], hash[i]
+	}
+	return swapped
+}
+
+// compactTarget returns the target of a block of the given difficulty in the
+// compact encoding of nbits, or 0 if the difficulty is not positive.
+func compactTarget(difficulty *big.Int) uint32 {
+	if difficulty == nil || difficulty.Sign() <= 0 {
+		return 0
+	}
+	target := new(big.Int).Div(maxUint256, difficulty)
+	compact, err := bigToCompact(target)
+	if err != nil {
+		log.Warn("[Session] invalid block target", "difficulty", difficulty, "error", err)
+	}
+	return compact
+}
+
+// bigToCompact encodes n as a 3 byte mantissa and a 1 byte exponent, the
+// mantissa sign bit being always clear.
+func bigToCompact(n *big.Int) (uint32, error) {
+	if n.Sign() == 0 {
+		return 0, nil
+	}
+	size := uint32(len(n.Bytes()))
+	var mantissa uint32
+	if size <= 3 {
+		mantissa = uint32(n.Uint64()) << (8 * (3 - size))
+	} else {
+		mantissa = uint32(new(big.Int).Rsh(n, uint(8*(size-3))).Uint64())
+	}
+	if mantissa&0x00800000 != 0 {
+		mantissa >>= 8
+		size++
+	}
+	if size > 0xff {
+		return 0, errCompactOverflow
+	}
+	return size<<24 | mantissa, nil
+}
+
+// standardNonce assembles the 64-bit nonce of a standard share.
+func standardNonce(extranonce1 uint16, extranonce2, nonce string) (uint64, error) {
+	if len(extranonce2) != 2*extranonce2Size || len(nonce) != 8 {
+		return 0, errStandardSubmit
+	}
+	en2, err := strconv.ParseUint(extranonce2, 16, 16)
+	if err != nil {
+		return 0, errStandardSubmit
+	}
+	low, err := strconv.ParseUint(nonce, 16, 32)
+	if err != nil {
+		return 0, errStandardSubmit
+	}
+	return uint64(extranonce1)<<48 | en2<<32 | low, nil
+}
+
+// handleStandardSubscribe answers mining.subscribe with the extranonce1 of the
+// session and sends the initial share difficulty.
+func (this *Session) handleStandardSubscribe(req *Request, subscriptionID string) {
+	result := &SubscribeResult{
+		Error: nil,
+		Id:    req.Id,
+		Result: []interface{}{
+			[]interface{}{
+				[]string{"mining.set_difficulty", subscriptionID},
+				[]string{"mining.notify", subscriptionID},
+			},
+			fmt.Sprintf("%0*x", 2*extranonce1Size, this.extranonce1),
+			extranonce2Size,
+		},
+	}
+	this.sendResponse(result)
+	this.sendResponse(&Notify{
+		Method: "mining.set_difficulty",
+		Params: []interface{}{this.vardiff.InitDifficulty},
+	})
+}
+
+// handleStandardSubmit parses the worker, job_id, extranonce2, ntime, nonce
+// parameters of a standard mining.submit.
+func (this *Session) handleStandardSubmit(req *Request) error {
+	if len(req.Params) != 5 {
+		return paramNumbersWrong
+	}
+	var fields [5]string
+	for i, param := range req.Params {
+		field, ok := param.(string)
+		if !ok {
+			log.Warn("[Session] handleStandardSubmit param not a string", "index", i)
+		}
+		fields[i] = strings.TrimPrefix(field, "0x")
+	}
+	taskId, err := strconv.ParseUint(fields[1], 16, 64)
+	if err != nil {
+		this.sendResponse(&Response{Error: &Error{Code: 1, Message: "taskId miss"}, Id: req.Id, Result: false})
+		return nil
+	}
+	nonce, err := standardNonce(this.extranonce1, fields[2], fields[4])
+	if err != nil {
+		this.sendResponse(&Response{Error: &Error{Code: 3, Message: "nonce miss"}, Id: req.Id, Result: false})
+		return nil
+	}
+	select {
+	case this.newNonce <- NonceResult{Nonce: nonce, TaskId: taskId, Id: req.Id}:
+	default:
+		log.Warn("nonce chan block")
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package stratum
+
+import (
+	"bufio"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/simplechain-org/go-simplechain/common"
+	"github.com/simplechain-org/go-simplechain/consensus/scrypt"
+)
+
+func TestStandardNonce(t *testing.T) {
+	tests := []struct {
+		extranonce1 uint16
+		extranonce2 string
+		nonce       string
+		want        uint64
+		err         error
+	}{
+		{0x0102, "0304", "05060708", 0x0102030405060708, nil},
+		{0xffff, "ffff", "ffffffff", 0xffffffffffffffff, nil},
+		{0x0001, "00", "00000001", 0, errStandardSubmit},
+		{0x0001, "0001", "001", 0, errStandardSubmit},
+		{0x0001, "zz00", "00000001", 0, errStandardSubmit},
+	}
+	for i, tt := range tests {
+		nonce, err := standardNonce(tt.extranonce1, tt.extranonce2, tt.nonce)
+		if err != tt.err || nonce != tt.want {
+			t.Errorf("test %d: have %#x, %v; want %#x, %v", i, nonce, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestStandardSubscribe(t *testing.T) {
+	server, client := net.Pipe()
+	defer client.Close()
+
+	session := NewSession(NewSimpleAuth(""), "test", server, ProtocolStandard, DefaultVardiffConfig, nil, false, HashRateLen)
+	session.extranonce1 = 0x0a0b
+	session.Start()
+	defer session.Close()
+
+	if _, err := client.Write([]byte(`{"id":1,"method":"mining.subscribe","params":[]}` + "\n")); err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	client.SetReadDeadline(time.Now().Add(5 * time.Second))
+	reader := bufio.NewReader(client)
+
+	var subscribe struct {
+		Result []interface{} `json:"result"`
+	}
+	line, err := reader.ReadBytes('\n')
+	if err != nil || json.Unmarshal(line, &subscribe) != nil {
+		t.Fatalf("failed to read subscribe result: %v %s", err, line)
+	}
+	if len(subscribe.Result) != 3 || subscribe.Result[1] != "0a0b" || subscribe.Result[2] != float64(extranonce2Size) {
+		t.Errorf("subscribe result mismatch: %v", subscribe.Result)
+	}
+	var notify Notify
+	line, err = reader.ReadBytes('\n')
+	if err != nil || json.Unmarshal(line, &notify) != nil {
+		t.Fatalf("failed to read set_difficulty: %v %s", err, line)
+	}
+	if notify.Method != "mining.set_difficulty" || len(notify.Params) != 1 || notify.Params[0] != float64(DefaultVardiffConfig.InitDifficulty) {
+		t.Errorf("set_difficulty mismatch: %+v", notify)
+	}
+}
+
+func TestCompactTarget(t *testing.T) {
+	tests := []struct {
+		difficulty int64
+		want       uint32
+	}{
+		{1, 0x21010000},          // 2^256
+		{0x10000, 0x1f010000},    // 2^240
+		{0x7fffffff, 0x1d020000}, // rounded down by the mantissa
+		{0, 0},
+	}
+	for i, tt := range tests {
+		if have := compactTarget(big.NewInt(tt.difficulty)); have != tt.want {
+			t.Errorf("test %d: compact target mismatch: have %#x, want %#x", i, have, tt.want)
+		}
+	}
+}
+
+func TestExtranonceCollision(t *testing.T) {
+	server := &Server{
+		extranonces:        make(map[uint16]string),
+		sessionExtranonces: make(map[string]uint16),
+	}
+	for i := 0; i < 1<<16; i++ {
+		if _, err := server.acquireExtranonce(strconv.Itoa(i)); err != nil {
+			t.Fatalf("session %d: failed to acquire extranonce1: %v", i, err)
+		}
+	}
+	if _, err := server.acquireExtranonce("overflow"); err != errNoExtranonce {
+		t.Fatalf("exhausted extranonce1 error mismatch: have %v, want %v", err, errNoExtranonce)
+	}
+	// A closed session frees its extranonce1 for the next one
+	freed := server.sessionExtranonces["1234"]
+	server.releaseExtranonce("1234")
+	if extranonce1, err := server.acquireExtranonce("next"); err != nil || extranonce1 != freed {
+		t.Errorf("freed extranonce1 mismatch: have %#x, %v; want %#x", extranonce1, err, freed)
+	}
+}
+
+// TestStandardMine solves a job the way a standard miner does, from the
+// subscription, the share difficulty and the mining.notify parameters only.
+func TestStandardMine(t *testing.T) {
+	server, client := net.Pipe()
+	defer client.Close()
+
+	vardiff := VardiffConfig{InitDifficulty: 8, MinDifficulty: 8}
+	session := NewSession(NewSimpleAuth(""), "test", server, ProtocolStandard, vardiff, &Server{}, false, HashRateLen)
+	session.extranonce1 = 0x0a0b
+	submitted := make(chan uint64, 1)
+	session.RegisterAuthorizeFunc(func(string) {})
+	session.RegisterSubmitFunc(func(worker string, nonce uint64) { submitted <- nonce })
+	session.Start()
+	defer session.Close()
+
+	client.SetDeadline(time.Now().Add(10 * time.Second))
+	reader := bufio.NewReader(client)
+	call := func(request string) {
+		if _, err := client.Write([]byte(request + "\n")); err != nil {
+			t.Fatalf("failed to send %s: %v", request, err)
+		}
+	}
+	read := func() map[string]interface{} {
+		var msg map[string]interface{}
+		line, err := reader.ReadBytes('\n')
+		if err != nil || json.Unmarshal(line, &msg) != nil {
+			t.Fatalf("failed to read message: %v %s", err, line)
+		}
+		return msg
+	}
+	call(`{"id":1,"method":"mining.subscribe","params":[]}`)
+	extranonce1 := read()["result"].([]interface{})[1].(string)
+	shareDifficulty := read()["params"].([]interface{})[0].(float64)
+	call(`{"id":2,"method":"mining.authorize","params":["rig1","x"]}`)
+	if result := read()["result"]; result != true {
+		t.Fatalf("authorize failed: %v", result)
+	}
+	powHash := common.HexToHash("0x0102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f20")
+	session.dispatchTask(&StratumTask{PowHash: powHash, Difficulty: big.NewInt(1024), Timestamp: time.Now().UnixNano(), IfClearTask: true})
+
+	job := read()
+	if job["method"] != "mining.notify" {
+		t.Fatalf("expected mining.notify, got %v", job)
+	}
+	params := job["params"].([]interface{})
+	prevhash, _ := hex.DecodeString(params[1].(string))
+	if nbits := params[6].(string); nbits != fmt.Sprintf("%08x", compactTarget(big.NewInt(1024))) {
+		t.Errorf("nbits mismatch: have %s", nbits)
+	}
+	// Roll the nonce of the header until the share target is met
+	en1, _ := strconv.ParseUint(extranonce1, 16, 16)
+	en2 := uint64(0x0304)
+	target := new(big.Int).Div(maxUint256, big.NewInt(int64(shareDifficulty)))
+	var nonce uint32
+	for ; ; nonce++ {
+		_, result := scrypt.ScryptHash(swapWords(prevhash), en1<<48|en2<<32|uint64(nonce))
+		if new(big.Int).SetBytes(result).Cmp(target) <= 0 {
+			break
+		}
+	}
+	call(fmt.Sprintf(`{"id":3,"method":"mining.submit","params":["rig1","%s","%04x","%s","%08x"]}`, params[0], en2, params[7], nonce))
+	if response := read(); response["result"] != true {
+		t.Fatalf("share rejected: %v", response)
+	}
+	if have, want := <-submitted, en1<<48|en2<<32|uint64(nonce); have != want {
+		t.Errorf("submitted nonce mismatch: have %#x, want %#x", have, want)
+	}
+}
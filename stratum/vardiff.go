@@ -0,0 +1,84 @@
+package stratum
+
+import (
+	"time"
+)
+
+// VardiffConfig tunes the variable difficulty of the sessions. Each session
+// aims at one share every TargetTime, and is retargeted once its estimated
+// share interval leaves TargetTime by more than Variance (relative).
+type VardiffConfig struct {
+	InitDifficulty uint64        // difficulty of new sessions without saved state
+	MinDifficulty  uint64        // lowest session difficulty
+	MaxDifficulty  uint64        // highest session difficulty, 0 for the network difficulty only
+	TargetTime     time.Duration // share interval sessions aim for
+	Variance       float64       // relative deviation from TargetTime tolerated without retarget
+	RetargetPeriod time.Duration // minimum time between two retargets of a session
+}
+
+var DefaultVardiffConfig = VardiffConfig{
+	InitDifficulty: uint64(InitDifficulty),
+	MinDifficulty:  MinDifficulty,
+	TargetTime:     3 * time.Second,
+	Variance:       0.5,
+	RetargetPeriod: 3 * time.Second,
+}
+
+// sanitize fills the unset fields with their defaults.
+func (c VardiffConfig) sanitize() VardiffConfig {
+	if c.MinDifficulty == 0 {
+		c.MinDifficulty = DefaultVardiffConfig.MinDifficulty
+	}
+	if c.MaxDifficulty != 0 && c.MaxDifficulty < c.MinDifficulty {
+		c.MaxDifficulty = c.MinDifficulty
+	}
+	if c.InitDifficulty == 0 {
+		c.InitDifficulty = DefaultVardiffConfig.InitDifficulty
+	}
+	c.InitDifficulty = c.clamp(c.InitDifficulty)
+	if c.TargetTime <= 0 {
+		c.TargetTime = DefaultVardiffConfig.TargetTime
+	}
+	if c.Variance <= 0 || c.Variance >= 1 {
+		c.Variance = DefaultVardiffConfig.Variance
+	}
+	return c
+}
+
+func (c VardiffConfig) clamp(difficulty uint64) uint64 {
+	if difficulty < c.MinDifficulty {
+		difficulty = c.MinDifficulty
+	}
+	if c.MaxDifficulty != 0 && difficulty > c.MaxDifficulty {
+		difficulty = c.MaxDifficulty
+	}
+	return difficulty
+}
+
+// retarget returns the new difficulty of a session. The hash rate is measured
+// in difficulty per second; without any, the difficulty decays with the time
+// since the last share. The result never exceeds the network difficulty.
+func (c VardiffConfig) retarget(difficulty, hashRate uint64, sinceSubmit time.Duration, serverDifficulty uint64) uint64 {
+	var (
+		target = c.TargetTime.Seconds()
+		low    = target * (1 - c.Variance)
+		high   = target * (1 + c.Variance)
+	)
+	if hashRate == 0 {
+		if elapsed := sinceSubmit.Seconds(); elapsed > high {
+			// No share for too long, assume the share interval is the silence
+			next := float64(difficulty) * target / elapsed
+			if next < float64(difficulty)/4 {
+				next = float64(difficulty) / 4
+			}
+			difficulty = uint64(next)
+		}
+	} else if interval := float64(difficulty) / float64(hashRate); interval < low || interval > high {
+		difficulty = uint64(float64(hashRate) * target)
+	}
+	difficulty = c.clamp(difficulty)
+	if serverDifficulty != 0 && difficulty > serverDifficulty {
+		difficulty = serverDifficulty
+	}
+	return difficulty
+}
@@ -0,0 +1,86 @@
+package stratum
+
+import (
+	"io"
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/simplechain-org/go-simplechain/core/rawdb"
+)
+
+func TestVardiffRetarget(t *testing.T) {
+	config := VardiffConfig{
+		MinDifficulty: 1000,
+		MaxDifficulty: 100000,
+		TargetTime:    10 * time.Second,
+		Variance:      0.3,
+	}.sanitize()
+
+	tests := []struct {
+		difficulty, hashRate uint64
+		silence              time.Duration
+		server               uint64
+		want                 uint64
+	}{
+		{5000, 500, 0, 0, 5000},              // on target
+		{5000, 600, 0, 0, 5000},              // within the variance
+		{5000, 1000, 0, 0, 10000},            // shares too fast
+		{5000, 200, 0, 0, 2000},              // shares too slow
+		{5000, 50, 0, 0, 1000},               // bounded by the minimum
+		{5000, 50000, 0, 0, 100000},          // bounded by the maximum
+		{5000, 1000, 0, 8000, 8000},          // bounded by the network
+		{8000, 0, 5 * time.Second, 0, 8000},  // silent, but not for long
+		{8000, 0, 20 * time.Second, 0, 4000}, // silent, decays
+		{8000, 0, 10 * time.Minute, 0, 2000}, // decays by four at most
+		{1200, 0, 10 * time.Minute, 0, 1000}, // decays down to the minimum
+	}
+	for i, tt := range tests {
+		if have := config.retarget(tt.difficulty, tt.hashRate, tt.silence, tt.server); have != tt.want {
+			t.Errorf("test %d: difficulty mismatch: have %d, want %d", i, have, tt.want)
+		}
+	}
+}
+
+func TestSessionStore(t *testing.T) {
+	db := rawdb.NewMemoryDatabase()
+	store := NewSessionStore(db)
+	if _, ok := store.Load("rig1"); ok {
+		t.Fatalf("unknown worker resumed")
+	}
+	store.Save("rig1", 4242)
+	if difficulty, ok := NewSessionStore(db).Load("rig1"); !ok || difficulty != 4242 {
+		t.Errorf("saved difficulty mismatch: have %d, want %d", difficulty, 4242)
+	}
+	// Expired sessions start over
+	defer func(expiry time.Duration) { SessionExpiry = expiry }(SessionExpiry)
+	SessionExpiry = -time.Second
+	if _, ok := store.Load("rig1"); ok {
+		t.Errorf("expired session resumed")
+	}
+}
+
+func TestSessionResumeWithoutHashRate(t *testing.T) {
+	store := NewSessionStore(rawdb.NewMemoryDatabase())
+	store.Save("rig1", 500000)
+
+	server, client := net.Pipe()
+	defer client.Close()
+	go io.Copy(ioutil.Discard, client)
+
+	session := NewSession(NewSimpleAuth(""), "test", server, ProtocolLegacy, DefaultVardiffConfig, &Server{sessionStore: store}, false, HashRateLen)
+	session.RegisterAuthorizeFunc(func(string) {})
+	session.Start()
+	defer session.Close()
+
+	if _, err := client.Write([]byte(`{"id":1,"method":"mining.authorize","params":["rig1","x"]}` + "\n")); err != nil {
+		t.Fatalf("failed to authorize: %v", err)
+	}
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if session.GetDifficulty() == 500000 {
+			return
+		}
+	}
+	t.Errorf("difficulty not resumed: have %d, want %d", session.GetDifficulty(), 500000)
+}